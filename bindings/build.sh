@@ -28,8 +28,8 @@ cd ..
 echo "Building Go bindings..."
 cd go
 # Copy C library for Go to link against
-cp ../c/target/release/libpath_security_c.so .
-go build -buildmode=c-shared -o libpath_security_go.so path_security.go
+cp ../c/target/release/libpath_security_c.so ../c/path_security.h .
+go build ./...
 cd ..
 
 # Build Java bindings
@@ -45,5 +45,5 @@ echo "To test the bindings:"
 echo "  Node.js: cd nodejs && npm test"
 echo "  Python:  cd python && python test.py"
 echo "  C:       cd c && gcc -o test test.c -Ltarget/release -lpath_security_c && ./test"
-echo "  Go:      cd go && go run test.go"
+echo "  Go:      cd go && go test ./..."
 echo "  Java:    cd java && mvn compile exec:java -Dexec.mainClass=com.asgardtech.pathsecurity.PathSecurityTest"
@@ -5,6 +5,14 @@
 extern "C" {
 #endif
 
+/*
+ * Functions return 0 on success or one of these negative codes:
+ *   -1  invalid argument: an input or result pointer is NULL
+ *   -2  invalid UTF-8: the input is not valid UTF-8
+ *   -3  internal error: the JSON result could not be encoded as a C string
+ *   -4  buffer too small: the result does not fit result_len bytes
+ */
+
 /**
  * Validate a file path for security issues
  * @param path Input path to validate
@@ -14,6 +22,16 @@ extern "C" {
  */
 int path_security_validate_path(const char* path, char* result, size_t result_len);
 
+/**
+ * Validate several file paths in one call
+ * @param paths Input paths, packed back to back and each terminated by a NUL byte
+ * @param count Number of paths in the buffer
+ * @param result Buffer to store a JSON array of validation results, in input order
+ * @param result_len Size of result buffer
+ * @return 0 on success, negative on error
+ */
+int path_security_validate_paths(const char* paths, size_t count, char* result, size_t result_len);
+
 /**
  * Detect if a path contains traversal patterns
  * @param path Input path to check
@@ -48,6 +66,19 @@ int path_security_sanitize_filename(const char* filename, char* result, size_t r
  */
 int path_security_validate_project_name(const char* name, char* result, size_t result_len);
 
+/**
+ * Get the version of the library
+ * @return Static NUL-terminated version string, such as "0.1.0"
+ */
+const char* path_security_version(void);
+
+/**
+ * Get the checks the validation functions perform
+ * @return Static NUL-terminated, comma-separated list such as
+ *         "traversal,encoding,unicode"
+ */
+const char* path_security_capabilities(void);
+
 #ifdef __cplusplus
 }
 #endif
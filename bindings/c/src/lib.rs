@@ -225,4 +225,82 @@ pub extern "C" fn path_security_validate_project_name(
         std::ptr::copy_nonoverlapping(bytes.as_ptr(), result as *mut u8, bytes.len());
     }
     0
-}
\ No newline at end of file
+}
+
+#[no_mangle]
+pub extern "C" fn path_security_validate_paths(
+    paths: *const c_char,
+    count: usize,
+    result: *mut c_char,
+    result_len: usize,
+) -> i32 {
+    if paths.is_null() || result.is_null() {
+        return -1;
+    }
+
+    let validator = PathValidator::new()
+        .with_traversal_detection(true)
+        .with_encoding_detection(true)
+        .with_unicode_detection(true);
+
+    // The paths are packed back to back, each terminated by a NUL byte
+    let mut documents = Vec::with_capacity(count);
+    let mut cursor = paths;
+    for _ in 0..count {
+        let path_cstr = unsafe { CStr::from_ptr(cursor) };
+        cursor = unsafe { cursor.add(path_cstr.to_bytes_with_nul().len()) };
+
+        let path_str = match path_cstr.to_str() {
+            Ok(s) => s,
+            Err(_) => return -2,
+        };
+
+        documents.push(match validator.validate_path(path_str) {
+            Ok(validated_path) => {
+                serde_json::json!({
+                    "valid": true,
+                    "path": validated_path,
+                    "sanitized": true
+                })
+            }
+            Err(error) => {
+                serde_json::json!({
+                    "valid": false,
+                    "error": error.to_string(),
+                    "path": path_str
+                })
+            }
+        });
+    }
+
+    let json_string = serde_json::Value::Array(documents).to_string();
+    let c_string = match CString::new(json_string) {
+        Ok(s) => s,
+        Err(_) => return -3,
+    };
+
+    let bytes = c_string.as_bytes_with_nul();
+    if bytes.len() > result_len {
+        return -4; // Buffer too small
+    }
+
+    unsafe {
+        std::ptr::copy_nonoverlapping(bytes.as_ptr(), result as *mut u8, bytes.len());
+    }
+    0
+}
+
+/// Checks performed by `path_security_validate_path` and
+/// `path_security_validate_paths`, comma-separated. Symlink resolution is
+/// not among them, as the C API takes no base directory.
+const CAPABILITIES: &str = "traversal,encoding,unicode,protocols,windows,batch\0";
+
+#[no_mangle]
+pub extern "C" fn path_security_version() -> *const c_char {
+    concat!(env!("CARGO_PKG_VERSION"), "\0").as_ptr() as *const c_char
+}
+
+#[no_mangle]
+pub extern "C" fn path_security_capabilities() -> *const c_char {
+    CAPABILITIES.as_ptr() as *const c_char
+}
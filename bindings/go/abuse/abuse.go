@@ -0,0 +1,188 @@
+// Package abuse counts rejected paths per client over a sliding window, so
+// servers can escalate from rejecting single requests to temporarily
+// blocking clients that are clearly scanning for traversal. Clients are
+// identified by a key the caller chooses, such as a remote address, an API
+// key or a session ID.
+package abuse
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults used unless the corresponding option is given
+const (
+	DefaultWindow    = time.Minute
+	DefaultThreshold = 10
+)
+
+// Option configures a Tracker
+type Option func(*Tracker)
+
+// WithWindow sets how far back rejections count towards the threshold. The
+// default is DefaultWindow.
+func WithWindow(window time.Duration) Option {
+	return func(t *Tracker) {
+		t.window = window
+	}
+}
+
+// WithThreshold sets how many rejections within the window make a client
+// abusive. The default is DefaultThreshold; values below 1 are treated
+// as 1.
+func WithThreshold(rejections int) Option {
+	return func(t *Tracker) {
+		t.threshold = rejections
+	}
+}
+
+// WithBlockDuration sets how long a client stays abusive once it reaches
+// the threshold, even if its rejections age out of the window sooner. The
+// default is the window.
+func WithBlockDuration(duration time.Duration) Option {
+	return func(t *Tracker) {
+		t.block = duration
+	}
+}
+
+// WithClock sets the function used to read the current time, for tests
+// and simulations. The default is time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(t *Tracker) {
+		t.now = now
+	}
+}
+
+// Tracker records rejections per client key. It is safe for concurrent
+// use. Memory is bounded by the number of clients seen within the last
+// window or block, each holding at most threshold timestamps.
+type Tracker struct {
+	window    time.Duration
+	threshold int
+	block     time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastSweep time.Time
+}
+
+// client is the rejection history of one key
+type client struct {
+	// rejections holds the most recent rejection times, oldest first
+	rejections   []time.Time
+	blockedUntil time.Time
+}
+
+// New returns a Tracker with no recorded rejections
+func New(opts ...Option) *Tracker {
+	t := &Tracker{
+		window:    DefaultWindow,
+		threshold: DefaultThreshold,
+		block:     -1,
+		now:       time.Now,
+		clients:   make(map[string]*client),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.threshold < 1 {
+		t.threshold = 1
+	}
+	if t.block < 0 {
+		t.block = t.window
+	}
+	t.lastSweep = t.now()
+	return t
+}
+
+// Record counts one rejection for key and reports whether the client is
+// abusive afterwards
+func (t *Tracker) Record(key string) bool {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sweep(now)
+	c := t.clients[key]
+	if c == nil {
+		c = &client{}
+		t.clients[key] = c
+	}
+	c.prune(now.Add(-t.window))
+	if len(c.rejections) == t.threshold {
+		c.rejections = append(c.rejections[:0], c.rejections[1:]...)
+	}
+	c.rejections = append(c.rejections, now)
+	if len(c.rejections) == t.threshold {
+		c.blockedUntil = now.Add(t.block)
+	}
+	return c.abusive(now, t.window, t.threshold)
+}
+
+// IsAbusive reports whether key has reached the threshold within the
+// window or is still blocked from having reached it earlier
+func (t *Tracker) IsAbusive(key string) bool {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.clients[key]
+	return c != nil && c.abusive(now, t.window, t.threshold)
+}
+
+// Rejections returns how many rejections of key fall within the window,
+// counting at most the threshold
+func (t *Tracker) Rejections(key string) int {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.clients[key]
+	if c == nil {
+		return 0
+	}
+	c.prune(now.Add(-t.window))
+	return len(c.rejections)
+}
+
+// Reset forgets the rejections of key and lifts any block on it
+func (t *Tracker) Reset(key string) {
+	t.mu.Lock()
+	delete(t.clients, key)
+	t.mu.Unlock()
+}
+
+// sweep drops clients with nothing left in the window and no block, at
+// most once per window so recording stays cheap
+func (t *Tracker) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.window {
+		return
+	}
+	t.lastSweep = now
+	cutoff := now.Add(-t.window)
+	for key, c := range t.clients {
+		c.prune(cutoff)
+		if len(c.rejections) == 0 && !now.Before(c.blockedUntil) {
+			delete(t.clients, key)
+		}
+	}
+}
+
+// prune drops rejections at or before cutoff
+func (c *client) prune(cutoff time.Time) {
+	i := 0
+	for i < len(c.rejections) && !c.rejections[i].After(cutoff) {
+		i++
+	}
+	c.rejections = append(c.rejections[:0], c.rejections[i:]...)
+}
+
+// abusive reports whether the client is blocked or has threshold
+// rejections within the window ending at now
+func (c *client) abusive(now time.Time, window time.Duration, threshold int) bool {
+	if now.Before(c.blockedUntil) {
+		return true
+	}
+	return len(c.rejections) == threshold && c.rejections[0].After(now.Add(-window))
+}
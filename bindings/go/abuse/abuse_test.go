@@ -0,0 +1,57 @@
+package abuse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := New(WithThreshold(3), WithWindow(time.Minute), WithBlockDuration(5*time.Minute), WithClock(func() time.Time { return clock }))
+	const key = "203.0.113.7"
+
+	for i, want := range []bool{false, false, true} {
+		if got := tracker.Record(key); got != want {
+			t.Errorf("Record %d = %t, want %t", i, got, want)
+		}
+	}
+	if got := tracker.Rejections(key); got != 3 {
+		t.Errorf("Rejections = %d, want 3", got)
+	}
+	if tracker.IsAbusive("198.51.100.1") {
+		t.Error("IsAbusive of an unseen client = true")
+	}
+
+	// The block outlives the window the rejections were counted in
+	clock = clock.Add(2 * time.Minute)
+	if got := tracker.Rejections(key); got != 0 {
+		t.Errorf("Rejections after the window = %d, want 0", got)
+	}
+	if !tracker.IsAbusive(key) {
+		t.Error("IsAbusive after the window = false, want blocked")
+	}
+
+	clock = clock.Add(4 * time.Minute)
+	if tracker.IsAbusive(key) {
+		t.Error("IsAbusive after the block = true")
+	}
+}
+
+func TestTrackerWindow(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := New(WithThreshold(2), WithWindow(time.Minute), WithClock(func() time.Time { return clock }))
+
+	tracker.Record("a")
+	clock = clock.Add(90 * time.Second)
+	if tracker.Record("a") {
+		t.Error("Record counted a rejection from outside the window")
+	}
+	if !tracker.Record("a") {
+		t.Error("Record within the window did not reach the threshold")
+	}
+
+	tracker.Reset("a")
+	if tracker.IsAbusive("a") || tracker.Rejections("a") != 0 {
+		t.Error("Reset left the client abusive")
+	}
+}
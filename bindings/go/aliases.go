@@ -0,0 +1,65 @@
+package pathsecurity
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxAliases bounds the number of spellings returned by Aliases
+const maxAliases = 64
+
+// Aliases returns the spellings of a path that canonicalize to the same
+// value under the instance's configuration, starting with the canonical form
+// itself. Candidates are built from case variants (lower, upper, title and
+// the input's own casing), forward or backward separators and an optional
+// trailing slash; only those that Canonicalize accepts and maps back to the
+// canonical form are kept, so case variants appear only with
+// WithCaseInsensitive and backslash variants only with
+// WithNormalizeSeparators, when the backend accepts backslashes. Dot segments and
+// repeated separators are not enumerated. At most maxAliases entries are
+// returned.
+func (ps *PathSecurity) Aliases(p string) ([]string, error) {
+	canonical, err := ps.Canonicalize(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// The input was validated by Canonicalize, so preprocessing cannot fail here
+	expanded, _ := ps.preprocess(p)
+	cfg := ps.cfg
+	cfg.caseInsensitive = false
+	spelled := cfg.canonicalize(expanded)
+
+	cases := []string{canonical, spelled, strings.ToLower(canonical), strings.ToUpper(canonical), titleSegments(canonical)}
+	quiet := ps.quiet()
+	seen := make(map[string]bool)
+	aliases := []string{}
+	for _, c := range cases {
+		for _, sep := range []string{"/", `\`} {
+			s := strings.ReplaceAll(c, "/", sep)
+			for _, alias := range []string{s, strings.TrimSuffix(s, sep) + sep} {
+				if len(aliases) == maxAliases || seen[alias] {
+					continue
+				}
+				seen[alias] = true
+				if aliased, err := quiet.Canonicalize(alias); err != nil || aliased != canonical {
+					continue
+				}
+				aliases = append(aliases, alias)
+			}
+		}
+	}
+	return aliases, nil
+}
+
+// titleSegments upper-cases the first letter of every slash-separated segment
+func titleSegments(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		for j, r := range segment {
+			segments[i] = segment[:j] + string(unicode.ToUpper(r)) + segment[j+len(string(r)):]
+			break
+		}
+	}
+	return strings.Join(segments, "/")
+}
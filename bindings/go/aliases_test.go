@@ -0,0 +1,72 @@
+package pathsecurity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		path string
+		want []string
+	}{
+		{
+			name: "case-sensitive",
+			path: "/Srv/Assets/Logo.PNG",
+			want: []string{"/Srv/Assets/Logo.PNG", "/Srv/Assets/Logo.PNG/"},
+		},
+		{
+			name: "case-insensitive",
+			opts: []Option{WithCaseInsensitive(true)},
+			path: "/Srv/Assets/Logo.PNG",
+			want: []string{
+				"/srv/assets/logo.png", "/srv/assets/logo.png/",
+				"/Srv/Assets/Logo.PNG", "/Srv/Assets/Logo.PNG/",
+				"/SRV/ASSETS/LOGO.PNG", "/SRV/ASSETS/LOGO.PNG/",
+				"/Srv/Assets/Logo.png", "/Srv/Assets/Logo.png/",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := NewPathSecurity(tt.opts...)
+			got, err := ps.Aliases(tt.path)
+			if err != nil {
+				t.Fatalf("Aliases(%q) error = %v", tt.path, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Aliases(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+			canonical, _ := ps.Canonicalize(tt.path)
+			for _, alias := range got {
+				if c, err := ps.Canonicalize(alias); err != nil || c != canonical {
+					t.Errorf("Canonicalize(%q) = %q, %v, want %q", alias, c, err, canonical)
+				}
+			}
+		})
+	}
+}
+
+func TestAliasesSeparators(t *testing.T) {
+	ps := NewPathSecurity(WithCaseInsensitive(true), WithNormalizeSeparators(true))
+	aliases, err := ps.Aliases("/Srv/Assets/Logo.PNG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aliases) < 8 {
+		t.Errorf("Aliases = %q, want at least the 8 slash-separated spellings", aliases)
+	}
+	for _, alias := range aliases {
+		if c, err := ps.Canonicalize(alias); err != nil || c != "/srv/assets/logo.png" {
+			t.Errorf("Canonicalize(%q) = %q, %v, want %q", alias, c, err, "/srv/assets/logo.png")
+		}
+	}
+}
+
+func TestAliasesRejected(t *testing.T) {
+	if _, err := NewPathSecurity().Aliases("../../etc/passwd"); err == nil {
+		t.Error("Aliases accepted a traversal")
+	}
+}
@@ -0,0 +1,494 @@
+// Package archive extracts zip and tar archives without letting entry names
+// or symlinks write outside the destination directory (zip-slip).
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+var (
+	// ErrUnsupportedEntry is returned for archive entries that are neither
+	// regular files, directories nor links, such as device nodes and FIFOs
+	ErrUnsupportedEntry = errors.New("unsupported archive entry type")
+
+	// ErrLinkDepth is returned when resolving a link target follows more
+	// symlinks than WithMaxLinkDepth allows
+	ErrLinkDepth = errors.New("archive link chain too deep")
+
+	// ErrLinkTarget is returned for hard links to anything but a file
+	// extracted earlier from the same archive
+	ErrLinkTarget = errors.New("hard link target is not an extracted file")
+)
+
+// DefaultMaxLinkDepth is the number of symlinks a link target may pass
+// through unless WithMaxLinkDepth is given
+const DefaultMaxLinkDepth = 8
+
+// Option configures extraction
+type Option func(*config)
+
+// config holds the extraction settings
+type config struct {
+	ps           *pathsecurity.PathSecurity
+	maxLinkDepth int
+}
+
+// WithPathSecurity sets the validator used for entry names. The default is
+// pathsecurity.NewPathSecurity() with no options.
+func WithPathSecurity(ps *pathsecurity.PathSecurity) Option {
+	return func(c *config) {
+		c.ps = ps
+	}
+}
+
+// WithMaxLinkDepth sets how many symlinks extracted from the archive a
+// symlink or hard link target may pass through, counting links inside
+// links. The default is DefaultMaxLinkDepth; zero allows no chains.
+func WithMaxLinkDepth(depth int) Option {
+	return func(c *config) {
+		c.maxLinkDepth = depth
+	}
+}
+
+// newConfig applies opts to the default settings
+func newConfig(opts []Option) config {
+	cfg := config{maxLinkDepth: DefaultMaxLinkDepth}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.ps == nil {
+		cfg.ps = pathsecurity.NewPathSecurity()
+	}
+	return cfg
+}
+
+// SafeExtractPath returns the path under destDir that an archive entry
+// named entryName should be written to. '\' in entry names is read as a
+// separator, as archives created on Windows use it. Absolute names, drive
+// letters and names that fail validation or would land outside destDir are
+// rejected.
+func SafeExtractPath(destDir, entryName string, opts ...Option) (string, error) {
+	cfg := newConfig(opts)
+	return cfg.extractPath(destDir, entryName)
+}
+
+// extractPath implements SafeExtractPath
+func (c *config) extractPath(destDir, entryName string) (string, error) {
+	name := strings.ReplaceAll(entryName, `\`, "/")
+	if isAbsoluteName(name) {
+		return "", &pathsecurity.ValidationError{Path: entryName, Reason: "Absolute archive entry name", Err: pathsecurity.ErrAbsolutePath}
+	}
+	if strings.Trim(name, "/") == "" {
+		return "", &pathsecurity.ValidationError{Path: entryName, Reason: "Empty archive entry name", Err: pathsecurity.ErrInvalidPath}
+	}
+	return c.ps.ResolveWithin(destDir, name)
+}
+
+// isAbsoluteName reports whether a slash-separated entry name is absolute
+// or starts with a Windows drive letter
+func isAbsoluteName(name string) bool {
+	if strings.HasPrefix(name, "/") {
+		return true
+	}
+	return len(name) >= 2 && name[1] == ':' &&
+		('a' <= name[0] && name[0] <= 'z' || 'A' <= name[0] && name[0] <= 'Z')
+}
+
+// CheckLinkTarget rejects the target of a symlink entry named entryName
+// if it is absolute or leaves the archive root when resolved lexically
+// against the directory of entryName. It cannot see symlinks among other
+// entries; SafeUnzip and SafeUntar also follow those.
+func CheckLinkTarget(entryName, target string) error {
+	slashed := strings.ReplaceAll(target, `\`, "/")
+	if isAbsoluteName(slashed) {
+		return &pathsecurity.ValidationError{Path: entryName, Reason: fmt.Sprintf("Absolute symlink target %q", target), Err: pathsecurity.ErrSymlinkEscape}
+	}
+	resolved := path.Join(path.Dir(strings.ReplaceAll(entryName, `\`, "/")), slashed)
+	if resolved == ".." || strings.HasPrefix(resolved, "../") {
+		return &pathsecurity.ValidationError{Path: entryName, Reason: fmt.Sprintf("Symlink target %q leaves the archive", target), Err: pathsecurity.ErrSymlinkEscape}
+	}
+	return nil
+}
+
+// extractor writes entries below a destination directory. Entries are
+// keyed by their slash-separated name with the symlinks of earlier entries
+// in their directory resolved. through records, for every name a symlink
+// target was resolved through, the keys of those symlinks, so they can be
+// checked again when a later entry makes that name a symlink.
+type extractor struct {
+	cfg      config
+	destDir  string
+	realDest string
+	links    map[string]string
+	files    map[string]bool
+	through  map[string]map[string]bool
+}
+
+// newExtractor creates destDir if needed and resolves its real location
+func newExtractor(destDir string, opts []Option) (*extractor, error) {
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(destDir)
+	if err != nil {
+		return nil, err
+	}
+	realDest, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, err
+	}
+	return &extractor{cfg: newConfig(opts), destDir: destDir, realDest: realDest, links: make(map[string]string), files: make(map[string]bool), through: make(map[string]map[string]bool)}, nil
+}
+
+// resolveEntry resolves the directory of an entry name through the
+// symlinks of earlier entries and returns the entry's key
+func (e *extractor) resolveEntry(entryName string) (string, error) {
+	name := strings.Trim(strings.ReplaceAll(entryName, `\`, "/"), "/")
+	depth := 0
+	dir, err := e.resolveLinks(entryName, "", path.Dir(name), &depth, nil)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dir, path.Base(name)), nil
+}
+
+// resolveLinks walks rel from base, both inside the archive root, one
+// component at a time, following the symlinks of earlier entries as the
+// file system would, and returns where it ends, "" being the root. Every
+// name looked up on the way is appended to trace unless it is nil.
+func (e *extractor) resolveLinks(entryName, base, rel string, depth *int, trace *[]string) (string, error) {
+	resolved := base
+	for _, component := range strings.Split(rel, "/") {
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			if resolved == "" {
+				return "", &pathsecurity.ValidationError{Path: entryName, Reason: "Link target leaves the archive through earlier symlinks", Err: pathsecurity.ErrSymlinkEscape}
+			}
+			if resolved = path.Dir(resolved); resolved == "." {
+				resolved = ""
+			}
+			continue
+		}
+
+		next := path.Join(resolved, component)
+		if trace != nil {
+			*trace = append(*trace, next)
+		}
+		target, ok := e.links[next]
+		if !ok {
+			resolved = next
+			continue
+		}
+		if *depth++; *depth > e.cfg.maxLinkDepth {
+			return "", &pathsecurity.ValidationError{Path: entryName, Reason: fmt.Sprintf("Link target passes through more than %d symlinks", e.cfg.maxLinkDepth), Err: ErrLinkDepth}
+		}
+		var err error
+		if resolved, err = e.resolveLinks(entryName, resolved, target, depth, trace); err != nil {
+			return "", err
+		}
+	}
+	return resolved, nil
+}
+
+// prepareParent creates the parent directory of target and checks that,
+// with symlinks created by earlier entries followed, it is still inside
+// the destination
+func (e *extractor) prepareParent(entryName, target string) error {
+	return e.prepareDir(entryName, filepath.Dir(target))
+}
+
+// prepareDir creates dir and any missing parents. The deepest ancestor of
+// dir that already exists is checked to resolve inside the destination
+// before anything is created, so a symlink planted by an earlier entry or
+// already in the destination cannot have directories made through it
+// outside, and dir itself is checked once it exists.
+func (e *extractor) prepareDir(entryName, dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	existing := dir
+	for {
+		_, err := os.Lstat(existing)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) || filepath.Dir(existing) == existing {
+			return err
+		}
+		existing = filepath.Dir(existing)
+	}
+	if err := e.checkInside(entryName, existing); err != nil {
+		return err
+	}
+	if existing == dir {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return e.checkInside(entryName, dir)
+}
+
+// checkInside checks that dir, with symlinks followed, is the destination
+// or below it
+func (e *extractor) checkInside(entryName, dir string) error {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(e.realDest, realDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return &pathsecurity.ValidationError{Path: entryName, Reason: fmt.Sprintf("Directory resolves to %q", realDir), Err: pathsecurity.ErrSymlinkEscape}
+	}
+	return nil
+}
+
+// writeFile writes the contents of r to a new file for entryName
+func (e *extractor) writeFile(entryName string, mode fs.FileMode, r io.Reader) error {
+	target, err := e.cfg.extractPath(e.destDir, entryName)
+	if err != nil {
+		return err
+	}
+	if err := e.prepareParent(entryName, target); err != nil {
+		return err
+	}
+
+	key, err := e.resolveEntry(entryName)
+	if err != nil {
+		return err
+	}
+
+	// O_EXCL refuses to follow a symlink planted at the target itself
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+	e.files[key] = true
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// makeDir creates the directory for entryName
+func (e *extractor) makeDir(entryName string) error {
+	target, err := e.cfg.extractPath(e.destDir, entryName)
+	if err != nil {
+		return err
+	}
+	return e.prepareDir(entryName, target)
+}
+
+// makeSymlink creates a symlink for entryName after checking its target,
+// both as written and through the symlinks of earlier entries. Earlier
+// symlinks whose targets were resolved through the new name are checked
+// again, as they now pass through the new symlink too.
+func (e *extractor) makeSymlink(entryName, linkTarget string) error {
+	target, err := e.cfg.extractPath(e.destDir, entryName)
+	if err != nil {
+		return err
+	}
+	if err := CheckLinkTarget(entryName, linkTarget); err != nil {
+		return err
+	}
+	key, err := e.resolveEntry(entryName)
+	if err != nil {
+		return err
+	}
+	slashed := strings.ReplaceAll(linkTarget, `\`, "/")
+	e.links[key] = slashed
+	if err := e.checkLinks(entryName, key); err != nil {
+		delete(e.links, key)
+		return err
+	}
+	if err := e.prepareParent(entryName, target); err != nil {
+		delete(e.links, key)
+		return err
+	}
+	if err := os.Symlink(filepath.FromSlash(slashed), target); err != nil {
+		delete(e.links, key)
+		return err
+	}
+	return nil
+}
+
+// checkLinks checks the symlink with the given key and the earlier ones
+// resolved through its name
+func (e *extractor) checkLinks(entryName, key string) error {
+	if err := e.checkLink(entryName, key); err != nil {
+		return err
+	}
+	for earlier := range e.through[key] {
+		if err := e.checkLink(entryName, earlier); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkLink resolves the target of the symlink with the given key through
+// the symlinks extracted so far and records the names it passes through
+func (e *extractor) checkLink(entryName, key string) error {
+	dir := path.Dir(key)
+	if dir == "." {
+		dir = ""
+	}
+	var trace []string
+	depth := 0
+	if _, err := e.resolveLinks(entryName, dir, e.links[key], &depth, &trace); err != nil {
+		return err
+	}
+	for _, name := range trace {
+		if e.through[name] == nil {
+			e.through[name] = make(map[string]bool)
+		}
+		e.through[name][key] = true
+	}
+	return nil
+}
+
+// makeHardLink links entryName to the earlier file entry linkName, so the
+// link cannot reach files that were in the destination before or are
+// outside it
+func (e *extractor) makeHardLink(entryName, linkName string) error {
+	target, err := e.cfg.extractPath(e.destDir, entryName)
+	if err != nil {
+		return err
+	}
+	source, err := e.cfg.extractPath(e.destDir, linkName)
+	if err != nil {
+		return err
+	}
+	sourceKey, err := e.resolveEntry(linkName)
+	if err != nil {
+		return err
+	}
+	if !e.files[sourceKey] {
+		return &pathsecurity.ValidationError{Path: entryName, Reason: fmt.Sprintf("Hard link target %q is not a file extracted from the archive", linkName), Err: ErrLinkTarget}
+	}
+	key, err := e.resolveEntry(entryName)
+	if err != nil {
+		return err
+	}
+	if err := e.prepareParent(linkName, source); err != nil {
+		return err
+	}
+	if err := e.prepareParent(entryName, target); err != nil {
+		return err
+	}
+	if err := os.Link(source, target); err != nil {
+		return err
+	}
+	e.files[key] = true
+	return nil
+}
+
+// SafeUnzip extracts the zip archive at zipPath into destDir, creating it
+// if needed. Every entry name is checked with SafeExtractPath, symlink
+// entries must point inside the archive, also when their target passes
+// through symlinks of other entries, earlier or later, which it may do at
+// most WithMaxLinkDepth times, and files are never written through existing
+// files or symlinks. Extraction stops at the first unsafe entry; entries
+// extracted before it are left in place.
+func SafeUnzip(zipPath, destDir string, opts ...Option) error {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	e, err := newExtractor(destDir, opts)
+	if err != nil {
+		return err
+	}
+	for _, f := range r.File {
+		if err := e.unzipEntry(f); err != nil {
+			return fmt.Errorf("extracting %q: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// unzipEntry extracts a single zip entry
+func (e *extractor) unzipEntry(f *zip.File) error {
+	mode := f.Mode()
+	if mode.IsDir() {
+		return e.makeDir(f.Name)
+	}
+	if mode&^fs.ModePerm != 0 && mode&fs.ModeSymlink == 0 {
+		return fmt.Errorf("%w: %v", ErrUnsupportedEntry, mode.Type())
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if mode&fs.ModeSymlink != 0 {
+		linkTarget, err := io.ReadAll(io.LimitReader(rc, 4096))
+		if err != nil {
+			return err
+		}
+		return e.makeSymlink(f.Name, string(linkTarget))
+	}
+	return e.writeFile(f.Name, mode, rc)
+}
+
+// SafeUntar extracts the uncompressed tar stream r into destDir, creating
+// it if needed, with the same checks as SafeUnzip. Hard link entries must
+// name a file extracted earlier from the archive. Wrap r with gzip.NewReader or similar
+// for compressed archives.
+func SafeUntar(r io.Reader, destDir string, opts ...Option) error {
+	e, err := newExtractor(destDir, opts)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := e.untarEntry(hdr, tr); err != nil {
+			return fmt.Errorf("extracting %q: %w", hdr.Name, err)
+		}
+	}
+}
+
+// untarEntry extracts a single tar entry
+func (e *extractor) untarEntry(hdr *tar.Header, r io.Reader) error {
+	switch hdr.Typeflag {
+	case tar.TypeXGlobalHeader:
+		return nil
+	case tar.TypeDir:
+		return e.makeDir(hdr.Name)
+	case tar.TypeReg, tar.TypeRegA:
+		return e.writeFile(hdr.Name, hdr.FileInfo().Mode(), r)
+	case tar.TypeSymlink:
+		return e.makeSymlink(hdr.Name, hdr.Linkname)
+	case tar.TypeLink:
+		return e.makeHardLink(hdr.Name, hdr.Linkname)
+	default:
+		return fmt.Errorf("%w: type %q", ErrUnsupportedEntry, hdr.Typeflag)
+	}
+}
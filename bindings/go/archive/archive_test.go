@@ -0,0 +1,202 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+// destDir returns an empty directory for extraction. It is relative to the
+// package directory rather than under os.TempDir, which the pure-Go engine
+// rejects as a sensitive location.
+func destDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp(".", "extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestSafeExtractPath(t *testing.T) {
+	dest := destDir(t)
+	tests := []struct {
+		entry   string
+		want    string
+		wantErr error
+	}{
+		{entry: "docs/readme.txt", want: filepath.Join(dest, "docs", "readme.txt")},
+		{entry: "../../etc/cron.d/evil", wantErr: pathsecurity.ErrTraversalDetected},
+		{entry: "/etc/passwd", wantErr: pathsecurity.ErrAbsolutePath},
+		{entry: `..\..\windows\evil.dll`, wantErr: pathsecurity.ErrTraversalDetected},
+	}
+	for _, tt := range tests {
+		got, err := SafeExtractPath(dest, tt.entry)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("SafeExtractPath(%q) = %q, %v, want %v", tt.entry, got, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("SafeExtractPath(%q) = %q, want %q", tt.entry, got, tt.want)
+		}
+	}
+}
+
+func TestCheckLinkTarget(t *testing.T) {
+	tests := []struct {
+		entry, target string
+		wantErr       error
+	}{
+		{entry: "docs/latest", target: "v2/readme.txt"},
+		{entry: "docs/up", target: ".."},
+		{entry: "docs/up", target: "../../etc", wantErr: pathsecurity.ErrSymlinkEscape},
+		{entry: "abs", target: "/etc/passwd", wantErr: pathsecurity.ErrSymlinkEscape},
+	}
+	for _, tt := range tests {
+		if err := CheckLinkTarget(tt.entry, tt.target); !errors.Is(err, tt.wantErr) {
+			t.Errorf("CheckLinkTarget(%q, %q) = %v, want %v", tt.entry, tt.target, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSafeUnzip(t *testing.T) {
+	dir := destDir(t)
+	write := func(name string, entries ...string) string {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, entry := range entries {
+			w, err := zw.Create(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte("<html></html>"))
+		}
+		zw.Close()
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	if err := SafeUnzip(write("ok.zip", "site/index.html", "site/css/app.css"), filepath.Join(dir, "ok")); err != nil {
+		t.Fatalf("SafeUnzip = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "ok", "site", "index.html")); err != nil || string(data) != "<html></html>" {
+		t.Errorf("extracted index.html = %q, %v", data, err)
+	}
+
+	if err := SafeUnzip(write("evil.zip", "site/index.html", "../escaped.html"), filepath.Join(dir, "evil")); !errors.Is(err, pathsecurity.ErrTraversalDetected) {
+		t.Errorf("SafeUnzip of an escaping entry = %v, want %v", err, pathsecurity.ErrTraversalDetected)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped.html")); !os.IsNotExist(err) {
+		t.Errorf("escaping entry was written: %v", err)
+	}
+}
+
+func TestSafeUntar(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []tar.Header
+		opts    []Option
+		wantErr error
+	}{
+		{name: "ok", headers: []tar.Header{
+			{Name: "data/a.txt", Typeflag: tar.TypeReg, Mode: 0o644},
+			{Name: "current", Typeflag: tar.TypeSymlink, Linkname: "data"},
+			{Name: "copy.txt", Typeflag: tar.TypeLink, Linkname: "current/a.txt"},
+		}},
+		{name: "absolute symlink", headers: []tar.Header{
+			{Name: "etc-link", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+		}, wantErr: pathsecurity.ErrSymlinkEscape},
+		{name: "chain escape", headers: []tar.Header{
+			{Name: "sub/up", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "escape", Typeflag: tar.TypeSymlink, Linkname: "sub/up/.."},
+		}, wantErr: pathsecurity.ErrSymlinkEscape},
+		{name: "later link under earlier target", headers: []tar.Header{
+			{Name: "l", Typeflag: tar.TypeSymlink, Linkname: "d/.."},
+			{Name: "d", Typeflag: tar.TypeSymlink, Linkname: "."},
+		}, wantErr: pathsecurity.ErrSymlinkEscape},
+		{name: "hard link outside", headers: []tar.Header{
+			{Name: "passwd", Typeflag: tar.TypeLink, Linkname: "../../../etc/passwd"},
+		}, wantErr: pathsecurity.ErrTraversalDetected},
+		{name: "hard link unknown", headers: []tar.Header{
+			{Name: "copy", Typeflag: tar.TypeLink, Linkname: "preexisting.txt"},
+		}, wantErr: ErrLinkTarget},
+		{name: "deep", headers: []tar.Header{
+			{Name: "l0", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "l1", Typeflag: tar.TypeSymlink, Linkname: "l0/l0"},
+			{Name: "l2", Typeflag: tar.TypeSymlink, Linkname: "l1/l1"},
+		}, opts: []Option{WithMaxLinkDepth(2)}, wantErr: ErrLinkDepth},
+		{name: "fifo", headers: []tar.Header{
+			{Name: "pipe", Typeflag: tar.TypeFifo},
+		}, wantErr: ErrUnsupportedEntry},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, hdr := range tt.headers {
+			if hdr.Typeflag == tar.TypeReg {
+				hdr.Size = 2
+			}
+			if err := tw.WriteHeader(&hdr); err != nil {
+				t.Fatal(err)
+			}
+			if hdr.Typeflag == tar.TypeReg {
+				tw.Write([]byte("ok"))
+			}
+		}
+		tw.Close()
+
+		dest := destDir(t)
+		if err := SafeUntar(&buf, dest, tt.opts...); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: SafeUntar = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if tt.wantErr == nil {
+			if data, err := os.ReadFile(filepath.Join(dest, "copy.txt")); err != nil || string(data) != "ok" {
+				t.Errorf("%s: copy.txt = %q, %v", tt.name, data, err)
+			}
+		}
+	}
+}
+
+func TestSafeUntarExistingSymlink(t *testing.T) {
+	tests := []struct {
+		name string
+		hdr  tar.Header
+	}{
+		{name: "directory", hdr: tar.Header{Name: "l/evil/sub/", Typeflag: tar.TypeDir, Mode: 0o755}},
+		{name: "file", hdr: tar.Header{Name: "l/evil/sub/a.txt", Typeflag: tar.TypeReg, Mode: 0o644}},
+	}
+	for _, tt := range tests {
+		outer := destDir(t)
+		dest := filepath.Join(outer, "dest")
+		if err := os.Mkdir(dest, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink("..", filepath.Join(dest, "l")); err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		if err := tw.WriteHeader(&tt.hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Close()
+
+		if err := SafeUntar(&buf, dest); !errors.Is(err, pathsecurity.ErrSymlinkEscape) {
+			t.Errorf("%s: SafeUntar = %v, want %v", tt.name, err, pathsecurity.ErrSymlinkEscape)
+		}
+		if _, err := os.Lstat(filepath.Join(outer, "evil")); !os.IsNotExist(err) {
+			t.Errorf("%s: directory created outside the destination: %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package pathsecurity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Decision is the outcome recorded in an audit Event
+type Decision int
+
+const (
+	// DecisionRejected means the path failed a check
+	DecisionRejected Decision = iota
+	// DecisionSanitized means sanitization changed the path
+	DecisionSanitized
+	// DecisionMonitored means the path failed a check below the threshold
+	// set with WithFailThreshold and was let through
+	DecisionMonitored
+)
+
+// String returns the name of the decision
+func (d Decision) String() string {
+	switch d {
+	case DecisionRejected:
+		return "rejected"
+	case DecisionSanitized:
+		return "sanitized"
+	case DecisionMonitored:
+		return "monitored"
+	}
+	return fmt.Sprintf("Decision(%d)", int(d))
+}
+
+// MarshalText encodes the decision as its name
+func (d Decision) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Event describes a rejected or sanitized path for the function set with
+// WithAuditFunc
+type Event struct {
+	Time     time.Time `json:"time"`
+	Decision Decision  `json:"decision"`
+	// Path is the path as checked, after environment expansion and decoding
+	Path string `json:"path"`
+	// Sanitized is the sanitized path for DecisionSanitized
+	Sanitized string `json:"sanitized,omitempty"`
+	// Rule names what triggered the decision: the validator's reason for a
+	// rejection, or the sanitize strategy, or "filename" for upload names
+	Rule string `json:"rule"`
+	// Err is the error returned to the caller for DecisionRejected, and the
+	// one that would have been for DecisionMonitored
+	Err error `json:"-"`
+}
+
+// auditRejection reports a rejection of path with err to the audit function
+func (ps *PathSecurity) auditRejection(path string, err error) {
+	if ps.cfg.auditFunc == nil {
+		return
+	}
+
+	ps.cfg.auditFunc(Event{Time: time.Now(), Decision: DecisionRejected, Path: path, Rule: rejectionRule(err), Err: err})
+}
+
+// rejectionRule returns the Rule of an audit Event for a rejection with err
+func rejectionRule(err error) string {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return verr.Reason
+	}
+	return err.Error()
+}
+
+// auditSanitized reports an input changed by the sanitizer named rule to the
+// audit function and metrics collector
+func (ps *PathSecurity) auditSanitized(result SanitizeResult, rule string) {
+	if !result.Changed {
+		return
+	}
+	if ps.cfg.metrics != nil {
+		ps.cfg.metrics.ObserveSanitization(rule)
+	}
+	if ps.cfg.auditFunc == nil {
+		return
+	}
+	ps.cfg.auditFunc(Event{Time: time.Now(), Decision: DecisionSanitized, Path: result.Original, Sanitized: result.Sanitized, Rule: rule})
+}
@@ -0,0 +1,42 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithAuditFunc(t *testing.T) {
+	var events []Event
+	ps := NewPathSecurity(WithAuditFunc(func(ev Event) { events = append(events, ev) }))
+	_, rejectErr := ps.ValidatePath("../../etc/shadow")
+	ps.SanitizePath("uploads/../../secret.txt")
+	ps.SanitizePath("uploads/clean.txt")
+	ps.ValidatePath("docs/readme.md")
+
+	if len(events) != 2 {
+		t.Fatalf("audit function saw %d events, want 2: %+v", len(events), events)
+	}
+	rejected, sanitized := events[0], events[1]
+	var verr *ValidationError
+	if rejected.Decision != DecisionRejected || rejected.Path != "../../etc/shadow" || rejected.Err != rejectErr ||
+		!errors.As(rejectErr, &verr) || rejected.Rule != verr.Reason || rejected.Time.IsZero() {
+		t.Errorf("rejection event = %+v", rejected)
+	}
+	if sanitized.Decision != DecisionSanitized || sanitized.Path != "uploads/../../secret.txt" ||
+		sanitized.Sanitized != "uploads/secret.txt" || sanitized.Rule != "strip" || sanitized.Err != nil || sanitized.Time.IsZero() {
+		t.Errorf("sanitize event = %+v", sanitized)
+	}
+}
+
+func TestDecisionString(t *testing.T) {
+	for decision, want := range map[Decision]string{
+		DecisionRejected:  "rejected",
+		DecisionSanitized: "sanitized",
+		DecisionMonitored: "monitored",
+		Decision(7):       "Decision(7)",
+	} {
+		if got := decision.String(); got != want {
+			t.Errorf("Decision(%d).String() = %q, want %q", int(decision), got, want)
+		}
+	}
+}
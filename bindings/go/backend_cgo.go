@@ -0,0 +1,559 @@
+//go:build cgo && !purego
+
+package pathsecurity
+
+/*
+#cgo !dlopen LDFLAGS: -L${SRCDIR} -lpath_security_c
+#cgo dlopen CFLAGS: -DPATH_SECURITY_DLOPEN
+#cgo linux LDFLAGS: -ldl
+#define _GNU_SOURCE
+#include <stddef.h>
+#include <stdlib.h>
+#ifndef _WIN32
+#include <dlfcn.h>
+#endif
+
+#ifdef PATH_SECURITY_DLOPEN
+// With the dlopen build tag the library is loaded on first use, so programs
+// start without it and fall back to the pure-Go engine. The functions below
+// stand in for those of path_security.h and are only called once
+// path_security_load has succeeded.
+static void* ps_lib;
+static int (*ps_validate_path)(const char*, char*, size_t);
+static int (*ps_validate_paths)(const char*, size_t, char*, size_t);
+static int (*ps_detect_traversal)(const char*);
+static int (*ps_sanitize_path)(const char*, char*, size_t);
+static int (*ps_sanitize_filename)(const char*, char*, size_t);
+
+static const char* path_security_load(const char* name) {
+	void* lib = dlopen(name, RTLD_NOW | RTLD_LOCAL);
+	if (lib == NULL) {
+		return dlerror();
+	}
+	ps_validate_path = dlsym(lib, "path_security_validate_path");
+	ps_validate_paths = dlsym(lib, "path_security_validate_paths");
+	ps_detect_traversal = dlsym(lib, "path_security_detect_traversal");
+	ps_sanitize_path = dlsym(lib, "path_security_sanitize_path");
+	ps_sanitize_filename = dlsym(lib, "path_security_sanitize_filename");
+	if (!ps_validate_path || !ps_validate_paths || !ps_detect_traversal || !ps_sanitize_path || !ps_sanitize_filename) {
+		dlclose(lib);
+		return "library lacks a path_security_ function";
+	}
+	ps_lib = lib;
+	return NULL;
+}
+
+static const char* path_security_info(const char* symbol) {
+	const char* (*info)(void) = dlsym(ps_lib, symbol);
+	return info != NULL ? info() : NULL;
+}
+
+static int path_security_validate_path(const char* path, char* result, size_t result_len) {
+	return ps_validate_path(path, result, result_len);
+}
+static int path_security_validate_paths(const char* paths, size_t count, char* result, size_t result_len) {
+	return ps_validate_paths(paths, count, result, result_len);
+}
+static int path_security_detect_traversal(const char* path) {
+	return ps_detect_traversal(path);
+}
+static int path_security_sanitize_path(const char* path, char* result, size_t result_len) {
+	return ps_sanitize_path(path, result, result_len);
+}
+static int path_security_sanitize_filename(const char* filename, char* result, size_t result_len) {
+	return ps_sanitize_filename(filename, result, result_len);
+}
+#else
+#include "path_security.h"
+
+static const char* path_security_load(const char* name) {
+	return NULL;
+}
+
+// The functions of path_security_info are looked up rather than linked, as
+// libraries before path_security_version lack them
+static const char* path_security_info(const char* symbol) {
+#ifdef _WIN32
+	return NULL;
+#else
+	const char* (*info)(void) = dlsym(RTLD_DEFAULT, symbol);
+	return info != NULL ? info() : NULL;
+#endif
+}
+#endif
+*/
+import "C"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// nativeLibrary returns the name under which the dlopen build looks for
+// the native library: $PATH_SECURITY_LIBRARY if set, or else the usual
+// file name of the platform, searched for as dlopen does
+func nativeLibrary() string {
+	if name := os.Getenv("PATH_SECURITY_LIBRARY"); name != "" {
+		return name
+	}
+	if runtime.GOOS == "darwin" {
+		return "libpath_security_c.dylib"
+	}
+	return "libpath_security_c.so"
+}
+
+// loadNative loads the native library once, in the dlopen build, and
+// returns why it is unavailable; the linked build always has it
+var loadNative = sync.OnceValue(func() error {
+	name := nativeLibrary()
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	if msg := C.path_security_load(cName); msg != nil {
+		return fmt.Errorf("%w: loading %s: %s", ErrBackendUnavailable, name, C.GoString(msg))
+	}
+	return nil
+})
+
+// useEngine reports whether the pure-Go engine stands in for the native
+// library on input: when the library is unavailable, or when the input is
+// not valid UTF-8, which WithRawBytes lets through and on which the
+// library's pattern matching is undefined
+func useEngine(input string) bool {
+	return loadNative() != nil || !utf8.ValidString(input)
+}
+
+// nativeInfo returns the string of an optional native function returning a
+// static string, such as path_security_version, and false if the library
+// is unavailable or lacks the function
+func nativeInfo(symbol string) (string, bool) {
+	if loadNative() != nil {
+		return "", false
+	}
+	cSymbol := C.CString(symbol)
+	defer C.free(unsafe.Pointer(cSymbol))
+	info := C.path_security_info(cSymbol)
+	if info == nil {
+		return "", false
+	}
+	return C.GoString(info), true
+}
+
+// backendVersion returns the version of the native library, and "" if it
+// is unavailable or predates path_security_version
+func backendVersion() string {
+	version, _ := nativeInfo("path_security_version")
+	return version
+}
+
+// backendCapabilities returns the capabilities the native library reports,
+// or those of the pure-Go engine if it is unavailable or reports none:
+// libraries before path_security_capabilities validate as the engine does
+func backendCapabilities() []string {
+	list, ok := nativeInfo("path_security_capabilities")
+	if !ok {
+		return engineCapabilities()
+	}
+	return strings.Split(list, ",")
+}
+
+// NativeBackend reports whether calls go to libpath_security_c. Built
+// normally the library is linked and this returns nil. Built with the
+// dlopen tag it is loaded on first use from $PATH_SECURITY_LIBRARY or the
+// library search path, and if that fails every call falls back to the
+// pure-Go engine and this returns an error wrapping ErrBackendUnavailable
+// and the reason. Deployments that require the native library can call it
+// at startup and refuse to run.
+func NativeBackend() error {
+	return loadNative()
+}
+
+// isolate runs call, a call into the native library for the operation op,
+// so that its failures stay with it: a panic is recovered and returned as
+// an error wrapping ErrNativeFailure, and with a timeout above zero a call
+// that has not returned in time is abandoned with an error wrapping
+// ErrNativeFailure and ErrNativeTimeout. An abandoned call keeps its
+// goroutine, thread and buffers until it returns, since C code cannot be
+// interrupted; crashes in C code still end the process, as Go cannot
+// recover from them.
+func isolate[T any](op string, timeout time.Duration, call func() (T, error)) (T, error) {
+	if timeout <= 0 {
+		return recovered(op, call)
+	}
+	type outcome struct {
+		value T
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := recovered(op, call)
+		done <- outcome{value, err}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case o := <-done:
+		return o.value, o.err
+	case <-timer.C:
+	}
+
+	nativeTimeouts.Add(1)
+	nativeStuck.Add(1)
+	go func() {
+		<-done
+		nativeStuck.Add(-1)
+	}()
+	var zero T
+	return zero, fmt.Errorf("%w: %w: %s did not return within %v", ErrNativeFailure, ErrNativeTimeout, op, timeout)
+}
+
+// recovered runs call, returning a panic as an error wrapping
+// ErrNativeFailure
+func recovered[T any](op string, call func() (T, error)) (value T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			nativePanics.Add(1)
+			var zero T
+			value, err = zero, fmt.Errorf("%w: %s panicked: %v", ErrNativeFailure, op, r)
+		}
+	}()
+	return call()
+}
+
+// Callers reject or strip NUL bytes before reaching this file, as the C
+// side would silently cut the input at the first one.
+
+// resultOverhead is the space reserved in a result buffer for the JSON
+// fields around each echoed path
+const resultOverhead = 256
+
+// maxResultSize bounds result buffer growth
+const maxResultSize = 64 << 20
+
+// guardSize is the number of bytes after each result buffer that are
+// filled with guardByte and checked after the call, to catch a native
+// library writing past the size it was given
+const guardSize = 64
+
+// guardByte fills the guard area; it is not a byte the native library
+// writes, as its results are JSON text
+const guardByte = 0xA5
+
+// maxPooledBuffer is the largest buffer returned to bufferPool, so a single
+// huge call does not pin its buffer for the life of the pool
+const maxPooledBuffer = 64 << 10
+
+// bufferPool recycles the input and result buffers of native calls. The
+// native library does not keep pointers past a call, so Go memory can be
+// handed to it directly instead of through C.CString and C.malloc.
+var bufferPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// getBuffer returns a pooled buffer of length n
+func getBuffer(n int) *[]byte {
+	b := bufferPool.Get().(*[]byte)
+	if cap(*b) < n {
+		*b = make([]byte, n)
+	}
+	*b = (*b)[:n]
+	return b
+}
+
+// putBuffer returns a buffer taken with getBuffer to the pool
+func putBuffer(b *[]byte) {
+	if cap(*b) <= maxPooledBuffer {
+		bufferPool.Put(b)
+	}
+}
+
+// withCString calls fn with a NUL-terminated copy of s in a pooled buffer
+func withCString[T any](s string, fn func(cs *C.char) T) T {
+	b := getBuffer(len(s) + 1)
+	defer putBuffer(b)
+	copy(*b, s)
+	(*b)[len(s)] = 0
+	return fn((*C.char)(unsafe.Pointer(&(*b)[0])))
+}
+
+// callWithBuffer runs a native call that writes a NUL-terminated result,
+// starting with a buffer of size bytes and doubling it for as long as the
+// call reports ErrBufferTooSmall, up to maxResultSize. use receives the
+// result without its terminator and must not keep it after returning.
+//
+// The result is never trusted to be well formed: the buffer is followed by
+// a guard area that must be intact after the call, the terminator must lie
+// within the size given to the call and the result must be valid UTF-8.
+// Anything else is reported as ErrNativeFailure, and a buffer with a broken
+// guard is not reused, rather than reading stale or foreign bytes.
+func callWithBuffer(op string, size int, call func(result *C.char, resultLen C.size_t) C.int, use func(result []byte) error) error {
+	for {
+		b := getBuffer(size + guardSize)
+		// A recycled buffer holds an earlier result, which must not pass
+		// for the terminator of this one
+		clear((*b)[:size])
+		guard := (*b)[size:]
+		for i := range guard {
+			guard[i] = guardByte
+		}
+
+		ret := call((*C.char)(unsafe.Pointer(&(*b)[0])), C.size_t(size))
+		if !guardIntact(guard) {
+			return fmt.Errorf("%w: %s wrote past its %d-byte result buffer", ErrNativeFailure, op, size)
+		}
+		if ErrorCode(ret) == CodeBufferTooSmall && size < maxResultSize {
+			putBuffer(b)
+			size *= 2
+			continue
+		}
+		if ret != 0 {
+			putBuffer(b)
+			return &NativeError{Op: op, Code: ErrorCode(ret)}
+		}
+
+		result, err := terminatedResult(op, (*b)[:size])
+		if err == nil {
+			err = use(result)
+		}
+		putBuffer(b)
+		return err
+	}
+}
+
+// guardIntact reports whether a guard area still holds only guardByte
+func guardIntact(guard []byte) bool {
+	for _, c := range guard {
+		if c != guardByte {
+			return false
+		}
+	}
+	return true
+}
+
+// terminatedResult returns the result a successful native call wrote to
+// buf, without its terminator, or an error if buf holds no terminator or
+// the result is not valid UTF-8
+func terminatedResult(op string, buf []byte) ([]byte, error) {
+	n := bytes.IndexByte(buf, 0)
+	if n < 0 {
+		return nil, fmt.Errorf("%w: %s returned a result without a terminator in %d bytes", ErrNativeFailure, op, len(buf))
+	}
+	if !utf8.Valid(buf[:n]) {
+		return nil, fmt.Errorf("%w: %s returned a result that is not valid UTF-8", ErrNativeFailure, op)
+	}
+	return buf[:n], nil
+}
+
+// resultSize estimates the result buffer needed for JSON echoing text, which
+// may double in length when escaped
+func resultSize(text string) int {
+	return 2*len(text) + resultOverhead
+}
+
+// backendValidatePath runs path_security_validate_path and returns its JSON response
+func backendValidatePath(path string, timeout time.Duration) (string, error) {
+	if useEngine(path) {
+		return engineValidatePath(path)
+	}
+	return isolate("path validation", timeout, func() (string, error) {
+		var response string
+		err := withCString(path, func(cPath *C.char) error {
+			return callWithBuffer("path validation", resultSize(path), func(result *C.char, resultLen C.size_t) C.int {
+				return C.path_security_validate_path(cPath, result, resultLen)
+			}, func(result []byte) error {
+				response = string(result)
+				return nil
+			})
+		})
+		return response, err
+	})
+}
+
+// backendValidatePaths runs path_security_validate_paths over all paths in a
+// single call and returns the JSON response for each, in input order. The
+// paths must not contain NUL bytes. Paths the native library cannot take
+// are validated by the pure-Go engine, as they are one at a time.
+func backendValidatePaths(paths []string, timeout time.Duration) ([]string, error) {
+	if loadNative() != nil {
+		return engineValidatePaths(paths)
+	}
+	for _, path := range paths {
+		if !utf8.ValidString(path) {
+			return splitValidatePaths(paths, timeout)
+		}
+	}
+	return isolate("batch path validation", timeout, func() ([]string, error) {
+		packedLen, size := 0, 2
+		for _, path := range paths {
+			packedLen += len(path) + 1
+			size += resultSize(path)
+		}
+		packed := getBuffer(packedLen)
+		defer putBuffer(packed)
+		offset := 0
+		for _, path := range paths {
+			offset += copy((*packed)[offset:], path)
+			(*packed)[offset] = 0
+			offset++
+		}
+		cPaths := (*C.char)(unsafe.Pointer(&(*packed)[0]))
+
+		var responses []string
+		err := callWithBuffer("batch path validation", size, func(result *C.char, resultLen C.size_t) C.int {
+			return C.path_security_validate_paths(cPaths, C.size_t(len(paths)), result, resultLen)
+		}, func(result []byte) error {
+			var err error
+			if responses, err = splitJSONArray(string(result), len(paths)); err != nil {
+				return fmt.Errorf("%w: batch path validation returned malformed result: %v", ErrNativeFailure, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(responses) != len(paths) {
+			return nil, fmt.Errorf("%w: batch path validation returned %d results for %d paths", ErrNativeFailure, len(responses), len(paths))
+		}
+		return responses, nil
+	})
+}
+
+// splitValidatePaths is backendValidatePaths for a batch holding paths
+// that are not valid UTF-8, which the pure-Go engine validates while the
+// native library validates the others
+func splitValidatePaths(paths []string, timeout time.Duration) ([]string, error) {
+	responses := make([]string, len(paths))
+	var native []string
+	var nativeIndex []int
+	for i, path := range paths {
+		if utf8.ValidString(path) {
+			native = append(native, path)
+			nativeIndex = append(nativeIndex, i)
+			continue
+		}
+		response, err := engineValidatePath(path)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+	}
+	if len(native) == 0 {
+		return responses, nil
+	}
+	nativeResponses, err := backendValidatePaths(native, timeout)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range nativeIndex {
+		responses[i] = nativeResponses[j]
+	}
+	return responses, nil
+}
+
+// splitJSONArray returns the elements of the JSON array s as substrings of
+// it, so a batch result is copied once rather than once per element. n is
+// the expected number of elements.
+func splitJSONArray(s string, n int) ([]string, error) {
+	decoder := json.NewDecoder(strings.NewReader(s))
+	if token, err := decoder.Token(); err != nil {
+		return nil, err
+	} else if token != json.Delim('[') {
+		return nil, fmt.Errorf("result is not an array")
+	}
+
+	elements := make([]string, 0, n)
+	// Decoding reuses the capacity of raw, which is only used for its
+	// length
+	var raw json.RawMessage
+	for decoder.More() {
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+		end := int(decoder.InputOffset())
+		elements = append(elements, s[end-len(raw):end])
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+// backendDetectTraversal runs path_security_detect_traversal
+func backendDetectTraversal(path string, timeout time.Duration) (bool, error) {
+	if useEngine(path) {
+		return engineDetectTraversal(path)
+	}
+	ret, err := isolate("traversal detection", timeout, func() (C.int, error) {
+		return withCString(path, func(cPath *C.char) C.int {
+			return C.path_security_detect_traversal(cPath)
+		}), nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	switch ret {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	}
+	return false, &NativeError{Op: "traversal detection", Code: ErrorCode(ret)}
+}
+
+// backendSanitizePath runs path_security_sanitize_path and decodes its response
+func backendSanitizePath(path string, timeout time.Duration) (SanitizeResult, error) {
+	if useEngine(path) {
+		return engineSanitizePath(path)
+	}
+	return isolate("path sanitization", timeout, func() (SanitizeResult, error) {
+		var parsed SanitizeResult
+		err := withCString(path, func(cPath *C.char) error {
+			return callWithBuffer("path sanitization", 2*resultSize(path), func(result *C.char, resultLen C.size_t) C.int {
+				return C.path_security_sanitize_path(cPath, result, resultLen)
+			}, func(result []byte) error {
+				if err := json.Unmarshal(result, &parsed); err != nil {
+					return fmt.Errorf("%w: path sanitization returned malformed result: %v", ErrNativeFailure, err)
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return SanitizeResult{}, err
+		}
+		return parsed, nil
+	})
+}
+
+// backendSanitizeFilename runs path_security_sanitize_filename and decodes its response
+func backendSanitizeFilename(name string, timeout time.Duration) (SanitizeResult, error) {
+	if useEngine(name) {
+		return engineSanitizeFilename(name)
+	}
+	return isolate("filename sanitization", timeout, func() (SanitizeResult, error) {
+		var parsed SanitizeResult
+		err := withCString(name, func(cName *C.char) error {
+			return callWithBuffer("filename sanitization", 2*resultSize(name), func(result *C.char, resultLen C.size_t) C.int {
+				return C.path_security_sanitize_filename(cName, result, resultLen)
+			}, func(result []byte) error {
+				if err := json.Unmarshal(result, &parsed); err != nil {
+					return fmt.Errorf("%w: filename sanitization returned malformed result: %v", ErrNativeFailure, err)
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return SanitizeResult{}, err
+		}
+		return parsed, nil
+	})
+}
@@ -0,0 +1,45 @@
+//go:build cgo && !purego
+
+package pathsecurity
+
+/*
+#include <stddef.h>
+#include <string.h>
+
+// These stand in for a native library that breaks the result buffer
+// contract, so the tests can check that callWithBuffer catches each fault
+
+static int ps_stub_overrun(char* result, size_t result_len) {
+	memset(result, 'a', result_len + 8);
+	return 0;
+}
+
+static int ps_stub_unterminated(char* result, size_t result_len) {
+	memset(result, 'a', result_len);
+	return 0;
+}
+
+static int ps_stub_invalid_utf8(char* result, size_t result_len) {
+	static const char text[] = "{\"result\":\"\xff\xfe\"}";
+	if (result_len < sizeof text) {
+		return -4;
+	}
+	memcpy(result, text, sizeof text);
+	return 0;
+}
+*/
+import "C"
+
+// nativeFaults are native calls that misbehave as a broken native library
+// could, by kind of fault
+var nativeFaults = map[string]func(result *C.char, resultLen C.size_t) C.int{
+	"overrun": func(result *C.char, resultLen C.size_t) C.int {
+		return C.ps_stub_overrun(result, resultLen)
+	},
+	"unterminated": func(result *C.char, resultLen C.size_t) C.int {
+		return C.ps_stub_unterminated(result, resultLen)
+	},
+	"invalid UTF-8": func(result *C.char, resultLen C.size_t) C.int {
+		return C.ps_stub_invalid_utf8(result, resultLen)
+	},
+}
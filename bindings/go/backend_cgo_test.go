@@ -0,0 +1,40 @@
+//go:build cgo && !purego
+
+package pathsecurity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestCallWithBufferFaults runs callWithBuffer on C functions that write
+// past the buffer, leave out the terminator or return invalid UTF-8. Each
+// must fail with ErrNativeFailure before the result is used. To also have
+// the accesses checked by a sanitizer, run it as
+//
+//	go test -asan -run TestCallWithBufferFaults
+func TestCallWithBufferFaults(t *testing.T) {
+	tests := []struct {
+		fault   string
+		size    int
+		wantMsg string
+	}{
+		{"overrun", 16, "wrote past"},
+		{"unterminated", 16, "without a terminator"},
+		{"invalid UTF-8", 4, "not valid UTF-8"},
+	}
+	for _, tt := range tests {
+		used := false
+		err := callWithBuffer("stub", tt.size, nativeFaults[tt.fault], func([]byte) error {
+			used = true
+			return nil
+		})
+		if !errors.Is(err, ErrNativeFailure) || !strings.Contains(err.Error(), tt.wantMsg) {
+			t.Errorf("%s: callWithBuffer error = %v, want %v with %q", tt.fault, err, ErrNativeFailure, tt.wantMsg)
+		}
+		if used {
+			t.Errorf("%s: callWithBuffer passed the result on", tt.fault)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package pathsecurity
+
+import (
+	"encoding/json"
+)
+
+// validateDocument matches the JSON written by path_security_validate_path
+type validateDocument struct {
+	Valid     bool   `json:"valid"`
+	Path      string `json:"path"`
+	Error     string `json:"error,omitempty"`
+	Sanitized bool   `json:"sanitized,omitempty"`
+}
+
+// engineValidatePath validates a path with the pure-Go engine and returns
+// the same JSON document as the native library
+func engineValidatePath(path string) (string, error) {
+	doc := validateDocument{Valid: true, Path: path, Sanitized: true}
+	if err := validateLexical(path); err != nil {
+		doc = validateDocument{Valid: false, Path: path, Error: err.Error()}
+	}
+
+	response, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(response), nil
+}
+
+// engineValidatePaths validates each path with the pure-Go engine
+func engineValidatePaths(paths []string) ([]string, error) {
+	responses := make([]string, len(paths))
+	for i, path := range paths {
+		response, err := engineValidatePath(path)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+	}
+	return responses, nil
+}
+
+// engineDetectTraversal reports whether the pure-Go engine rejects a path
+func engineDetectTraversal(path string) (bool, error) {
+	return validateLexical(path) != nil, nil
+}
+
+// engineSanitizePath sanitizes a path with the pure-Go engine
+func engineSanitizePath(path string) (SanitizeResult, error) {
+	sanitized := sanitizeLexical(path)
+	return SanitizeResult{Original: path, Sanitized: sanitized, Changed: sanitized != path}, nil
+}
+
+// engineSanitizeFilename sanitizes a file name with the pure-Go engine
+func engineSanitizeFilename(name string) (SanitizeResult, error) {
+	sanitized := sanitizeFilenameLexical(name)
+	return SanitizeResult{Original: name, Sanitized: sanitized, Changed: sanitized != name}, nil
+}
@@ -0,0 +1,53 @@
+//go:build !cgo || purego
+
+package pathsecurity
+
+import (
+	"fmt"
+	"time"
+)
+
+// NativeBackend reports whether calls go to libpath_security_c. In this
+// build they never do: the pure-Go engine was selected with CGO_ENABLED=0
+// or the purego build tag, so the error wraps ErrBackendUnavailable.
+func NativeBackend() error {
+	return fmt.Errorf("%w: built with the pure-Go engine", ErrBackendUnavailable)
+}
+
+// backendVersion returns "", as there is no native library
+func backendVersion() string {
+	return ""
+}
+
+// backendCapabilities returns the capabilities of the pure-Go engine
+func backendCapabilities() []string {
+	return engineCapabilities()
+}
+
+// The backend functions take the timeout of WithNativeTimeout, which does
+// not apply to the pure-Go engine
+
+// backendValidatePath validates a path with the pure-Go engine
+func backendValidatePath(path string, _ time.Duration) (string, error) {
+	return engineValidatePath(path)
+}
+
+// backendValidatePaths validates each path with the pure-Go engine
+func backendValidatePaths(paths []string, _ time.Duration) ([]string, error) {
+	return engineValidatePaths(paths)
+}
+
+// backendDetectTraversal detects traversal with the pure-Go engine
+func backendDetectTraversal(path string, _ time.Duration) (bool, error) {
+	return engineDetectTraversal(path)
+}
+
+// backendSanitizePath sanitizes a path with the pure-Go engine
+func backendSanitizePath(path string, _ time.Duration) (SanitizeResult, error) {
+	return engineSanitizePath(path)
+}
+
+// backendSanitizeFilename sanitizes a file name with the pure-Go engine
+func backendSanitizeFilename(name string, _ time.Duration) (SanitizeResult, error) {
+	return engineSanitizeFilename(name)
+}
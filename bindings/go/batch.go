@@ -0,0 +1,191 @@
+package pathsecurity
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Result is the outcome of validating one path with ValidatePaths
+type Result struct {
+	// Path is the input path as given
+	Path string
+	// Report is the validator's JSON report, empty when Err is set
+	Report string
+	// Err is the error ValidatePath would have returned for Path
+	Err error
+}
+
+// MarshalJSON encodes the result with its schema version as "path",
+// "valid", the validator's "report" as a nested object for accepted paths,
+// and the "error" message and its RejectionCategory as "category" for
+// rejected ones
+func (r Result) MarshalJSON() ([]byte, error) {
+	encoded := struct {
+		SchemaVersion int             `json:"schema_version"`
+		Path          string          `json:"path"`
+		Valid         bool            `json:"valid"`
+		Report        json.RawMessage `json:"report,omitempty"`
+		Error         string          `json:"error,omitempty"`
+		Category      string          `json:"category,omitempty"`
+	}{SchemaVersion: ReportSchemaVersion, Path: r.Path, Valid: r.Err == nil}
+	if r.Err != nil {
+		encoded.Error = r.Err.Error()
+		encoded.Category = RejectionCategory(r.Err)
+	} else if json.Valid([]byte(r.Report)) {
+		encoded.Report = json.RawMessage(r.Report)
+	}
+	return json.Marshal(encoded)
+}
+
+// ValidatePaths validates many paths at once and returns one Result per
+// input, in input order. Each path gets the same checks as ValidatePath,
+// but the backend validator runs once for the whole batch, so the native
+// library is entered a single time instead of once per path. A failure of
+// the batch call itself is reported in the Err of every path that reached
+// it. A metrics collector sees each path that reaches the validator, paths
+// in the batch call being timed with an equal share of it, and the
+// function set with WithStdlibCrossCheck sees each verdict as it would
+// from ValidatePath. Results found in the cache set with
+// WithValidationCache skip the batch call, and under WithFailThreshold and
+// WithStdlibParity every path is validated on its own.
+func (ps *PathSecurity) ValidatePaths(paths []string) []Result {
+	results := make([]Result, len(paths))
+	pending := make([]int, 0, len(paths))
+	prepared := make([]string, 0, len(paths))
+	backendPaths := make([]string, 0, len(paths))
+	crossCheck := func(i int, checked string) {
+		if ps.cfg.stdlibCrossCheck != nil {
+			ps.crossCheck(paths[i], checked, results[i].Err == nil)
+		}
+	}
+
+	// A path rejected below the fail threshold has the rest of its checks
+	// run as well, which the batch call cannot do, and parity modes never
+	// reach the backend
+	if ps.cfg.failThreshold > SeverityLow || ps.cfg.stdlibParity != ParityOff {
+		for i, path := range paths {
+			results[i].Path = path
+			p, err := ps.preprocess(path)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			results[i].Report, results[i].Err = ps.validate(p)
+			crossCheck(i, p)
+			if results[i].Err == nil {
+				ps.warn(p)
+			}
+		}
+		return results
+	}
+
+	ruleset := RulesetVersion()
+	for i, path := range paths {
+		results[i].Path = path
+
+		p, err := ps.preprocess(path)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		start := time.Now()
+		if entry, ok := ps.cachedValidation(p, ruleset); ok {
+			results[i].Report, results[i].Err = ps.finishValidation(p, entry.response, entry.err)
+			if ps.cfg.metrics != nil {
+				ps.cfg.metrics.ObserveValidation(time.Since(start), results[i].Err == nil)
+			}
+			crossCheck(i, p)
+			if results[i].Err == nil {
+				ps.warn(p)
+			}
+			continue
+		}
+		backendPath, err := ps.precheck(p)
+		if err != nil {
+			if ps.cfg.metrics != nil {
+				ps.cfg.metrics.ObserveValidation(time.Since(start), false)
+			}
+			ps.cacheValidation(p, ruleset, "", err)
+			results[i].Err = err
+			crossCheck(i, p)
+			continue
+		}
+
+		pending = append(pending, i)
+		prepared = append(prepared, p)
+		backendPaths = append(backendPaths, backendPath)
+	}
+	if len(pending) == 0 {
+		return results
+	}
+
+	start := time.Now()
+	responses, err := backendValidatePaths(backendPaths, ps.cfg.nativeTimeout)
+	if err != nil {
+		for _, i := range pending {
+			results[i].Err = err
+		}
+		return results
+	}
+
+	for j, i := range pending {
+		report, err := ps.interpret(prepared[j], responses[j])
+		ps.cacheValidation(prepared[j], ruleset, report, err)
+		results[i].Report, results[i].Err = ps.finishValidation(prepared[j], report, err)
+		crossCheck(i, prepared[j])
+		if results[i].Err == nil {
+			ps.warn(prepared[j])
+		}
+	}
+	if ps.cfg.metrics != nil {
+		share := time.Since(start) / time.Duration(len(pending))
+		for _, i := range pending {
+			ps.cfg.metrics.ObserveValidation(share, results[i].Err == nil)
+		}
+	}
+	return results
+}
+
+// scanBatchSize is the number of lines ScanReader validates per batch
+const scanBatchSize = 1024
+
+// maxScanLineLength bounds the length of a line read by ScanReader
+const maxScanLineLength = 1 << 20
+
+// ScanReader reads newline-delimited paths from r and calls fn with the
+// result of validating each one, in input order. Lines are validated in
+// batches with ValidatePaths, so large manifests need one native call per
+// batch rather than per line. A trailing carriage return is removed from
+// each line and empty lines are skipped. Reading stops at the first read
+// error, or at a line longer than 1 MiB, which is returned after fn has
+// seen every earlier line.
+func (ps *PathSecurity) ScanReader(r io.Reader, fn func(Result)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxScanLineLength)
+
+	batch := make([]string, 0, scanBatchSize)
+	flush := func() {
+		for _, result := range ps.ValidatePaths(batch) {
+			fn(result)
+		}
+		batch = batch[:0]
+	}
+
+	for scanner.Scan() {
+		line := bytes.TrimSuffix(scanner.Bytes(), []byte{'\r'})
+		if len(line) == 0 {
+			continue
+		}
+		batch = append(batch, string(line))
+		if len(batch) == scanBatchSize {
+			flush()
+		}
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,100 @@
+package pathsecurity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidatePaths(t *testing.T) {
+	paths := []string{"docs/a.txt", "../etc/passwd", "images/logo.png", "docs/a\x00.txt", "docs/a.txt"}
+	ps := NewPathSecurity()
+	results := ps.ValidatePaths(paths)
+	if len(results) != len(paths) {
+		t.Fatalf("ValidatePaths returned %d results for %d paths", len(results), len(paths))
+	}
+	for i, result := range results {
+		_, want := ps.ValidatePath(paths[i])
+		if result.Path != paths[i] {
+			t.Errorf("result %d is for %q, want %q", i, result.Path, paths[i])
+		}
+		if (result.Err == nil) != (want == nil) || (want != nil && result.Err.Error() != want.Error()) {
+			t.Errorf("ValidatePaths error for %q = %v, ValidatePath gives %v", paths[i], result.Err, want)
+		}
+		if (result.Err == nil) == (result.Report == "") {
+			t.Errorf("ValidatePaths(%q) = report %q, error %v", paths[i], result.Report, result.Err)
+		}
+	}
+	if !errors.Is(results[1].Err, ErrTraversalDetected) || !errors.Is(results[3].Err, ErrNullByte) {
+		t.Errorf("ValidatePaths errors = %v, %v, want %v, %v", results[1].Err, results[3].Err, ErrTraversalDetected, ErrNullByte)
+	}
+	if got := ps.ValidatePaths(nil); len(got) != 0 {
+		t.Errorf("ValidatePaths(nil) = %v", got)
+	}
+}
+
+func TestResultMarshalJSON(t *testing.T) {
+	results := NewPathSecurity().ValidatePaths([]string{"docs/readme.md", "../etc/passwd"})
+
+	var accepted struct {
+		SchemaVersion int             `json:"schema_version"`
+		Path          string          `json:"path"`
+		Valid         bool            `json:"valid"`
+		Report        json.RawMessage `json:"report"`
+		Error         *string         `json:"error"`
+	}
+	encoded, _ := json.Marshal(results[0])
+	if err := json.Unmarshal(encoded, &accepted); err != nil || accepted.SchemaVersion != ReportSchemaVersion ||
+		accepted.Path != "docs/readme.md" || !accepted.Valid || !json.Valid(accepted.Report) || accepted.Error != nil {
+		t.Errorf("json.Marshal of an accepted result = %s, %v", encoded, err)
+	}
+
+	var rejected map[string]any
+	encoded, _ = json.Marshal(results[1])
+	if err := json.Unmarshal(encoded, &rejected); err != nil || rejected["valid"] != false ||
+		rejected["category"] != "traversal" || rejected["error"] != results[1].Err.Error() || rejected["report"] != nil {
+		t.Errorf("json.Marshal of a rejected result = %s, %v", encoded, err)
+	}
+}
+
+func TestScanReader(t *testing.T) {
+	manifest := strings.NewReader("assets/app.js\r\n\n../../etc/shadow\nassets/app.css")
+	var paths []string
+	var rejected int
+	err := NewPathSecurity().ScanReader(manifest, func(result Result) {
+		paths = append(paths, result.Path)
+		if result.Err != nil {
+			rejected++
+		}
+	})
+	want := []string{"assets/app.js", "../../etc/shadow", "assets/app.css"}
+	if err != nil || strings.Join(paths, ",") != strings.Join(want, ",") || rejected != 1 {
+		t.Errorf("ScanReader saw %q with %d rejected, %v, want %q with 1 rejected", paths, rejected, err, want)
+	}
+
+	// More lines than one batch
+	var count int
+	err = NewPathSecurity().ScanReader(strings.NewReader(strings.Repeat("docs/a.txt\n", 3*scanBatchSize+1)), func(Result) { count++ })
+	if err != nil || count != 3*scanBatchSize+1 {
+		t.Errorf("ScanReader saw %d lines, %v, want %d", count, err, 3*scanBatchSize+1)
+	}
+
+	long := strings.Repeat("a", maxScanLineLength+1)
+	if err := NewPathSecurity().ScanReader(strings.NewReader(long), func(Result) {}); err == nil {
+		t.Error("ScanReader accepted a line longer than the limit")
+	}
+}
+
+func BenchmarkValidatePaths(b *testing.B) {
+	ps := NewPathSecurity()
+	paths := make([]string, 64)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("uploads/%d/usr/local/bin/app", i)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ps.ValidatePaths(paths)
+	}
+}
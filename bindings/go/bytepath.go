@@ -0,0 +1,108 @@
+package pathsecurity
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// SourceEncoding is the character encoding of the byte paths given to
+// ValidateBytes, SanitizeBytes and DecodeBytes, such as the names sent by
+// SMB clients of Windows versions that predate Unicode file names. Paths in
+// a legacy encoding are transcoded to UTF-8 before any other stage, so the
+// checks, and the native library, see the characters the client meant
+// rather than bytes they would misread: the second byte of the Shift-JIS
+// "表" is 0x5C, a '\' on its own.
+type SourceEncoding int
+
+const (
+	// EncodingUTF8 takes byte paths as UTF-8. Invalid sequences are
+	// rejected with ErrInvalidEncoding unless WithRawBytes lets them
+	// through.
+	EncodingUTF8 SourceEncoding = iota
+	// EncodingShiftJIS is Shift-JIS as Japanese Windows writes it, code
+	// page 932
+	EncodingShiftJIS
+	// EncodingLatin1 is ISO 8859-1, where each byte is the code point of
+	// the same value, so every byte path is valid
+	EncodingLatin1
+	// EncodingWindows1252 is code page 1252 of western European Windows,
+	// Latin-1 with printable characters in place of most C1 controls
+	EncodingWindows1252
+)
+
+// String returns the name of the encoding
+func (e SourceEncoding) String() string {
+	switch e {
+	case EncodingUTF8:
+		return "utf-8"
+	case EncodingShiftJIS:
+		return "shift-jis"
+	case EncodingLatin1:
+		return "latin-1"
+	case EncodingWindows1252:
+		return "windows-1252"
+	}
+	return fmt.Sprintf("SourceEncoding(%d)", int(e))
+}
+
+// MarshalText encodes the encoding as its name
+func (e SourceEncoding) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// legacyEncodings are the decoders of every encoding but EncodingUTF8.
+// None of them can encode U+FFFD, which their decoders substitute for
+// invalid input.
+var legacyEncodings = map[SourceEncoding]encoding.Encoding{
+	EncodingShiftJIS:    japanese.ShiftJIS,
+	EncodingLatin1:      charmap.ISO8859_1,
+	EncodingWindows1252: charmap.Windows1252,
+}
+
+// DecodeBytes transcodes a byte path from the encoding set with
+// WithSourceEncoding to UTF-8. Input longer than WithMaxInputBytes allows,
+// before or after transcoding, is rejected with ErrPathTooLong, and input
+// that is not valid in its encoding with ErrInvalidEncoding; UTF-8 input
+// that is not valid is only accepted under WithRawBytes.
+func (ps *PathSecurity) DecodeBytes(p []byte) (string, error) {
+	if verr := ps.cfg.checkInputLength(string(p[:min(len(p), maxReportedInput+1)]), len(p)); verr != nil {
+		return "", ps.reject(verr.Path, verr)
+	}
+	decoded := string(p)
+	if enc, ok := legacyEncodings[ps.cfg.sourceEncoding]; ok {
+		transcoded, err := enc.NewDecoder().Bytes(p)
+		if err != nil || bytes.ContainsRune(transcoded, utf8.RuneError) {
+			return "", ps.reject(decoded, &ValidationError{Path: decoded, Reason: fmt.Sprintf("Input is not valid %s", ps.cfg.sourceEncoding), Err: ErrInvalidEncoding})
+		}
+		decoded = string(transcoded)
+	}
+	if verr := ps.cfg.checkInput(decoded); verr != nil {
+		return "", ps.reject(verr.Path, verr)
+	}
+	return decoded, nil
+}
+
+// ValidateBytes is ValidatePath for a byte path, transcoded to UTF-8 with
+// DecodeBytes first
+func (ps *PathSecurity) ValidateBytes(p []byte) (string, error) {
+	decoded, err := ps.DecodeBytes(p)
+	if err != nil {
+		return "", err
+	}
+	return ps.ValidatePath(decoded)
+}
+
+// SanitizeBytes is SanitizePath for a byte path, transcoded to UTF-8 with
+// DecodeBytes first. The sanitized path is returned as UTF-8.
+func (ps *PathSecurity) SanitizeBytes(p []byte) (string, error) {
+	decoded, err := ps.DecodeBytes(p)
+	if err != nil {
+		return "", err
+	}
+	return ps.SanitizePath(decoded)
+}
@@ -0,0 +1,57 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeBytes(t *testing.T) {
+	tests := []struct {
+		encoding SourceEncoding
+		path     string
+		want     string
+		wantErr  error
+	}{
+		{EncodingUTF8, "shares/report.txt", "shares/report.txt", nil},
+		{EncodingUTF8, "shares/caf\xe9", "", ErrInvalidEncoding},
+		{EncodingShiftJIS, "shares/\x95\x5c.txt", "shares/\u8868.txt", nil},
+		{EncodingShiftJIS, "shares/\x81", "", ErrInvalidEncoding},
+		{EncodingLatin1, "shares/caf\xe9", "shares/caf\u00e9", nil},
+		{EncodingLatin1, "shares/\x81", "shares/\u0081", nil},
+		{EncodingWindows1252, "shares/\x95.txt", "shares/\u2022.txt", nil},
+		{EncodingWindows1252, "shares/\x81", "", ErrInvalidEncoding},
+	}
+	for _, tt := range tests {
+		ps := NewPathSecurity(WithSourceEncoding(tt.encoding))
+		got, err := ps.DecodeBytes([]byte(tt.path))
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("%s: DecodeBytes(%q) = %q, %v, want %q, %v", tt.encoding, tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateBytes(t *testing.T) {
+	tests := []struct {
+		encoding SourceEncoding
+		path     string
+		wantErr  error
+	}{
+		// The second byte of this Shift-JIS character is '\'
+		{EncodingShiftJIS, "shares/\x95\x5c.txt", nil},
+		{EncodingLatin1, "shares/caf\xe9/../../etc", ErrTraversalDetected},
+		{EncodingLatin1, "shares/\x81", ErrControlCharacter},
+		{EncodingWindows1252, "shares/caf\xe9/../../etc", ErrTraversalDetected},
+		{EncodingWindows1252, "shares/report.txt", nil},
+	}
+	for _, tt := range tests {
+		ps := NewPathSecurity(WithSourceEncoding(tt.encoding))
+		if _, err := ps.ValidateBytes([]byte(tt.path)); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: ValidateBytes(%q) error = %v, want %v", tt.encoding, tt.path, err, tt.wantErr)
+		}
+	}
+
+	sanitized, err := NewPathSecurity(WithSourceEncoding(EncodingLatin1)).SanitizeBytes([]byte("shares/caf\xe9/../notes.txt"))
+	if err != nil || sanitized != "shares/caf\u00e9/notes.txt" {
+		t.Errorf("SanitizeBytes = %q, %v", sanitized, err)
+	}
+}
@@ -0,0 +1,142 @@
+package pathsecurity
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// configIDs hands out the identities that key a configuration's entries
+// in a ValidationCache
+var configIDs atomic.Uint64
+
+// ValidationCache is a fixed-size cache of validation results that evicts
+// the least recently used entry when full, for workloads that validate
+// the same paths over and over. It is passed to WithValidationCache and
+// may be shared by several PathSecurity instances; it is safe for
+// concurrent use.
+type ValidationCache struct {
+	size   int
+	hits   atomic.Uint64
+	misses atomic.Uint64
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	order   *list.List // of *cacheEntry, most recently used first
+}
+
+// cacheKey identifies a preprocessed path under one configuration and
+// the Version of the ruleset in use when it was validated
+type cacheKey struct {
+	config  uint64
+	ruleset int
+	path    string
+}
+
+// cacheEntry is a cached validation result
+type cacheEntry struct {
+	key      cacheKey
+	response string
+	err      error
+}
+
+// NewValidationCache returns an empty cache holding up to size results.
+// A size below 1 is treated as 1.
+func NewValidationCache(size int) *ValidationCache {
+	if size < 1 {
+		size = 1
+	}
+	return &ValidationCache{
+		size:    size,
+		entries: make(map[cacheKey]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// Len returns the number of cached results
+func (c *ValidationCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Stats returns how many lookups found a cached result and how many did
+// not since the cache was created
+func (c *ValidationCache) Stats() (hits, misses uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// Purge removes every cached result, as needed after changing anything
+// validation depends on outside the configuration, such as the native
+// library
+func (c *ValidationCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.order.Init()
+}
+
+// get returns the result cached for key and marks it recently used
+func (c *ValidationCache) get(key cacheKey) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry), true
+}
+
+// add caches a result, evicting the least recently used one if the cache
+// is full
+func (c *ValidationCache) add(entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[entry.key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.order.Remove(oldest)
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+}
+
+// cacheable reports whether a validation result depends only on the path
+// and configuration, which failures of the native library do not
+func cacheable(err error) bool {
+	var nerr *NativeError
+	return !errors.As(err, &nerr) && !errors.Is(err, ErrNativeFailure)
+}
+
+// cachedValidation returns the cached result for an already preprocessed
+// path under the ruleset of the given Version, reporting a cached
+// rejection to the audit function and metrics collector as the validation
+// that produced it did
+func (ps *PathSecurity) cachedValidation(path string, ruleset int) (*cacheEntry, bool) {
+	if ps.cfg.cache == nil {
+		return nil, false
+	}
+	entry, ok := ps.cfg.cache.get(cacheKey{config: ps.cfg.id, ruleset: ruleset, path: path})
+	if ok && entry.err != nil {
+		ps.observeRejection(path, entry.err)
+	}
+	return entry, ok
+}
+
+// cacheValidation caches the result of validating an already preprocessed
+// path if it is cacheable. ruleset is the Version read before validation
+// began, so a result is never cached under a newer ruleset than the one
+// that produced it.
+func (ps *PathSecurity) cacheValidation(path string, ruleset int, response string, err error) {
+	if ps.cfg.cache != nil && cacheable(err) {
+		ps.cfg.cache.add(&cacheEntry{key: cacheKey{config: ps.cfg.id, ruleset: ruleset, path: path}, response: response, err: err})
+	}
+}
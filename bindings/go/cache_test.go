@@ -0,0 +1,51 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithValidationCache(t *testing.T) {
+	cache := NewValidationCache(2)
+	audits := 0
+	ps := NewPathSecurity(WithValidationCache(cache), WithAuditFunc(func(Event) { audits++ }))
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{path: "assets/app.js"},
+		{path: "assets/app.js"},
+		{path: "../etc/passwd", wantErr: ErrTraversalDetected},
+		{path: "../etc/passwd", wantErr: ErrTraversalDetected},
+		{path: "assets/app.js"},
+	}
+	for _, tt := range tests {
+		if _, err := ps.ValidatePath(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidatePath(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+
+	// A clone with different settings does not see the cached verdicts
+	if _, err := ps.Clone(WithMaxDepth(1)).ValidatePath("assets/app.js"); !errors.Is(err, ErrPathTooDeep) {
+		t.Errorf("cloned ValidatePath error = %v, want %v", err, ErrPathTooDeep)
+	}
+	for _, result := range ps.ValidatePaths([]string{"assets/app.js", "docs/readme.md", "docs/readme.md"}) {
+		if result.Err != nil {
+			t.Errorf("ValidatePaths(%q) error = %v", result.Path, result.Err)
+		}
+	}
+
+	hits, misses := cache.Stats()
+	if cache.Len() != 2 || hits != 4 || misses != 5 {
+		t.Errorf("cache = %d entries, %d hits, %d misses, want 2, 4, 5", cache.Len(), hits, misses)
+	}
+	// Cached rejections are still audited
+	if audits != 3 {
+		t.Errorf("audit function saw %d rejections, want 3", audits)
+	}
+
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Errorf("Len after Purge = %d", cache.Len())
+	}
+}
@@ -0,0 +1,226 @@
+package pathsecurity
+
+import (
+	"path"
+	"strings"
+
+	"golang.org/x/text/cases"
+)
+
+// unicodeFolder maps Unicode lookalikes of path syntax to their ASCII form
+// and drops invisible formatting characters
+var unicodeFolder = strings.NewReplacer(
+	"\u2024", ".", // ONE DOT LEADER
+	"\ufe52", ".", // SMALL FULL STOP
+	"\uff0e", ".", // FULLWIDTH FULL STOP
+	"\u2044", "/", // FRACTION SLASH
+	"\u2215", "/", // DIVISION SLASH
+	"\uff0f", "/", // FULLWIDTH SOLIDUS
+	"\u2216", `\`, // SET MINUS
+	"\ufe68", `\`, // SMALL REVERSE SOLIDUS
+	"\uff3c", `\`, // FULLWIDTH REVERSE SOLIDUS
+	"\u200b", "", // ZERO WIDTH SPACE
+	"\u200c", "", // ZERO WIDTH NON-JOINER
+	"\u200d", "", // ZERO WIDTH JOINER
+	"\u2060", "", // WORD JOINER
+	"\ufeff", "", // ZERO WIDTH NO-BREAK SPACE
+	"\u202a", "", // LEFT-TO-RIGHT EMBEDDING
+	"\u202b", "", // RIGHT-TO-LEFT EMBEDDING
+	"\u202c", "", // POP DIRECTIONAL FORMATTING
+	"\u202d", "", // LEFT-TO-RIGHT OVERRIDE
+	"\u202e", "", // RIGHT-TO-LEFT OVERRIDE
+)
+
+// Canonicalize returns the canonical form of a path under the instance's
+// configuration. The steps always run in this order:
+//
+//  1. Unicode folding (WithUnicodeFolding)
+//  2. Separator normalization, "\" to "/" (WithNormalizeSeparators)
+//  3. Unicode case folding (WithCaseInsensitive)
+//  4. Lexical cleaning with path.Clean
+//
+// Each step is idempotent and none of them produces input that an earlier
+// step would rewrite, so Canonicalize(Canonicalize(x)) == Canonicalize(x)
+// for every configuration. Both the input and the canonical form must pass
+// traversal detection; otherwise an error wrapping ErrTraversalDetected is
+// returned.
+func (ps *PathSecurity) Canonicalize(p string) (string, error) {
+	p, err := ps.prepare(p)
+	if err != nil {
+		return "", err
+	}
+
+	canonical := ps.cfg.canonicalize(p)
+	if err := ps.check(canonical); err != nil {
+		return "", err
+	}
+	return canonical, nil
+}
+
+// CanonicalStep is one transformation recorded by CanonicalizeDetailed
+type CanonicalStep struct {
+	// Name is one of "expand-home", "expand-env", "decode", "normalize",
+	// "native-separators", "segments", "unicode-fold", "separators",
+	// "case-fold" or "clean"
+	Name string `json:"name"`
+	// Path is the path after the step
+	Path string `json:"path"`
+}
+
+// Canonical is the canonical form of a path together with how it was
+// reached, for callers that derive quota, cache or deduplication keys from
+// it
+type Canonical struct {
+	// Path is the canonical form, as returned by Canonicalize
+	Path     string `json:"path"`
+	Original string `json:"original"`
+	// Steps lists, in order, the preprocessing and canonicalization steps
+	// that changed the path
+	Steps []CanonicalStep `json:"steps,omitempty"`
+	// Decodings is the number of percent-decoding passes applied
+	Decodings int `json:"decodings"`
+	// RemovedSegments lists the segments dropped by lexical cleaning, in
+	// order: "." segments, empty segments from doubled or trailing slashes,
+	// and ".." segments together with the segment each one cancelled
+	RemovedSegments []string `json:"removed_segments,omitempty"`
+	Absolute        bool     `json:"absolute"`
+	// Volume is the drive letter or UNC host and share prefix of the path,
+	// such as "C:" or "//server/share", and empty for other paths
+	Volume string `json:"volume,omitempty"`
+	// Depth is the number of segments of Path below its root or volume
+	Depth int `json:"depth"`
+}
+
+// CanonicalizeDetailed is Canonicalize reporting the intermediate form of
+// the path after each step, from environment expansion through lexical
+// cleaning, along with facts about the result. It accepts and rejects
+// exactly the paths Canonicalize does.
+func (ps *PathSecurity) CanonicalizeDetailed(p string) (Canonical, error) {
+	c := Canonical{Original: p}
+	apply := func(name, next string) {
+		if next != p {
+			c.Steps = append(c.Steps, CanonicalStep{Name: name, Path: next})
+			p = next
+		}
+	}
+
+	// The standard library sees the input as given, as in preprocess
+	if ps.cfg.stdlibParity == ParityOff {
+		_, passes, _, err := ps.preprocessStages(p, apply)
+		if err != nil {
+			return Canonical{}, err
+		}
+		c.Decodings = passes
+	}
+	if err := ps.check(p); err != nil {
+		return Canonical{}, err
+	}
+	ps.warn(p)
+
+	if ps.cfg.unicodeFolding {
+		apply("unicode-fold", unicodeFolder.Replace(p))
+	}
+	if ps.cfg.normalizeSeparators {
+		apply("separators", strings.ReplaceAll(p, `\`, "/"))
+	}
+	apply("case-fold", ps.cfg.foldCase(p))
+	// Cleaning turns "//server/share" into "/server/share", so the volume
+	// is read first
+	c.Volume = volumeName(p)
+	c.RemovedSegments = removedSegments(p)
+	apply("clean", path.Clean(p))
+	if err := ps.check(p); err != nil {
+		return Canonical{}, err
+	}
+
+	c.Path = p
+	c.Absolute = ps.cfg.isAbsolute(p) || c.Volume != ""
+	c.Depth = pathDepth(p, c.Volume)
+	return c, nil
+}
+
+// volumeName returns the drive letter or UNC host and share prefix of p
+func volumeName(p string) string {
+	if len(p) >= 2 && p[1] == ':' && isASCIILetter(p[0]) {
+		return p[:2]
+	}
+	if len(p) < 3 || !isSeparator(rune(p[0])) || !isSeparator(rune(p[1])) || isSeparator(rune(p[2])) {
+		return ""
+	}
+	// UNC: the host and share segments after the leading separators
+	end, segments := 2, 0
+	for end < len(p) {
+		if isSeparator(rune(p[end])) {
+			if segments++; segments == 2 {
+				break
+			}
+		}
+		end++
+	}
+	return p[:end]
+}
+
+// removedSegments lists the segments path.Clean drops from p
+func removedSegments(p string) []string {
+	var removed, kept []string
+	for i, segment := range strings.Split(p, "/") {
+		switch {
+		case segment == "" && i == 0:
+			// The root of an absolute path
+		case segment == "", segment == ".":
+			removed = append(removed, segment)
+		case segment == ".." && len(kept) > 0 && kept[len(kept)-1] != "..":
+			removed = append(removed, kept[len(kept)-1], segment)
+			kept = kept[:len(kept)-1]
+		case segment == ".." && strings.HasPrefix(p, "/"):
+			// ".." at the root of an absolute path stays at the root
+			removed = append(removed, segment)
+		default:
+			kept = append(kept, segment)
+		}
+	}
+	return removed
+}
+
+// pathDepth counts the segments of a cleaned path below its root or volume
+func pathDepth(p, volume string) int {
+	skip := 0
+	if strings.HasPrefix(p, volume) {
+		p = p[len(volume):]
+	} else {
+		// Cleaning reduced the leading separators of a UNC volume to one
+		skip = len(strings.FieldsFunc(volume, isSeparator))
+	}
+	if p == "." {
+		return 0
+	}
+	return len(strings.FieldsFunc(p, isSeparator)) - skip
+}
+
+// canonicalize applies the canonicalization steps without validating
+func (c *config) canonicalize(p string) string {
+	if c.unicodeFolding {
+		p = unicodeFolder.Replace(p)
+	}
+	if c.normalizeSeparators {
+		p = strings.ReplaceAll(p, `\`, "/")
+	}
+	return path.Clean(c.foldCase(p))
+}
+
+// foldCase applies Unicode case folding when WithCaseInsensitive is
+// enabled. Folding maps "ß" to "ss" and the Kelvin sign to "k", which
+// strings.ToLower does not.
+func (c *config) foldCase(s string) string {
+	if !c.caseInsensitive {
+		return s
+	}
+	// A Caser keeps state, so one is created per call
+	return cases.Fold().String(s)
+}
+
+// withinRoot is withinRoot comparing case-folded forms when
+// WithCaseInsensitive is enabled
+func (c *config) withinRoot(root, clean string) bool {
+	return withinRoot(c.foldCase(root), c.foldCase(clean))
+}
@@ -0,0 +1,132 @@
+package pathsecurity
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// FuzzCanonicalize checks that Canonicalize is idempotent under every
+// combination of the case, Unicode and separator settings
+func FuzzCanonicalize(f *testing.F) {
+	for _, sample := range []string{
+		"/Var/WWW/./Site//Index.HTML",
+		`Docs\Reports\2024\`,
+		"uploads\uff0fAvatar\u200b.png",
+		"a/b/../c",
+		"STRASSE/Stra\u00dfe/\u01c5",
+		"",
+	} {
+		f.Add(sample, false, false, false)
+		f.Add(sample, true, true, true)
+	}
+
+	f.Fuzz(func(t *testing.T, p string, caseInsensitive, normalizeSeparators, unicodeFolding bool) {
+		ps := NewPathSecurity(
+			WithCaseInsensitive(caseInsensitive),
+			WithNormalizeSeparators(normalizeSeparators),
+			WithUnicodeFolding(unicodeFolding),
+		)
+		once, err := ps.Canonicalize(p)
+		if err != nil {
+			return
+		}
+		twice, err := ps.Canonicalize(once)
+		if err != nil {
+			t.Fatalf("Canonicalize(%q) = %q, which is rejected: %v", p, once, err)
+		}
+		if twice != once {
+			t.Fatalf("Canonicalize(%q) = %q, but Canonicalize(%q) = %q", p, once, once, twice)
+		}
+	})
+}
+
+func TestCanonicalizeDetailed(t *testing.T) {
+	tests := []struct {
+		path string
+		want Canonical
+	}{
+		{"Reports/./2024/Q1%2520Summary.PDF", Canonical{
+			Path:     "reports/2024/q1 summary.pdf",
+			Original: "Reports/./2024/Q1%2520Summary.PDF",
+			Steps: []CanonicalStep{
+				{Name: "decode", Path: "Reports/./2024/Q1 Summary.PDF"},
+				{Name: "case-fold", Path: "reports/./2024/q1 summary.pdf"},
+				{Name: "clean", Path: "reports/2024/q1 summary.pdf"},
+			},
+			Decodings:       2,
+			RemovedSegments: []string{"."},
+			Depth:           3,
+		}},
+		{`C:\Users\Public\notes.txt`, Canonical{
+			Path:     "c:/users/public/notes.txt",
+			Original: `C:\Users\Public\notes.txt`,
+			Steps: []CanonicalStep{
+				{Name: "separators", Path: "C:/Users/Public/notes.txt"},
+				{Name: "case-fold", Path: "c:/users/public/notes.txt"},
+			},
+			Absolute: true,
+			Volume:   "c:",
+			Depth:    3,
+		}},
+	}
+	ps := NewPathSecurity(
+		WithDecoding(DecodeRecursive),
+		WithNormalizeSeparators(true),
+		WithCaseInsensitive(true),
+		WithWindowsSemantics(true),
+	)
+	for _, tt := range tests {
+		got, err := ps.CanonicalizeDetailed(tt.path)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CanonicalizeDetailed(%q) = %+v, %v, want %+v", tt.path, got, err, tt.want)
+		}
+		if plain, err := ps.Canonicalize(tt.path); err != nil || plain != got.Path {
+			t.Errorf("Canonicalize(%q) = %q, %v, want %q", tt.path, plain, err, got.Path)
+		}
+	}
+
+	if _, err := ps.CanonicalizeDetailed("../../etc/passwd"); err == nil {
+		t.Error("CanonicalizeDetailed accepted a path Canonicalize rejects")
+	}
+}
+
+func TestCanonicalizeDetailedRejects(t *testing.T) {
+	tests := []struct {
+		path    string
+		opts    []Option
+		wantErr error
+	}{
+		{path: "a/\xff/b", wantErr: ErrInvalidEncoding},
+		{path: "docs/" + strings.Repeat("a", 64), opts: []Option{WithMaxInputBytes(32)}, wantErr: ErrPathTooLong},
+		{path: "../../etc/passwd", wantErr: ErrTraversalDetected},
+		{path: "docs/%zz", opts: []Option{WithDecoding(DecodeOnce)}, wantErr: ErrInvalidEncoding},
+		{path: "../../etc/passwd", opts: []Option{WithStdlibParity(ParityIsLocal)}, wantErr: ErrTraversalDetected},
+		{path: "a/\xff/b", opts: []Option{WithStdlibParity(ParityIsLocal)}},
+	}
+	for _, tt := range tests {
+		ps := NewPathSecurity(tt.opts...)
+		_, err := ps.Canonicalize(tt.path)
+		_, detailedErr := ps.CanonicalizeDetailed(tt.path)
+		if !errors.Is(err, tt.wantErr) || !errors.Is(detailedErr, tt.wantErr) {
+			t.Errorf("Canonicalize(%q) = %v and CanonicalizeDetailed = %v, want %v", tt.path, err, detailedErr, tt.wantErr)
+		}
+	}
+}
+
+func TestCanonicalizeCaseFolding(t *testing.T) {
+	ps := NewPathSecurity(WithCaseInsensitive(true))
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/SRV/Data/Reports/Q1.PDF", "/srv/data/reports/q1.pdf"},
+		{"/srv/data/Stra\u00dfe/\u212a.txt", "/srv/data/strasse/k.txt"},
+	}
+	for _, tt := range tests {
+		if got, err := ps.Canonicalize(tt.path); err != nil || got != tt.want {
+			t.Errorf("Canonicalize(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
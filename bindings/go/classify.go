@@ -0,0 +1,51 @@
+package pathsecurity
+
+import (
+	"errors"
+)
+
+// RejectedPath is a path that could not be made safe, with the reason
+type RejectedPath struct {
+	Path string
+	Err  error
+}
+
+// Classify validates a batch of paths and groups them by outcome. Paths that
+// pass validation unchanged are returned in valid. Paths that fail but whose
+// sanitized form passes are returned in sanitized. Everything else is
+// returned in rejected together with the error that caused the rejection.
+// Input order is preserved within each group.
+func (ps *PathSecurity) Classify(paths []string) (valid []string, sanitized []SanitizeResult, rejected []RejectedPath) {
+	for _, path := range paths {
+		expanded, err := ps.preprocess(path)
+		if err != nil {
+			rejected = append(rejected, RejectedPath{Path: path, Err: err})
+			continue
+		}
+
+		err = ps.check(expanded)
+		if err == nil {
+			ps.warn(expanded)
+			valid = append(valid, path)
+			continue
+		}
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			rejected = append(rejected, RejectedPath{Path: path, Err: err})
+			continue
+		}
+
+		result, serr := ps.sanitize(expanded, nil)
+		if serr != nil {
+			rejected = append(rejected, RejectedPath{Path: path, Err: serr})
+			continue
+		}
+		if cerr := ps.check(result.Sanitized); cerr != nil {
+			rejected = append(rejected, RejectedPath{Path: path, Err: err})
+			continue
+		}
+		result.Original = path
+		sanitized = append(sanitized, result)
+	}
+	return valid, sanitized, rejected
+}
@@ -0,0 +1,60 @@
+package pathsecurity
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	ps := NewPathSecurity(
+		WithMaxPathLength(64),
+		WithExpandEnv(func(name string) (string, bool) {
+			return "/srv/data", name == "DATA"
+		}),
+	)
+	valid, sanitized, rejected := ps.Classify([]string{
+		"/usr/local/bin/app",
+		"/var/www/html/../app/config.json",
+		"$MISSING/reports",
+		"$DATA/reports/q3.csv",
+		"docs/../../etc/passwd",
+		"safe.txt\x00/../../etc/passwd",
+		"archive/" + strings.Repeat("x", 64) + ".tar",
+		"/home/user/documents/file.txt",
+	})
+
+	if want := []string{"/usr/local/bin/app", "$DATA/reports/q3.csv", "/home/user/documents/file.txt"}; !reflect.DeepEqual(valid, want) {
+		t.Errorf("valid = %q, want %q", valid, want)
+	}
+
+	wantSanitized := []string{"/var/www/html/../app/config.json", "docs/../../etc/passwd", "safe.txt\x00/../../etc/passwd"}
+	if len(sanitized) != len(wantSanitized) {
+		t.Fatalf("sanitized = %+v, want originals %q", sanitized, wantSanitized)
+	}
+	for i, result := range sanitized {
+		if result.Original != wantSanitized[i] || !result.Changed {
+			t.Errorf("sanitized[%d] = %+v, want changed %q", i, result, wantSanitized[i])
+		}
+		if _, err := ps.ValidatePath(result.Sanitized); err != nil {
+			t.Errorf("sanitized form %q of %q does not validate: %v", result.Sanitized, result.Original, err)
+		}
+	}
+
+	wantRejected := []struct {
+		path string
+		err  error
+	}{
+		{"$MISSING/reports", ErrUnresolvedVariable},
+		{"archive/" + strings.Repeat("x", 64) + ".tar", ErrPathTooLong},
+	}
+	if len(rejected) != len(wantRejected) {
+		t.Fatalf("rejected = %+v, want %d entries", rejected, len(wantRejected))
+	}
+	for i, want := range wantRejected {
+		if rejected[i].Path != want.path || !errors.Is(rejected[i].Err, want.err) {
+			t.Errorf("rejected[%d] = %q, %v, want %q, %v", i, rejected[i].Path, rejected[i].Err, want.path, want.err)
+		}
+	}
+}
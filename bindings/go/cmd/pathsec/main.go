@@ -0,0 +1,285 @@
+// Command pathsec validates or sanitizes paths given as arguments or read
+// from standard input, one per line.
+//
+// Usage:
+//
+//	pathsec [-sanitize] [-json] [-report] [-sarif] [-source file] [-root dir] [-tilde keep|reject|expand] [path ...]
+//	pathsec -scan dir [-json] [-sarif]
+//	pathsec -conformance | -vectors
+//	pathsec -version
+//
+// Without path arguments, or with the single argument "-", paths are read
+// from standard input. pathsec exits with status 0 when every path is
+// valid, 1 when at least one is rejected and 2 on usage or read errors, so
+// it can gate CI pipelines. Sanitizing never rejects a path, so it only
+// fails on errors. With -tilde expand, a leading "~" or "~user" is
+// replaced with the home directory from the operating system, as a shell
+// would.
+//
+// With -json, each path is printed as a JSON object carrying
+// "schema_version", "path", "valid" and, for rejected paths, "error" and
+// "category". With -report, the Analyze report of each path is printed as
+// JSON instead, listing every finding with its category, severity and
+// offset; paths reported as traversal or failing analysis count as
+// rejected.
+//
+// With -sarif, a single SARIF 2.1.0 log of the rejected paths, or with
+// -report of every finding, is printed once all paths are checked, for
+// upload to GitHub code scanning. -source names the file the paths were
+// extracted from, such as a manifest, relative to the root of the
+// repository; results are located in it, at the line of standard input
+// each path was read from.
+//
+// With -scan, the directory tree at dir is audited with ScanTree instead
+// and every finding is printed, as a line, a JSON object with -json or a
+// SARIF result located at the entry with -sarif; run it from the root of
+// the repository with a relative dir for the locations to resolve. pathsec
+// exits with status 1 if there are findings.
+//
+// With -conformance, pathsec checks the build against the conformance
+// suite of pathsecurity.ConformanceVectors, printing each failing vector,
+// and exits with status 1 if any fails. With -vectors, it prints the suite
+// as JSON, for testing other bindings and rule sets against it.
+//
+// With -version, pathsec prints the versions of the binding and the native
+// library and the capabilities of the validator, and exits.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+// maxLineLength bounds the length of a path read from standard input
+const maxLineLength = 1 << 20
+
+// outcome is the result for one path, printed as a JSON line with -json
+type outcome struct {
+	SchemaVersion int    `json:"schema_version"`
+	Path          string `json:"path"`
+	Valid         bool   `json:"valid"`
+	Sanitized     string `json:"sanitized,omitempty"`
+	Resolved      string `json:"resolved,omitempty"`
+	Error         string `json:"error,omitempty"`
+	Category      string `json:"category,omitempty"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command and returns its exit status
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("pathsec", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	sanitize := flags.Bool("sanitize", false, "print sanitized paths instead of validating")
+	jsonOutput := flags.Bool("json", false, "print one JSON object per path")
+	reportOutput := flags.Bool("report", false, "print the findings of each path as a JSON report")
+	sarifOutput := flags.Bool("sarif", false, "print a SARIF log of the rejections or findings")
+	source := flags.String("source", "", "file the paths were read from, to locate SARIF results")
+	scan := flags.String("scan", "", "audit the directory tree at `dir` instead of checking paths")
+	root := flags.String("root", "", "require paths to stay inside this directory")
+	tilde := flags.String("tilde", "keep", "treatment of a leading `~`: keep, reject or expand")
+	conformance := flags.Bool("conformance", false, "check the build against the conformance suite and exit")
+	vectors := flags.Bool("vectors", false, "print the conformance suite as JSON and exit")
+	version := flags.Bool("version", false, "print versions and validator capabilities and exit")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *version {
+		binding, clib := pathsecurity.Version()
+		if clib == "" {
+			clib = "unknown"
+		}
+		if err := pathsecurity.NativeBackend(); err != nil {
+			clib = "none (" + err.Error() + ")"
+		}
+		fmt.Fprintf(stdout, "binding %s\nlibrary %s\nruleset %d\ncapabilities %s\n", binding, clib, pathsecurity.RulesetVersion(), strings.Join(pathsecurity.Capabilities(), ","))
+		return 0
+	}
+	if *vectors {
+		stdout.Write(pathsecurity.ConformanceJSON())
+		return 0
+	}
+	if *conformance {
+		suite := pathsecurity.ConformanceVectors()
+		failed := 0
+		for _, v := range suite.Vectors {
+			if err := v.Check(); err != nil {
+				fmt.Fprintln(stdout, err)
+				failed++
+			}
+		}
+		fmt.Fprintf(stdout, "conformance suite version %d: %d vectors, %d failed\n", suite.Version, len(suite.Vectors), failed)
+		if failed > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	var opts []pathsecurity.Option
+	if *root != "" {
+		opts = append(opts, pathsecurity.WithAllowedRoots(*root))
+	}
+	switch *tilde {
+	case "keep":
+	case "reject":
+		opts = append(opts, pathsecurity.WithTildePolicy(pathsecurity.TildeReject))
+	case "expand":
+		opts = append(opts, pathsecurity.WithTildePolicy(pathsecurity.TildeExpand), pathsecurity.WithHomeDirs(pathsecurity.LookupHomeDir))
+	default:
+		fmt.Fprintf(stderr, "pathsec: invalid -tilde %q: want keep, reject or expand\n", *tilde)
+		return 2
+	}
+	ps := pathsecurity.NewPathSecurity(opts...)
+
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+	encoder := json.NewEncoder(out)
+	var log pathsecurity.SARIFLog
+	writeLog := func() {
+		encoded, _ := json.MarshalIndent(log, "", "  ")
+		fmt.Fprintf(out, "%s\n", encoded)
+	}
+
+	if *scan != "" {
+		findings, err := ps.ScanTree(*scan)
+		for _, f := range findings {
+			f.Path = filepath.Join(*scan, filepath.FromSlash(f.Path))
+			switch {
+			case *sarifOutput:
+				log.AddFinding(f.Path, f, pathsecurity.SARIFLocation{})
+			case *jsonOutput:
+				encoder.Encode(f)
+			default:
+				fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", f.Severity, f.Category, f.Path, f.Message)
+			}
+		}
+		if *sarifOutput {
+			writeLog()
+		}
+		if err != nil {
+			out.Flush()
+			fmt.Fprintf(stderr, "pathsec: scanning %s: %v\n", *scan, err)
+			return 2
+		}
+		if len(findings) > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	status := 0
+	handle := func(path string, line int) {
+		at := pathsecurity.SARIFLocation{URI: *source}
+		if *source != "" {
+			at.Line = line
+		}
+		if *reportOutput {
+			report, err := ps.Analyze(path)
+			if err != nil {
+				status = 1
+				if *sarifOutput {
+					log.AddRejection(path, err, at)
+					return
+				}
+				encoder.Encode(outcome{SchemaVersion: pathsecurity.ReportSchemaVersion, Path: path, Error: err.Error(), Category: pathsecurity.RejectionCategory(err)})
+				return
+			}
+			if report.Traversal {
+				status = 1
+			}
+			if *sarifOutput {
+				log.AddReport(report, at)
+				return
+			}
+			encoder.Encode(report)
+			return
+		}
+
+		o, err := check(ps, path, *sanitize, *root)
+		if !o.Valid {
+			status = 1
+		}
+		switch {
+		case *sarifOutput:
+			if err != nil {
+				log.AddRejection(path, err, at)
+			}
+		case *jsonOutput:
+			encoder.Encode(o)
+		case *sanitize && o.Valid:
+			fmt.Fprintln(out, o.Sanitized)
+		case o.Valid:
+			fmt.Fprintf(out, "ok\t%s\n", o.Path)
+		default:
+			fmt.Fprintf(out, "invalid\t%s\t%s\n", o.Path, o.Error)
+		}
+	}
+
+	paths := flags.Args()
+	if len(paths) > 0 && !(len(paths) == 1 && paths[0] == "-") {
+		for _, path := range paths {
+			handle(path, 0)
+		}
+		if *sarifOutput {
+			writeLog()
+		}
+		return status
+	}
+
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	for line := 1; scanner.Scan(); line++ {
+		handle(scanner.Text(), line)
+	}
+	if err := scanner.Err(); err != nil {
+		out.Flush()
+		fmt.Fprintf(stderr, "pathsec: reading standard input: %v\n", err)
+		return 2
+	}
+	if *sarifOutput {
+		writeLog()
+	}
+	return status
+}
+
+// check validates or sanitizes a single path and returns the error of a
+// rejected one. With a root, absolute paths must lie under it and relative
+// paths are resolved against it.
+func check(ps *pathsecurity.PathSecurity, path string, sanitize bool, root string) (outcome, error) {
+	o := outcome{SchemaVersion: pathsecurity.ReportSchemaVersion, Path: path}
+	var err error
+	switch {
+	case sanitize:
+		o.Sanitized, err = ps.SanitizePath(path)
+	case root != "" && filepath.IsAbs(path):
+		var rel string
+		if _, rel, err = ps.RelativeToMatchedRoot(path); err == nil {
+			o.Resolved = filepath.Join(root, filepath.FromSlash(rel))
+		}
+	case root != "":
+		o.Resolved, err = ps.ResolveWithin(root, path)
+	default:
+		_, err = ps.ValidatePath(path)
+	}
+	if err != nil {
+		o.Error = err.Error()
+		o.Category = pathsecurity.RejectionCategory(err)
+		return o, err
+	}
+	o.Valid = true
+	return o, nil
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+// grpcService is the full name of the service in pathsec.proto
+const grpcService = "pathsecurity.v1.PathSecurity"
+
+// gRPC status codes used by the service
+const (
+	grpcOK              = 0
+	grpcInvalidArgument = 3
+	grpcResourceLimit   = 8
+	grpcUnimplemented   = 12
+	grpcInternal        = 13
+)
+
+// grpcError is a failed call with its gRPC status code
+type grpcError struct {
+	code    int
+	message string
+}
+
+// Error implements error
+func (e *grpcError) Error() string {
+	return e.message
+}
+
+// newGRPCHandler serves the unary methods of pathsec.proto over HTTP/2
+// with the rules of policy in force when each call arrives. It speaks the
+// gRPC wire protocol directly, with length-prefixed protobuf messages and
+// the status in the trailers, so the command needs no gRPC module.
+// Compressed messages are refused with UNIMPLEMENTED.
+func newGRPCHandler(policy *pathsecurity.Policy) http.Handler {
+	methods := map[string]func([]byte) ([]byte, error){
+		"Validate": func(msg []byte) ([]byte, error) {
+			paths, err := decodePaths(msg)
+			if err != nil {
+				return nil, err
+			}
+			if len(paths) == 0 {
+				return nil, &grpcError{grpcInvalidArgument, "no paths given"}
+			}
+			response := appendVarintField(nil, 1, pathsecurity.ReportSchemaVersion)
+			for _, v := range checkPaths(policy, paths) {
+				var result []byte
+				result = appendStringField(result, 1, v.Path)
+				result = appendBoolField(result, 2, v.Valid)
+				result = appendStringField(result, 3, v.Error)
+				result = appendStringField(result, 4, v.Category)
+				response = appendBytesField(response, 2, result)
+			}
+			return response, nil
+		},
+		"Sanitize": func(msg []byte) ([]byte, error) {
+			path, err := decodePath(msg)
+			if err != nil {
+				return nil, err
+			}
+			response := appendStringField(nil, 1, path)
+			sanitized, err := policy.PathSecurity().SanitizePath(path)
+			if err != nil {
+				return appendStringField(response, 4, err.Error()), nil
+			}
+			response = appendStringField(response, 2, sanitized)
+			return appendBoolField(response, 3, sanitized != path), nil
+		},
+		"DetectTraversal": func(msg []byte) ([]byte, error) {
+			path, err := decodePath(msg)
+			if err != nil {
+				return nil, err
+			}
+			response := appendStringField(nil, 1, path)
+			traversal, err := policy.PathSecurity().DetectTraversal(path)
+			if err != nil {
+				return appendStringField(response, 3, err.Error()), nil
+			}
+			return appendBoolField(response, 2, traversal), nil
+		},
+		"Analyze": func(msg []byte) ([]byte, error) {
+			path, err := decodePath(msg)
+			if err != nil {
+				return nil, err
+			}
+			report, err := policy.PathSecurity().Analyze(path)
+			if err != nil {
+				response := appendStringField(nil, 2, err.Error())
+				return appendStringField(response, 3, pathsecurity.RejectionCategory(err)), nil
+			}
+			encoded, err := json.Marshal(report)
+			if err != nil {
+				return nil, err
+			}
+			return appendBytesField(nil, 1, encoded), nil
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+			return
+		}
+		if r.Method != http.MethodPost || !isGRPCContentType(r.Header.Get("Content-Type")) {
+			http.Error(w, "not a gRPC request", http.StatusUnsupportedMediaType)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+
+		name, ok := strings.CutPrefix(r.URL.Path, "/"+grpcService+"/")
+		method := methods[name]
+		if !ok || method == nil {
+			writeGRPCStatus(w, &grpcError{grpcUnimplemented, "unknown method " + r.URL.Path})
+			return
+		}
+		if encoding := r.Header.Get("Grpc-Encoding"); encoding != "" && encoding != "identity" {
+			writeGRPCStatus(w, &grpcError{grpcUnimplemented, "compression " + encoding + " is not supported"})
+			return
+		}
+
+		request, err := readGRPCMessage(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		if err != nil {
+			writeGRPCStatus(w, err)
+			return
+		}
+		response, err := method(request)
+		if err != nil {
+			writeGRPCStatus(w, err)
+			return
+		}
+		frame := make([]byte, 5, 5+len(response))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(response)))
+		w.Write(append(frame, response...))
+		writeGRPCStatus(w, nil)
+	})
+}
+
+// isGRPCContentType reports whether a request content type is gRPC with
+// protobuf messages
+func isGRPCContentType(contentType string) bool {
+	base, _, _ := strings.Cut(contentType, ";")
+	return base == "application/grpc" || base == "application/grpc+proto"
+}
+
+// readGRPCMessage reads the single length-prefixed message of a unary call
+func readGRPCMessage(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, grpcReadError(err)
+	}
+	if header[0] != 0 {
+		return nil, &grpcError{grpcUnimplemented, "compressed messages are not supported"}
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxRequestBody {
+		return nil, &grpcError{grpcResourceLimit, fmt.Sprintf("message of %d bytes is larger than %d", size, maxRequestBody)}
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, grpcReadError(err)
+	}
+	if n, _ := r.Read(header[:1]); n != 0 {
+		return nil, &grpcError{grpcInvalidArgument, "more than one request message in a unary call"}
+	}
+	return msg, nil
+}
+
+// grpcReadError converts an error reading the request into a status
+func grpcReadError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &grpcError{grpcResourceLimit, "request is too large"}
+	}
+	return &grpcError{grpcInvalidArgument, "malformed request: " + err.Error()}
+}
+
+// writeGRPCStatus sets the status trailers for err, OK if it is nil
+func writeGRPCStatus(w http.ResponseWriter, err error) {
+	code, message := grpcOK, ""
+	if err != nil {
+		code, message = grpcInternal, err.Error()
+		var status *grpcError
+		if errors.As(err, &status) {
+			code = status.code
+		}
+	}
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", percentEncode(message))
+	}
+}
+
+// percentEncode escapes a status message as the gRPC protocol requires
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// decodePaths returns the values of field 1 of a request message, the
+// paths of a ValidateRequest or the path of a PathRequest
+func decodePaths(msg []byte) ([]string, error) {
+	fields, err := parseProto(msg)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, f := range fields {
+		if f.num != 1 {
+			continue
+		}
+		if f.wire != wireBytes || !utf8.Valid(f.bytes) {
+			return nil, &grpcError{grpcInvalidArgument, "path is not a UTF-8 string"}
+		}
+		paths = append(paths, string(f.bytes))
+	}
+	return paths, nil
+}
+
+// decodePath returns the path of a PathRequest, the last one given as
+// protobuf requires for a field that is not repeated
+func decodePath(msg []byte) (string, error) {
+	paths, err := decodePaths(msg)
+	if err != nil || len(paths) == 0 {
+		return "", err
+	}
+	return paths[len(paths)-1], nil
+}
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// protoField is one field of an encoded protobuf message
+type protoField struct {
+	num    int
+	wire   int
+	varint uint64
+	bytes  []byte
+}
+
+// parseProto splits an encoded protobuf message into its fields
+func parseProto(msg []byte) ([]protoField, error) {
+	malformed := &grpcError{grpcInvalidArgument, "malformed protobuf message"}
+	var fields []protoField
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 || key>>3 == 0 || key>>3 > 1<<29-1 {
+			return nil, malformed
+		}
+		msg = msg[n:]
+		f := protoField{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			if f.varint, n = binary.Uvarint(msg); n <= 0 {
+				return nil, malformed
+			}
+			msg = msg[n:]
+		case wireFixed64, wireFixed32:
+			size := 8
+			if f.wire == wireFixed32 {
+				size = 4
+			}
+			if len(msg) < size {
+				return nil, malformed
+			}
+			f.bytes, msg = msg[:size], msg[size:]
+		case wireBytes:
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || size > uint64(len(msg)-n) {
+				return nil, malformed
+			}
+			f.bytes, msg = msg[n:n+int(size)], msg[n+int(size):]
+		default:
+			return nil, malformed
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// appendVarintField appends field num with the varint v, leaving out zero
+// as proto3 does
+func appendVarintField(b []byte, num int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(num)<<3|wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+// appendBoolField appends the bool field num if v is true
+func appendBoolField(b []byte, num int, v bool) []byte {
+	if !v {
+		return b
+	}
+	return appendVarintField(b, num, 1)
+}
+
+// appendBytesField appends the length-delimited field num
+func appendBytesField(b []byte, num int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// appendStringField appends the string field num unless s is empty
+func appendStringField(b []byte, num int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return appendBytesField(b, num, []byte(s))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// callGRPC makes a unary call of method with the encoded request msg and
+// returns the encoded response, if any, and the grpc-status trailer
+func callGRPC(t *testing.T, server *httptest.Server, method string, msg []byte) ([]byte, string) {
+	t.Helper()
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/"+grpcService+"/"+method, bytes.NewReader(append(frame, msg...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ProtoMajor != 2 || resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s: %s %d", method, resp.Proto, resp.StatusCode)
+	}
+	if len(body) == 0 {
+		return nil, resp.Trailer.Get("Grpc-Status")
+	}
+	if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+		t.Fatalf("%s: malformed response frame %q", method, body)
+	}
+	return body[5:], resp.Trailer.Get("Grpc-Status")
+}
+
+// fieldsOf decodes a response message into its fields by number
+func fieldsOf(t *testing.T, msg []byte) map[int][]protoField {
+	t.Helper()
+	fields, err := parseProto(msg)
+	if err != nil {
+		t.Fatalf("parseProto(%q) error = %v", msg, err)
+	}
+	byNum := make(map[int][]protoField)
+	for _, f := range fields {
+		byNum[f.num] = append(byNum[f.num], f)
+	}
+	return byNum
+}
+
+func TestGRPCHandler(t *testing.T) {
+	server := httptest.NewUnstartedServer(newGRPCHandler(testPolicy(t)))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	response, status := callGRPC(t, server, "Validate", appendStringField(appendStringField(nil, 1, "docs/readme.md"), 1, "../../etc/passwd"))
+	fields := fieldsOf(t, response)
+	if status != "0" || len(fields[1]) != 1 || fields[1][0].varint != 1 || len(fields[2]) != 2 {
+		t.Fatalf("Validate = %q, status %s", response, status)
+	}
+	valid, rejected := fieldsOf(t, fields[2][0].bytes), fieldsOf(t, fields[2][1].bytes)
+	if string(valid[1][0].bytes) != "docs/readme.md" || len(valid[2]) != 1 || valid[2][0].varint != 1 {
+		t.Errorf("Validate result for docs/readme.md = %q", fields[2][0].bytes)
+	}
+	if string(rejected[1][0].bytes) != "../../etc/passwd" || len(rejected[2]) != 0 || string(rejected[4][0].bytes) != "traversal" {
+		t.Errorf("Validate result for ../../etc/passwd = %q", fields[2][1].bytes)
+	}
+
+	response, status = callGRPC(t, server, "Sanitize", appendStringField(nil, 1, "docs/readme.md"))
+	if fields := fieldsOf(t, response); status != "0" || string(fields[2][0].bytes) != "docs/readme.md" || len(fields[3]) != 0 {
+		t.Errorf("Sanitize = %q, status %s", response, status)
+	}
+	response, status = callGRPC(t, server, "DetectTraversal", appendStringField(nil, 1, "../../etc/passwd"))
+	if fields := fieldsOf(t, response); status != "0" || len(fields[2]) != 1 || fields[2][0].varint != 1 {
+		t.Errorf("DetectTraversal = %q, status %s", response, status)
+	}
+	response, status = callGRPC(t, server, "Analyze", appendStringField(nil, 1, "../../etc/passwd"))
+	if fields := fieldsOf(t, response); status != "0" || len(fields[1]) != 1 || !strings.Contains(string(fields[1][0].bytes), `"schema_version":1`) {
+		t.Errorf("Analyze = %q, status %s", response, status)
+	}
+
+	for _, tt := range []struct {
+		method string
+		msg    []byte
+		want   string
+	}{
+		{"Validate", nil, "3"},
+		{"Validate", []byte{0x0a, 0x10}, "3"},
+		{"Validate", appendStringField(nil, 1, "a/\xff"), "3"},
+		{"Explain", appendStringField(nil, 1, "a"), "12"},
+	} {
+		if _, status := callGRPC(t, server, tt.method, tt.msg); status != tt.want {
+			t.Errorf("%s(%q) status = %s, want %s", tt.method, tt.msg, status, tt.want)
+		}
+	}
+}
+
+func TestGRPCHandlerHTTP1(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/"+grpcService+"/Validate", nil)
+	req.Header.Set("Content-Type", "application/grpc")
+	newGRPCHandler(testPolicy(t)).ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusHTTPVersionNotSupported {
+		t.Errorf("HTTP/1.1 call = %d, want %d", recorder.Code, http.StatusHTTPVersionNotSupported)
+	}
+}
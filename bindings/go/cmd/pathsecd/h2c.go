@@ -0,0 +1,14 @@
+//go:build go1.24
+
+package main
+
+import "net/http"
+
+// enableH2C lets server accept HTTP/2 without TLS, as gRPC clients connect
+// to plaintext endpoints
+func enableH2C(server *http.Server) error {
+	server.Protocols = new(http.Protocols)
+	server.Protocols.SetHTTP1(true)
+	server.Protocols.SetUnencryptedHTTP2(true)
+	return nil
+}
@@ -0,0 +1,341 @@
+// Command pathsecd serves the validation API over HTTP with JSON bodies,
+// and over gRPC, so services written in other languages can share one
+// centrally configured policy instead of each embedding the native
+// library.
+//
+// Usage:
+//
+//	pathsecd [-addr host:port] [-grpc-addr host:port] [-root dir] [-policy file] [-native-timeout d] [-webhook url] [-ruleset file -ruleset-keys keys]
+//
+// With -policy, the JSON policy file read by pathsecurity.LoadPolicy
+// configures validation, including its reject severity; -root adds a root
+// to it. The file is watched and reloaded when it changes, without
+// interrupting requests in flight; a file that fails to load is logged and
+// the previous policy stays in force. -native-timeout abandons calls into
+// the native library that take longer, as set with
+// pathsecurity.WithNativeTimeout. -webhook posts rejection events in
+// batches to a URL, in the schema documented by package webhook.
+//
+// With -ruleset, the signed ruleset bundle in file, as written by
+// pathsecurity.SignRuleset, replaces the embedded traversal patterns once
+// its signature verifies against one of the comma-separated base64
+// Ed25519 public keys of -ruleset-keys. Like the policy, the file is
+// watched, and a bundle with a newer version is put in use when it is
+// replaced; a bundle that fails to verify is logged and ignored.
+//
+// Endpoints, all taking and returning JSON:
+//
+//	POST /v1/validate   {"paths": ["a", "b"]} or {"path": "a"}
+//	POST /v1/sanitize   {"path": "a"}
+//	POST /v1/traversal  {"path": "a"}
+//	POST /v1/analyze    {"path": "a"}
+//	GET  /metrics       Prometheus metrics
+//	GET  /healthz       liveness, 503 while abandoned native calls run
+//
+// A rejected path is not an HTTP error: /v1/validate answers 200 with
+// "valid": false and the reason and category of the rejection, and
+// /v1/analyze with the JSON encoding of pathsecurity.Report, listing every
+// finding. Both carry a "schema_version" that changes only when fields are
+// removed or change meaning. Malformed requests get 400.
+//
+// With -grpc-addr, the service pathsecurity.v1.PathSecurity defined in
+// pathsec.proto is served on that address as well, over HTTP/2 without
+// TLS. Its methods Validate, Sanitize, DetectTraversal and Analyze answer
+// as the endpoints of the same name do, and malformed requests fail with
+// INVALID_ARGUMENT. The command implements the wire protocol itself rather
+// than importing the gRPC module, which would become a dependency of every
+// user of the Go binding. Unencrypted HTTP/2 needs pathsecd built with Go
+// 1.24 or later.
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+	"github.com/redasgard/path-security/bindings/go/metrics"
+	"github.com/redasgard/path-security/bindings/go/webhook"
+)
+
+// maxRequestBody bounds the size of a request body
+const maxRequestBody = 1 << 20
+
+// request is the body of every POST endpoint
+type request struct {
+	Path  string   `json:"path"`
+	Paths []string `json:"paths"`
+}
+
+// validation is the outcome for one path returned by /v1/validate
+type validation struct {
+	Path     string `json:"path"`
+	Valid    bool   `json:"valid"`
+	Error    string `json:"error,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+// run executes the command and returns its exit status
+func run(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("pathsecd", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", "127.0.0.1:8780", "address to listen on")
+	grpcAddr := flags.String("grpc-addr", "", "also serve gRPC on this address")
+	root := flags.String("root", "", "require paths to stay inside this directory")
+	policyFile := flags.String("policy", "", "read the validation policy from this JSON file")
+	nativeTimeout := flags.Duration("native-timeout", 0, "abandon native library calls that take longer than this")
+	webhookURL := flags.String("webhook", "", "post rejection events in batches to this URL")
+	rulesetFile := flags.String("ruleset", "", "check the traversal patterns of the signed ruleset bundle in this file")
+	rulesetKeys := flags.String("ruleset-keys", "", "comma-separated base64 Ed25519 public keys trusted to sign -ruleset")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	var keys []ed25519.PublicKey
+	if *rulesetFile != "" {
+		var err error
+		if keys, err = parseKeys(*rulesetKeys); err != nil {
+			fmt.Fprintf(stderr, "pathsecd: -ruleset-keys: %v\n", err)
+			return 2
+		}
+	}
+
+	registry := metrics.New()
+	opts := []pathsecurity.Option{pathsecurity.WithMetrics(registry), pathsecurity.WithNativeTimeout(*nativeTimeout)}
+	if *root != "" {
+		opts = append(opts, pathsecurity.WithAllowedRoots(*root))
+	}
+	if *webhookURL != "" {
+		emitter := webhook.New(*webhookURL, webhook.WithSource("pathsecd"), webhook.WithErrorFunc(func(err error) {
+			fmt.Fprintf(stderr, "pathsecd: %v\n", err)
+		}))
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			emitter.Close(ctx)
+		}()
+		opts = append(opts, pathsecurity.WithAuditFunc(emitter.Emit))
+	}
+	policy, err := loadPolicy(*policyFile, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "pathsecd: %v\n", err)
+		return 2
+	}
+
+	server := newServer(*addr, newHandler(policy, registry))
+	var grpcServer *http.Server
+	if *grpcAddr != "" {
+		grpcServer = newServer(*grpcAddr, newGRPCHandler(policy))
+		if err := enableH2C(grpcServer); err != nil {
+			fmt.Fprintf(stderr, "pathsecd: -grpc-addr: %v\n", err)
+			return 2
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *policyFile != "" {
+		go policy.Watch(ctx, *policyFile, func(err error) {
+			if err != nil {
+				fmt.Fprintf(stderr, "pathsecd: reloading %s: %v\n", *policyFile, err)
+			}
+		})
+	}
+	if *rulesetFile != "" {
+		go pathsecurity.WatchRuleset(ctx, *rulesetFile, keys, func(err error) {
+			if err != nil {
+				fmt.Fprintf(stderr, "pathsecd: loading ruleset %s: %v\n", *rulesetFile, err)
+			}
+		})
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if grpcServer != nil {
+			grpcServer.Shutdown(shutdownCtx)
+		}
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if grpcServer != nil {
+		go func() {
+			if err := grpcServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				fmt.Fprintf(stderr, "pathsecd: gRPC: %v\n", err)
+				stop()
+			}
+		}()
+	}
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(stderr, "pathsecd: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// newServer returns a server for handler on addr with the timeouts of the
+// service
+func newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+}
+
+// parseKeys decodes comma-separated base64 Ed25519 public keys
+func parseKeys(s string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(field)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("key %q is %d bytes, want %d", field, len(key), ed25519.PublicKeySize)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no keys given")
+	}
+	return keys, nil
+}
+
+// loadPolicy reads the policy file, or builds a default policy without one
+func loadPolicy(file string, opts []pathsecurity.Option) (*pathsecurity.Policy, error) {
+	if file == "" {
+		return pathsecurity.NewPolicy(pathsecurity.PolicySpec{}, opts...)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return pathsecurity.LoadPolicy(f, opts...)
+}
+
+// newHandler routes the API endpoints to the rules of policy in force when
+// each request arrives
+func newHandler(policy *pathsecurity.Policy, registry *metrics.Registry) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/validate", post(func(req request) (any, error) {
+		paths := req.Paths
+		if req.Path != "" {
+			paths = append([]string{req.Path}, paths...)
+		}
+		if len(paths) == 0 {
+			return nil, errors.New("no paths given")
+		}
+		return map[string]any{"schema_version": pathsecurity.ReportSchemaVersion, "results": checkPaths(policy, paths)}, nil
+	}))
+	mux.HandleFunc("/v1/sanitize", post(func(req request) (any, error) {
+		sanitized, err := policy.PathSecurity().SanitizePath(req.Path)
+		if err != nil {
+			return map[string]any{"path": req.Path, "error": err.Error()}, nil
+		}
+		return map[string]any{"path": req.Path, "sanitized": sanitized, "changed": sanitized != req.Path}, nil
+	}))
+	mux.HandleFunc("/v1/traversal", post(func(req request) (any, error) {
+		traversal, err := policy.PathSecurity().DetectTraversal(req.Path)
+		if err != nil {
+			return map[string]any{"path": req.Path, "error": err.Error()}, nil
+		}
+		return map[string]any{"path": req.Path, "traversal": traversal}, nil
+	}))
+	mux.HandleFunc("/v1/analyze", post(func(req request) (any, error) {
+		report, err := policy.PathSecurity().Analyze(req.Path)
+		if err != nil {
+			return map[string]any{"schema_version": pathsecurity.ReportSchemaVersion, "path": req.Path, "error": err.Error(), "category": pathsecurity.RejectionCategory(err)}, nil
+		}
+		return report, nil
+	}))
+	mux.Handle("/metrics", registry)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if health := pathsecurity.Health(); !health.Healthy() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "%d native calls stuck\n", health.Stuck)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+	return mux
+}
+
+// checkPaths checks paths against policy for /v1/validate and its gRPC
+// method
+func checkPaths(policy *pathsecurity.Policy, paths []string) []validation {
+	results := make([]validation, 0, len(paths))
+	for _, path := range paths {
+		v := validation{Path: path, Valid: true}
+		if err := policy.Check(path); err != nil {
+			v.Valid = false
+			v.Error = err.Error()
+			v.Category = pathsecurity.RejectionCategory(err)
+		}
+		results = append(results, v)
+	}
+	return results
+}
+
+// post adapts fn to an endpoint that accepts only POST requests with a
+// JSON request body. An error from fn is a bad request.
+func post(fn func(request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var req request
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "malformed request: "+err.Error())
+			return
+		}
+		response, err := fn(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// writeError writes an error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
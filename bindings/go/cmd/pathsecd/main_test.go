@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+	"github.com/redasgard/path-security/bindings/go/metrics"
+)
+
+// testPolicy returns the default policy the server starts with
+func testPolicy(t *testing.T) *pathsecurity.Policy {
+	t.Helper()
+	policy, err := loadPolicy("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policy
+}
+
+func TestHandler(t *testing.T) {
+	registry := metrics.New()
+	policy, err := loadPolicy("", []pathsecurity.Option{pathsecurity.WithMetrics(registry)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := newHandler(policy, registry)
+	tests := []struct {
+		method, target, body string
+		wantCode             int
+		want                 []string
+	}{
+		{http.MethodPost, "/v1/validate", `{"path": "docs/readme.md"}`, http.StatusOK,
+			[]string{`"schema_version":1`, `{"path":"docs/readme.md","valid":true}`}},
+		{http.MethodPost, "/v1/validate", `{"paths": ["docs/readme.md", "../../etc/passwd"]}`, http.StatusOK,
+			[]string{`"valid":true`, `"path":"../../etc/passwd","valid":false`, `"category":"traversal"`}},
+		{http.MethodPost, "/v1/validate", `{}`, http.StatusBadRequest, []string{`"error":"no paths given"`}},
+		{http.MethodPost, "/v1/validate", `{"pth": "a"}`, http.StatusBadRequest, []string{`"error":"malformed request`}},
+		{http.MethodPost, "/v1/validate", `{"path": `, http.StatusBadRequest, []string{`"error":"malformed request`}},
+		{http.MethodGet, "/v1/validate", "", http.StatusMethodNotAllowed, []string{`"error":"method not allowed"`}},
+		{http.MethodPost, "/v1/sanitize", `{"path": "docs/readme.md"}`, http.StatusOK,
+			[]string{`"changed":false`, `"sanitized":"docs/readme.md"`}},
+		{http.MethodPost, "/v1/traversal", `{"path": "../../etc/passwd"}`, http.StatusOK, []string{`"traversal":true`}},
+		{http.MethodPost, "/v1/traversal", `{"path": "docs/readme.md"}`, http.StatusOK, []string{`"traversal":false`}},
+		{http.MethodPost, "/v1/analyze", `{"path": "../../etc/passwd"}`, http.StatusOK, []string{`"schema_version":1`, `"traversal"`}},
+		{http.MethodGet, "/healthz", "", http.StatusOK, []string{"ok"}},
+		{http.MethodGet, "/metrics", "", http.StatusOK, []string{"pathsecurity_validations_total"}},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+		if recorder.Code != tt.wantCode {
+			t.Errorf("%s %s %s = %d, want %d", tt.method, tt.target, tt.body, recorder.Code, tt.wantCode)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(recorder.Body.String(), want) {
+				t.Errorf("%s %s %s = %s, want it to contain %s", tt.method, tt.target, tt.body, recorder.Body, want)
+			}
+		}
+	}
+}
+
+func TestHandlerRequestLimit(t *testing.T) {
+	handler := newHandler(testPolicy(t), metrics.New())
+	body := `{"path": "` + strings.Repeat("a", maxRequestBody) + `"}`
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/validate", strings.NewReader(body)))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("POST of %d bytes = %d, want %d", len(body), recorder.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,104 @@
+package pathsecurity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"runtime"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// hostCaseInsensitive reports whether the default file systems of the
+// platform ignore case: NTFS on Windows and APFS on macOS and iOS
+const hostCaseInsensitive = runtime.GOOS == "windows" || runtime.GOOS == "darwin" || runtime.GOOS == "ios"
+
+// Equal reports whether a and b name the same path on the host platform,
+// ignoring case on Windows and macOS. It builds a PathSecurity from opts
+// for the call, after that default; use the method of the same name to
+// reuse one.
+func Equal(a, b string, opts ...Option) bool {
+	return NewPathSecurity(append([]Option{WithCaseInsensitive(hostCaseInsensitive)}, opts...)...).Equal(a, b)
+}
+
+// Contains reports whether child is parent or lies below it on the host
+// platform, ignoring case on Windows and macOS. It builds a PathSecurity
+// from opts for the call, after that default; use the method of the same
+// name to reuse one.
+func Contains(parent, child string, opts ...Option) bool {
+	return NewPathSecurity(append([]Option{WithCaseInsensitive(hostCaseInsensitive)}, opts...)...).Contains(parent, child)
+}
+
+// Equal reports whether a and b name the same path, comparing their
+// canonical forms rather than their text. Both are canonicalized as by
+// Canonicalize and then put in NFC, so composed and decomposed accents
+// match as on macOS; case is ignored under WithCaseInsensitive and Windows
+// semantics, where '\' and '/' are also the same separator. The comparison
+// is lexical and does not follow symlinks. A path that Canonicalize rejects
+// equals nothing, not even itself, and rejections are not reported to the
+// audit function or metrics collector.
+func (ps *PathSecurity) Equal(a, b string) bool {
+	formA, ok := ps.comparable(a)
+	if !ok {
+		return false
+	}
+	formB, ok := ps.comparable(b)
+	return ok && formA == formB
+}
+
+// Contains reports whether child is parent or lies below it, comparing
+// whole segments of the forms Equal compares, so "/data/a/../b" and
+// "/data/ab" are not under "/data/a". Both paths are untrusted and a path
+// that Canonicalize rejects contains, and is contained in, nothing. Use
+// RequirePrefix to enforce containment with an error.
+func (ps *PathSecurity) Contains(parent, child string) bool {
+	parentForm, ok := ps.comparable(parent)
+	if !ok {
+		return false
+	}
+	childForm, ok := ps.comparable(child)
+	return ok && hasPathPrefix(childForm, parentForm)
+}
+
+// CanonicalKey returns a stable key for the path p names, for
+// deduplication and cache or rate-limit keys: the hex SHA-256 of the form
+// Equal compares, so different spellings of a path that Equal reports as
+// the same, such as "a/./b" and "a/b/", or "A\B" and "a/b" under Windows
+// semantics, have the same key and other paths different ones. Keys depend
+// on the configuration, as canonicalization does, and should only be
+// compared between instances configured alike. Like Equal, they are
+// lexical and do not follow symlinks. A path that Canonicalize rejects has
+// no key, and the rejection is reported as by Canonicalize.
+func (ps *PathSecurity) CanonicalKey(p string) (string, error) {
+	canonical, err := ps.Canonicalize(p)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(ps.cfg.comparisonForm(canonical)))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// comparable returns the form of p that Equal and Contains compare, and
+// false if Canonicalize rejects p
+func (ps *PathSecurity) comparable(p string) (string, bool) {
+	canonical, err := ps.quiet().Canonicalize(p)
+	if err != nil {
+		return "", false
+	}
+	return ps.cfg.comparisonForm(canonical), true
+}
+
+// comparisonForm puts a canonical path in NFC with '/' as its only
+// separator, folding case under Windows semantics, for comparisons
+func (c *config) comparisonForm(canonical string) string {
+	form := norm.NFC.String(canonical)
+	if c.windowsSemantics {
+		form = strings.ReplaceAll(form, `\`, "/")
+		if !c.caseInsensitive {
+			// Canonicalization folds case itself under WithCaseInsensitive
+			form = cases.Fold().String(form)
+		}
+	}
+	return form
+}
@@ -0,0 +1,85 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b               string
+		want, wantCaseless bool
+	}{
+		{"/data/a/./b", "/data/a/b/", true, true},
+		{"/data/a/b", "/data/A/B", false, true},
+		{"caf\u00e9/menu", "cafe\u0301/menu", true, true},
+		// Paths that fail validation equal nothing, not even themselves
+		{"../x", "../x", false, false},
+	}
+	posix := NewPathSecurity(WithWindowsSemantics(false))
+	caseless := posix.Clone(WithCaseInsensitive(true))
+	for _, tt := range tests {
+		if got := posix.Equal(tt.a, tt.b); got != tt.want {
+			t.Errorf("Equal(%q, %q) = %t, want %t", tt.a, tt.b, got, tt.want)
+		}
+		if got := caseless.Equal(tt.a, tt.b); got != tt.wantCaseless {
+			t.Errorf("case-insensitive Equal(%q, %q) = %t, want %t", tt.a, tt.b, got, tt.wantCaseless)
+		}
+		if got := Equal(tt.a, tt.b, WithWindowsSemantics(false)); got != tt.want {
+			t.Errorf("package Equal(%q, %q) = %t, want %t", tt.a, tt.b, got, tt.want)
+		}
+	}
+	if !NewPathSecurity(WithWindowsSemantics(true)).Equal(`C:\Data\x`, "c:/data/X") {
+		t.Error(`Windows Equal(C:\Data\x, c:/data/X) = false`)
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		parent, child string
+		want          bool
+	}{
+		{"/data/a", "/data/a/b.txt", true},
+		{"/data/a", "/data/a", true},
+		{"/data/a", "/data/ab/x", false},
+		{"/data/a", "/data/a/../b", false},
+		{"/", "/etc", true},
+		{"docs", "docs/x", true},
+	}
+	ps := NewPathSecurity(WithWindowsSemantics(false))
+	for _, tt := range tests {
+		if got := ps.Contains(tt.parent, tt.child); got != tt.want {
+			t.Errorf("Contains(%q, %q) = %t, want %t", tt.parent, tt.child, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalKey(t *testing.T) {
+	posix := NewPathSecurity(WithWindowsSemantics(false))
+	caseless := posix.Clone(WithCaseInsensitive(true))
+	key := func(ps *PathSecurity, p string) string {
+		t.Helper()
+		k, err := ps.CanonicalKey(p)
+		if err != nil {
+			t.Fatalf("CanonicalKey(%q) error = %v", p, err)
+		}
+		return k
+	}
+
+	if a, b := key(posix, "/data/a/./b"), key(posix, "/data/a/b/"); a != b {
+		t.Errorf("CanonicalKey differs for spellings of one path: %s, %s", a, b)
+	}
+	if a, b := key(posix, "/data/a/b"), key(posix, "/data/A/B"); a == b {
+		t.Error("case-sensitive CanonicalKey folds case")
+	}
+	if a, b := key(caseless, "/data/a/b"), key(caseless, "/data/A/B"); a != b {
+		t.Errorf("case-insensitive CanonicalKey differs by case: %s, %s", a, b)
+	}
+	windows := NewPathSecurity(WithWindowsSemantics(true))
+	if a, b := key(windows, `C:\Data\x`), key(windows, "c:/data/X"); a != b {
+		t.Errorf("Windows CanonicalKey differs: %s, %s", a, b)
+	}
+	if _, err := posix.CanonicalKey("/data/a/../../etc"); !errors.Is(err, ErrTraversalDetected) {
+		t.Errorf("CanonicalKey of a traversal error = %v, want %v", err, ErrTraversalDetected)
+	}
+}
@@ -0,0 +1,120 @@
+package pathsecurity
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// conformanceJSON is the conformance suite as shipped in
+// conformance/vectors.json
+//
+//go:embed conformance/vectors.json
+var conformanceJSON []byte
+
+// ConformanceVector is a path with the verdict validation must reach on it
+// under a policy
+type ConformanceVector struct {
+	// ID names the vector stably across versions of the suite, as its
+	// group and a number, such as "payload-01"
+	ID   string `json:"id"`
+	Path string `json:"path"`
+	// Policy configures validation as the JSON policies of LoadPolicy do.
+	// Every vector sets its target_os, so that verdicts do not depend on
+	// the platform running the suite.
+	Policy PolicySpec `json:"policy"`
+	// Valid is the expected verdict
+	Valid bool `json:"valid"`
+	// Category is the label of RejectionCategory a rejection must have,
+	// and empty for accepted paths and for rejections that the native
+	// library and the pure-Go engine classify differently
+	Category string `json:"category,omitempty"`
+}
+
+// ConformanceSuite is the versioned set of test vectors that every binding
+// of the library, and rules written to match it such as those of a WAF,
+// must agree with. Its Version increases whenever a vector is added or
+// removed or its expected verdict changes.
+type ConformanceSuite struct {
+	Version int                 `json:"version"`
+	Vectors []ConformanceVector `json:"vectors"`
+}
+
+// ConformanceFailure describes a vector whose verdict differs from the one
+// expected
+type ConformanceFailure struct {
+	Vector ConformanceVector
+	// Err is the error validation returned, nil if it accepted the path
+	Err error
+}
+
+func (f *ConformanceFailure) Error() string {
+	if f.Err == nil {
+		return fmt.Sprintf("conformance vector %s: %q accepted, want rejected", f.Vector.ID, f.Vector.Path)
+	}
+	if f.Vector.Valid {
+		return fmt.Sprintf("conformance vector %s: %q rejected, want accepted: %v", f.Vector.ID, f.Vector.Path, f.Err)
+	}
+	return fmt.Sprintf("conformance vector %s: %q rejected as %s, want %s: %v", f.Vector.ID, f.Vector.Path, RejectionCategory(f.Err), f.Vector.Category, f.Err)
+}
+
+func (f *ConformanceFailure) Unwrap() error {
+	return f.Err
+}
+
+// conformanceSuite decodes the embedded suite once
+var conformanceSuite = sync.OnceValue(func() ConformanceSuite {
+	var suite ConformanceSuite
+	if err := json.Unmarshal(conformanceJSON, &suite); err != nil {
+		panic(fmt.Sprintf("pathsecurity: embedded conformance suite: %v", err))
+	}
+	return suite
+})
+
+// ConformanceVectors returns a copy of the conformance suite embedded in
+// the binding. The same document is in conformance/vectors.json in the
+// source tree, for bindings and tools in other languages to load; use
+// ConformanceJSON to export it from a build.
+func ConformanceVectors() ConformanceSuite {
+	suite := conformanceSuite()
+	suite.Vectors = append([]ConformanceVector(nil), suite.Vectors...)
+	return suite
+}
+
+// ConformanceJSON returns a copy of the conformance suite as the JSON
+// document ConformanceVectors decodes
+func ConformanceJSON() []byte {
+	return append([]byte(nil), conformanceJSON...)
+}
+
+// Check validates the vector's path with Policy.Check under its policy,
+// with opts applied after it, and returns a *ConformanceFailure if the
+// verdict or category differs from the one expected
+func (v ConformanceVector) Check(opts ...Option) error {
+	policy, err := NewPolicy(v.Policy, opts...)
+	if err != nil {
+		return fmt.Errorf("conformance vector %s: %w", v.ID, err)
+	}
+	err = policy.Check(v.Path)
+	switch {
+	case v.Valid && err != nil,
+		!v.Valid && err == nil,
+		!v.Valid && v.Category != "" && RejectionCategory(err) != v.Category:
+		return &ConformanceFailure{Vector: v, Err: err}
+	}
+	return nil
+}
+
+// Check runs every vector of the suite and joins the errors of those that
+// fail, returning nil if all pass
+func (s ConformanceSuite) Check(opts ...Option) error {
+	var failures []error
+	for _, v := range s.Vectors {
+		if err := v.Check(opts...); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	return errors.Join(failures...)
+}
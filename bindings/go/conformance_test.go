@@ -0,0 +1,50 @@
+package pathsecurity
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestConformanceVectors(t *testing.T) {
+	suite := ConformanceVectors()
+	if suite.Version < 1 || len(suite.Vectors) == 0 {
+		t.Fatalf("ConformanceVectors = version %d, %d vectors", suite.Version, len(suite.Vectors))
+	}
+	for _, v := range suite.Vectors {
+		if err := v.Check(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	var exported ConformanceSuite
+	if err := json.Unmarshal(ConformanceJSON(), &exported); err != nil || len(exported.Vectors) != len(suite.Vectors) {
+		t.Errorf("ConformanceJSON decodes to %d vectors, %v, want %d", len(exported.Vectors), err, len(suite.Vectors))
+	}
+	// Callers get a copy of the embedded suite
+	suite.Vectors[0].Valid = !suite.Vectors[0].Valid
+	if err := ConformanceVectors().Check(); err != nil {
+		t.Errorf("Check after changing a copy of the suite = %v", err)
+	}
+}
+
+func TestConformanceVectorCheck(t *testing.T) {
+	vector := ConformanceVector{ID: "local-01", Path: "docs/../readme.md", Valid: true}
+	var failure *ConformanceFailure
+	err := vector.Check()
+	if !errors.As(err, &failure) || failure.Vector.ID != "local-01" || !errors.Is(err, ErrTraversalDetected) {
+		t.Errorf("Check = %v, want a *ConformanceFailure wrapping %v", err, ErrTraversalDetected)
+	}
+
+	vector = ConformanceVector{ID: "local-02", Path: "docs/readme.md"}
+	if err := vector.Check(); !errors.As(err, &failure) || failure.Err != nil {
+		t.Errorf("Check of an accepted path expected to fail = %v", err)
+	}
+	vector = ConformanceVector{ID: "local-03", Path: "../x", Category: "null_byte"}
+	if err := vector.Check(); !errors.As(err, &failure) {
+		t.Errorf("Check of a rejection in the wrong category = %v", err)
+	}
+	if err := (ConformanceVector{ID: "local-04", Path: "../x", Category: "traversal"}).Check(); err != nil {
+		t.Errorf("Check of a matching rejection = %v", err)
+	}
+}
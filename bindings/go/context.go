@@ -0,0 +1,99 @@
+package pathsecurity
+
+import (
+	"context"
+)
+
+// The lexical variants below only check ctx before starting, as validation
+// without file system access cannot block. The variants that touch the file
+// system run the call in a separate goroutine and return ctx.Err() as soon
+// as ctx is done; a system call stuck on a hung mount keeps that goroutine
+// alive until it returns, but the caller is released.
+
+// ValidatePathContext is ValidatePath honoring ctx
+func (ps *PathSecurity) ValidatePathContext(ctx context.Context, path string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return ps.ValidatePath(path)
+}
+
+// DetectTraversalContext is DetectTraversal honoring ctx
+func (ps *PathSecurity) DetectTraversalContext(ctx context.Context, path string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return ps.DetectTraversal(path)
+}
+
+// ValidatePathsContext is ValidatePaths honoring ctx. If ctx is done before
+// the batch starts, every Result carries ctx.Err().
+func (ps *PathSecurity) ValidatePathsContext(ctx context.Context, paths []string) []Result {
+	if err := ctx.Err(); err != nil {
+		results := make([]Result, len(paths))
+		for i, path := range paths {
+			results[i] = Result{Path: path, Err: err}
+		}
+		return results
+	}
+	return ps.ValidatePaths(paths)
+}
+
+// ValidateResolvedContext is ValidateResolved honoring ctx while symlinks
+// are resolved
+func (ps *PathSecurity) ValidateResolvedContext(ctx context.Context, path string) (string, error) {
+	return runContext(ctx, func() (string, error) {
+		return ps.ValidateResolved(path)
+	})
+}
+
+// CheckWritableContext is CheckWritable honoring ctx while the parent
+// directory is inspected
+func (ps *PathSecurity) CheckWritableContext(ctx context.Context, root, path string) error {
+	_, err := runContext(ctx, func() (struct{}, error) {
+		return struct{}{}, ps.CheckWritable(root, path)
+	})
+	return err
+}
+
+// AuthorizeContext is Authorize honoring ctx while symlinks are resolved
+func (ps *PathSecurity) AuthorizeContext(ctx context.Context, path string, op Operation) error {
+	_, err := runContext(ctx, func() (struct{}, error) {
+		return struct{}{}, ps.Authorize(path, op)
+	})
+	return err
+}
+
+// AnalyzeContext is Analyze honoring ctx while path components are checked
+// for symlinks
+func (ps *PathSecurity) AnalyzeContext(ctx context.Context, path string) (Report, error) {
+	return runContext(ctx, func() (Report, error) {
+		return ps.analyze(ctx, path)
+	})
+}
+
+// runContext runs fn in its own goroutine and returns its result, or
+// ctx.Err() if ctx is done first
+func runContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	type outcome struct {
+		value T
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := fn()
+		done <- outcome{value, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.value, o.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
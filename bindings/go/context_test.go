@@ -0,0 +1,44 @@
+package pathsecurity
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestContextVariants(t *testing.T) {
+	root := testTree(t)
+	ps := NewPathSecurity(WithAllowedRoots(root))
+	target := filepath.Join(root, "inbox", "new.txt")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if resolved, err := ps.ValidateResolvedContext(ctx, target); err != nil || filepath.Base(resolved) != "new.txt" {
+		t.Errorf("ValidateResolvedContext = %q, %v", resolved, err)
+	}
+	if err := ps.AuthorizeContext(ctx, target, OpWrite); err != nil {
+		t.Errorf("AuthorizeContext = %v", err)
+	}
+	if _, err := ps.ValidatePathContext(ctx, "../../etc/passwd"); !errors.Is(err, ErrTraversalDetected) {
+		t.Errorf("ValidatePathContext = %v, want %v", err, ErrTraversalDetected)
+	}
+	cancel()
+
+	if _, err := ps.ValidateResolvedContext(ctx, target); !errors.Is(err, context.Canceled) {
+		t.Errorf("ValidateResolvedContext after cancel = %v", err)
+	}
+	if _, err := ps.ValidatePathContext(ctx, "docs/readme.md"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ValidatePathContext after cancel = %v", err)
+	}
+	if _, err := ps.DetectTraversalContext(ctx, "docs/readme.md"); !errors.Is(err, context.Canceled) {
+		t.Errorf("DetectTraversalContext after cancel = %v", err)
+	}
+	if err := ps.CheckWritableContext(ctx, root, "inbox/new.txt"); !errors.Is(err, context.Canceled) {
+		t.Errorf("CheckWritableContext after cancel = %v", err)
+	}
+	for _, result := range ps.ValidatePathsContext(ctx, []string{"a.txt", "b.txt"}) {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("ValidatePathsContext after cancel: %q = %v", result.Path, result.Err)
+		}
+	}
+}
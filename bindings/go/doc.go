@@ -0,0 +1,34 @@
+// Package pathsecurity provides Go bindings for Path Security, validating
+// and sanitizing file paths against traversal, encoding and Unicode attacks.
+//
+// By default the package links libpath_security_c through cgo. Building
+// with CGO_ENABLED=0 or the purego build tag selects a pure-Go port of the
+// validator instead, so programs can be cross-compiled and linked
+// statically without the native library. Building with the dlopen tag, on
+// Linux and macOS, loads the library at run time and falls back to the
+// pure-Go port if it is missing, for deployments where it is optional;
+// NativeBackend reports which one is in use. Builds for WebAssembly, with
+// GOOS=js or GOOS=wasip1, have no cgo and always use the port, so the same
+// checks can run in browsers and WASI plugins. Resolved checks there see
+// whatever file system the host provides, such as that of Node.js or the
+// directories a WASI runtime preopens, and fail where there is none.
+//
+// A PathSecurity is immutable once created and may be shared freely between
+// goroutines, for example by all handlers of an HTTP server. The native
+// library builds a fresh validator for every call and holds no global
+// state, so no locking or pooling is needed around it. Callbacks such as
+// those passed to WithWarnObserver may in turn be called concurrently.
+//
+// Checks come in two modes, which CheckPath selects per call. Lexical
+// checks judge a path by its text and the configuration, make no system
+// calls and give the same result everywhere; ValidatePath, DetectTraversal,
+// SanitizePath, Canonicalize, ValidatePaths, ResolveWithin, ResolveExport,
+// ValidateFileURL and the other functions that take a path without opening
+// it are lexical, except that they call the lookups set with WithHomeDirs
+// and WithExpandEnv and stat existing paths for the special file check of
+// WithSensitivePaths. Resolved checks consult the file system, following
+// symlinks and reading modes: ValidateResolved, Authorize, CheckWritable,
+// Analyze, CheckTempPath, SecureTempPath, OpenInRoot and the file systems
+// returned by FS and WrapFS. Their results hold only at the time of the
+// call, as the tree may change before the path is used.
+package pathsecurity
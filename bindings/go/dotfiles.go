@@ -0,0 +1,86 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"slices"
+)
+
+// DotfilePolicy selects how segments starting with a dot, such as .git,
+// .env, .htaccess and .ssh, are treated. Servers exposing these through a
+// static file handler leak repositories, credentials and configuration,
+// and a policy that denies them is the direct way to prevent it.
+type DotfilePolicy int
+
+const (
+	// DotfilesAllow accepts hidden files and directories like any other
+	// name
+	DotfilesAllow DotfilePolicy = iota
+	// DotfilesDeny rejects every path with a segment starting with a dot,
+	// other than "." and "..", with an error wrapping ErrHiddenPath. The
+	// segment may be anywhere in the path, so "a/.git/config" is rejected
+	// as well as ".env".
+	DotfilesDeny
+	// DotfilesAllowlist is DotfilesDeny except for the names set with
+	// WithAllowedDotfiles, such as ".well-known". An allowed name covers
+	// only itself: the segments below it are checked in turn, so
+	// ".well-known/.git" is still rejected.
+	DotfilesAllowlist
+)
+
+// String returns the name of the policy
+func (p DotfilePolicy) String() string {
+	switch p {
+	case DotfilesAllow:
+		return "allow"
+	case DotfilesDeny:
+		return "deny"
+	case DotfilesAllowlist:
+		return "allowlist"
+	}
+	return fmt.Sprintf("DotfilePolicy(%d)", int(p))
+}
+
+// FindDotfile returns the byte offset and text of the first segment of p
+// that starts with a dot, other than "." and "..", or -1 and "" if there is
+// none. Both '/' and '\' separate segments, so that a name hidden behind
+// either is found whatever the platform serving it.
+func FindDotfile(p string) (int, string) {
+	return findDotfile(p, func(string) bool { return false })
+}
+
+// findDotfile is FindDotfile skipping the segments allowed reports true for
+func findDotfile(p string, allowed func(segment string) bool) (int, string) {
+	start := 0
+	for i := 0; i <= len(p); i++ {
+		if i < len(p) && !isSeparator(rune(p[i])) {
+			continue
+		}
+		if segment := p[start:i]; len(segment) > 1 && segment[0] == '.' && segment != ".." && !allowed(segment) {
+			return start, segment
+		}
+		start = i + 1
+	}
+	return -1, ""
+}
+
+// checkDotfiles applies the policy set with WithDotfilePolicy. Allowed
+// names are compared case-folded under WithCaseInsensitive.
+func (c *config) checkDotfiles(p string) *ValidationError {
+	if c.dotfilePolicy == DotfilesAllow {
+		return nil
+	}
+	allowed := func(segment string) bool {
+		if c.dotfilePolicy != DotfilesAllowlist {
+			return false
+		}
+		folded := c.foldCase(segment)
+		return slices.ContainsFunc(c.allowedDotfiles, func(name string) bool {
+			return c.foldCase(name) == folded
+		})
+	}
+	offset, segment := findDotfile(p, allowed)
+	if offset < 0 {
+		return nil
+	}
+	return &ValidationError{Path: p, Reason: fmt.Sprintf("Hidden file or directory %q at offset %d", segment, offset), Err: ErrHiddenPath}
+}
@@ -0,0 +1,45 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithDotfilePolicy(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{path: "site/index.html"},
+		{path: ".git/config", wantErr: ErrHiddenPath},
+		{path: "app/.env", wantErr: ErrHiddenPath},
+		{path: "site/.htaccess", wantErr: ErrHiddenPath},
+		{path: ".well-known/security.txt"},
+		{path: ".well-known/.git/HEAD", wantErr: ErrHiddenPath},
+		{path: "./site/index.html"},
+	}
+	ps := NewPathSecurity(WithDotfilePolicy(DotfilesAllowlist), WithAllowedDotfiles(".well-known"))
+	for _, tt := range tests {
+		if _, err := ps.ValidatePath(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidatePath(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFindDotfile(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantOffset  int
+		wantSegment string
+	}{
+		{"home/user/.ssh/id_rsa", 10, ".ssh"},
+		{".env", 0, ".env"},
+		{"./site/../index.html", -1, ""},
+		{"site/index.html", -1, ""},
+	}
+	for _, tt := range tests {
+		if offset, segment := FindDotfile(tt.path); offset != tt.wantOffset || segment != tt.wantSegment {
+			t.Errorf("FindDotfile(%q) = %d, %q, want %d, %q", tt.path, offset, segment, tt.wantOffset, tt.wantSegment)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Package echosec provides Echo middleware that rejects requests whose
+// route parameters fail Path Security validation, such as the wildcard of
+// "/files/*". It is a module of its own so that the bindings do not depend
+// on Echo.
+package echosec
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+	"github.com/redasgard/path-security/bindings/go/httpsec"
+)
+
+// Option configures the middleware
+type Option func(*config)
+
+// config holds the middleware settings
+type config struct {
+	ps         *pathsecurity.PathSecurity
+	params     []string
+	statusCode int
+	observer   func(c echo.Context, err error)
+}
+
+// WithPathSecurity sets the validator used for route parameters. The
+// default is pathsecurity.NewPathSecurity() with no options.
+func WithPathSecurity(ps *pathsecurity.PathSecurity) Option {
+	return func(c *config) {
+		c.ps = ps
+	}
+}
+
+// WithParams limits validation to the parameters with the given names, as
+// Echo reports them: without ':', and "*" for the wildcard. By default
+// every parameter of the matched route is validated.
+func WithParams(names ...string) Option {
+	return func(c *config) {
+		c.params = names
+	}
+}
+
+// WithStatusCode sets the status of the error returned for rejected
+// requests. The default is http.StatusBadRequest.
+func WithStatusCode(code int) Option {
+	return func(c *config) {
+		c.statusCode = code
+	}
+}
+
+// WithObserver registers a function called with each rejected request and
+// the *httpsec.ParamError, for logging or metrics
+func WithObserver(observer func(c echo.Context, err error)) Option {
+	return func(c *config) {
+		c.observer = observer
+	}
+}
+
+// Protect returns middleware that passes a request on only if the route
+// parameters pass httpsec.CheckParams, and otherwise returns an
+// *echo.HTTPError with the configured status code and the validation error
+// as its internal error, for the HTTP error handler to render. Register it
+// with Echo.Use, on a group or on a route; middleware registered with
+// Echo.Pre runs before routing and sees no parameters.
+func Protect(opts ...Option) echo.MiddlewareFunc {
+	cfg := config{statusCode: http.StatusBadRequest}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.ps == nil {
+		cfg.ps = pathsecurity.NewPathSecurity()
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			names, values := c.ParamNames(), c.ParamValues()
+			params := make([]httpsec.Param, 0, len(names))
+			for i, name := range names {
+				if i < len(values) {
+					params = append(params, httpsec.Param{Name: name, Value: values[i]})
+				}
+			}
+			if err := httpsec.CheckParams(cfg.ps, params, cfg.params...); err != nil {
+				if cfg.observer != nil {
+					cfg.observer(c, err)
+				}
+				return echo.NewHTTPError(cfg.statusCode).SetInternal(err)
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// DecodePolicy controls how percent-encoded input is handled before validation
+type DecodePolicy int
+
+const (
+	// DecodeNone passes percent-encoding through to the validator unchanged
+	DecodeNone DecodePolicy = iota
+	// DecodeOnce decodes percent-encoding a single time, as most servers do
+	DecodeOnce
+	// DecodeRecursive decodes until the path no longer changes, unwrapping
+	// double encodings such as %252e, up to maxDecodePasses times
+	DecodeRecursive
+	// RejectEncoded rejects any path that contains percent-encoding
+	RejectEncoded
+)
+
+// String returns the name of the policy
+func (p DecodePolicy) String() string {
+	switch p {
+	case DecodeNone:
+		return "none"
+	case DecodeOnce:
+		return "once"
+	case DecodeRecursive:
+		return "recursive"
+	case RejectEncoded:
+		return "reject"
+	}
+	return fmt.Sprintf("DecodePolicy(%d)", int(p))
+}
+
+// maxDecodePasses bounds DecodeRecursive; deeper nesting is rejected
+const maxDecodePasses = 4
+
+// encodedSlashReplacer rewrites percent-encoded forward slashes to literal ones
+var encodedSlashReplacer = strings.NewReplacer("%2F", "/", "%2f", "/")
+
+// splitEncodedSlashes treats %2F as a path separator, mirroring servers that
+// decode it before routing
+func splitEncodedSlashes(path string) string {
+	if !strings.Contains(path, "%2") {
+		return path
+	}
+	return encodedSlashReplacer.Replace(path)
+}
+
+// hasDotDotSegment reports whether any slash-separated segment is ".."
+func hasDotDotSegment(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// hasBackslashDotDot reports whether path has a ".." segment once '\' is
+// treated as a separator alongside '/', as in `..\../..\./etc/passwd`,
+// which many frameworks resolve even on systems where '\' is a name
+// character
+func hasBackslashDotDot(path string) bool {
+	return strings.IndexByte(path, '\\') >= 0 && hasDotDotSegment(strings.ReplaceAll(path, `\`, "/"))
+}
+
+// decode applies the decoding policy configured with WithDecoding. Decoded
+// output must be valid UTF-8 without overlong sequences such as %c0%ae,
+// otherwise an error wrapping ErrInvalidEncoding is returned.
+func (ps *PathSecurity) decode(path string) (string, error) {
+	decoded, _, err := ps.decodePasses(path)
+	return decoded, err
+}
+
+// decodePasses is decode that also returns the number of decoding passes
+// applied
+func (ps *PathSecurity) decodePasses(path string) (string, int, error) {
+	policy := ps.cfg.decoding
+	if policy == DecodeNone || !strings.Contains(path, "%") {
+		return path, 0, nil
+	}
+	if policy == RejectEncoded {
+		return "", 0, ps.reject(path, &ValidationError{Path: path, Reason: "Percent-encoding is not allowed", Err: ErrInvalidEncoding})
+	}
+
+	decoded := path
+	for pass := 0; ; pass++ {
+		if pass == maxDecodePasses {
+			return "", 0, ps.reject(path, &ValidationError{Path: path, Reason: fmt.Sprintf("Percent-encoding nested more than %d levels", maxDecodePasses), Err: ErrInvalidEncoding})
+		}
+
+		next, err := url.PathUnescape(decoded)
+		if err != nil {
+			return "", 0, ps.reject(path, &ValidationError{Path: path, Reason: "Malformed percent-encoding", Err: ErrInvalidEncoding})
+		}
+		if i := findOverlongUTF8(next); i >= 0 {
+			return "", 0, ps.reject(path, &ValidationError{Path: path, Reason: fmt.Sprintf("Overlong UTF-8 sequence at offset %d", i), Err: ErrInvalidEncoding})
+		}
+		if !utf8.ValidString(next) {
+			return "", 0, ps.reject(path, &ValidationError{Path: path, Reason: "Decoded path is not valid UTF-8", Err: ErrInvalidEncoding})
+		}
+
+		decoded = next
+		if policy == DecodeOnce || !strings.Contains(decoded, "%") {
+			return decoded, pass + 1, nil
+		}
+	}
+}
+
+// findOverlongUTF8 returns the offset of the first overlong UTF-8 lead
+// sequence in s, such as C0 AE for '.', or -1 if there is none
+func findOverlongUTF8(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == 0xc0, c == 0xc1:
+			return i
+		case c == 0xe0 && i+1 < len(s) && s[i+1] < 0xa0:
+			return i
+		case c == 0xf0 && i+1 < len(s) && s[i+1] < 0x90:
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,66 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithTreatEncodedSlashAsSeparator(t *testing.T) {
+	const reason = "Directory traversal through encoded slashes"
+	for _, enabled := range []bool{false, true} {
+		ps := NewPathSecurity(WithTreatEncodedSlashAsSeparator(enabled))
+		if traversal, err := ps.DetectTraversal("a%2F..%2Fb"); err != nil || !traversal {
+			t.Errorf("enabled=%t: DetectTraversal(a%%2F..%%2Fb) = %t, %v, want true", enabled, traversal, err)
+		}
+
+		_, err := ps.ValidatePath("a%2F..%2Fb")
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("enabled=%t: ValidatePath(a%%2F..%%2Fb) error = %v, want a *ValidationError", enabled, err)
+		}
+		if got := verr.Reason == reason; got != enabled {
+			t.Errorf("enabled=%t: ValidatePath(a%%2F..%%2Fb) reason = %q", enabled, verr.Reason)
+		}
+		if enabled && !errors.Is(err, ErrTraversalDetected) {
+			t.Errorf("ValidatePath(a%%2F..%%2Fb) error = %v, want %v", err, ErrTraversalDetected)
+		}
+	}
+
+	if got := splitEncodedSlashes("a%2F..%2fb"); got != "a/../b" {
+		t.Errorf("splitEncodedSlashes(a%%2F..%%2fb) = %q, want a/../b", got)
+	}
+}
+
+func TestWithDecoding(t *testing.T) {
+	tests := []struct {
+		path string
+		want map[DecodePolicy]bool
+	}{
+		{path: "%2e%2e%2fetc%2fpasswd", want: map[DecodePolicy]bool{DecodeOnce: true, DecodeRecursive: true, RejectEncoded: true}},
+		{path: "%252e%252e/etc", want: map[DecodePolicy]bool{DecodeOnce: true, DecodeRecursive: true, RejectEncoded: true}},
+		{path: "..%c0%afetc", want: map[DecodePolicy]bool{DecodeOnce: true, DecodeRecursive: true, RejectEncoded: true}},
+		{path: "My%20Files/a.txt", want: map[DecodePolicy]bool{DecodeOnce: false, DecodeRecursive: false, RejectEncoded: true}},
+	}
+	for _, tt := range tests {
+		for policy, want := range tt.want {
+			ps := NewPathSecurity(WithDecoding(policy))
+			if got, err := ps.DetectTraversal(tt.path); err != nil || got != want {
+				t.Errorf("%s: DetectTraversal(%q) = %t, %v, want %t", policy, tt.path, got, err, want)
+			}
+		}
+	}
+}
+
+func TestDecodePolicyString(t *testing.T) {
+	for policy, want := range map[DecodePolicy]string{
+		DecodeNone:      "none",
+		DecodeOnce:      "once",
+		DecodeRecursive: "recursive",
+		RejectEncoded:   "reject",
+		DecodePolicy(9): "DecodePolicy(9)",
+	} {
+		if got := policy.String(); got != want {
+			t.Errorf("DecodePolicy(%d).String() = %q, want %q", int(policy), got, want)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package pathsecurity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// The pure-Go engine ports the lexical phases of the Rust validator in
+// src/validation.rs. It backs the purego build, and the dlopen build when
+// the native library cannot be loaded, and mirrors the patterns in
+// src/constants.rs, which remain the reference definitions.
+
+var suspiciousEncodedPatterns = []string{
+	"%2e", "%2E", // .
+	"%2f", "%2F", // /
+	"%5c", "%5C", // \
+	"%00",        // null byte
+	"%0a", "%0A", // newline
+	"%0d", "%0D", // carriage return
+}
+
+var overlongUTF8Patterns = []string{
+	"%c0%ae",    // overlong .
+	"%c0%af",    // overlong /
+	"%c1%9c",    // overlong \
+	"%c0%2e",    // invalid encoding
+	"%e0%80%ae", // 3-byte overlong .
+}
+
+var traversalPatterns = []string{
+	"..",
+	"...",  // Some systems treat this specially
+	"....", // Quad dots
+	". .",  // Space between dots
+	". . ", // Multiple spaces
+	".\t.", // Tab between dots
+	".|.",  // Pipe between dots
+}
+
+var nestedTraversalPatterns = []string{
+	"....//", // Quad dot double slash
+	`....\/`, // Quad dot mixed separator
+	"..../",  // Quad dot slash
+	`....\\`, // Quad dot backslash
+	".|./",   // Pipe dot slash
+	`.|\/`,   // Pipe dot backslash-slash
+}
+
+var dangerousSeparators = []string{";", "\t", "\n", "\r"}
+
+var windowsReservedNames = []string{
+	"CON", "PRN", "AUX", "NUL", "COM1", "COM2", "COM3", "COM4",
+	"COM5", "COM6", "COM7", "COM8", "COM9", "LPT1", "LPT2",
+	"LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+}
+
+var dangerousProtocols = []string{
+	"file://", "file:/",
+	"http://", "https://",
+	"ftp://", "ftps://", "sftp://",
+	"gopher://", "data:", "javascript:",
+	"vbscript:", "jar:", "php://",
+}
+
+var systemPaths = []string{
+	"/proc/", "/sys/", "/dev/",
+	`C:\Windows\System32`, `C:\Windows\Temp`,
+	"/tmp/", "/var/tmp/",
+	"/etc/", "/boot/",
+}
+
+var suspiciousPatterns = []string{
+	"~",    // Home directory expansion
+	"$",    // Environment variable expansion
+	"\x00", // Null byte injection
+	`\`,    // Backslash
+}
+
+// maxPathLength mirrors MAX_PATH_LENGTH in src/constants.rs
+const maxPathLength = 4096
+
+// validateLexical runs the lexical validation phases of the Rust validator.
+// Unlike validate_path it has no base directory, so absolute paths are
+// accepted and no file system canonicalization takes place.
+func validateLexical(path string) error {
+	// Phase 1: pre-processing and normalization checks
+	if strings.TrimSpace(path) != path {
+		return errors.New("Leading or trailing whitespace detected in path")
+	}
+	if strings.Contains(path, "  ") {
+		return errors.New("Multiple consecutive spaces detected in path")
+	}
+	if len(path) > maxPathLength {
+		return fmt.Errorf("Path too long: %d characters (max: %d)", len(path), maxPathLength)
+	}
+	if i := strings.IndexByte(path, 0); i >= 0 {
+		return fmt.Errorf("Null byte detected at position %d in path", i)
+	}
+
+	// Phase 2: protocol and URL scheme detection
+	lower := strings.ToLower(path)
+	for _, protocol := range dangerousProtocols {
+		if strings.HasPrefix(lower, protocol) {
+			return fmt.Errorf("Dangerous protocol scheme detected: %s", protocol)
+		}
+	}
+
+	// Phase 3: encoding attack detection
+	for _, pattern := range suspiciousEncodedPatterns {
+		if strings.Contains(path, pattern) {
+			return fmt.Errorf("URL-encoded characters detected in path: %s", pattern)
+		}
+	}
+	if strings.Contains(path, "%25") {
+		return errors.New("Double URL encoding detected in path")
+	}
+	for _, pattern := range overlongUTF8Patterns {
+		if strings.Contains(lower, pattern) {
+			return fmt.Errorf("UTF-8 overlong encoding detected: %s", pattern)
+		}
+	}
+	if strings.Contains(path, "%u") {
+		return errors.New("Unicode percent encoding (%u) detected in path")
+	}
+	if strings.Contains(path, "&#") {
+		return errors.New("HTML entity encoding detected in path")
+	}
+	if err := detectDangerousUnicode(path); err != nil {
+		return err
+	}
+
+	// Phase 4: path structure validation
+	if strings.Contains(path, "//") || strings.Contains(path, `\\`) {
+		if !strings.HasPrefix(path, "//") && !strings.HasPrefix(path, `\\`) {
+			return errors.New("Multiple consecutive path separators detected")
+		}
+	}
+	if strings.Contains(path, "/") && strings.Contains(path, `\`) {
+		return errors.New("Mixed path separators detected (possible evasion)")
+	}
+	for _, sep := range dangerousSeparators {
+		if strings.Contains(path, sep) {
+			return errors.New("Unusual separator character detected in path")
+		}
+	}
+	for _, pattern := range traversalPatterns {
+		if strings.Contains(path, pattern) {
+			return fmt.Errorf("Directory traversal pattern detected: %s", pattern)
+		}
+	}
+	for _, pattern := range nestedTraversalPatterns {
+		if strings.Contains(path, pattern) {
+			return fmt.Errorf("Nested traversal pattern detected: %s", pattern)
+		}
+	}
+	if strings.Contains(path, `\x2e`) || strings.Contains(path, `\x2f`) || strings.Contains(path, `\x5c`) {
+		return errors.New("Hex-encoded path characters detected")
+	}
+
+	// Phase 5: Windows-specific attack detection
+	if err := detectWindowsAttacks(path); err != nil {
+		return err
+	}
+
+	// Phase 6: basic suspicious pattern checks
+	for _, pattern := range suspiciousPatterns {
+		if strings.Contains(path, pattern) {
+			return fmt.Errorf("Suspicious pattern '%s' detected in path: %s", pattern, path)
+		}
+	}
+
+	// Phase 7: special path validation
+	for _, dangerous := range systemPaths {
+		if strings.HasPrefix(lower, strings.ToLower(dangerous)) {
+			return fmt.Errorf("Access to sensitive system path denied: %s", dangerous)
+		}
+	}
+
+	if detectMixedEncoding(path) {
+		return fmt.Errorf("Mixed encoding attack detected in path: %s", path)
+	}
+	return nil
+}
+
+// detectDangerousUnicode mirrors detect_dangerous_unicode in src/encoding.rs
+func detectDangerousUnicode(path string) error {
+	for _, r := range path {
+		switch {
+		case r == '\u200b', r == '\u200c', r == '\u200d', r == '\ufeff':
+			return errors.New("Zero-width Unicode character detected in path")
+		case r == '\u202e':
+			return errors.New("Right-to-left override character detected in path")
+		case r == '\u2024', r == '\u2025', r == '\u2026':
+			return errors.New("Unicode dot homoglyph detected in path")
+		case r == '\u2044', r == '\u2215', r == '\u2571', r == '\u29f8', r == '\uff0f':
+			return errors.New("Unicode slash homoglyph detected in path")
+		case r == '\u2216', r == '\uff3c':
+			return errors.New("Unicode backslash homoglyph detected in path")
+		case r == '\u00a5', r == '\u20a9', r == '\u00b4':
+			return errors.New("Code page specific path separator homoglyph detected in path")
+		case r >= '\uff01' && r <= '\uff5e':
+			return errors.New("Full-width Unicode character detected in path")
+		case r == '?', r == '*':
+			return fmt.Errorf("Wildcard character detected in path: %c", r)
+		}
+	}
+	return nil
+}
+
+// detectWindowsAttacks mirrors detect_windows_attacks in src/attacks.rs
+func detectWindowsAttacks(path string) error {
+	if strings.Contains(path, ":") {
+		startsWithDrive := len(path) >= 2 && isASCIILetter(path[0]) && path[1] == ':'
+		if !startsWithDrive {
+			return fmt.Errorf("Colon detected in path (possible NTFS stream or device): %s", path)
+		}
+		if strings.Contains(path[2:], ":") {
+			return fmt.Errorf("NTFS alternate data stream syntax detected: %s", path)
+		}
+	}
+
+	if strings.HasPrefix(path, `\\`) || strings.HasPrefix(path, "//") {
+		return fmt.Errorf("UNC path detected: %s", path)
+	}
+
+	if strings.Contains(strings.ToUpper(path), `\DEVICE\`) {
+		return errors.New("Windows device path detected")
+	}
+
+	segments := strings.FieldsFunc(path, isSeparator)
+	if len(segments) > 0 {
+		last := segments[len(segments)-1]
+		if strings.HasSuffix(last, ".") && last != "." && last != ".." {
+			return fmt.Errorf("Trailing dot detected in path component (Windows exploit): %s", last)
+		}
+		if strings.HasSuffix(last, " ") {
+			return errors.New("Trailing space detected in path component (Windows exploit)")
+		}
+	}
+	return nil
+}
+
+// detectMixedEncoding mirrors detect_mixed_encoding in src/encoding.rs
+func detectMixedEncoding(path string) bool {
+	if strings.HasPrefix(path, "\ufeff") || strings.HasPrefix(path, "\ufffe") {
+		return true
+	}
+	if strings.Contains(path, "&#") {
+		return true
+	}
+	if len(path) >= 4 {
+		nulls := 0
+		for i := 0; i+1 < len(path); i += 2 {
+			if path[i+1] == 0 {
+				nulls++
+			}
+		}
+		if nulls > len(path)/8 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodedSeparatorReplacer decodes percent-encoded dots and separators
+var encodedSeparatorReplacer = strings.NewReplacer(
+	"%2e", ".", "%2E", ".",
+	"%2f", "/", "%2F", "/",
+	"%5c", `\`, "%5C", `\`,
+	"%25", "%",
+)
+
+// maxSanitizeDecodePasses bounds how often nested encodings are unwrapped
+const maxSanitizeDecodePasses = 4
+
+// sanitizeLexical removes dangerous patterns from a path: control characters
+// and invisible Unicode are dropped, lookalikes of dots and slashes and
+// percent-encoded separators are decoded, backslashes become forward
+// slashes, and empty, dot-only or traversal segments are removed along with
+// trailing dots and spaces. Leading slashes are reduced to one.
+func sanitizeLexical(path string) string {
+	s := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, path)
+	s = unicodeFolder.Replace(s)
+	for i := 0; i < maxSanitizeDecodePasses; i++ {
+		decoded := encodedSeparatorReplacer.Replace(s)
+		if decoded == s {
+			break
+		}
+		s = decoded
+	}
+	s = strings.ReplaceAll(s, `\`, "/")
+
+	var kept []string
+	for _, segment := range strings.Split(s, "/") {
+		segment = strings.TrimRight(segment, ". ")
+		if strings.Trim(segment, ". ") == "" {
+			continue
+		}
+		kept = append(kept, segment)
+	}
+
+	sanitized := strings.Join(kept, "/")
+	if strings.HasPrefix(s, "/") {
+		sanitized = "/" + sanitized
+	}
+	return sanitized
+}
+
+// sanitizeFilenameLexical reduces a name to a single component by removing
+// separators, colons and control characters and trimming trailing dots and
+// spaces, which Windows ignores. Reserved device names get a leading
+// underscore.
+func sanitizeFilenameLexical(name string) string {
+	s := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, unicodeFolder.Replace(name))
+	s = strings.TrimRight(s, ". ")
+
+	if IsWindowsReservedName(s) {
+		return "_" + s
+	}
+	return s
+}
+
+// isSeparator reports whether r is a path separator on any supported platform
+func isSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
+
+// isASCIILetter reports whether c is an ASCII letter
+func isASCIILetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
@@ -0,0 +1,94 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// expandEnv replaces $NAME and ${NAME} references, and %NAME% references
+// under ShellExpand, using the lookup registered with WithExpandEnv.
+// Expansion is a single pass, so values are never expanded again. A '$' or
+// '%' that does not start a reference is kept as is.
+func (ps *PathSecurity) expandEnv(path string) (string, error) {
+	lookup := ps.cfg.expandEnv
+	windowsRefs := ps.cfg.shellPolicy == ShellExpand && strings.Contains(path, "%")
+	if lookup == nil || !strings.Contains(path, "$") && !windowsRefs {
+		return path, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if windowsRefs && path[i] == '%' {
+			if ref, ok := shellConstructAt(path, i); ok && ref.Kind == ShellWindowsVariable {
+				name := ref.Text[1 : len(ref.Text)-1]
+				value, ok := lookup(name)
+				if !ok {
+					return "", ps.reject(path, fmt.Errorf("%w: %q", ErrUnresolvedVariable, name))
+				}
+				b.WriteString(value)
+				i += len(ref.Text) - 1
+				continue
+			}
+		}
+		if path[i] != '$' || i+1 == len(path) {
+			b.WriteByte(path[i])
+			continue
+		}
+
+		var name string
+		var end int
+		if path[i+1] == '{' {
+			closing := strings.IndexByte(path[i+2:], '}')
+			if closing < 0 {
+				return "", ps.reject(path, fmt.Errorf("%w: unterminated reference in %q", ErrUnresolvedVariable, path))
+			}
+			name = path[i+2 : i+2+closing]
+			end = i + 2 + closing + 1
+			if !isEnvName(name) {
+				return "", ps.reject(path, fmt.Errorf("%w: invalid name %q in %q", ErrUnresolvedVariable, name, path))
+			}
+		} else {
+			end = i + 1
+			for end < len(path) && isEnvNameByte(path[end], end == i+1) {
+				end++
+			}
+			name = path[i+1 : end]
+			if name == "" {
+				b.WriteByte('$')
+				continue
+			}
+		}
+
+		value, ok := lookup(name)
+		if !ok {
+			return "", ps.reject(path, fmt.Errorf("%w: %q", ErrUnresolvedVariable, name))
+		}
+		b.WriteString(value)
+		i = end - 1
+	}
+	return b.String(), nil
+}
+
+// isEnvName reports whether name is a valid variable name
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isEnvNameByte(name[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEnvNameByte reports whether c may appear in a variable name
+func isEnvNameByte(c byte, first bool) bool {
+	switch {
+	case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		return true
+	case '0' <= c && c <= '9':
+		return !first
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithExpandEnv(t *testing.T) {
+	vars := map[string]string{
+		"DATA":   "/srv/data",
+		"YEAR":   "2024",
+		"ESCAPE": "/srv/data/../../etc",
+	}
+	ps := NewPathSecurity(WithExpandEnv(func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}))
+	tests := []struct {
+		path    string
+		want    string
+		wantErr error
+	}{
+		{path: "${DATA}/reports/q3.csv", want: "/srv/data/reports/q3.csv"},
+		{path: "$DATA/reports/$YEAR.csv", want: "/srv/data/reports/2024.csv"},
+		{path: "$MISSING/reports/q3.csv", wantErr: ErrUnresolvedVariable},
+		{path: "${DATA/reports", wantErr: ErrUnresolvedVariable},
+		{path: "${1DATA}/reports", wantErr: ErrUnresolvedVariable},
+		{path: "$ESCAPE/passwd", wantErr: ErrTraversalDetected},
+	}
+	for _, tt := range tests {
+		got, err := ps.Canonicalize(tt.path)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Canonicalize(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Canonicalize(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestWithExpandEnvContainment(t *testing.T) {
+	ps := NewPathSecurity(
+		WithAllowedRoots("/srv/data"),
+		WithExpandEnv(func(name string) (string, bool) {
+			switch name {
+			case "DATA":
+				return "/srv/data", true
+			case "LOGS":
+				return "/var/log", true
+			}
+			return "", false
+		}),
+	)
+	if _, rel, err := ps.RelativeToMatchedRoot("$DATA/reports/q3.csv"); err != nil || rel != "reports/q3.csv" {
+		t.Errorf("RelativeToMatchedRoot($DATA/reports/q3.csv) = %q, %v, want reports/q3.csv", rel, err)
+	}
+	if _, _, err := ps.RelativeToMatchedRoot("$LOGS/syslog"); !errors.Is(err, ErrNoMatchingRoot) {
+		t.Errorf("RelativeToMatchedRoot($LOGS/syslog) error = %v, want %v", err, ErrNoMatchingRoot)
+	}
+}
+
+func TestExpandEnvSinglePass(t *testing.T) {
+	vars := map[string]string{"DATA": "/srv/data", "NESTED": "$DATA"}
+	ps := NewPathSecurity(WithExpandEnv(func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}))
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "$NESTED/q3.csv", want: "$DATA/q3.csv"},
+		{path: "reports/cost$/q3.csv", want: "reports/cost$/q3.csv"},
+		{path: "reports/$", want: "reports/$"},
+		{path: "$DATA$DATA", want: "/srv/data/srv/data"},
+	}
+	for _, tt := range tests {
+		if got, err := ps.expandEnv(tt.path); err != nil || got != tt.want {
+			t.Errorf("expandEnv(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package pathsecurity
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+var (
+	// ErrInvalidPath is returned when the validator rejects a path for a reason
+	// not covered by a more specific error
+	ErrInvalidPath = errors.New("invalid path")
+
+	// ErrTraversalDetected is returned when a path contains traversal patterns
+	ErrTraversalDetected = errors.New("path traversal detected")
+
+	// ErrNullByte is returned when a path contains a NUL byte
+	ErrNullByte = errors.New("path contains null byte")
+
+	// ErrPathTooLong is returned when a path exceeds the maximum length
+	ErrPathTooLong = errors.New("path too long")
+
+	// ErrPathTooDeep is returned when a path has more segments than WithMaxDepth allows
+	ErrPathTooDeep = errors.New("path too deep")
+
+	// ErrInvalidArgument is returned when the native library rejects its arguments
+	ErrInvalidArgument = errors.New("invalid argument")
+
+	// ErrBufferTooSmall is returned when a native result does not fit the result buffer
+	ErrBufferTooSmall = errors.New("result buffer too small")
+
+	// ErrNativeFailure is returned for internal failures of the native library
+	ErrNativeFailure = errors.New("native library failure")
+
+	// ErrNativeTimeout is returned, together with ErrNativeFailure, for
+	// native calls that did not return within WithNativeTimeout
+	ErrNativeTimeout = errors.New("native library call timed out")
+
+	// ErrValidatorClosed is returned for paths submitted to a Validator
+	// after Close
+	ErrValidatorClosed = errors.New("validator closed")
+
+	// ErrRulesetSignature is returned by LoadRuleset for a bundle not
+	// signed by any of the trusted keys
+	ErrRulesetSignature = errors.New("ruleset signature not valid")
+
+	// ErrRulesetOutdated is returned by UseRuleset for a ruleset no newer
+	// than the one in use
+	ErrRulesetOutdated = errors.New("ruleset not newer than the one in use")
+
+	// ErrBackendUnavailable is returned by NativeBackend when calls do not
+	// go to the native library
+	ErrBackendUnavailable = errors.New("native library unavailable")
+
+	// ErrNoMatchingRoot is returned when a path is not under any allowed root
+	ErrNoMatchingRoot = errors.New("path is not under any allowed root")
+
+	// ErrOutsidePrefix is returned by RequirePrefix when a path is not under
+	// the required prefix
+	ErrOutsidePrefix = errors.New("path is outside the required prefix")
+
+	// ErrUnknownExport is returned when a share path names an unconfigured export
+	ErrUnknownExport = errors.New("unknown export")
+
+	// ErrInvalidEncoding is returned for invalid UTF-8 or ambiguous percent-encoding
+	ErrInvalidEncoding = errors.New("invalid path encoding")
+
+	// ErrControlCharacter is returned when a path contains a control character
+	ErrControlCharacter = errors.New("path contains control character")
+
+	// ErrNotCanonical is returned when a stored path is not in canonical storage form
+	ErrNotCanonical = errors.New("path is not in canonical storage form")
+
+	// ErrUnresolvedVariable is returned when an environment reference cannot be expanded
+	ErrUnresolvedVariable = errors.New("unresolved environment variable")
+
+	// ErrShellConstruct is returned for environment references and command
+	// substitutions rejected under WithShellPolicy
+	ErrShellConstruct = errors.New("shell construct in path")
+
+	// ErrHomeDirectory is returned for "~" prefixes rejected or left
+	// unresolved under WithTildePolicy
+	ErrHomeDirectory = errors.New("home directory reference")
+
+	// ErrUnservableType is returned when a path's extension is not configured as servable
+	ErrUnservableType = errors.New("path does not have a servable extension")
+
+	// ErrParentMissing is returned when a destination's parent directory does not exist
+	ErrParentMissing = errors.New("parent directory does not exist")
+
+	// ErrParentNotWritable is returned when a destination's parent directory is not writable
+	ErrParentNotWritable = errors.New("parent directory is not writable")
+
+	// ErrPredictablePath is returned by CheckTempPath for a name in a
+	// shared temporary directory without a random component
+	ErrPredictablePath = errors.New("predictable path in shared directory")
+
+	// ErrInsecureTempDir is returned for temporary directories that are
+	// writable by everyone without the sticky bit
+	ErrInsecureTempDir = errors.New("insecure temporary directory")
+
+	// ErrInvalidFilename is returned when no safe file name can be derived
+	ErrInvalidFilename = errors.New("invalid file name")
+
+	// ErrInvalidSegment is returned by JoinUntrusted for components that are
+	// not a single path segment
+	ErrInvalidSegment = errors.New("not a single path segment")
+
+	// ErrNoUniqueName is returned by UniqueFilename when every candidate
+	// name is taken
+	ErrNoUniqueName = errors.New("no unique file name available")
+
+	// ErrReservedName is returned when a path component is a reserved device name
+	ErrReservedName = errors.New("reserved device name")
+
+	// ErrDevicePath is returned for Windows device and verbatim paths
+	ErrDevicePath = errors.New("device path")
+
+	// ErrUNCPath is returned for UNC network paths
+	ErrUNCPath = errors.New("UNC path")
+
+	// ErrNetworkPath is returned for UNC paths, SMB URLs and NFS exports
+	// rejected under WithNetworkPolicy
+	ErrNetworkPath = errors.New("network path")
+
+	// ErrObjectKey is returned by ValidateObjectKey for keys that break the
+	// rules of S3 or GCS
+	ErrObjectKey = errors.New("invalid object key")
+
+	// ErrAlternateDataStream is returned for paths naming an NTFS alternate data stream
+	ErrAlternateDataStream = errors.New("NTFS alternate data stream")
+
+	// ErrShortName is returned for 8.3 short names when WithRejectShortNames is set
+	ErrShortName = errors.New("8.3 short name")
+
+	// ErrUnicodeTrick is returned when a path contains invisible, bidirectional
+	// or lookalike Unicode characters
+	ErrUnicodeTrick = errors.New("deceptive Unicode character")
+
+	// ErrAbsolutePath is returned for absolute paths when WithAllowAbsolute(false) is set
+	ErrAbsolutePath = errors.New("absolute path not allowed")
+
+	// ErrDeniedPattern is returned when a path matches a pattern set with WithDeniedPatterns
+	ErrDeniedPattern = errors.New("path matches denied pattern")
+
+	// ErrExtensionNotAllowed is returned when a file name's extension is not
+	// among those set with WithAllowedExtensions
+	ErrExtensionNotAllowed = errors.New("file extension not allowed")
+
+	// ErrDeniedExtension is returned when a file name carries an extension set
+	// with WithDeniedExtensions
+	ErrDeniedExtension = errors.New("file extension denied")
+
+	// ErrFilenameTooLong is returned when a file name exceeds WithMaxFilenameLength
+	ErrFilenameTooLong = errors.New("file name too long")
+
+	// ErrSensitivePath is returned for sensitive system paths and special files
+	// when WithSensitivePaths is set
+	ErrSensitivePath = errors.New("sensitive path")
+
+	// ErrHiddenPath is returned for paths through a hidden file or
+	// directory when WithDotfilePolicy denies them
+	ErrHiddenPath = errors.New("hidden file or directory")
+
+	// ErrIllegalCharacter is returned for names holding a character, or
+	// ending in a dot or space, that the file system set with
+	// WithFilesystemProfile does not allow
+	ErrIllegalCharacter = errors.New("character not allowed by target file system")
+
+	// ErrOperationDenied is returned by Authorize when a path's root does not
+	// permit the requested operation
+	ErrOperationDenied = errors.New("operation not permitted under root")
+
+	// ErrRuleViolation is returned when a rule registered with WithRule
+	// reports a high or critical finding
+	ErrRuleViolation = errors.New("path violates custom rule")
+
+	// ErrGlobTooComplex is returned by ValidateGlob for patterns with more
+	// wildcards than WithMaxGlobWildcards allows
+	ErrGlobTooComplex = errors.New("glob pattern too complex")
+
+	// ErrSeverityExceeded is returned by Policy.Check when a finding reaches
+	// the policy's reject severity
+	ErrSeverityExceeded = errors.New("finding severity exceeds policy threshold")
+
+	// ErrMagicLink is returned for paths through a procfs magic symlink such
+	// as /proc/self/root, as found by FindMagicLink
+	ErrMagicLink = errors.New("path through magic symlink")
+
+	// ErrMountBoundary is returned when resolving a path would leave the
+	// boundary set with WithMountBoundary or cross a mount point below it
+	ErrMountBoundary = errors.New("path crosses mount boundary")
+
+	// ErrTargetCheck is returned by ValidateResolved when the target of a
+	// path fails a check set with WithTargetChecks or WithTargetOwner
+	ErrTargetCheck = errors.New("target failed resolved checks")
+
+	// ErrSymlinkEscape is returned when a symlink resolves outside the allowed roots
+	ErrSymlinkEscape = errors.New("symlink resolves outside allowed root")
+)
+
+// ValidationError describes why the validator rejected a path. Err is the
+// sentinel for the category of the failure, such as ErrTraversalDetected or
+// ErrInvalidEncoding, and Reason is the validator's own message.
+type ValidationError struct {
+	Path   string
+	Reason string
+	Err    error
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: %q: %s", e.Err, e.Path, e.Reason)
+}
+
+// Unwrap returns the sentinel for the failure category
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// newValidationError builds a ValidationError, deriving the category from
+// the validator's message
+func newValidationError(path, reason string) *ValidationError {
+	lower := strings.ToLower(reason)
+	category := ErrInvalidPath
+	switch {
+	case strings.Contains(lower, "null byte"):
+		category = ErrNullByte
+	case strings.Contains(lower, "too long"):
+		category = ErrPathTooLong
+	case strings.Contains(lower, "encoding"), strings.Contains(lower, "encoded"):
+		category = ErrInvalidEncoding
+	case strings.Contains(lower, "traversal"):
+		category = ErrTraversalDetected
+	}
+	return &ValidationError{Path: path, Reason: reason, Err: category}
+}
+
+// ErrorCode is a failure code returned by a libpath_security_c call. The
+// library reports nothing beyond the code, so String and Detail carry
+// everything known about a failure.
+type ErrorCode int
+
+const (
+	// CodeInvalidArgument means an input or result pointer was NULL
+	CodeInvalidArgument ErrorCode = -1
+	// CodeInvalidUTF8 means the input was not valid UTF-8
+	CodeInvalidUTF8 ErrorCode = -2
+	// CodeInternal means the library could not encode its result as a C
+	// string
+	CodeInternal ErrorCode = -3
+	// CodeBufferTooSmall means the result did not fit the result buffer
+	CodeBufferTooSmall ErrorCode = -4
+)
+
+// String returns the name of the code
+func (c ErrorCode) String() string {
+	switch c {
+	case CodeInvalidArgument:
+		return "invalid argument"
+	case CodeInvalidUTF8:
+		return "invalid UTF-8"
+	case CodeInternal:
+		return "internal error"
+	case CodeBufferTooSmall:
+		return "buffer too small"
+	}
+	return fmt.Sprintf("ErrorCode(%d)", int(c))
+}
+
+// Detail explains what the code means for a caller of the binding
+func (c ErrorCode) Detail() string {
+	switch c {
+	case CodeInvalidArgument:
+		return "the library was passed a NULL pointer, which indicates a bug in the binding"
+	case CodeInvalidUTF8:
+		return "the input is not valid UTF-8 and was rejected before validation"
+	case CodeInternal:
+		return "the library's JSON result contained a NUL byte and could not be returned"
+	case CodeBufferTooSmall:
+		return "the result exceeded the largest buffer the binding allocates"
+	}
+	return "the library returned a code this binding does not know, which may mean it is newer than the binding"
+}
+
+// NativeError is returned when a libpath_security_c call reports a failure
+// code. It unwraps to the sentinel matching the code.
+type NativeError struct {
+	Op   string
+	Code ErrorCode
+}
+
+// Error implements the error interface, as in "path validation failed:
+// buffer too small (code -4)"
+func (e *NativeError) Error() string {
+	return fmt.Sprintf("%s failed: %s (code %d)", e.Op, e.Code, int(e.Code))
+}
+
+// Detail explains the failure, as ErrorCode.Detail does
+func (e *NativeError) Detail() string {
+	return e.Code.Detail()
+}
+
+// Unwrap returns the sentinel for the failure code
+func (e *NativeError) Unwrap() error {
+	switch e.Code {
+	case CodeInvalidArgument:
+		return ErrInvalidArgument
+	case CodeInvalidUTF8:
+		return ErrInvalidEncoding
+	case CodeBufferTooSmall:
+		return ErrBufferTooSmall
+	default:
+		return ErrNativeFailure
+	}
+}
+
+// reject returns err, the error for a path that failed a check, or an
+// *fs.PathError carrying the underlying sentinel when WithFSPathErrors is
+// enabled. Every rejection is reported to the audit function and metrics
+// collector.
+func (ps *PathSecurity) reject(path string, err error) error {
+	ps.observeRejection(path, err)
+	if !ps.cfg.fsPathErrors {
+		return err
+	}
+
+	sentinel := err
+	for next := errors.Unwrap(sentinel); next != nil; next = errors.Unwrap(sentinel) {
+		sentinel = next
+	}
+	return &fs.PathError{Op: "validate", Path: path, Err: sentinel}
+}
+
+// observeRejection reports a rejection of path with err to the audit
+// function and metrics collector
+func (ps *PathSecurity) observeRejection(path string, err error) {
+	ps.auditRejection(path, err)
+	if ps.cfg.metrics != nil {
+		ps.cfg.metrics.ObserveRejection(RejectionCategory(err))
+	}
+}
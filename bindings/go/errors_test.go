@@ -0,0 +1,103 @@
+package pathsecurity
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestWithFSPathErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		path     string
+		sentinel error
+		call     func(ps *PathSecurity, path string) error
+	}{
+		{
+			name:     "validate traversal",
+			path:     "../../../etc/passwd",
+			sentinel: ErrTraversalDetected,
+			call: func(ps *PathSecurity, path string) error {
+				_, err := ps.ValidatePath(path)
+				return err
+			},
+		},
+		{
+			name:     "canonicalize null byte",
+			path:     "safe.txt\x00.php",
+			sentinel: ErrNullByte,
+			call: func(ps *PathSecurity, path string) error {
+				_, err := ps.Canonicalize(path)
+				return err
+			},
+		},
+		{
+			name:     "matched root",
+			opts:     []Option{WithAllowedRoots("/srv/data")},
+			path:     "/var/log/syslog",
+			sentinel: ErrNoMatchingRoot,
+			call: func(ps *PathSecurity, path string) error {
+				_, _, err := ps.RelativeToMatchedRoot(path)
+				return err
+			},
+		},
+		{
+			name:     "too long",
+			opts:     []Option{WithMaxPathLength(16)},
+			path:     "docs/" + strings.Repeat("a", 32),
+			sentinel: ErrPathTooLong,
+			call: func(ps *PathSecurity, path string) error {
+				_, err := ps.ValidatePath(path)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(NewPathSecurity(append(tt.opts, WithFSPathErrors(true))...), tt.path)
+			var pathErr *fs.PathError
+			if !errors.As(err, &pathErr) {
+				t.Fatalf("error %v (%T) is not an *fs.PathError", err, err)
+			}
+			if pathErr.Op != "validate" || pathErr.Path != tt.path {
+				t.Errorf("PathError Op, Path = %q, %q, want %q, %q", pathErr.Op, pathErr.Path, "validate", tt.path)
+			}
+			if !errors.Is(err, tt.sentinel) {
+				t.Errorf("error %v does not wrap %v", err, tt.sentinel)
+			}
+
+			err = tt.call(NewPathSecurity(tt.opts...), tt.path)
+			if errors.As(err, &pathErr) || !errors.Is(err, tt.sentinel) {
+				t.Errorf("without WithFSPathErrors, error = %v (%T), want %v unwrapped", err, err, tt.sentinel)
+			}
+		})
+	}
+}
+
+func TestNativeError(t *testing.T) {
+	tests := []struct {
+		code     ErrorCode
+		want     string
+		sentinel error
+	}{
+		{CodeInvalidArgument, "path validation failed: invalid argument (code -1)", ErrInvalidArgument},
+		{CodeInvalidUTF8, "path validation failed: invalid UTF-8 (code -2)", ErrInvalidEncoding},
+		{CodeInternal, "path validation failed: internal error (code -3)", ErrNativeFailure},
+		{CodeBufferTooSmall, "path validation failed: buffer too small (code -4)", ErrBufferTooSmall},
+		{-9, "path validation failed: ErrorCode(-9) (code -9)", ErrNativeFailure},
+	}
+	for _, tt := range tests {
+		err := &NativeError{Op: "path validation", Code: tt.code}
+		if got := err.Error(); got != tt.want {
+			t.Errorf("NativeError{%d}.Error() = %q, want %q", int(tt.code), got, tt.want)
+		}
+		if !errors.Is(err, tt.sentinel) {
+			t.Errorf("NativeError{%d} does not match %v", int(tt.code), tt.sentinel)
+		}
+		if err.Detail() == "" || err.Detail() != tt.code.Detail() {
+			t.Errorf("NativeError{%d}.Detail() = %q", int(tt.code), err.Detail())
+		}
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
 )
 
 func main() {
@@ -9,7 +11,7 @@ func main() {
 	fmt.Println()
 
 	// Create PathSecurity instance
-	ps := NewPathSecurity()
+	ps := pathsecurity.NewPathSecurity()
 
 	// Test ValidatePath
 	validPath := "/usr/local/bin/app"
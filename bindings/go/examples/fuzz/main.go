@@ -0,0 +1,134 @@
+// Command fuzz mutates the corpus from pathsecurity.TraversalPayloads and
+// checks on every input that no call panics and that CheckInvariants finds
+// no broken invariant:
+//
+//   - a path accepted by ValidatePath is not reported by DetectTraversal
+//   - Explain agrees with ValidatePath on whether the path is valid
+//   - CompareWithStdlib finds no disagreement with path/filepath
+//   - sanitized output has no ".." segment under either separator
+//   - sanitizing sanitized output changes nothing
+//   - DetectTraversal does not report sanitized output
+//   - accepted relative paths and relative sanitized output stay confined
+//     to a root they are joined to
+//   - under a parity mode, ValidatePath accepts what filepath.IsLocal or
+//     fs.ValidPath accepts
+//
+// It runs under both backends; with cgo, the native library writes into
+// Go-managed buffers sized by the binding, which checks a guard area after
+// each of them and fails the call with ErrNativeFailure if it was written.
+// Building with -asan, or -msan and clang, and a native library compiled
+// with the same sanitizer also catches reads past the buffers and of
+// uninitialized memory. Failing inputs are printed with %q and the command
+// exits with status 1.
+//
+// Usage:
+//
+//	go run ./examples/fuzz [-n iterations] [-seed n]
+//	go run -asan ./examples/fuzz
+//	CC=clang go run -msan ./examples/fuzz
+//	GOOS=js GOARCH=wasm go run ./examples/fuzz
+//
+// The last needs node and the go_js_wasm_exec script from
+// $(go env GOROOT)/lib/wasm in PATH.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+// fragments are spliced into inputs by the mutator
+var fragments = []string{
+	"..", ".", "/", `\`, "%2e", "%2f", "%5c", "%25", "%c0%ae", "%00", "\x00",
+	"\uff0e", "\u2215", "\u200b", "\u202e", ":", "::$DATA", "~1", "CON", "//", " ",
+	".git", ".WELL-KNOWN", "<", "|", ". ",
+}
+
+func main() {
+	iterations := flag.Int("n", 100000, "number of mutated inputs to check")
+	seed := flag.Int64("seed", 1, "random seed")
+	flag.Parse()
+
+	rng := rand.New(rand.NewSource(*seed))
+	corpus := pathsecurity.TraversalPayloads()
+	instances := []*pathsecurity.PathSecurity{
+		pathsecurity.NewPathSecurity(),
+		pathsecurity.NewPathSecurity(pathsecurity.WithDecoding(pathsecurity.DecodeRecursive), pathsecurity.WithUnicodeNormalization(pathsecurity.NormalizeNFKC)),
+		pathsecurity.NewPathSecurity(pathsecurity.WithWindowsSemantics(true), pathsecurity.WithSanitizeStrategy(pathsecurity.SanitizeCollapse)),
+		pathsecurity.NewPathSecurity(pathsecurity.WithFailThreshold(pathsecurity.SeverityHigh), pathsecurity.WithMaxDepth(4)),
+		pathsecurity.NewPathSecurity(pathsecurity.WithWindowsSemantics(false), pathsecurity.WithNativeSeparators(true), pathsecurity.WithSegmentPolicy(pathsecurity.SegmentsCollapse), pathsecurity.WithSanitizeStrategy(pathsecurity.SanitizeCollapse)),
+		pathsecurity.NewPathSecurity(pathsecurity.WithTreatEncodedSlashAsSeparator(true), pathsecurity.WithSanitizeStrategy(pathsecurity.SanitizeEncode)),
+		pathsecurity.NewPathSecurity(pathsecurity.WithStdlibParity(pathsecurity.ParityIsLocal)),
+		pathsecurity.NewPathSecurity(pathsecurity.WithStdlibParity(pathsecurity.ParityValidPath)),
+		pathsecurity.NewPathSecurity(pathsecurity.WithDotfilePolicy(pathsecurity.DotfilesAllowlist), pathsecurity.WithAllowedDotfiles(".well-known"), pathsecurity.WithCaseInsensitive(true), pathsecurity.WithSanitizeStrategy(pathsecurity.SanitizeReject)),
+		pathsecurity.NewPathSecurity(pathsecurity.WithFilesystemProfile(pathsecurity.FilesystemNTFS), pathsecurity.WithWindowsSemantics(true)),
+		pathsecurity.NewPathSecurity(pathsecurity.WithFilesystemProfile(pathsecurity.FilesystemFAT), pathsecurity.WithWindowsSemantics(false), pathsecurity.WithSanitizeStrategy(pathsecurity.SanitizeStrip)),
+	}
+
+	failures := 0
+	check := func(input string) {
+		for i, ps := range instances {
+			if msg := violation(ps, input); msg != "" {
+				failures++
+				fmt.Printf("instance %d, input %q: %s\n", i, input, msg)
+			}
+		}
+	}
+	for _, input := range corpus {
+		check(input)
+	}
+	for n := 0; n < *iterations; n++ {
+		check(mutate(rng, corpus[rng.Intn(len(corpus))]))
+	}
+
+	fmt.Printf("checked %d inputs, %d violations\n", len(corpus)+*iterations, failures)
+	if failures > 0 {
+		os.Exit(1)
+	}
+}
+
+// violation runs CheckInvariants on input and describes the broken
+// invariants, or returns ""
+func violation(ps *pathsecurity.PathSecurity, input string) (msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg = fmt.Sprintf("panic: %v", r)
+		}
+	}()
+
+	if err := ps.CheckInvariants(input); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// mutate applies one to four random edits to s
+func mutate(rng *rand.Rand, s string) string {
+	for edits := 1 + rng.Intn(4); edits > 0; edits-- {
+		i := 0
+		if len(s) > 0 {
+			i = rng.Intn(len(s) + 1)
+		}
+		switch rng.Intn(4) {
+		case 0:
+			s = s[:i] + fragments[rng.Intn(len(fragments))] + s[i:]
+		case 1:
+			if i < len(s) {
+				s = s[:i] + s[i+1:]
+			}
+		case 2:
+			s = s[:i] + s
+		default:
+			s = strings.Repeat(s, 2)
+		}
+	}
+	if len(s) > 8192 {
+		s = s[:8192]
+	}
+	return s
+}
@@ -0,0 +1,40 @@
+//go:build parity && cgo
+
+package main
+
+/*
+#cgo LDFLAGS: -L${SRCDIR}/../.. -lpath_security_c
+#include <stdlib.h>
+#include "path_security.h"
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"unsafe"
+)
+
+// responseSize is the size of the buffer the C library writes its JSON
+// response into
+const responseSize = 1 << 16
+
+// validateNative returns the verdict of path_security_validate_path on
+// path, bypassing the Go binding
+func validateNative(path string) (bool, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	buf := (*C.char)(C.malloc(responseSize))
+	defer C.free(unsafe.Pointer(buf))
+
+	if rc := C.path_security_validate_path(cpath, buf, responseSize); rc != 0 {
+		return false, fmt.Errorf("C library: path_security_validate_path returned %d", int(rc))
+	}
+	var response struct {
+		Valid bool `json:"valid"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(buf)), &response); err != nil {
+		return false, fmt.Errorf("C library: %w", err)
+	}
+	return response.Valid, nil
+}
@@ -0,0 +1,8 @@
+//go:build parity && !cgo
+
+package main
+
+// validateNative fails in builds without cgo
+func validateNative(string) (bool, error) {
+	return false, errNoCgo
+}
@@ -0,0 +1,15 @@
+// Reads paths as JSON strings, one per line, and writes the verdict of the
+// Node.js binding loaded from the directory given as argument on each as a
+// JSON line, for examples/parity
+const path = require('path');
+const readline = require('readline');
+const { validatePath } = require(path.resolve(process.argv[1]));
+
+readline.createInterface({ input: process.stdin }).on('line', line => {
+    try {
+        const { valid } = JSON.parse(validatePath(JSON.parse(line)));
+        process.stdout.write(JSON.stringify({ valid }) + '\n');
+    } catch (e) {
+        process.stdout.write(JSON.stringify({ error: String(e) }) + '\n');
+    }
+});
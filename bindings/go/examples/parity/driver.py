@@ -0,0 +1,15 @@
+"""Reads paths as JSON strings, one per line, and writes the verdict of the
+Python binding on each as a JSON line, for examples/parity"""
+
+import json
+import sys
+
+from path_security_python import PathSecurity
+
+validator = PathSecurity()
+for line in sys.stdin:
+    try:
+        valid = json.loads(validator.validate_path(json.loads(line)))["valid"]
+        print(json.dumps({"valid": valid}), flush=True)
+    except Exception as e:
+        print(json.dumps({"error": str(e)}), flush=True)
@@ -0,0 +1,238 @@
+//go:build parity
+
+// Command parity runs the paths of the conformance suite of
+// pathsecurity.ConformanceVectors through the other language bindings and
+// the C library, and checks that their verdicts agree with each other and
+// with those of the Go binding:
+//
+//   - every binding returns the same verdict as the C library called
+//     directly, as they all wrap the same validator
+//   - the Go binding, under the policy of each vector, accepts no path the
+//     C library rejects, unless the policy has the binding rewrite the path
+//     before the validator sees it, as Windows semantics, decoding,
+//     normalization, max_parent_hops and the stdlib parity modes do
+//
+// The Go binding layers its own checks over the validator's, so it rejects
+// paths the other bindings accept; those are counted, not failed. Paths
+// holding a NUL byte cannot be passed to the C library and are only
+// compared between the other bindings. Failing vectors are printed and the
+// command exits with status 1, or with status 2 if a binding cannot be
+// run.
+//
+// The command builds only with the parity tag and needs cgo and the C
+// library, which it links as the binding does, the Python binding
+// installed as path_security_python and the Node.js binding built in
+// ../nodejs/native. Select fewer bindings with -bindings:
+//
+//	go run -tags parity ./examples/parity [-bindings python,node] [-python python3] [-node node] [-node-module dir]
+package main
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+// driverPy and driverJS speak the protocol of binding to the Python and
+// Node.js bindings: a path as a JSON string per line in, a verdict as a
+// JSON object per line out
+var (
+	//go:embed driver.py
+	driverPy string
+	//go:embed driver.js
+	driverJS string
+)
+
+// binding is another language binding run as a child process
+type binding struct {
+	name   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Scanner
+}
+
+// start runs the interpreter with a driver script and its arguments
+func start(name string, args ...string) (*binding, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%s binding: %w", name, err)
+	}
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(nil, 1<<20)
+	return &binding{name: name, cmd: cmd, stdin: stdin, stdout: scanner}, nil
+}
+
+// validate returns the binding's verdict on path
+func (b *binding) validate(path string) (bool, error) {
+	line, err := json.Marshal(path)
+	if err != nil {
+		return false, err
+	}
+	if _, err := fmt.Fprintf(b.stdin, "%s\n", line); err != nil {
+		return false, fmt.Errorf("%s binding: %w", b.name, err)
+	}
+	if !b.stdout.Scan() {
+		err := b.stdout.Err()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return false, fmt.Errorf("%s binding: %w", b.name, err)
+	}
+	var verdict struct {
+		Valid bool   `json:"valid"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(b.stdout.Bytes(), &verdict); err != nil {
+		return false, fmt.Errorf("%s binding: %w", b.name, err)
+	}
+	if verdict.Error != "" {
+		return false, fmt.Errorf("%s binding: %w: %s", b.name, errRaised, verdict.Error)
+	}
+	return verdict.Valid, nil
+}
+
+// close ends the child process
+func (b *binding) close() error {
+	b.stdin.Close()
+	return b.cmd.Wait()
+}
+
+// rewrites reports whether the Go binding hands the validator a different
+// path than it was given under spec
+func rewrites(spec pathsecurity.PolicySpec) bool {
+	return spec.TargetOS == "windows" || spec.WindowsSemantics != nil && *spec.WindowsSemantics ||
+		spec.MaxParentHops > 0 || spec.NativeSeparators ||
+		spec.StdlibParity != "" && spec.StdlibParity != "off" ||
+		spec.Decoding == "once" || spec.Decoding == "recursive" ||
+		spec.Normalization != "" && spec.Normalization != "none" ||
+		spec.Segments == "collapse" || spec.Tilde == "expand" || spec.Shell == "expand"
+}
+
+func main() {
+	os.Exit(run())
+}
+
+// run checks every vector and returns the exit status
+func run() int {
+	names := flag.String("bindings", "python,node", "comma-separated bindings to compare with the C library")
+	python := flag.String("python", "python3", "Python interpreter with path_security_python installed")
+	node := flag.String("node", "node", "Node.js interpreter")
+	nodeModule := flag.String("node-module", "../nodejs/native", "directory of the built Node.js binding")
+	flag.Parse()
+
+	var bindings []*binding
+	defer func() {
+		for _, b := range bindings {
+			b.close()
+		}
+	}()
+	for _, name := range strings.Split(*names, ",") {
+		var b *binding
+		var err error
+		switch name {
+		case "":
+			continue
+		case "python":
+			b, err = start(name, *python, "-c", driverPy)
+		case "node":
+			b, err = start(name, *node, "-e", driverJS, *nodeModule)
+		default:
+			err = fmt.Errorf("unknown binding %q", name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "parity:", err)
+			return 2
+		}
+		bindings = append(bindings, b)
+	}
+
+	suite := pathsecurity.ConformanceVectors()
+	failures, stricter := 0, 0
+	fail := func(v pathsecurity.ConformanceVector, format string, args ...any) {
+		failures++
+		fmt.Printf("vector %s %q: %s\n", v.ID, v.Path, fmt.Sprintf(format, args...))
+	}
+	for _, v := range suite.Vectors {
+		reference, referenceName := false, "C library"
+		if strings.IndexByte(v.Path, 0) < 0 {
+			valid, err := validateNative(v.Path)
+			if errors.Is(err, errNoCgo) {
+				fmt.Fprintln(os.Stderr, "parity:", err)
+				return 2
+			}
+			if err != nil {
+				fail(v, "%v", err)
+				continue
+			}
+			reference = valid
+		} else if len(bindings) > 0 {
+			referenceName = bindings[0].name + " binding"
+		} else {
+			continue
+		}
+
+		for i, b := range bindings {
+			valid, err := b.validate(v.Path)
+			if errors.Is(err, errRaised) {
+				fail(v, "%v", err)
+				continue
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "parity:", err)
+				return 2
+			}
+			if referenceName != "C library" && i == 0 {
+				reference = valid
+				continue
+			}
+			if valid != reference {
+				fail(v, "%s binding returns valid %v, %s %v", b.name, valid, referenceName, reference)
+			}
+		}
+
+		policy, err := pathsecurity.NewPolicy(v.Policy)
+		if err != nil {
+			fail(v, "policy: %v", err)
+			continue
+		}
+		goValid := policy.Check(v.Path) == nil
+		switch {
+		case goValid && !reference && !rewrites(v.Policy):
+			fail(v, "Go binding accepts what the %s rejects", referenceName)
+		case !goValid && reference:
+			stricter++
+		}
+	}
+
+	fmt.Printf("parity over %d vectors of suite version %d with the C library and %d bindings: %d failed, %d rejected only by the Go binding\n",
+		len(suite.Vectors), suite.Version, len(bindings), failures, stricter)
+	if failures > 0 {
+		return 1
+	}
+	return 0
+}
+
+var (
+	// errNoCgo is returned by validateNative in builds without cgo
+	errNoCgo = errors.New("calling the C library requires cgo")
+	// errRaised is returned for a path on which a binding raised an error
+	errRaised = errors.New("error raised")
+)
@@ -0,0 +1,86 @@
+//go:build js && wasm
+
+// Command wasm exposes validation to JavaScript, for browser-based admin
+// tools that check paths before sending them to the backend. It registers
+// a global pathSecurity object whose functions return plain objects:
+//
+//	pathSecurity.validate(path)  // {valid, error, category}
+//	pathSecurity.sanitize(path)  // {path, error}
+//	pathSecurity.detect(path)    // {traversal, error}
+//	pathSecurity.explain(path)   // the Explanation as JSON
+//	pathSecurity.version()       // {binding, capabilities}
+//
+// Build it and load it with the wasm_exec.js of the Go distribution:
+//
+//	GOOS=js GOARCH=wasm go build -o pathsecurity.wasm ./examples/wasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" .
+//
+// The module runs the pure-Go engine, as cgo is not available for wasm.
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+func main() {
+	ps := pathsecurity.NewPathSecurity()
+	api := js.Global().Get("Object").New()
+	api.Set("validate", pathFunc(func(p string) any {
+		_, err := ps.ValidatePath(p)
+		if err != nil {
+			return map[string]any{"valid": false, "error": err.Error(), "category": pathsecurity.RejectionCategory(err)}
+		}
+		return map[string]any{"valid": true}
+	}))
+	api.Set("sanitize", pathFunc(func(p string) any {
+		sanitized, err := ps.SanitizePath(p)
+		if err != nil {
+			return map[string]any{"error": err.Error()}
+		}
+		return map[string]any{"path": sanitized}
+	}))
+	api.Set("detect", pathFunc(func(p string) any {
+		traversal, err := ps.DetectTraversal(p)
+		if err != nil {
+			return map[string]any{"error": err.Error()}
+		}
+		return map[string]any{"traversal": traversal}
+	}))
+	api.Set("explain", pathFunc(func(p string) any {
+		explanation, err := ps.Explain(p)
+		if err != nil {
+			return map[string]any{"error": err.Error()}
+		}
+		encoded, err := json.Marshal(explanation)
+		if err != nil {
+			return map[string]any{"error": err.Error()}
+		}
+		return js.Global().Get("JSON").Call("parse", string(encoded))
+	}))
+	api.Set("version", js.FuncOf(func(js.Value, []js.Value) any {
+		binding, _ := pathsecurity.Version()
+		capabilities := make([]any, 0)
+		for _, c := range pathsecurity.Capabilities() {
+			capabilities = append(capabilities, c)
+		}
+		return map[string]any{"binding": binding, "capabilities": capabilities}
+	}))
+	js.Global().Set("pathSecurity", api)
+
+	// The functions stay callable only while main is running
+	select {}
+}
+
+// pathFunc wraps a function of one path as a JavaScript function, which
+// returns an error object unless called with a string
+func pathFunc(fn func(p string) any) js.Func {
+	return js.FuncOf(func(_ js.Value, args []js.Value) any {
+		if len(args) != 1 || args[0].Type() != js.TypeString {
+			return map[string]any{"error": "expected one string argument"}
+		}
+		return fn(args[0].String())
+	})
+}
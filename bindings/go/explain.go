@@ -0,0 +1,226 @@
+package pathsecurity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Explanation describes how validation treats a path, as returned by
+// Explain
+type Explanation struct {
+	// Path is the input path
+	Path string `json:"path"`
+	// Stages lists every preprocessing and canonicalization stage that ran,
+	// in order, with the path it produced
+	Stages []ExplainStage `json:"stages"`
+	// Checks lists every check evaluated on the preprocessed path, in the
+	// order validation runs them
+	Checks []ExplainCheck `json:"checks"`
+	// Canonical is the canonical form of the path, whether or not it is
+	// valid, and empty if preprocessing rejects the path
+	Canonical string `json:"canonical,omitempty"`
+	// Sanitized is the result of SanitizePath, and empty if it fails
+	Sanitized string `json:"sanitized,omitempty"`
+	// Valid reports whether ValidatePath accepts the path
+	Valid bool `json:"valid"`
+}
+
+// ExplainStage is one preprocessing or canonicalization stage of an
+// Explanation
+type ExplainStage struct {
+	// Name is one of the names of CanonicalStep
+	Name string `json:"name"`
+	// Path is the path after the stage
+	Path    string `json:"path"`
+	Changed bool   `json:"changed"`
+}
+
+// ExplainCheck is one check of an Explanation
+type ExplainCheck struct {
+	// Name identifies the check: "input", the pre-flight check of the raw
+	// input, a preprocessing stage that can reject, "stdlib-parity", the
+	// only check under WithStdlibParity, "control-characters",
+	// "encoded-slashes", "backslash-traversal", "unicode", "shell",
+	// "network", "long-path", "traversal-patterns", "magic-links", "policy",
+	// "sensitive", "dotfiles", "windows-path", "windows-aliases",
+	// "filesystem", "parent-hops", "validator", "rule:" followed by the
+	// name given to WithRule, or "special-file"
+	Name string `json:"name"`
+	// Matched reports whether the check rejects the path
+	Matched bool `json:"matched"`
+	// Reason and Category, the label RejectionCategory returns, describe
+	// the rejection of a matched check
+	Reason   string `json:"reason,omitempty"`
+	Category string `json:"category,omitempty"`
+	// Err is the error validation returns when this is the first check to
+	// match
+	Err error `json:"-"`
+}
+
+// Explain reports how validation treats path without enforcing anything:
+// the path after each preprocessing stage and every check run on the
+// result, including those after a check that rejects it, and custom rules
+// even though validation stops early. The validator is left out for paths
+// with a NUL byte, which the native library cannot see past. The audit
+// function, metrics collector and warn observer are not called. A stage
+// that rejects ends the explanation, as later stages have no input. The
+// error is only non-nil if the native library fails.
+func (ps *PathSecurity) Explain(p string) (Explanation, error) {
+	quiet := ps.quiet()
+	e := Explanation{Path: p}
+	stage := func(name, next string) {
+		e.Stages = append(e.Stages, ExplainStage{Name: name, Path: next, Changed: next != p})
+		p = next
+	}
+	failed := func(name string, err error) (Explanation, error) {
+		e.check(name, err)
+		return e, nil
+	}
+
+	if ps.cfg.stdlibParity != ParityOff {
+		check := validationErr(ps.cfg.checkParity(p))
+		e.check("stdlib-parity", check)
+		e.Valid = check == nil
+		quiet.explainCanonical(&e, p)
+		return e, nil
+	}
+	if _, _, name, err := quiet.preprocessStages(p, stage); err != nil {
+		return failed(name, err)
+	}
+
+	if err := quiet.explainChecks(&e, p); err != nil {
+		return Explanation{}, err
+	}
+	e.Valid = ps.cfg.enforced(e.Checks) == nil
+	quiet.explainCanonical(&e, p)
+	return e, nil
+}
+
+// explainCanonical adds the result of SanitizePath and the canonicalization
+// stages of the preprocessed path p to e
+func (ps *PathSecurity) explainCanonical(e *Explanation, p string) {
+	if sanitized, err := ps.SanitizePath(e.Path); err == nil {
+		e.Sanitized = sanitized
+	}
+	stage := func(name, next string) {
+		e.Stages = append(e.Stages, ExplainStage{Name: name, Path: next, Changed: next != p})
+		p = next
+	}
+	if ps.cfg.unicodeFolding {
+		stage("unicode-fold", unicodeFolder.Replace(p))
+	}
+	if ps.cfg.normalizeSeparators {
+		stage("separators", strings.ReplaceAll(p, `\`, "/"))
+	}
+	stage("case-fold", ps.cfg.foldCase(p))
+	stage("clean", path.Clean(p))
+	e.Canonical = p
+}
+
+// explainChecks evaluates the checks of validation on a preprocessed path
+// for e, mirroring precheck, interpret and finishValidation
+func (ps *PathSecurity) explainChecks(e *Explanation, p string) error {
+	c := &ps.cfg
+	check := e.check
+
+	check("control-characters", validationErr(checkControlChars(p)))
+	if c.treatEncodedSlashAsSeparator {
+		var err error
+		if hasDotDotSegment(splitEncodedSlashes(p)) {
+			err = &ValidationError{Path: p, Reason: "Directory traversal through encoded slashes", Err: ErrTraversalDetected}
+		}
+		check("encoded-slashes", err)
+	}
+	if !c.windowsSemantics {
+		var err error
+		if hasBackslashDotDot(p) {
+			err = &ValidationError{Path: p, Reason: "Directory traversal through backslash separators", Err: ErrTraversalDetected}
+		}
+		check("backslash-traversal", err)
+	}
+	check("unicode", validationErr(checkUnicodeTricks(p)))
+	check("shell", validationErr(c.checkShellConstructs(p)))
+
+	// Checks after a failed rewrite see the path as it was before
+	checked := p
+	next, verr := c.checkNetworkPath(checked)
+	if check("network", validationErr(verr)); verr == nil {
+		checked = next
+	}
+	next, verr = c.checkLongPath(checked)
+	if check("long-path", validationErr(verr)); verr == nil {
+		checked = next
+	}
+	check("traversal-patterns", validationErr(c.checkTraversalPatterns(checked)))
+	check("magic-links", validationErr(c.checkMagicLinks(checked)))
+	check("policy", c.checkPolicy(checked))
+	check("sensitive", c.checkSensitive(checked))
+	check("dotfiles", validationErr(c.checkDotfiles(checked)))
+	backendPath := checked
+	if c.windowsSemantics {
+		check("windows-path", validationErr(checkWindowsPath(checked)))
+		backendPath = strings.ReplaceAll(checked, `\`, "/")
+	}
+	check("windows-aliases", validationErr(c.checkWindowsAliases(checked)))
+	check("filesystem", validationErr(c.checkFilesystem(checked)))
+	if c.maxParentHops > 0 {
+		resolved, err := c.resolveParentHops(backendPath)
+		if err == nil {
+			backendPath = resolved
+		}
+		check("parent-hops", err)
+	}
+
+	// The native library would only see the path up to a NUL byte
+	if strings.IndexByte(backendPath, 0) < 0 {
+		response, err := backendValidatePath(backendPath, ps.cfg.nativeTimeout)
+		if err != nil {
+			return err
+		}
+		var parsed validateResponse
+		if err := json.Unmarshal([]byte(response), &parsed); err != nil {
+			return fmt.Errorf("%w: path validation returned malformed result: %v", ErrNativeFailure, err)
+		}
+		var rejection error
+		if !parsed.Valid {
+			rejection = newValidationError(p, parsed.Error)
+		}
+		check("validator", rejection)
+	}
+
+	findings := ps.ruleFindings(context.Background(), p)
+	for _, r := range c.rules {
+		var err error
+		for _, f := range findings {
+			if f.Rule == r.name && err == nil {
+				err = validationErr(ruleViolation(p, f))
+			}
+		}
+		check("rule:"+r.name, err)
+	}
+
+	if c.sensitivePaths != nil && !c.lexicalOnly {
+		check("special-file", validationErr(c.checkSpecialFile(p)))
+	}
+	return nil
+}
+
+// check records a check that rejects with err, or passes if err is nil
+func (e *Explanation) check(name string, err error) {
+	if err == nil {
+		e.Checks = append(e.Checks, ExplainCheck{Name: name})
+		return
+	}
+	e.Checks = append(e.Checks, ExplainCheck{Name: name, Matched: true, Reason: rejectionRule(err), Category: RejectionCategory(err), Err: err})
+}
+
+// validationErr converts verr to an error that is nil if verr is
+func validationErr(verr *ValidationError) error {
+	if verr == nil {
+		return nil
+	}
+	return verr
+}
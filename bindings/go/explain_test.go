@@ -0,0 +1,71 @@
+package pathsecurity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		path          string
+		wantValid     bool
+		wantChecks    int
+		wantChanged   []string
+		wantMatched   []string
+		wantCanonical string
+		wantSanitized string
+	}{
+		{
+			path: "tenants/acme/report.csv", wantValid: true, wantChecks: 16,
+			wantCanonical: "tenants/acme/report.csv", wantSanitized: "tenants/acme/report.csv",
+		},
+		{
+			path: "tenants/acme/%2e%2e/secrets/old.bak", wantChecks: 16,
+			wantChanged:   []string{"decode", "clean"},
+			wantMatched:   []string{"policy", "validator", "rule:no-secrets"},
+			wantCanonical: "tenants/secrets/old.bak", wantSanitized: "tenants/acme/secrets/old.bak",
+		},
+		{
+			path: "tenants/acme/a\x00b", wantChecks: 15,
+			wantMatched:   []string{"control-characters"},
+			wantCanonical: "tenants/acme/a\x00b", wantSanitized: "tenants/acme/ab",
+		},
+	}
+	ps := NewPathSecurity(
+		WithWindowsSemantics(false),
+		WithRule("no-secrets", noSecrets),
+		WithRule("tenant-prefix", tenantPrefix),
+		WithDecoding(DecodeOnce),
+		WithDeniedPatterns("*.bak"),
+		WithFailThreshold(SeverityCritical),
+	)
+	for _, tt := range tests {
+		explanation, err := ps.Explain(tt.path)
+		if err != nil {
+			t.Fatalf("Explain(%q) error = %v", tt.path, err)
+		}
+		var changed, matched []string
+		for _, stage := range explanation.Stages {
+			if stage.Changed {
+				changed = append(changed, stage.Name)
+			}
+		}
+		for _, check := range explanation.Checks {
+			if check.Matched {
+				matched = append(matched, check.Name)
+			}
+		}
+		if !reflect.DeepEqual(changed, tt.wantChanged) || !reflect.DeepEqual(matched, tt.wantMatched) {
+			t.Errorf("Explain(%q) changed %q and matched %q, want %q and %q", tt.path, changed, matched, tt.wantChanged, tt.wantMatched)
+		}
+		if len(explanation.Checks) != tt.wantChecks || explanation.Canonical != tt.wantCanonical || explanation.Sanitized != tt.wantSanitized {
+			t.Errorf("Explain(%q) = %d checks, canonical %q, sanitized %q, want %d, %q, %q", tt.path,
+				len(explanation.Checks), explanation.Canonical, explanation.Sanitized, tt.wantChecks, tt.wantCanonical, tt.wantSanitized)
+		}
+		// Explain agrees with ValidatePath
+		_, validErr := ps.ValidatePath(tt.path)
+		if explanation.Valid != tt.wantValid || explanation.Valid != (validErr == nil) {
+			t.Errorf("Explain(%q) valid = %t, ValidatePath error = %v, want valid %t", tt.path, explanation.Valid, validErr, tt.wantValid)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveExport maps a share-relative path received through an NFS/SMB
+// export onto the export's backend directory. The export must be configured
+// with WithExports, backslashes in relPath are treated as separators as SMB
+// clients send them, and the resolved path must stay inside the export root.
+func (ps *PathSecurity) ResolveExport(exportName, relPath string) (string, error) {
+	root, ok := ps.cfg.exports[exportName]
+	if !ok {
+		return "", ps.reject(relPath, fmt.Errorf("%w: %q", ErrUnknownExport, exportName))
+	}
+
+	rel := strings.ReplaceAll(relPath, `\`, "/")
+	rel, err := ps.prepare(rel)
+	if err != nil {
+		return "", err
+	}
+
+	resolved := filepath.Join(root, filepath.FromSlash(path.Clean("/"+rel)))
+	if !withinRoot(root, resolved) {
+		return "", ps.reject(relPath, fmt.Errorf("%w: %q escapes export %q", ErrTraversalDetected, relPath, exportName))
+	}
+	return resolved, nil
+}
@@ -0,0 +1,27 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolveExport(t *testing.T) {
+	tests := []struct {
+		export  string
+		path    string
+		want    string
+		wantErr error
+	}{
+		{export: "export1", path: `projects\q3\plan.docx`, want: "/srv/e1/projects/q3/plan.docx"},
+		{export: "export1", path: "projects/q3/plan.docx", want: "/srv/e1/projects/q3/plan.docx"},
+		{export: "export1", path: `..\..\etc\shadow`, wantErr: ErrTraversalDetected},
+		{export: "export9", path: `projects\q3\plan.docx`, wantErr: ErrUnknownExport},
+	}
+	ps := NewPathSecurity(WithExports(map[string]string{"export1": "/srv/e1"}))
+	for _, tt := range tests {
+		got, err := ps.ResolveExport(tt.export, tt.path)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("ResolveExport(%q, %q) = %q, %v, want %q, %v", tt.export, tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
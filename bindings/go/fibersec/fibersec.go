@@ -0,0 +1,93 @@
+// Package fibersec provides Fiber handlers that reject requests whose
+// route parameters fail Path Security validation, such as the wildcard of
+// "/files/*". It is a module of its own so that the bindings do not depend
+// on Fiber.
+package fibersec
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+	"github.com/redasgard/path-security/bindings/go/httpsec"
+)
+
+// Option configures the handler
+type Option func(*config)
+
+// config holds the handler settings
+type config struct {
+	ps         *pathsecurity.PathSecurity
+	params     []string
+	statusCode int
+	observer   func(c *fiber.Ctx, err error)
+}
+
+// WithPathSecurity sets the validator used for route parameters. The
+// default is pathsecurity.NewPathSecurity() with no options.
+func WithPathSecurity(ps *pathsecurity.PathSecurity) Option {
+	return func(c *config) {
+		c.ps = ps
+	}
+}
+
+// WithParams limits validation to the parameters with the given names, as
+// passed to Ctx.Params: without ':', and "*" or "+" for the first wildcard.
+// By default every parameter of the route is validated, with wildcards
+// named "*1", "*2" and so on.
+func WithParams(names ...string) Option {
+	return func(c *config) {
+		c.params = names
+	}
+}
+
+// WithStatusCode sets the status of the error returned for rejected
+// requests. The default is http.StatusBadRequest.
+func WithStatusCode(code int) Option {
+	return func(c *config) {
+		c.statusCode = code
+	}
+}
+
+// WithObserver registers a function called with each rejected request and
+// the *httpsec.ParamError, for logging or metrics
+func WithObserver(observer func(c *fiber.Ctx, err error)) Option {
+	return func(c *config) {
+		c.observer = observer
+	}
+}
+
+// Protect returns a handler that passes a request on only if the route
+// parameters pass httpsec.CheckParams, and otherwise returns a *fiber.Error
+// with the configured status code for the error handler to render. Fiber
+// gives handlers the parameters of their own route only, and those added
+// with App.Use or Group.Use have none, so pass Protect with the route:
+//
+//	app.Get("/files/*", fibersec.Protect(), serveFile)
+func Protect(opts ...Option) fiber.Handler {
+	cfg := config{statusCode: http.StatusBadRequest}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.ps == nil {
+		cfg.ps = pathsecurity.NewPathSecurity()
+	}
+
+	return func(c *fiber.Ctx) error {
+		names := cfg.params
+		if len(names) == 0 {
+			names = c.Route().Params
+		}
+		params := make([]httpsec.Param, len(names))
+		for i, name := range names {
+			params[i] = httpsec.Param{Name: name, Value: c.Params(name)}
+		}
+		if err := httpsec.CheckParams(cfg.ps, params); err != nil {
+			if cfg.observer != nil {
+				cfg.observer(c, err)
+			}
+			return fiber.NewError(cfg.statusCode, http.StatusText(cfg.statusCode))
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package fibersec
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+	"github.com/redasgard/path-security/bindings/go/httpsec"
+)
+
+func TestProtect(t *testing.T) {
+	tests := []struct {
+		target string
+		opts   []Option
+		want   int
+	}{
+		{target: "/files/docs/css/site.css", want: http.StatusOK},
+		{target: "/files/docs/../../etc/passwd", want: http.StatusBadRequest},
+		{target: "/files/docs/../../etc/passwd", opts: []Option{WithStatusCode(http.StatusForbidden)}, want: http.StatusForbidden},
+		{target: "/files/../css/site.css", want: http.StatusBadRequest},
+		{target: "/files/../css/site.css", opts: []Option{WithParams("*")}, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		var observed error
+		opts := append(tt.opts, WithObserver(func(c *fiber.Ctx, err error) {
+			observed = err
+		}))
+		app := fiber.New()
+		app.Get("/files/:bucket/*", Protect(opts...), func(c *fiber.Ctx) error {
+			return c.SendString("ok")
+		})
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, tt.target, nil), -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", tt.target, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.target, resp.StatusCode, tt.want)
+		}
+
+		var paramErr *httpsec.ParamError
+		if tt.want == http.StatusOK {
+			if observed != nil {
+				t.Errorf("GET %s observed %v", tt.target, observed)
+			}
+		} else if !errors.As(observed, &paramErr) || !errors.Is(observed, pathsecurity.ErrTraversalDetected) {
+			t.Errorf("GET %s observed %v, want a *httpsec.ParamError wrapping %v", tt.target, observed, pathsecurity.ErrTraversalDetected)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package pathsecurity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// maxFilenameBytes is the longest name SanitizeFilename returns unless
+	// WithMaxFilenameLength sets a limit, the NAME_MAX of common file
+	// systems
+	maxFilenameBytes = 255
+	// fallbackFilename replaces names that sanitize to nothing
+	fallbackFilename = "file"
+	// slugHashLength is the number of hex digits of SHA-256 that stand in
+	// for a name without transliterable characters
+	slugHashLength = 12
+)
+
+// SanitizeFilename reduces name to a single file name that is safe to
+// create in any directory, for uploads and other client-supplied names
+// where full path handling is overkill. It builds a PathSecurity from opts
+// for the call; use the method of the same name to reuse one.
+func SanitizeFilename(name string, opts ...Option) string {
+	return NewPathSecurity(opts...).SanitizeFilename(name)
+}
+
+// SanitizeFilename reduces name to a single safe file name; it never fails.
+// Everything up to the last '/' or '\', or a lookalike of either, is
+// dropped, so traversal and directory parts go with it. Control,
+// invisible and bidirectional characters are removed, characters Windows
+// forbids in names are replaced with '_', and leading dashes, dots and
+// spaces, which make names read as options or hide them, are trimmed along
+// with trailing dots and spaces. Reserved device names such as "CON" get a
+// "_" prefix. The result is cut to WithMaxFilenameLength bytes, or 255
+// without a limit, keeping a short extension. A name left empty becomes
+// "file". WithSlugFilenames turns the name into an ASCII slug beforehand.
+func (ps *PathSecurity) SanitizeFilename(name string) string {
+	s := unicodeFolder.Replace(ps.cfg.normalization.normalize(name))
+	if i := strings.LastIndexAny(s, `/\`); i >= 0 {
+		s = s[i+1:]
+	}
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		if r >= utf8.RuneSelf {
+			// Fullwidth letters are kept, lookalikes of path syntax have
+			// been folded and the rest only disguise the name
+			if kind, ok := unicodeTrickKind(r); ok && kind != TrickFullwidth {
+				return -1
+			}
+		}
+		if strings.ContainsRune(`<>:"|?*`, r) {
+			return '_'
+		}
+		return r
+	}, s)
+	if ps.cfg.slugFilenames {
+		s = slugFilename(s)
+	}
+	s = strings.TrimLeft(s, "-. ")
+
+	max := ps.cfg.maxFilenameLength
+	if max <= 0 {
+		max = maxFilenameBytes
+	}
+	if limits, ok := filesystemProfiles[ps.cfg.filesystem]; ok && limits.maxName < max {
+		max = limits.maxName
+	}
+	s = truncateFilename(strings.TrimRight(s, ". "), max)
+	if IsWindowsReservedName(s) {
+		s = truncateFilename("_"+s, max)
+	}
+	if s == "" {
+		s = fallbackFilename
+	}
+	ps.auditSanitized(SanitizeResult{Original: name, Sanitized: s, Changed: s != name}, "filename")
+	return s
+}
+
+// truncateFilename cuts name to at most max bytes on a rune boundary,
+// keeping its extension if that takes at most half the limit, and trims
+// the dots and spaces the cut exposes
+func truncateFilename(name string, max int) string {
+	if len(name) <= max {
+		return name
+	}
+	ext := path.Ext(name)
+	if ext == name || len(ext) > max/2 {
+		ext = ""
+	}
+	base := name[:len(name)-len(ext)]
+	limit := max - len(ext)
+	for limit > 0 && !utf8.RuneStart(base[limit]) {
+		limit--
+	}
+	return strings.TrimRight(base[:limit], ". ") + ext
+}
+
+// transliterations spell letters that do not decompose into ASCII and
+// marks, and drops apostrophes so "O'Brien" stays one word
+var transliterations = map[rune]string{
+	'ß': "ss", 'Æ': "ae", 'æ': "ae", 'Œ': "oe", 'œ': "oe",
+	'Ø': "o", 'ø': "o", 'Đ': "d", 'đ': "d", 'Ð': "d", 'ð': "d",
+	'Ł': "l", 'ł': "l", 'Þ': "th", 'þ': "th", 'ı': "i",
+	'\'': "", '\u2019': "",
+}
+
+// slugFilename turns a file name into a lowercase ASCII slug of letters,
+// digits, '.', '_' and single '-' between words, keeping the extension as
+// letters and digits only. Accents are dropped after decomposition and
+// other letters transliterated; a base with nothing left becomes a hash of
+// the original, so distinct names stay distinct.
+func slugFilename(name string) string {
+	ext := path.Ext(name)
+	if ext == name {
+		ext = ""
+	}
+	base := slugify(name[:len(name)-len(ext)])
+	if base == "" {
+		sum := sha256.Sum256([]byte(name))
+		base = hex.EncodeToString(sum[:])[:slugHashLength]
+	}
+	if ext = strings.NewReplacer("-", "", ".", "", "_", "").Replace(slugify(strings.TrimPrefix(ext, "."))); ext != "" {
+		return base + "." + ext
+	}
+	return base
+}
+
+// slugify lowercases s to ASCII letters, digits, '.' and '_' and turns
+// every other run of characters into a single '-'
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range norm.NFKD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if t, ok := transliterations[r]; ok {
+			b.WriteString(t)
+			dash = false
+			continue
+		}
+		switch r = unicode.ToLower(r); {
+		case 'a' <= r && r <= 'z', '0' <= r && r <= '9', r == '.', r == '_':
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimRight(slugDots.Replace(b.String()), "-")
+}
+
+// slugDots drops the '-' that punctuation next to a dot leaves
+var slugDots = strings.NewReplacer("-.", ".", ".-", ".")
@@ -0,0 +1,57 @@
+package pathsecurity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"report.pdf", "report.pdf"},
+		{`C:\Users\alice\Desktop\report.pdf`, "report.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{"..", "file"},
+		{"-rf", "rf"},
+		{".htaccess", "htaccess"},
+		{"CON.txt", "_CON.txt"},
+		{"invoice\u202egpj.exe", "invoicegpj.exe"},
+		{"a:b*c?.txt", "a_b_c_.txt"},
+		{"notes.txt. . ", "notes.txt"},
+		{"r\u00e9sum\u00e9\x00.doc", "r\u00e9sum\u00e9.doc"},
+		// Truncation keeps the extension and never splits a character
+		{strings.Repeat("\u9577", 100) + ".jpeg", strings.Repeat("\u9577", 83) + ".jpeg"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.name); got != tt.want {
+			t.Errorf("SanitizeFilename(%.40q) = %.40q, want %.40q", tt.name, got, tt.want)
+		}
+	}
+
+	if got := SanitizeFilename("quarterly-report-final.xlsx", WithMaxFilenameLength(16)); got != "quarterly-r.xlsx" {
+		t.Errorf("SanitizeFilename with a 16 byte limit = %q", got)
+	}
+}
+
+func TestWithSlugFilenames(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"R\u00e9sum\u00e9 final (v2).PDF", "resume-final-v2.pdf"},
+		{"Stra\u00dfe   \u00dcbersicht.xlsx", "strasse-ubersicht.xlsx"},
+		{"O'Brien's notes.txt", "obriens-notes.txt"},
+		{"--weird__name!!.tar.gz", "weird__name.tar.gz"},
+		{"CON.txt", "_con.txt"},
+		// Names with nothing to transliterate become a stable hash
+		{"\u9577\u3044\u540d\u524d.jpeg", "e34f0bbc24a6.jpeg"},
+		{"\u6771\u4eac.jpeg", "a5df467afeec.jpeg"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.name, WithSlugFilenames(true)); got != tt.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FilesystemProfile selects the file system paths are written to, whose
+// rules on names and lengths validation and sanitization then enforce, so
+// that accepted and sanitized paths can be created there. The limits are
+// those of the file system as reached from the operating systems that
+// usually mount it: the Windows family counts names and paths in UTF-16
+// code units and forbids the characters, reserved device names and
+// trailing dots and spaces Windows does, the others count bytes of UTF-8.
+type FilesystemProfile int
+
+const (
+	// FilesystemAny enforces no file system's rules
+	FilesystemAny FilesystemProfile = iota
+	// FilesystemExt4 allows any byte in names but '/' and NUL, up to 255
+	// bytes, in paths of up to 4095 bytes, the PATH_MAX of Linux
+	FilesystemExt4
+	// FilesystemNTFS forbids `<>:"/\|?*` and control characters, names
+	// up to 255 UTF-16 code units and paths up to 32766, the limit of long
+	// paths
+	FilesystemNTFS
+	// FilesystemFAT covers FAT32 and exFAT as Windows and removable media
+	// use them: the rules of FilesystemNTFS, in paths of at most 259
+	// UTF-16 code units, MAX_PATH without its terminator
+	FilesystemFAT
+	// FilesystemAPFS forbids ':', which macOS shows as '/', in names of
+	// up to 255 bytes and paths of up to 1023, the PATH_MAX of macOS
+	FilesystemAPFS
+	// FilesystemSMB is a share served by Windows or Samba, with the rules
+	// of FilesystemNTFS
+	FilesystemSMB
+	// FilesystemNFS allows any byte in names but '/' and NUL, up to 255
+	// bytes, in paths of up to 1023, the MAXPATHLEN of NFSv3
+	FilesystemNFS
+)
+
+// String returns the name of the profile
+func (f FilesystemProfile) String() string {
+	switch f {
+	case FilesystemAny:
+		return "any"
+	case FilesystemExt4:
+		return "ext4"
+	case FilesystemNTFS:
+		return "ntfs"
+	case FilesystemFAT:
+		return "fat"
+	case FilesystemAPFS:
+		return "apfs"
+	case FilesystemSMB:
+		return "smb"
+	case FilesystemNFS:
+		return "nfs"
+	}
+	return fmt.Sprintf("FilesystemProfile(%d)", int(f))
+}
+
+// filesystemLimits are the rules of a profile
+type filesystemLimits struct {
+	// illegal are the characters names may not hold besides '/', NUL and,
+	// on the Windows family, control characters
+	illegal string
+	// windows marks the Windows family
+	windows bool
+	// maxName and maxPath are the longest name and path, in UTF-16 code
+	// units on the Windows family and in bytes otherwise
+	maxName, maxPath int
+}
+
+// filesystemProfiles are the rules of each profile but FilesystemAny
+var filesystemProfiles = map[FilesystemProfile]filesystemLimits{
+	FilesystemExt4: {maxName: maxFilenameBytes, maxPath: 4095},
+	FilesystemNTFS: {illegal: `<>:"\|?*`, windows: true, maxName: maxFilenameBytes, maxPath: maxVerbatimPath - 1},
+	FilesystemFAT:  {illegal: `<>:"\|?*`, windows: true, maxName: maxFilenameBytes, maxPath: maxPath - 1},
+	FilesystemAPFS: {illegal: ":", maxName: maxFilenameBytes, maxPath: 1023},
+	FilesystemSMB:  {illegal: `<>:"\|?*`, windows: true, maxName: maxFilenameBytes, maxPath: maxVerbatimPath - 1},
+	FilesystemNFS:  {maxName: maxFilenameBytes, maxPath: 1023},
+}
+
+// length measures s in the unit of the profile's limits
+func (l filesystemLimits) length(s string) int {
+	if l.windows {
+		return utf16Len(s)
+	}
+	return len(s)
+}
+
+// unit names the unit of the profile's limits
+func (l filesystemLimits) unit() string {
+	if l.windows {
+		return "UTF-16 code units"
+	}
+	return "bytes"
+}
+
+// illegalRune reports whether r may not appear in a name. '\' separates
+// segments under Windows semantics, and is only illegal within a name
+// otherwise.
+func (l filesystemLimits) illegalRune(r rune) bool {
+	return r == 0 || l.windows && unicode.IsControl(r) || strings.ContainsRune(l.illegal, r)
+}
+
+// filesystemSegments splits p after its drive, UNC or verbatim prefix,
+// which Windows semantics keep out of the names, into the segments the
+// profile's rules apply to
+func (c *config) filesystemSegments(p string) (prefix string, segments []string) {
+	if verbatim, rest, ok := c.splitVerbatim(p); ok {
+		prefix, p = verbatim, rest
+	} else if c.windowsSemantics {
+		prefix = volumeName(p)
+		p = p[len(prefix):]
+	}
+	separator := func(r rune) bool { return r == '/' || c.windowsSemantics && r == '\\' }
+	return prefix, strings.FieldsFunc(p, separator)
+}
+
+// checkFilesystem applies the profile set with WithFilesystemProfile
+func (c *config) checkFilesystem(p string) *ValidationError {
+	limits, ok := filesystemProfiles[c.filesystem]
+	if !ok {
+		return nil
+	}
+	if n := limits.length(p); n > limits.maxPath {
+		return &ValidationError{Path: p, Reason: fmt.Sprintf("Path is %d %s, limit %d on %s", n, limits.unit(), limits.maxPath, c.filesystem), Err: ErrPathTooLong}
+	}
+	_, segments := c.filesystemSegments(p)
+	for _, segment := range segments {
+		if segment == "." || segment == ".." {
+			continue
+		}
+		if i := strings.IndexFunc(segment, limits.illegalRune); i >= 0 {
+			r, _ := utf8.DecodeRuneInString(segment[i:])
+			return &ValidationError{Path: p, Reason: fmt.Sprintf("Character %q in %q is not allowed on %s", r, segment, c.filesystem), Err: ErrIllegalCharacter}
+		}
+		if limits.windows {
+			if IsWindowsReservedName(segment) {
+				return &ValidationError{Path: p, Reason: fmt.Sprintf("Reserved device name %q on %s", segment, c.filesystem), Err: ErrReservedName}
+			}
+			if strings.TrimRight(segment, ". ") != segment {
+				return &ValidationError{Path: p, Reason: fmt.Sprintf("Trailing dot or space in %q is not allowed on %s", segment, c.filesystem), Err: ErrIllegalCharacter}
+			}
+		}
+		if n := limits.length(segment); n > limits.maxName {
+			return &ValidationError{Path: p, Reason: fmt.Sprintf("Name %q is %d %s, limit %d on %s", segment, n, limits.unit(), limits.maxName, c.filesystem), Err: ErrFilenameTooLong}
+		}
+	}
+	return nil
+}
+
+// fitFilesystem rewrites sanitized output to follow the profile's rules:
+// illegal characters become '_', the Windows family loses trailing dots
+// and spaces and prefixes reserved device names with '_', and names are
+// cut to the limit, counted in bytes, which is never fewer than UTF-16
+// code units. A name the trimming empties or turns into ".", ".." or a
+// lookalike of "..", becomes "_". Separators, and the prefix Windows
+// semantics keep out of the names, are kept as they are; a path still too
+// long is rejected with ErrPathTooLong, as no rewriting preserves what it
+// names.
+func (c *config) fitFilesystem(p string) (string, *ValidationError) {
+	limits, ok := filesystemProfiles[c.filesystem]
+	if !ok {
+		return p, nil
+	}
+	prefix, _ := c.filesystemSegments(p)
+	var b strings.Builder
+	b.WriteString(prefix)
+	rest := p[len(prefix):]
+	for rest != "" {
+		end := strings.IndexFunc(rest, func(r rune) bool { return r == '/' || c.windowsSemantics && r == '\\' })
+		if end == 0 {
+			b.WriteByte(rest[0])
+			rest = rest[1:]
+			continue
+		}
+		if end < 0 {
+			end = len(rest)
+		}
+		b.WriteString(limits.fitName(rest[:end]))
+		rest = rest[end:]
+	}
+	fitted := b.String()
+	if n := limits.length(fitted); n > limits.maxPath {
+		return "", &ValidationError{Path: p, Reason: fmt.Sprintf("Sanitized path is %d %s, limit %d on %s", n, limits.unit(), limits.maxPath, c.filesystem), Err: ErrPathTooLong}
+	}
+	return fitted, nil
+}
+
+// fitsFilesystem reports whether fitFilesystem leaves p as it is
+func (c *config) fitsFilesystem(p string) bool {
+	if c.filesystem == FilesystemAny {
+		return true
+	}
+	fitted, verr := c.fitFilesystem(p)
+	return verr == nil && fitted == p
+}
+
+// fitName rewrites a single name for fitFilesystem
+func (l filesystemLimits) fitName(name string) string {
+	if name == "." || name == ".." {
+		return name
+	}
+	s := strings.Map(func(r rune) rune {
+		if l.illegalRune(r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if l.windows {
+		s = strings.TrimRight(s, ". ")
+	}
+	s = truncateFilename(s, l.maxName)
+	if l.windows && IsWindowsReservedName(s) {
+		s = truncateFilename("_"+s, l.maxName)
+	}
+	if s == "" || s == "." || s == ".." || normalizesToTraversal(s) {
+		s = "_"
+	}
+	return s
+}
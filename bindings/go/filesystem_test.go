@@ -0,0 +1,42 @@
+package pathsecurity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithFilesystemProfile(t *testing.T) {
+	long := "reports/" + strings.Repeat("x", 300)
+	truncated := "reports/" + strings.Repeat("x", 255)
+	tests := []struct {
+		profile       FilesystemProfile
+		path          string
+		wantErr       error
+		wantSanitized string
+	}{
+		{FilesystemExt4, "reports/q1<draft>.txt", nil, "reports/q1<draft>.txt"},
+		{FilesystemExt4, "reports/aux.txt", nil, "reports/aux.txt"},
+		{FilesystemExt4, long, ErrFilenameTooLong, truncated},
+		{FilesystemNTFS, "reports/q1<draft>.txt", ErrIllegalCharacter, "reports/q1_draft_.txt"},
+		{FilesystemNTFS, "reports/aux.txt", ErrReservedName, "reports/_aux.txt"},
+		{FilesystemNTFS, long, ErrFilenameTooLong, truncated},
+		{FilesystemFAT, "reports/q1<draft>.txt", ErrIllegalCharacter, "reports/q1_draft_.txt"},
+		{FilesystemFAT, "reports/aux.txt", ErrReservedName, "reports/_aux.txt"},
+		// FAT limits whole paths to MAX_PATH, which truncation cannot fix
+		{FilesystemFAT, long, ErrPathTooLong, ""},
+		{FilesystemAPFS, "reports/q1<draft>.txt", nil, "reports/q1<draft>.txt"},
+		{FilesystemAPFS, "reports/aux.txt", nil, "reports/aux.txt"},
+		{FilesystemAPFS, long, ErrFilenameTooLong, truncated},
+	}
+	for _, tt := range tests {
+		ps := NewPathSecurity(WithFilesystemProfile(tt.profile), WithTargetOS("linux"))
+		if _, err := ps.ValidatePath(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: ValidatePath(%.24q) error = %v, want %v", tt.profile, tt.path, err, tt.wantErr)
+		}
+		sanitized, err := ps.SanitizePath(tt.path)
+		if sanitized != tt.wantSanitized || (err == nil) != (tt.wantSanitized != "") {
+			t.Errorf("%s: SanitizePath(%.24q) = %.32q, %v, want %.32q", tt.profile, tt.path, sanitized, err, tt.wantSanitized)
+		}
+	}
+}
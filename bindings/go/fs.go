@@ -0,0 +1,161 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsValidFSName reports whether ValidateFSName accepts name
+func (ps *PathSecurity) IsValidFSName(name string) bool {
+	return ps.ValidateFSName(name) == nil
+}
+
+// ValidateFSName checks name against the rules of fs.ValidPath, extended
+// with the instance's validator, so fs.FS implementations can vet the
+// names they are asked for. A name must be unrooted and slash-separated,
+// with no empty, "." or ".." elements except for "." naming the root;
+// names breaking these rules yield an error wrapping fs.ErrInvalid. Beyond
+// fs.ValidPath, names must not contain '\' or ':', which os.DirFS
+// rejects on Windows and other implementations may treat as separators or
+// stream names, and must pass the same checks as ValidatePath without its
+// preprocessing, so "%2e%2e" or a fullwidth dot is judged as the literal
+// name it is.
+func (ps *PathSecurity) ValidateFSName(name string) error {
+	if reason := invalidFSName(name); reason != "" {
+		return ps.reject(name, &ValidationError{Path: name, Reason: reason, Err: fs.ErrInvalid})
+	}
+	if name == "." {
+		return nil
+	}
+	return ps.checkFSName(name)
+}
+
+// invalidFSName returns why fs.ValidPath rejects name, or ""
+func invalidFSName(name string) string {
+	if fs.ValidPath(name) {
+		return ""
+	}
+	switch {
+	case name == "":
+		return "Empty name"
+	case strings.HasPrefix(name, "/"):
+		return "Rooted name"
+	case strings.HasSuffix(name, "/"):
+		return "Trailing slash"
+	}
+	for _, element := range strings.Split(name, "/") {
+		switch element {
+		case "":
+			return "Empty element"
+		case ".":
+			return "Dot element"
+		case "..":
+			return "Directory traversal element"
+		}
+	}
+	return "Not a valid io/fs name"
+}
+
+// checkFSName applies the checks of ValidateFSName beyond fs.ValidPath to
+// a name other than "."
+func (ps *PathSecurity) checkFSName(name string) error {
+	if i := strings.IndexAny(name, `\:`); i >= 0 {
+		return ps.reject(name, &ValidationError{Path: name, Reason: fmt.Sprintf("%q at offset %d in io/fs name", name[i], i), Err: ErrInvalidPath})
+	}
+	return ps.check(name)
+}
+
+// FS returns a file system for the directory tree rooted at root, like
+// os.DirFS, that validates every name it is asked for. Names must be valid
+// fs.FS names and pass the validator configured by opts, and a name whose
+// symlinks resolve outside root is rejected with ErrSymlinkEscape. The
+// result implements fs.StatFS, fs.ReadDirFS and fs.ReadFileFS, so it can
+// be passed to http.FS, template.ParseFS and other io/fs consumers.
+func FS(root string, opts ...Option) fs.FS {
+	return &secureFS{fsys: os.DirFS(root), root: root, ps: NewPathSecurity(opts...)}
+}
+
+// WrapFS returns a file system that validates every name before passing it
+// to fsys. Unlike FS it cannot follow symlinks itself, so confinement of
+// symlinked names is left to fsys.
+func WrapFS(fsys fs.FS, opts ...Option) fs.FS {
+	return &secureFS{fsys: fsys, ps: NewPathSecurity(opts...)}
+}
+
+// secureFS validates names before delegating to the wrapped file system.
+// root is set only for file systems created by FS.
+type secureFS struct {
+	fsys fs.FS
+	root string
+	ps   *PathSecurity
+}
+
+// Open implements fs.FS
+func (f *secureFS) Open(name string) (fs.File, error) {
+	if err := f.checkName("open", name); err != nil {
+		return nil, err
+	}
+	return f.fsys.Open(name)
+}
+
+// Stat implements fs.StatFS
+func (f *secureFS) Stat(name string) (fs.FileInfo, error) {
+	if err := f.checkName("stat", name); err != nil {
+		return nil, err
+	}
+	return fs.Stat(f.fsys, name)
+}
+
+// ReadDir implements fs.ReadDirFS
+func (f *secureFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := f.checkName("readdir", name); err != nil {
+		return nil, err
+	}
+	return fs.ReadDir(f.fsys, name)
+}
+
+// ReadFile implements fs.ReadFileFS
+func (f *secureFS) ReadFile(name string) ([]byte, error) {
+	if err := f.checkName("readfile", name); err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(f.fsys, name)
+}
+
+// checkName validates name and, for file systems created by FS, confines
+// its resolved location to the root. Failures are returned as
+// *fs.PathError with Op set to op.
+func (f *secureFS) checkName(op, name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return nil
+	}
+	if err := f.ps.checkFSName(name); err != nil {
+		return &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if f.root == "" {
+		return nil
+	}
+
+	absRoot, err := filepath.Abs(f.root)
+	if err != nil {
+		return &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	realRoot, err := filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		realRoot = absRoot
+	}
+	resolved, err := f.ps.evalSymlinks(filepath.Join(realRoot, filepath.FromSlash(name)))
+	if err != nil {
+		return &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if !withinRoot(realRoot, resolved) {
+		return &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("%w: %q resolves to %q", ErrSymlinkEscape, name, resolved)}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package pathsecurity
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestValidateFSName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr error
+	}{
+		{name: "."},
+		{name: "docs/report.txt"},
+		{name: "/etc/passwd", wantErr: fs.ErrInvalid},
+		{name: "docs/", wantErr: fs.ErrInvalid},
+		{name: "docs//report.txt", wantErr: fs.ErrInvalid},
+		{name: "docs/../secret", wantErr: fs.ErrInvalid},
+		{name: `docs\report.txt`, wantErr: ErrInvalidPath},
+		{name: "c:report.txt", wantErr: ErrInvalidPath},
+		{name: "docs/\uff0e\uff0e/secret", wantErr: ErrUnicodeTrick},
+	}
+	ps := NewPathSecurity(WithWindowsSemantics(false))
+	for _, tt := range tests {
+		err := ps.ValidateFSName(tt.name)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidateFSName(%q) = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if got := ps.IsValidFSName(tt.name); got != (tt.wantErr == nil) {
+			t.Errorf("IsValidFSName(%q) = %t", tt.name, got)
+		}
+	}
+
+	// The name is judged literally, not decoded and then accepted
+	if err := ps.ValidateFSName("docs/%2e%2e/secret"); err == nil || errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("ValidateFSName(docs/%%2e%%2e/secret) = %v, want a validator rejection", err)
+	}
+}
+
+func TestFS(t *testing.T) {
+	fsys := FS(testTree(t))
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: "inbox/hello.txt", want: "hello"},
+		{name: "../outside.txt", wantErr: fs.ErrInvalid},
+		{name: "inbox/escape/loot.txt", wantErr: ErrSymlinkEscape},
+	}
+	for _, tt := range tests {
+		data, err := fs.ReadFile(fsys, tt.name)
+		if !errors.Is(err, tt.wantErr) || string(data) != tt.want {
+			t.Errorf("ReadFile(%q) = %q, %v, want %q, %v", tt.name, data, err, tt.want, tt.wantErr)
+		}
+	}
+
+	entries, err := fs.ReadDir(fsys, "inbox")
+	if err != nil || len(entries) != 2 {
+		t.Errorf("ReadDir(inbox) = %v, %v, want 2 entries", entries, err)
+	}
+	if _, err := fs.Stat(fsys, "inbox/hello.txt"); err != nil {
+		t.Errorf("Stat(inbox/hello.txt) = %v", err)
+	}
+}
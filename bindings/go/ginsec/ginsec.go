@@ -0,0 +1,89 @@
+// Package ginsec provides Gin middleware that rejects requests whose route
+// parameters fail Path Security validation, such as the wildcard of
+// "/files/*filepath". It is a module of its own so that the bindings do not
+// depend on Gin.
+package ginsec
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+	"github.com/redasgard/path-security/bindings/go/httpsec"
+)
+
+// Option configures the middleware
+type Option func(*config)
+
+// config holds the middleware settings
+type config struct {
+	ps         *pathsecurity.PathSecurity
+	params     []string
+	statusCode int
+	observer   func(c *gin.Context, err error)
+}
+
+// WithPathSecurity sets the validator used for route parameters. The
+// default is pathsecurity.NewPathSecurity() with no options.
+func WithPathSecurity(ps *pathsecurity.PathSecurity) Option {
+	return func(c *config) {
+		c.ps = ps
+	}
+}
+
+// WithParams limits validation to the parameters with the given names, as
+// written in the route without ':' or '*'. By default every parameter of
+// the matched route is validated.
+func WithParams(names ...string) Option {
+	return func(c *config) {
+		c.params = names
+	}
+}
+
+// WithStatusCode sets the status written for rejected requests. The
+// default is http.StatusBadRequest.
+func WithStatusCode(code int) Option {
+	return func(c *config) {
+		c.statusCode = code
+	}
+}
+
+// WithObserver registers a function called with each rejected request and
+// the *httpsec.ParamError, for logging or metrics. It runs before the error
+// response is written.
+func WithObserver(observer func(c *gin.Context, err error)) Option {
+	return func(c *config) {
+		c.observer = observer
+	}
+}
+
+// Protect returns middleware that passes a request on only if the route
+// parameters pass httpsec.CheckParams, and otherwise aborts it with the
+// configured status code. Gin matches the route before running any
+// handler, so Protect may be registered with Engine.Use as well as on a
+// group or a single route.
+func Protect(opts ...Option) gin.HandlerFunc {
+	cfg := config{statusCode: http.StatusBadRequest}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.ps == nil {
+		cfg.ps = pathsecurity.NewPathSecurity()
+	}
+
+	return func(c *gin.Context) {
+		params := make([]httpsec.Param, len(c.Params))
+		for i, p := range c.Params {
+			params[i] = httpsec.Param{Name: p.Key, Value: p.Value}
+		}
+		if err := httpsec.CheckParams(cfg.ps, params, cfg.params...); err != nil {
+			if cfg.observer != nil {
+				cfg.observer(c, err)
+			}
+			c.String(cfg.statusCode, http.StatusText(cfg.statusCode))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,53 @@
+package ginsec
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+	"github.com/redasgard/path-security/bindings/go/httpsec"
+)
+
+func TestProtect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		target string
+		opts   []Option
+		want   int
+	}{
+		{target: "/files/docs/css/site.css", want: http.StatusOK},
+		{target: "/files/docs/../../etc/passwd", want: http.StatusBadRequest},
+		{target: "/files/docs/../../etc/passwd", opts: []Option{WithStatusCode(http.StatusForbidden)}, want: http.StatusForbidden},
+		{target: "/files/../css/site.css", want: http.StatusBadRequest},
+		{target: "/files/../css/site.css", opts: []Option{WithParams("filepath")}, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		var observed error
+		opts := append(tt.opts, WithObserver(func(c *gin.Context, err error) {
+			observed = err
+		}))
+		r := gin.New()
+		r.Use(Protect(opts...))
+		r.GET("/files/:bucket/*filepath", func(c *gin.Context) {
+			c.String(http.StatusOK, "ok")
+		})
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if rec.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.target, rec.Code, tt.want)
+		}
+
+		var paramErr *httpsec.ParamError
+		if tt.want == http.StatusOK {
+			if observed != nil {
+				t.Errorf("GET %s observed %v", tt.target, observed)
+			}
+		} else if !errors.As(observed, &paramErr) || !errors.Is(observed, pathsecurity.ErrTraversalDetected) {
+			t.Errorf("GET %s observed %v, want a *httpsec.ParamError wrapping %v", tt.target, observed, pathsecurity.ErrTraversalDetected)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// defaultMaxGlobWildcards is the wildcard limit of ValidateGlob unless set
+// with WithMaxGlobWildcards
+const defaultMaxGlobWildcards = 16
+
+// ValidateGlob checks a user-supplied glob pattern before it is handed to
+// filepath.Glob, fs.Glob or a doublestar library. The pattern uses '/' as
+// separator and the syntax of path.Match, plus "**" and "{a,b}" groups; a
+// malformed pattern yields an error wrapping path.ErrBadPattern. Patterns
+// are rejected with ErrTraversalDetected for ".." segments, with
+// ErrGlobTooComplex for runs of three or more '*' or more wildcards than
+// WithMaxGlobWildcards allows, since matching such patterns backtracks
+// exponentially, and with ErrNoMatchingRoot when WithAllowedRoots is set
+// and the directory before the first wildcard of an absolute pattern is
+// not under a root. The literal text of the pattern, with each wildcard
+// standing in for a single character, must also pass ValidatePath.
+func (ps *PathSecurity) ValidateGlob(pattern string) error {
+	if _, err := path.Match(strings.NewReplacer("{", "", "}", "", "**", "*").Replace(pattern), ""); err != nil {
+		return fmt.Errorf("glob %q: %w", pattern, err)
+	}
+
+	limit := ps.cfg.maxGlobWildcards
+	if limit <= 0 {
+		limit = defaultMaxGlobWildcards
+	}
+
+	var literal strings.Builder
+	wildcards, prefixEnd := 0, -1
+	wildcard := func() {
+		if wildcards == 0 {
+			prefixEnd = strings.LastIndexByte(literal.String(), '/')
+		}
+		wildcards++
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				literal.WriteByte(pattern[i])
+			}
+		case '*':
+			run := 1
+			for i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				run++
+			}
+			if run > 2 {
+				return ps.reject(pattern, fmt.Errorf("%w: %q has a run of %d '*'", ErrGlobTooComplex, pattern, run))
+			}
+			wildcard()
+			literal.WriteByte('x')
+		case '?':
+			wildcard()
+			literal.WriteByte('x')
+		case '[':
+			if end := strings.IndexByte(pattern[i+1:], ']'); end >= 0 {
+				i += end + 1
+			}
+			wildcard()
+			literal.WriteByte('x')
+		case '{':
+			wildcard()
+			// The alternatives stay part of the literal text, so that each
+			// of them is validated
+			if end := strings.IndexByte(pattern[i+1:], '}'); end >= 0 {
+				literal.WriteString(strings.ReplaceAll(pattern[i+1:i+1+end], ",", "x"))
+				i += end + 1
+			}
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	if wildcards > limit {
+		return ps.reject(pattern, fmt.Errorf("%w: %q has %d wildcards, more than the configured maximum of %d", ErrGlobTooComplex, pattern, wildcards, limit))
+	}
+
+	for _, segment := range strings.FieldsFunc(pattern, func(r rune) bool { return strings.ContainsRune("/{},", r) }) {
+		if segment == ".." {
+			return ps.reject(pattern, fmt.Errorf("%w: %q", ErrTraversalDetected, pattern))
+		}
+	}
+	text := literal.String()
+	if _, err := ps.ValidatePath(text); err != nil {
+		return err
+	}
+
+	if len(ps.cfg.allowedRoots) == 0 || !strings.HasPrefix(text, "/") {
+		return nil
+	}
+	prefix := text
+	if prefixEnd >= 0 {
+		prefix = text[:prefixEnd+1]
+	}
+	if _, ok := ps.matchRoot(filepath.Clean(filepath.FromSlash(prefix))); !ok {
+		return ps.reject(pattern, fmt.Errorf("%w: %q", ErrNoMatchingRoot, pattern))
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package pathsecurity
+
+import (
+	"errors"
+	"path"
+	"testing"
+)
+
+func TestValidateGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr error
+	}{
+		{pattern: "/srv/data/reports/**/*.csv"},
+		{pattern: "logs/2024-??-*.log"},
+		{pattern: "/srv/data/{2023,2024}/[a-z]*.txt"},
+		{pattern: "../*/secret", wantErr: ErrTraversalDetected},
+		{pattern: "/srv/{data,../etc}/passwd", wantErr: ErrTraversalDetected},
+		{pattern: "/var/log/*.log", wantErr: ErrNoMatchingRoot},
+		{pattern: "a*a*a*a*a*a*a*b", wantErr: ErrGlobTooComplex},
+		{pattern: "/srv/data/***", wantErr: ErrGlobTooComplex},
+		{pattern: "/srv/data/[a-", wantErr: path.ErrBadPattern},
+		{pattern: "/srv/data/\x00*", wantErr: ErrNullByte},
+	}
+	ps := NewPathSecurity(WithAllowedRoots("/srv/data"), WithMaxGlobWildcards(6))
+	for _, tt := range tests {
+		if err := ps.ValidateGlob(tt.pattern); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidateGlob(%q) = %v, want %v", tt.pattern, err, tt.wantErr)
+		}
+	}
+
+	// Without roots an absolute pattern only has to be a valid path
+	if err := NewPathSecurity().ValidateGlob("/var/log/*.log"); err != nil {
+		t.Errorf("ValidateGlob(/var/log/*.log) without roots = %v", err)
+	}
+}
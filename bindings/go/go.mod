@@ -1,7 +1,8 @@
-module path-security-go
+module github.com/redasgard/path-security/bindings/go
 
 go 1.21
 
 require (
-	// No external dependencies for CGO bindings
+	golang.org/x/sys v0.15.0
+	golang.org/x/text v0.14.0
 )
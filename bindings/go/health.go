@@ -0,0 +1,38 @@
+package pathsecurity
+
+import "sync/atomic"
+
+// nativeTimeouts, nativePanics and nativeStuck count the incidents
+// reported by Health
+var (
+	nativeTimeouts atomic.Uint64
+	nativePanics   atomic.Uint64
+	nativeStuck    atomic.Int64
+)
+
+// HealthReport counts the native library calls that failed to complete
+// normally since the process started, across every PathSecurity instance
+type HealthReport struct {
+	// Timeouts is the number of calls abandoned after the timeout set with
+	// WithNativeTimeout
+	Timeouts uint64
+	// Panics is the number of calls whose panic was recovered and returned
+	// as an error
+	Panics uint64
+	// Stuck is the number of abandoned calls that have not returned yet.
+	// Each holds a goroutine and an operating system thread, so a count
+	// that keeps growing calls for a restart.
+	Stuck int64
+}
+
+// Health returns the incidents of the native library. The counts stay zero
+// in builds with the pure-Go engine and when the dlopen build fell back to
+// it.
+func Health() HealthReport {
+	return HealthReport{Timeouts: nativeTimeouts.Load(), Panics: nativePanics.Load(), Stuck: nativeStuck.Load()}
+}
+
+// Healthy reports whether no abandoned native call is still running
+func (h HealthReport) Healthy() bool {
+	return h.Stuck == 0
+}
@@ -0,0 +1,18 @@
+package pathsecurity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	before := Health()
+	ps := NewPathSecurity(WithNativeTimeout(time.Second))
+	if _, err := ps.ValidatePath("uploads/report.pdf"); err != nil {
+		t.Errorf("ValidatePath with a native timeout error = %v", err)
+	}
+	// Calls that return in time leave the counters alone
+	if after := Health(); after != before || !after.Healthy() {
+		t.Errorf("Health = %+v after a prompt call, was %+v", after, before)
+	}
+}
@@ -0,0 +1,101 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+)
+
+// TildePolicy selects how a leading "~" or "~user" is treated
+type TildePolicy int
+
+const (
+	// TildeKeep passes the prefix to the validator unchanged, which
+	// treats "~" as suspicious wherever it appears
+	TildeKeep TildePolicy = iota
+	// TildeReject rejects paths starting with "~" with an error wrapping
+	// ErrHomeDirectory, whatever follows it
+	TildeReject
+	// TildeExpand replaces "~" and "~user" with the home directory given
+	// by the lookup set with WithHomeDirs, before environment expansion
+	// and every check. Prefixes the lookup cannot resolve, or with a
+	// malformed user name, are rejected with ErrHomeDirectory.
+	TildeExpand
+)
+
+// String returns the name of the policy
+func (p TildePolicy) String() string {
+	switch p {
+	case TildeKeep:
+		return "keep"
+	case TildeReject:
+		return "reject"
+	case TildeExpand:
+		return "expand"
+	}
+	return fmt.Sprintf("TildePolicy(%d)", int(p))
+}
+
+// LookupHomeDir returns the home directory of the named user, or of the
+// current user if name is empty, from the operating system. It can be
+// passed to WithHomeDirs.
+func LookupHomeDir(name string) (string, bool) {
+	if name == "" {
+		home, err := os.UserHomeDir()
+		return home, err == nil && home != ""
+	}
+	u, err := user.Lookup(name)
+	if err != nil || u.HomeDir == "" {
+		return "", false
+	}
+	return u.HomeDir, true
+}
+
+// expandHome applies the policy set with WithTildePolicy to a leading "~"
+// or "~user" up to the first separator
+func (ps *PathSecurity) expandHome(path string) (string, error) {
+	if ps.cfg.tildePolicy == TildeKeep || !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+	if ps.cfg.tildePolicy == TildeReject {
+		return "", ps.reject(path, fmt.Errorf("%w: %q", ErrHomeDirectory, path))
+	}
+
+	end := 1
+	for end < len(path) && !ps.cfg.isSegmentSeparator(path[end]) {
+		end++
+	}
+	name, rest := path[1:end], path[end:]
+	if name != "" && !isUserName(name) {
+		return "", ps.reject(path, fmt.Errorf("%w: invalid user name %q in %q", ErrHomeDirectory, name, path))
+	}
+
+	var home string
+	ok := false
+	if lookup := ps.cfg.homeDirs; lookup != nil {
+		home, ok = lookup(name)
+	}
+	if !ok || home == "" {
+		return "", ps.reject(path, fmt.Errorf("%w: no home directory for %q", ErrHomeDirectory, path[:end]))
+	}
+	if rest == "" {
+		return home, nil
+	}
+	return strings.TrimRight(home, `/\`) + rest, nil
+}
+
+// isUserName reports whether name has the form of a user name: letters,
+// digits, '_', '-' and '.', not starting with '-' or '.'
+func isUserName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case isASCIILetter(c), '0' <= c && c <= '9', c == '_':
+		case (c == '-' || c == '.') && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithTildePolicy(t *testing.T) {
+	homes := map[string]string{"": "/home/demo", "alice": "/home/alice"}
+	lookup := func(user string) (string, bool) {
+		home, ok := homes[user]
+		return home, ok
+	}
+	tests := []struct {
+		policy  TildePolicy
+		path    string
+		want    string
+		wantErr error
+	}{
+		{policy: TildeKeep, path: "~/../../etc/passwd", wantErr: ErrTraversalDetected},
+		{policy: TildeReject, path: "~/notes.txt", wantErr: ErrHomeDirectory},
+		{policy: TildeReject, path: "~alice/.profile", wantErr: ErrHomeDirectory},
+		{policy: TildeReject, path: "~/../../etc/passwd", wantErr: ErrHomeDirectory},
+		{policy: TildeExpand, path: "~/notes.txt", want: "/home/demo/notes.txt"},
+		{policy: TildeExpand, path: "~alice/.profile", want: "/home/alice/.profile"},
+		{policy: TildeExpand, path: "~mallory/x", wantErr: ErrHomeDirectory},
+		{policy: TildeExpand, path: "~/../../etc/passwd", wantErr: ErrTraversalDetected},
+	}
+	for _, tt := range tests {
+		ps := NewPathSecurity(WithWindowsSemantics(false), WithTildePolicy(tt.policy), WithHomeDirs(lookup))
+		got, err := ps.Canonicalize(tt.path)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("%s: Canonicalize(%q) = %q, %v, want %q, %v", tt.policy, tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTildePolicyString(t *testing.T) {
+	for policy, want := range map[TildePolicy]string{
+		TildeKeep:      "keep",
+		TildeReject:    "reject",
+		TildeExpand:    "expand",
+		TildePolicy(5): "TildePolicy(5)",
+	} {
+		if got := policy.String(); got != want {
+			t.Errorf("TildePolicy(%d).String() = %q, want %q", int(policy), got, want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Package httpsec provides HTTP middleware that rejects requests whose URL
+// path fails Path Security validation, for use in front of file servers and
+// reverse proxies.
+package httpsec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+	"github.com/redasgard/path-security/bindings/go/abuse"
+)
+
+// ErrClientBlocked is passed to the observer for requests refused because
+// the abuse tracker considers their client abusive
+var ErrClientBlocked = errors.New("client blocked after repeated rejections")
+
+// Option configures the middleware
+type Option func(*config)
+
+// config holds the middleware settings
+type config struct {
+	ps         *pathsecurity.PathSecurity
+	statusCode int
+	observer   func(r *http.Request, err error)
+	tracker    *abuse.Tracker
+	clientKey  func(r *http.Request) string
+	rewrite    bool
+}
+
+// originalPathKey is the context key of the path of a rewritten request
+type originalPathKey struct{}
+
+// WithPathSecurity sets the validator used for request paths. The default
+// is pathsecurity.NewPathSecurity() with no options.
+func WithPathSecurity(ps *pathsecurity.PathSecurity) Option {
+	return func(c *config) {
+		c.ps = ps
+	}
+}
+
+// WithStatusCode sets the status written for rejected requests. The
+// default is http.StatusBadRequest.
+func WithStatusCode(code int) Option {
+	return func(c *config) {
+		c.statusCode = code
+	}
+}
+
+// WithObserver registers a function called with each rejected request and
+// the validation error, or ErrClientBlocked for requests refused by the
+// abuse tracker, for logging or metrics. It runs before the error response
+// is written.
+func WithObserver(observer func(r *http.Request, err error)) Option {
+	return func(c *config) {
+		c.observer = observer
+	}
+}
+
+// WithAbuseTracker records every rejected request in tracker under the
+// key returned by clientKey, and refuses all requests from clients the
+// tracker considers abusive with http.StatusTooManyRequests before
+// validating them. A nil clientKey uses RemoteIP. Behind a proxy, pass a
+// function that reads the client address from a header the proxy sets.
+func WithAbuseTracker(tracker *abuse.Tracker, clientKey func(r *http.Request) string) Option {
+	return func(c *config) {
+		c.tracker = tracker
+		c.clientKey = clientKey
+	}
+}
+
+// WithRewrite passes requests whose path fails validation only for its
+// spelling on to the handler with the path rewritten, for legacy clients
+// that send sloppy but benign paths such as "/a/./b//c". The path is
+// rewritten to its sanitized form, as configured with
+// pathsecurity.WithSanitizeStrategy, when that passes validation, the
+// original has no ".." segment and both clean to the same path, so only
+// "." segments, repeated and trailing slashes and what the sanitizer
+// removes besides may differ. pathsecurity.SanitizeCollapse suits this
+// mode. Other rejected requests are rejected as usual. The original path
+// is kept for the handler, see OriginalPath, while the URL and RequestURI
+// show the rewritten one, and the sanitizer reports the
+// rewrite to the audit function of the PathSecurity; the observer and the
+// abuse tracker do not see rewritten requests.
+func WithRewrite(enabled bool) Option {
+	return func(c *config) {
+		c.rewrite = enabled
+	}
+}
+
+// OriginalPath returns the URL path a request had before WithRewrite
+// rewrote it, and false if it was not rewritten
+func OriginalPath(r *http.Request) (string, bool) {
+	original, ok := r.Context().Value(originalPathKey{}).(string)
+	return original, ok
+}
+
+// RemoteIP returns the host part of r.RemoteAddr, or all of it if it has
+// no port
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Protect wraps next so that requests are passed on only if their decoded
+// URL path passes validation and their escaped path shows no traversal,
+// which catches encodings such as "%2e%2e%2f" that decode to separators.
+// Other requests get the configured status code and never reach next.
+func Protect(next http.Handler, opts ...Option) http.Handler {
+	cfg := config{statusCode: http.StatusBadRequest}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.ps == nil {
+		cfg.ps = pathsecurity.NewPathSecurity()
+	}
+	if cfg.clientKey == nil {
+		cfg.clientKey = RemoteIP
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var key string
+		if cfg.tracker != nil {
+			key = cfg.clientKey(r)
+			if cfg.tracker.IsAbusive(key) {
+				if cfg.observer != nil {
+					cfg.observer(r, ErrClientBlocked)
+				}
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+		}
+		if err := check(cfg.ps, r); err != nil {
+			if rewritten, ok := rewrite(cfg, r); ok {
+				next.ServeHTTP(w, rewritten)
+				return
+			}
+			if cfg.tracker != nil {
+				cfg.tracker.Record(key)
+			}
+			if cfg.observer != nil {
+				cfg.observer(r, err)
+			}
+			http.Error(w, http.StatusText(cfg.statusCode), cfg.statusCode)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rewrite returns r with its path sanitized under WithRewrite, if the
+// sanitized path names the same file and passes validation
+func rewrite(cfg config, r *http.Request) (*http.Request, bool) {
+	if !cfg.rewrite || slices.Contains(strings.Split(r.URL.Path, "/"), "..") {
+		return nil, false
+	}
+	sanitized, err := cfg.ps.SanitizePath(r.URL.Path)
+	if err != nil {
+		return nil, false
+	}
+	if !strings.HasPrefix(sanitized, "/") {
+		sanitized = "/" + sanitized
+	}
+	if path.Clean(sanitized) != path.Clean(r.URL.Path) {
+		return nil, false
+	}
+	rewritten := r.Clone(context.WithValue(r.Context(), originalPathKey{}, r.URL.Path))
+	rewritten.URL.Path = sanitized
+	rewritten.URL.RawPath = ""
+	rewritten.RequestURI = rewritten.URL.RequestURI()
+	if check(cfg.ps, rewritten) != nil {
+		return nil, false
+	}
+	return rewritten, true
+}
+
+// check validates the request's URL path in decoded and escaped form
+func check(ps *pathsecurity.PathSecurity, r *http.Request) error {
+	if r.URL.Path == "" || r.URL.Path == "/" {
+		return nil
+	}
+	if _, err := ps.ValidatePath(r.URL.Path); err != nil {
+		return err
+	}
+
+	escaped := r.URL.EscapedPath()
+	traversal, err := ps.DetectTraversal(escaped)
+	if err != nil {
+		return err
+	}
+	if traversal {
+		return &pathsecurity.ValidationError{Path: escaped, Reason: "Traversal detected in escaped request path", Err: pathsecurity.ErrTraversalDetected}
+	}
+	return nil
+}
+
+// Param is a named route parameter as a router extracts it from the URL,
+// such as the wildcard of "/files/*filepath". Routers match and decode
+// parameters after middleware wrapping an http.Handler has run, so Protect
+// never sees them; the adapters in ginsec, echosec and fibersec validate
+// them with CheckParams instead.
+type Param struct {
+	Name  string
+	Value string
+}
+
+// ParamError reports a route parameter that failed validation
+type ParamError struct {
+	// Name is the name of the parameter
+	Name string
+	// Err is the validation error
+	Err error
+}
+
+// Error implements error
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("route parameter %q: %v", e.Name, e.Err)
+}
+
+// Unwrap returns the validation error, so errors.Is matches the sentinels
+// of pathsecurity
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// CheckParams validates the values of the parameters named in names, or of
+// all of them if names is empty, as paths, and returns a *ParamError for
+// the first that fails. Empty values and "/", which wildcards match for the
+// route itself, pass, and names without a parameter are ignored.
+func CheckParams(ps *pathsecurity.PathSecurity, params []Param, names ...string) error {
+	for _, p := range params {
+		if len(names) > 0 && !slices.Contains(names, p.Name) {
+			continue
+		}
+		if p.Value == "" || p.Value == "/" {
+			continue
+		}
+		if _, err := ps.ValidatePath(p.Value); err != nil {
+			return &ParamError{Name: p.Name, Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package httpsec
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+	"github.com/redasgard/path-security/bindings/go/abuse"
+)
+
+// serve is the protected handler, echoing the path it was given
+var serve = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, r.URL.Path)
+})
+
+func TestProtect(t *testing.T) {
+	var rejected []string
+	handler := Protect(serve, WithStatusCode(http.StatusForbidden), WithObserver(func(r *http.Request, err error) {
+		if !errors.Is(err, pathsecurity.ErrTraversalDetected) {
+			t.Errorf("observer error for %s = %v, want %v", r.URL.RequestURI(), err, pathsecurity.ErrTraversalDetected)
+		}
+		rejected = append(rejected, r.URL.RequestURI())
+	}))
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/", http.StatusOK},
+		{"/static/My%20Files/app.css", http.StatusOK},
+		{"/static/..%2f..%2fetc/passwd", http.StatusForbidden},
+		{"/static/%2e%2e/secret", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if recorder.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.target, recorder.Code, tt.want)
+		}
+	}
+	if len(rejected) != 2 {
+		t.Errorf("observer saw %q, want the 2 rejected requests", rejected)
+	}
+}
+
+func TestWithRewrite(t *testing.T) {
+	ps := pathsecurity.NewPathSecurity(pathsecurity.WithSanitizeStrategy(pathsecurity.SanitizeCollapse))
+	handler := Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		original, rewritten := OriginalPath(r)
+		fmt.Fprintf(w, "%s %t %s", r.URL.Path, rewritten, original)
+	}), WithRewrite(true), WithPathSecurity(ps))
+	tests := []struct {
+		target   string
+		wantCode int
+	}{
+		{"/static/app.css", http.StatusOK},
+		{"/static/./css//app.css", http.StatusOK},
+		{"/static/../config.yaml", http.StatusBadRequest},
+		{"/static/%2e%2e/config.yaml", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if recorder.Code != tt.wantCode {
+			t.Errorf("GET %s = %d, want %d", tt.target, recorder.Code, tt.wantCode)
+		}
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+		// Only a path the backend rejects is rewritten, to exactly what
+		// SanitizePath makes of it
+		want := tt.target + " false "
+		if _, err := ps.ValidatePath(tt.target); err != nil {
+			sanitized, err := ps.SanitizePath(tt.target)
+			if err != nil {
+				t.Fatalf("SanitizePath(%q) error = %v", tt.target, err)
+			}
+			want = sanitized + " true " + tt.target
+		}
+		if got := recorder.Body.String(); got != want {
+			t.Errorf("GET %s served %q, want %q", tt.target, got, want)
+		}
+	}
+}
+
+func TestWithAbuseTracker(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := abuse.New(abuse.WithThreshold(3), abuse.WithWindow(time.Minute), abuse.WithBlockDuration(5*time.Minute), abuse.WithClock(func() time.Time { return clock }))
+	var blocked int
+	handler := Protect(serve, WithAbuseTracker(tracker, nil), WithObserver(func(r *http.Request, err error) {
+		if errors.Is(err, ErrClientBlocked) {
+			blocked++
+		}
+	}))
+	tests := []struct {
+		target      string
+		wantCode    int
+		wantAbusive bool
+	}{
+		{"/static/app.css", http.StatusOK, false},
+		{"/static/%2e%2e/etc/passwd", http.StatusBadRequest, false},
+		{"/static/..%2fsecret", http.StatusBadRequest, false},
+		{"/static/%2e%2e%2f%2e%2e/shadow", http.StatusBadRequest, true},
+		{"/static/app.css", http.StatusTooManyRequests, true},
+	}
+	for i, tt := range tests {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		request.RemoteAddr = "203.0.113.7:4711"
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != tt.wantCode {
+			t.Errorf("request %d GET %s = %d, want %d", i, tt.target, recorder.Code, tt.wantCode)
+		}
+		if got := tracker.IsAbusive("203.0.113.7"); got != tt.wantAbusive {
+			t.Errorf("after request %d, IsAbusive = %t, want %t", i, got, tt.wantAbusive)
+		}
+	}
+	if blocked != 1 {
+		t.Errorf("observer saw %d blocked requests, want 1", blocked)
+	}
+
+	clock = clock.Add(6 * time.Minute)
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/static/app.css", nil)
+	request.RemoteAddr = "203.0.113.7:4711"
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("GET after the block expired = %d, want %d", recorder.Code, http.StatusOK)
+	}
+}
+
+func TestCheckParams(t *testing.T) {
+	ps := pathsecurity.NewPathSecurity()
+	params := []Param{{Name: "id", Value: "42"}, {Name: "filepath", Value: "/../../etc/passwd"}, {Name: "empty", Value: ""}}
+
+	err := CheckParams(ps, params)
+	var paramErr *ParamError
+	if !errors.As(err, &paramErr) || paramErr.Name != "filepath" || !errors.Is(err, pathsecurity.ErrTraversalDetected) {
+		t.Errorf("CheckParams = %v, want a *ParamError for filepath wrapping %v", err, pathsecurity.ErrTraversalDetected)
+	}
+	if err := CheckParams(ps, params, "id", "empty", "missing"); err != nil {
+		t.Errorf("CheckParams of the safe parameters = %v", err)
+	}
+}
+
+func TestRemoteIP(t *testing.T) {
+	for addr, want := range map[string]string{
+		"203.0.113.7:4711": "203.0.113.7",
+		"[2001:db8::1]:80": "2001:db8::1",
+		"unix-socket":      "unix-socket",
+	} {
+		if got := RemoteIP(&http.Request{RemoteAddr: addr}); got != want {
+			t.Errorf("RemoteIP(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// defaultMaxInputBytes is the longest input accepted unless
+// WithMaxInputBytes sets another limit
+const defaultMaxInputBytes = 64 << 10
+
+// checkInput is the pre-flight check of raw input, run before any other
+// stage so that nothing is handed to the native library that it cannot
+// take whole. It rejects input longer than WithMaxInputBytes with
+// ErrPathTooLong and, unless WithRawBytes allows it, input that is not
+// valid UTF-8 with ErrInvalidEncoding. NUL bytes, which C strings cannot
+// carry, are rejected with ErrNullByte by validation and stripped by the
+// sanitizers, which must not fail on them.
+func (c *config) checkInput(p string) *ValidationError {
+	if verr := c.checkInputLength(p, len(p)); verr != nil {
+		return verr
+	}
+	if !c.rawBytes && !utf8.ValidString(p) {
+		offset := 0
+		for offset < len(p) {
+			r, size := utf8.DecodeRuneInString(p[offset:])
+			if r == utf8.RuneError && size == 1 {
+				break
+			}
+			offset += size
+		}
+		return &ValidationError{Path: p, Reason: fmt.Sprintf("Invalid UTF-8 at offset %d", offset), Err: ErrInvalidEncoding}
+	}
+	return nil
+}
+
+// checkInputLength rejects input of n bytes, of which p holds at least the
+// first maxReportedInput, if it is longer than WithMaxInputBytes allows
+func (c *config) checkInputLength(p string, n int) *ValidationError {
+	limit := c.maxInputBytes
+	if limit <= 0 {
+		limit = defaultMaxInputBytes
+	}
+	if n > limit {
+		return &ValidationError{Path: truncateInput(p), Reason: fmt.Sprintf("Input is %d bytes, more than the limit of %d", n, limit), Err: ErrPathTooLong}
+	}
+	return nil
+}
+
+// maxReportedInput is the number of bytes of oversized input kept in its
+// ValidationError, so the error does not hold on to all of it
+const maxReportedInput = 256
+
+// truncateInput cuts oversized input down for reporting
+func truncateInput(p string) string {
+	if len(p) <= maxReportedInput {
+		return p
+	}
+	cut := maxReportedInput
+	for cut > 0 && !utf8.RuneStart(p[cut]) {
+		cut--
+	}
+	return p[:cut] + "..."
+}
@@ -0,0 +1,39 @@
+package pathsecurity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInputPreflight(t *testing.T) {
+	ps := NewPathSecurity()
+	huge := strings.Repeat("a/", 512<<10)
+	_, err := ps.ValidatePath(huge)
+	var validationErr *ValidationError
+	if !errors.Is(err, ErrPathTooLong) || !errors.As(err, &validationErr) {
+		t.Errorf("ValidatePath of %d bytes error = %v, want a *ValidationError wrapping %v", len(huge), err, ErrPathTooLong)
+	}
+	if _, err := ps.DetectTraversal(huge); !errors.Is(err, ErrPathTooLong) {
+		t.Errorf("DetectTraversal of %d bytes error = %v, want %v", len(huge), err, ErrPathTooLong)
+	}
+	if _, err := ps.SanitizePath(huge); !errors.Is(err, ErrPathTooLong) {
+		t.Errorf("SanitizePath of %d bytes error = %v, want %v", len(huge), err, ErrPathTooLong)
+	}
+
+	tests := []struct {
+		ps      *PathSecurity
+		path    string
+		wantErr error
+	}{
+		{ps.Clone(WithMaxInputBytes(16)), "uploads/2024/report.pdf", ErrPathTooLong},
+		{ps, "uploads/caf\xe9.txt", ErrInvalidEncoding},
+		{ps.Clone(WithRawBytes(true)), "uploads/caf\xe9.txt", nil},
+		{ps, "uploads/a\x00b", ErrNullByte},
+	}
+	for _, tt := range tests {
+		if _, err := tt.ps.ValidatePath(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidatePath(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
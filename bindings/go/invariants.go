@@ -0,0 +1,160 @@
+package pathsecurity
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Invariants checked by CheckInvariants, as reported in
+// InvariantViolation.Invariant
+const (
+	// InvariantValidNotTraversal: a path ValidatePath accepts is not
+	// reported by DetectTraversal
+	InvariantValidNotTraversal = "valid-not-traversal"
+	// InvariantExplainAgrees: Explain agrees with ValidatePath on whether
+	// the path is valid
+	InvariantExplainAgrees = "explain-agrees"
+	// InvariantStdlibAgrees: CompareWithStdlib finds no disagreement
+	InvariantStdlibAgrees = "stdlib-agrees"
+	// InvariantSanitizeNoParent: sanitized output has no ".." segment under
+	// either separator
+	InvariantSanitizeNoParent = "sanitize-no-parent"
+	// InvariantSanitizeIdempotent: sanitizing sanitized output changes
+	// nothing, except under SanitizeEncode, whose output is meant to be
+	// decoded once
+	InvariantSanitizeIdempotent = "sanitize-idempotent"
+	// InvariantSanitizeNotTraversal: DetectTraversal finds no traversal in
+	// sanitized output: no ".." segment once encoded slashes are split, if
+	// set with WithTreatEncodedSlashAsSeparator, and lookalikes of dots and
+	// separators are folded. Names the validator rejects for other reasons,
+	// such as "a..b", are not traversal.
+	InvariantSanitizeNotTraversal = "sanitize-not-traversal"
+	// InvariantConfinement: a relative path ValidatePath accepts, and
+	// relative sanitized output, stay confined to a root they are joined
+	// to, and stay confined when the result is joined to the root again
+	InvariantConfinement = "confinement"
+	// InvariantStdlibParity: under WithStdlibParity, ValidatePath accepts
+	// exactly the paths filepath.IsLocal or fs.ValidPath accepts
+	InvariantStdlibParity = "stdlib-parity"
+)
+
+// confinementRoot is the root CheckInvariants joins paths to
+var confinementRoot = filepath.Join(string(filepath.Separator), "invariant-root")
+
+// InvariantViolation describes an input that breaks an invariant
+type InvariantViolation struct {
+	// Invariant is one of the Invariant constants
+	Invariant string
+	Path      string
+	Detail    string
+}
+
+func (v *InvariantViolation) Error() string {
+	return fmt.Sprintf("invariant %s broken by %q: %s", v.Invariant, v.Path, v.Detail)
+}
+
+// IsConfined reports whether candidate, read relative to root unless it is
+// absolute, lies inside root or is root itself once both are cleaned, so
+// "a/../b" is confined to any root and "../b" or "a/../../b" to none. The
+// check is lexical and case-sensitive, uses the separators of the host
+// platform and follows no symlinks; use OpenInRoot to confine access to
+// real files.
+func IsConfined(root, candidate string) bool {
+	root = filepath.Clean(root)
+	if !filepath.IsAbs(candidate) {
+		candidate = filepath.Join(root, candidate)
+	}
+	rel, err := filepath.Rel(root, filepath.Clean(candidate))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// CheckInvariants runs ValidatePath, DetectTraversal, Explain,
+// CompareWithStdlib and SanitizePath on path and checks the properties
+// every configuration should have, listed as the Invariant constants, so
+// fuzz and property tests of applications can assert them on their own
+// configuration and inputs. The error joins an *InvariantViolation for
+// each broken invariant and is nil if all hold; failures of the calls
+// themselves, such as a rejected path, are not violations. Confinement is
+// not checked under WithMaxParentHops, which deliberately accepts paths
+// that climb out of the directory they are joined to, nor is
+// InvariantValidNotTraversal under WithStdlibParity. Nothing is reported
+// to the audit function, metrics collector or warn observer.
+func (ps *PathSecurity) CheckInvariants(path string) error {
+	quiet := ps.quiet()
+	var violations []error
+	broken := func(invariant, format string, args ...any) {
+		violations = append(violations, &InvariantViolation{Invariant: invariant, Path: path, Detail: fmt.Sprintf(format, args...)})
+	}
+	confined := func(p string) bool {
+		joined := filepath.Join(confinementRoot, filepath.FromSlash(p))
+		rel, err := filepath.Rel(confinementRoot, joined)
+		return err == nil && IsConfined(confinementRoot, p) && IsConfined(confinementRoot, joined) &&
+			IsConfined(confinementRoot, filepath.Join(confinementRoot, rel))
+	}
+	checkConfinement := ps.cfg.maxParentHops <= 0
+
+	_, validErr := quiet.ValidatePath(path)
+	traversal, _ := quiet.DetectTraversal(path)
+	// Parity modes accept paths such as "a/../b" that stay local
+	if validErr == nil && traversal && ps.cfg.stdlibParity == ParityOff {
+		broken(InvariantValidNotTraversal, "accepted by ValidatePath but reported by DetectTraversal")
+	}
+	if validErr == nil && checkConfinement && isRelative(path) && !confined(path) {
+		broken(InvariantConfinement, "accepted by ValidatePath but escapes %q", confinementRoot)
+	}
+	if parity := ps.cfg.stdlibParity; parity == ParityIsLocal && filepath.IsLocal(path) != (validErr == nil) ||
+		parity == ParityValidPath && fs.ValidPath(path) != (validErr == nil) {
+		broken(InvariantStdlibParity, "%v parity, ValidatePath returned %v", parity, validErr)
+	}
+	if explanation, err := quiet.Explain(path); err == nil && explanation.Valid != (validErr == nil) {
+		broken(InvariantExplainAgrees, "Explain reports valid %v, ValidatePath returned %v", explanation.Valid, validErr)
+	}
+	if comparison, err := quiet.CompareWithStdlib(path); err == nil && !comparison.Agrees() {
+		broken(InvariantStdlibAgrees, "%s", strings.Join(comparison.Disagreements, "; "))
+	}
+
+	sanitized, err := quiet.SanitizePath(path)
+	if err != nil {
+		return errors.Join(violations...)
+	}
+	for _, segment := range strings.FieldsFunc(sanitized, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == ".." {
+			broken(InvariantSanitizeNoParent, "sanitized to %q, which has a \"..\" segment", sanitized)
+			break
+		}
+	}
+	if ps.cfg.sanitizeStrategy == SanitizeEncode {
+		// Encoding encoded output encodes its escapes
+	} else if again, err := quiet.SanitizePath(sanitized); err != nil {
+		broken(InvariantSanitizeIdempotent, "sanitized to %q, which SanitizePath rejects: %v", sanitized, err)
+	} else if again != sanitized {
+		broken(InvariantSanitizeIdempotent, "sanitized to %q, then to %q", sanitized, again)
+	}
+	if checked, err := quiet.preprocess(sanitized); err == nil && quiet.cfg.reachesParent(checked) {
+		broken(InvariantSanitizeNotTraversal, "sanitized to %q, which DetectTraversal reports as traversal", sanitized)
+	}
+	if checkConfinement && isRelative(sanitized) && !confined(sanitized) {
+		broken(InvariantConfinement, "sanitized to %q, which escapes %q", sanitized, confinementRoot)
+	}
+	return errors.Join(violations...)
+}
+
+// isRelative reports whether p is relative under both separators and
+// carries no volume name
+func isRelative(p string) bool {
+	return p != "" && !strings.HasPrefix(p, "/") && !strings.HasPrefix(p, `\`) && !filepath.IsAbs(p) && filepath.VolumeName(p) == ""
+}
+
+// reachesParent reports whether the preprocessed path p has a ".." segment
+// by any of the spellings detectTraversal reports before the validator runs
+func (c *config) reachesParent(p string) bool {
+	return hasDotDotSegment(strings.ReplaceAll(p, `\`, "/")) ||
+		c.treatEncodedSlashAsSeparator && hasDotDotSegment(splitEncodedSlashes(p)) ||
+		normalizesToTraversal(p)
+}
@@ -0,0 +1,80 @@
+package pathsecurity
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestIsConfined(t *testing.T) {
+	tests := []struct {
+		root, candidate string
+		want            bool
+	}{
+		{"/srv/data", "uploads/report.pdf", true},
+		{"/srv/data", "a/../b", true},
+		{"/srv/data", "", true},
+		{"/srv/data", "/srv/data", true},
+		{"/srv/data", "/srv/data/x", true},
+		{"/srv/data/", "/srv/data/./x", true},
+		{"/srv/data", "../b", false},
+		{"/srv/data", "a/../../b", false},
+		{"/srv", "a/../../b", false},
+		{"/srv", "/srvx", false},
+		{"/srv", "/srv/../srvx", false},
+		{"/srv/data", "/etc/passwd", false},
+		{"/", "/etc/passwd", true},
+	}
+	for _, tt := range tests {
+		if got := IsConfined(tt.root, tt.candidate); got != tt.want {
+			t.Errorf("IsConfined(%q, %q) = %t, want %t", tt.root, tt.candidate, got, tt.want)
+		}
+	}
+}
+
+// generatedFragments are joined by generatedPaths into inputs
+var generatedFragments = []string{
+	"..", ".", "/", `\`, "a", "etc", "passwd", "%2e", "%2f", "%5c", "%25", "%c0%ae",
+	"\x00", "\uff0e", "\u2215", "\u200b", "\u202e", "\u2025", "~", ":", "::$DATA",
+	"CON", ".git", "....//", "..;/", " ",
+}
+
+// generatedPaths returns n inputs of one to eight fragments, the same for
+// each run
+func generatedPaths(n int) []string {
+	rng := rand.New(rand.NewSource(1))
+	paths := make([]string, n)
+	for i := range paths {
+		var b strings.Builder
+		for k := 1 + rng.Intn(8); k > 0; k-- {
+			b.WriteString(generatedFragments[rng.Intn(len(generatedFragments))])
+		}
+		paths[i] = b.String()
+	}
+	return paths
+}
+
+func TestCheckInvariants(t *testing.T) {
+	configs := []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"recursive decoding", []Option{WithDecoding(DecodeRecursive), WithUnicodeNormalization(NormalizeNFKC)}},
+		{"windows collapse", []Option{WithWindowsSemantics(true), WithSanitizeStrategy(SanitizeCollapse)}},
+		{"native separators", []Option{WithWindowsSemantics(false), WithNativeSeparators(true), WithSegmentPolicy(SegmentsCollapse), WithSanitizeStrategy(SanitizeCollapse)}},
+		{"encoded slashes", []Option{WithTreatEncodedSlashAsSeparator(true), WithSanitizeStrategy(SanitizeEncode)}},
+		{"IsLocal parity", []Option{WithStdlibParity(ParityIsLocal)}},
+		{"dotfile allowlist", []Option{WithDotfilePolicy(DotfilesAllowlist), WithAllowedDotfiles(".well-known"), WithCaseInsensitive(true), WithSanitizeStrategy(SanitizeReject)}},
+		{"NTFS", []Option{WithFilesystemProfile(FilesystemNTFS), WithWindowsSemantics(true)}},
+	}
+	inputs := append(TraversalPayloads(), generatedPaths(500)...)
+	for _, config := range configs {
+		ps := NewPathSecurity(config.opts...)
+		for _, input := range inputs {
+			if err := ps.CheckInvariants(input); err != nil {
+				t.Errorf("%s: %v", config.name, err)
+			}
+		}
+	}
+}
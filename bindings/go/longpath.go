@@ -0,0 +1,155 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxPath is MAX_PATH, the length in UTF-16 code units, terminator
+	// included, that Windows APIs accept for paths without the verbatim
+	// prefix unless long path support is enabled system-wide
+	maxPath = 260
+	// maxVerbatimPath is the length in UTF-16 code units that Windows
+	// accepts for verbatim paths
+	maxVerbatimPath = 32767
+)
+
+// utf16Len returns the number of UTF-16 code units that encode s
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// splitVerbatim splits a verbatim path to a drive or UNC share, such as
+// `\\?\C:\a` or `\\?\UNC\host\share\a`, into its prefix and the rest. It
+// reports false unless WithVerbatimPaths and Windows semantics are both
+// enabled. The prefix must be written with '\', as Windows only skips
+// normalization for that spelling.
+func (c *config) splitVerbatim(path string) (prefix, rest string, ok bool) {
+	if !c.windowsSemantics || !c.verbatimPaths || !strings.HasPrefix(path, `\\?\`) {
+		return "", "", false
+	}
+	p := path[4:]
+	if len(p) >= 2 && isASCIILetter(p[0]) && p[1] == ':' && (len(p) == 2 || p[2] == '\\') {
+		return path[:6], p[2:], true
+	}
+	if len(p) < 4 || !strings.EqualFold(p[:4], `UNC\`) {
+		return "", "", false
+	}
+	host, after, _ := strings.Cut(p[4:], `\`)
+	share, _, _ := strings.Cut(after, `\`)
+	if host == "" || share == "" {
+		return "", "", false
+	}
+	n := 8 + len(host) + 1 + len(share)
+	return path[:n], path[n:], true
+}
+
+// checkLongPath applies WithVerbatimPaths and WithWindowsMaxPath and returns
+// the form of path the remaining checks apply to: the equivalent normalized
+// Windows path for an accepted verbatim path and path itself otherwise.
+// Windows resolves neither "." nor ".." in verbatim paths, does not treat
+// '/' as a separator and keeps trailing dots and spaces, so those are
+// rejected rather than checked as they would read without the prefix.
+func (c *config) checkLongPath(path string) (string, *ValidationError) {
+	prefix, rest, ok := c.splitVerbatim(path)
+	if !ok {
+		if c.windowsMaxPath && c.windowsSemantics && utf16Len(path)+1 > maxPath {
+			return "", &ValidationError{Path: path, Reason: fmt.Sprintf("Path exceeds MAX_PATH of %d UTF-16 code units without the \\\\?\\ prefix", maxPath), Err: ErrPathTooLong}
+		}
+		return path, nil
+	}
+
+	if utf16Len(path)+1 > maxVerbatimPath {
+		return "", &ValidationError{Path: path, Reason: fmt.Sprintf("Verbatim path exceeds %d UTF-16 code units", maxVerbatimPath), Err: ErrPathTooLong}
+	}
+	if strings.Contains(rest, "/") {
+		return "", &ValidationError{Path: path, Reason: "'/' in verbatim path, where it is part of a name", Err: ErrInvalidPath}
+	}
+	segments := strings.Split(strings.TrimPrefix(rest, `\`), `\`)
+	for i, segment := range segments {
+		switch {
+		case segment == "":
+			if i < len(segments)-1 {
+				return "", &ValidationError{Path: path, Reason: "Empty component in verbatim path", Err: ErrInvalidPath}
+			}
+		case segment == "." || segment == "..":
+			return "", &ValidationError{Path: path, Reason: fmt.Sprintf("%q component in verbatim path, which Windows does not resolve", segment), Err: ErrTraversalDetected}
+		case strings.HasSuffix(segment, ".") || strings.HasSuffix(segment, " "):
+			return "", &ValidationError{Path: path, Reason: fmt.Sprintf("Trailing dot or space in verbatim component %q, which Windows keeps", segment), Err: ErrInvalidPath}
+		}
+	}
+
+	if rest == "" {
+		rest = `\`
+	}
+	if prefix[4] == 'U' || prefix[4] == 'u' {
+		return `\\` + prefix[8:] + rest, nil
+	}
+	return prefix[4:] + rest, nil
+}
+
+// sanitizeVerbatim applies the sanitize strategy to the part of a verbatim
+// path after its prefix and keeps the prefix, so the result stays a
+// verbatim path with '\' separators
+func (ps *PathSecurity) sanitizeVerbatim(path, prefix, rest string) (SanitizeResult, error) {
+	result, err := ps.applyStrategy("/" + strings.TrimLeft(strings.ReplaceAll(rest, `\`, "/"), "/"))
+	if err != nil {
+		return SanitizeResult{}, err
+	}
+	sanitized := prefix + `\` + strings.ReplaceAll(strings.TrimLeft(result.Sanitized, "/"), "/", `\`)
+	return SanitizeResult{Original: path, Sanitized: sanitized, Changed: sanitized != path}, nil
+}
+
+// VerbatimPath validates a drive-absolute or UNC path and returns its
+// verbatim form, such as `\\?\C:\a\b` for "C:/a/./b" or `\\?\UNC\host\share\a`
+// for `\\host\share\a`, for opening paths longer than MAX_PATH. Separators
+// are converted to '\' and "." and empty components removed first, since
+// Windows no longer does so once the prefix is added. Paths already in
+// verbatim form are returned as validated. Other paths, and paths with
+// components ending in a dot or space, which the prefix would make
+// Windows keep, are rejected with an error wrapping ErrInvalidPath.
+func (ps *PathSecurity) VerbatimPath(path string) (string, error) {
+	p, err := ps.prepare(path)
+	if err != nil {
+		return "", err
+	}
+	if _, _, ok := ps.cfg.splitVerbatim(p); ok {
+		return p, nil
+	}
+
+	var prefix string
+	switch ClassifyWindowsPath(p) {
+	case WindowsDriveAbsolute:
+		prefix, p = `\\?\`+p[:2], p[2:]
+	case WindowsUNC:
+		prefix = `\\?\UNC`
+	default:
+		return "", ps.reject(path, &ValidationError{Path: path, Reason: "Only drive-absolute and UNC paths have a verbatim form", Err: ErrInvalidPath})
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	for _, segment := range windowsSegments(p) {
+		if segment == "." {
+			continue
+		}
+		if strings.HasSuffix(segment, ".") || strings.HasSuffix(segment, " ") {
+			return "", ps.reject(path, &ValidationError{Path: path, Reason: fmt.Sprintf("Trailing dot or space in component %q", segment), Err: ErrInvalidPath})
+		}
+		b.WriteByte('\\')
+		b.WriteString(segment)
+	}
+	if b.Len() == len(prefix) {
+		b.WriteByte('\\')
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,72 @@
+package pathsecurity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithVerbatimPaths(t *testing.T) {
+	long := `C:\data\` + strings.Repeat(`archive\`, 40) + "report.txt"
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{path: `C:\data\report.txt`},
+		{path: long, wantErr: ErrPathTooLong},
+		{path: `\\?\` + long},
+		{path: `\\?\C:\data\..\Windows\win.ini`, wantErr: ErrTraversalDetected},
+		{path: `\\?\C:\data\report.txt.`, wantErr: ErrInvalidPath},
+		{path: `\\?\C:\data/report.txt`, wantErr: ErrInvalidPath},
+		{path: `\\?\GLOBALROOT\Device\HarddiskVolume1`, wantErr: ErrDevicePath},
+	}
+	ps := NewPathSecurity(WithWindowsSemantics(true), WithVerbatimPaths(true), WithWindowsMaxPath(true))
+	for _, tt := range tests {
+		if _, err := ps.ValidatePath(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidatePath(%.40q) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+
+	if _, err := NewPathSecurity(WithWindowsSemantics(true)).ValidatePath(`\\?\C:\data\report.txt`); !errors.Is(err, ErrDevicePath) {
+		t.Errorf("verbatim path without WithVerbatimPaths error = %v, want %v", err, ErrDevicePath)
+	}
+}
+
+func TestVerbatimPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr error
+	}{
+		{path: `C:/data/./reports/q1.csv`, want: `\\?\C:\data\reports\q1.csv`},
+		{path: `\\?\C:\data\reports\q1.csv`, want: `\\?\C:\data\reports\q1.csv`},
+		{path: `\\?\C:\data\..\..\secret`, wantErr: ErrTraversalDetected},
+		{path: "reports/q1.csv", wantErr: ErrInvalidPath},
+	}
+	ps := NewPathSecurity(WithWindowsSemantics(true), WithVerbatimPaths(true), WithWindowsMaxPath(true))
+	for _, tt := range tests {
+		got, err := ps.VerbatimPath(tt.path)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerbatimPath(%q) = %q, %v, want %v", tt.path, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("VerbatimPath(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestSanitizeVerbatimPath(t *testing.T) {
+	ps := NewPathSecurity(WithWindowsSemantics(true), WithVerbatimPaths(true))
+	for strategy, want := range map[SanitizeStrategy]string{
+		SanitizeStrip:    `\\?\C:\data\Windows\win.ini`,
+		SanitizeCollapse: `\\?\C:\Windows\win.ini`,
+	} {
+		got, err := ps.Clone(WithSanitizeStrategy(strategy)).SanitizePath(`\\?\C:\data\..\Windows\win.ini`)
+		if err != nil || got != want {
+			t.Errorf("%s: SanitizePath = %q, %v, want %q", strategy, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// procLinks are the entries of /proc/<pid> that the kernel resolves to the
+// root, working directory, executable or open files of the process,
+// wherever those are, rather than following a stored target
+var procLinks = []string{"root", "cwd", "exe", "fd", "map_files"}
+
+// devLinks are the entries of /dev that lead into /proc/self/fd
+var devLinks = []string{"fd", "stdin", "stdout", "stderr"}
+
+// FindMagicLink returns the byte offset and text of the first part of the
+// '/'-separated path p that routes through a procfs magic symlink, or -1
+// and "" if there is none: /proc/self and /proc/thread-self, the root, cwd,
+// exe, fd and map_files entries of /proc/<pid> and /proc/<pid>/task/<tid>,
+// and /dev/fd, /dev/stdin, /dev/stdout and /dev/stderr. The kernel resolves
+// these to another process's view of the file system or to open files, so
+// they escape any confinement a lexical check sees. The /proc forms match
+// at any depth, as procfs is often mounted elsewhere in containers, and the
+// /dev forms only at the root. Names are compared case-sensitively.
+func FindMagicLink(p string) (int, string) {
+	if !strings.Contains(p, "proc/") && !strings.HasPrefix(p, "/dev/") {
+		return -1, ""
+	}
+	for start := 0; start < len(p); {
+		end := strings.IndexByte(p[start:], '/')
+		if end < 0 {
+			end = len(p)
+		} else {
+			end += start
+		}
+		if segment := p[start:end]; segment == "proc" || segment == "dev" {
+			if n := magicLinkSegments(p[start:], start == 1); n > 0 {
+				return start, p[start : start+n]
+			}
+		}
+		start = end + 1
+	}
+	return -1, ""
+}
+
+// magicLinkSegments returns the length of the magic symlink reference at
+// the start of p, which begins with a segment, or 0 if there is none; atRoot
+// reports whether that segment is the first of an absolute path
+func magicLinkSegments(p string, atRoot bool) int {
+	segments := strings.SplitN(p, "/", 8)
+	if len(segments) < 2 {
+		return 0
+	}
+	length := func(n int) int {
+		return len(strings.Join(segments[:n], "/"))
+	}
+	switch segments[0] {
+	case "dev":
+		if atRoot && slices.Contains(devLinks, segments[1]) {
+			return length(2)
+		}
+	case "proc":
+		if segments[1] == "self" || segments[1] == "thread-self" {
+			return length(2)
+		}
+		if !isDecimal(segments[1]) {
+			return 0
+		}
+		n := 2
+		if n+1 < len(segments) && segments[n] == "task" && isDecimal(segments[n+1]) {
+			n += 2
+		}
+		if n < len(segments) && slices.Contains(procLinks, segments[n]) {
+			return length(n + 1)
+		}
+	}
+	return 0
+}
+
+// isDecimal reports whether s is a non-empty run of ASCII digits
+func isDecimal(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkMagicLinks rejects a path through a procfs magic symlink. Under
+// Windows semantics '\' separates segments too, as it does for
+// DetectTraversal.
+func (c *config) checkMagicLinks(p string) *ValidationError {
+	checked := p
+	if c.windowsSemantics {
+		checked = strings.ReplaceAll(p, `\`, "/")
+	}
+	offset, link := FindMagicLink(checked)
+	if offset < 0 {
+		return nil
+	}
+	return &ValidationError{Path: p, Reason: fmt.Sprintf("Magic symlink %q at offset %d", link, offset), Err: ErrMagicLink}
+}
@@ -0,0 +1,34 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFindMagicLink(t *testing.T) {
+	tests := []struct {
+		path       string
+		wantOffset int
+		wantLink   string
+	}{
+		{"/proc/self/root/etc/passwd", 1, "proc/self"},
+		{"/proc/1/fd/3", 1, "proc/1/fd"},
+		{"/proc/42/task/43/cwd/x", 1, "proc/42/task/43/cwd"},
+		{"/host/proc/1/root", 6, "proc/1/root"},
+		{"/dev/fd/0", 1, "dev/fd"},
+		{"/dev/stdout", 1, "dev/stdout"},
+		{"srv/dev/fd", -1, ""},
+		{"/proc/cpuinfo", -1, ""},
+		{"/proc/1/status", -1, ""},
+	}
+	ps := NewPathSecurity()
+	for _, tt := range tests {
+		offset, link := FindMagicLink(tt.path)
+		if offset != tt.wantOffset || link != tt.wantLink {
+			t.Errorf("FindMagicLink(%q) = %d, %q, want %d, %q", tt.path, offset, link, tt.wantOffset, tt.wantLink)
+		}
+		if _, err := ps.ValidatePath(tt.path); (tt.wantLink != "") != errors.Is(err, ErrMagicLink) {
+			t.Errorf("ValidatePath(%q) error = %v, want magic link rejection %t", tt.path, err, tt.wantLink != "")
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package pathsecurity
+
+import (
+	"errors"
+	"time"
+)
+
+// MetricsCollector receives counts and timings from a PathSecurity, for
+// export to a monitoring system. The metrics subpackage provides an
+// implementation served in the Prometheus text format; collectors for
+// other systems such as OpenTelemetry only need these three methods.
+// Methods are called synchronously, possibly from several goroutines at
+// once, and should not block.
+type MetricsCollector interface {
+	// ObserveValidation records one path run through the validator, how
+	// long it took and whether it was accepted
+	ObserveValidation(duration time.Duration, valid bool)
+	// ObserveRejection records one rejected path under the category
+	// returned by RejectionCategory
+	ObserveRejection(category string)
+	// ObserveSanitization records one input changed by a sanitizer, under
+	// the rule an audit Event would carry
+	ObserveSanitization(rule string)
+}
+
+// rejectionCategories maps sentinel errors to the labels returned by
+// RejectionCategory and the severities returned by RejectionSeverity, most
+// specific first
+var rejectionCategories = []struct {
+	err      error
+	label    string
+	severity Severity
+}{
+	{ErrTraversalDetected, "traversal", SeverityCritical},
+	{ErrNullByte, "null_byte", SeverityCritical},
+	{ErrControlCharacter, "control_character", SeverityHigh},
+	{ErrInvalidEncoding, "encoding", SeverityHigh},
+	{ErrUnicodeTrick, "unicode", SeverityHigh},
+	{ErrPathTooLong, "too_long", SeverityMedium},
+	{ErrPathTooDeep, "too_deep", SeverityMedium},
+	{ErrAbsolutePath, "absolute", SeverityMedium},
+	{ErrDeniedPattern, "denied_pattern", SeverityHigh},
+	{ErrInvalidSegment, "segment", SeverityHigh},
+	{ErrSensitivePath, "sensitive", SeverityHigh},
+	{ErrHiddenPath, "hidden", SeverityHigh},
+	{ErrIllegalCharacter, "illegal_character", SeverityMedium},
+	{ErrMountBoundary, "mount_boundary", SeverityHigh},
+	{ErrMagicLink, "magic_link", SeverityCritical},
+	{ErrSymlinkEscape, "symlink_escape", SeverityCritical},
+	{ErrTargetCheck, "target", SeverityMedium},
+	{ErrOperationDenied, "operation_denied", SeverityHigh},
+	{ErrRuleViolation, "rule", SeverityHigh},
+	{ErrGlobTooComplex, "glob_too_complex", SeverityMedium},
+	{ErrSeverityExceeded, "severity", SeverityHigh},
+	{ErrNoMatchingRoot, "no_matching_root", SeverityHigh},
+	{ErrOutsidePrefix, "outside_prefix", SeverityHigh},
+	{ErrUnknownExport, "unknown_export", SeverityMedium},
+	{ErrReservedName, "reserved_name", SeverityMedium},
+	{ErrDevicePath, "device_path", SeverityHigh},
+	{ErrUNCPath, "unc_path", SeverityHigh},
+	{ErrNetworkPath, "network_path", SeverityHigh},
+	{ErrObjectKey, "object_key", SeverityMedium},
+	{ErrAlternateDataStream, "alternate_stream", SeverityHigh},
+	{ErrShortName, "short_name", SeverityMedium},
+	{ErrNotCanonical, "not_canonical", SeverityMedium},
+	{ErrUnresolvedVariable, "unresolved_variable", SeverityMedium},
+	{ErrHomeDirectory, "home_directory", SeverityMedium},
+	{ErrShellConstruct, "shell_construct", SeverityHigh},
+	{ErrUnservableType, "unservable_type", SeverityMedium},
+	{ErrExtensionNotAllowed, "extension", SeverityMedium},
+	{ErrDeniedExtension, "extension", SeverityMedium},
+	{ErrFilenameTooLong, "filename", SeverityLow},
+	{ErrInvalidFilename, "filename", SeverityLow},
+	{ErrNoUniqueName, "filename", SeverityLow},
+	{ErrParentMissing, "parent", SeverityLow},
+	{ErrParentNotWritable, "parent", SeverityLow},
+	{ErrPredictablePath, "predictable_path", SeverityMedium},
+	{ErrInsecureTempDir, "insecure_temp_dir", SeverityHigh},
+}
+
+// RejectionCategory returns a short label such as "traversal", "encoding"
+// or "sensitive" for the sentinel err matches, suitable as a metric label,
+// and "invalid" for any other error
+func RejectionCategory(err error) string {
+	for _, c := range rejectionCategories {
+		if errors.Is(err, c.err) {
+			return c.label
+		}
+	}
+	return "invalid"
+}
@@ -0,0 +1,164 @@
+// Package metrics provides a pathsecurity.MetricsCollector that keeps
+// counters and a latency histogram in memory and serves them in the
+// Prometheus text exposition format, so operators can scrape and alert on
+// spikes in traversal attempts without adding a client library dependency.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency histogram
+// buckets used unless WithBuckets is given
+var DefaultBuckets = []float64{
+	0.000001, 0.0000025, 0.000005, 0.00001, 0.000025, 0.00005,
+	0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01,
+}
+
+// Option configures a Registry
+type Option func(*Registry)
+
+// WithBuckets sets the upper bounds, in seconds, of the latency histogram
+// buckets. They are sorted; a +Inf bucket is always added.
+func WithBuckets(seconds ...float64) Option {
+	return func(r *Registry) {
+		r.buckets = append([]float64(nil), seconds...)
+		sort.Float64s(r.buckets)
+	}
+}
+
+// Registry collects Path Security metrics. It implements
+// pathsecurity.MetricsCollector, pathsecurity.MonitorCollector and
+// http.Handler and is safe for concurrent use.
+type Registry struct {
+	valid    atomic.Uint64
+	rejected atomic.Uint64
+
+	buckets     []float64
+	counts      []atomic.Uint64
+	durationSum atomic.Int64 // nanoseconds
+
+	mu            sync.Mutex
+	rejections    map[string]uint64
+	monitored     map[string]uint64
+	sanitizations map[string]uint64
+}
+
+// New returns an empty Registry, to be passed to pathsecurity.WithMetrics
+func New(opts ...Option) *Registry {
+	r := &Registry{
+		buckets:       DefaultBuckets,
+		rejections:    make(map[string]uint64),
+		monitored:     make(map[string]uint64),
+		sanitizations: make(map[string]uint64),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.counts = make([]atomic.Uint64, len(r.buckets)+1)
+	return r
+}
+
+// ObserveValidation implements pathsecurity.MetricsCollector
+func (r *Registry) ObserveValidation(duration time.Duration, valid bool) {
+	if valid {
+		r.valid.Add(1)
+	} else {
+		r.rejected.Add(1)
+	}
+	r.durationSum.Add(int64(duration))
+	r.counts[sort.SearchFloat64s(r.buckets, duration.Seconds())].Add(1)
+}
+
+// ObserveRejection implements pathsecurity.MetricsCollector
+func (r *Registry) ObserveRejection(category string) {
+	r.mu.Lock()
+	r.rejections[category]++
+	r.mu.Unlock()
+}
+
+// ObserveMonitored implements pathsecurity.MonitorCollector
+func (r *Registry) ObserveMonitored(category string) {
+	r.mu.Lock()
+	r.monitored[category]++
+	r.mu.Unlock()
+}
+
+// ObserveSanitization implements pathsecurity.MetricsCollector
+func (r *Registry) ObserveSanitization(rule string) {
+	r.mu.Lock()
+	r.sanitizations[rule]++
+	r.mu.Unlock()
+}
+
+// WriteTo writes every metric to w in the Prometheus text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString("# HELP pathsecurity_validations_total Paths run through the validator.\n")
+	buf.WriteString("# TYPE pathsecurity_validations_total counter\n")
+	fmt.Fprintf(&buf, "pathsecurity_validations_total{result=\"valid\"} %d\n", r.valid.Load())
+	fmt.Fprintf(&buf, "pathsecurity_validations_total{result=\"rejected\"} %d\n", r.rejected.Load())
+
+	r.mu.Lock()
+	writeCounters(&buf, "pathsecurity_rejections_total", "Rejected paths by category.", "category", r.rejections)
+	writeCounters(&buf, "pathsecurity_monitored_total", "Paths let through below the fail threshold, by category.", "category", r.monitored)
+	writeCounters(&buf, "pathsecurity_sanitizations_total", "Inputs changed by a sanitizer, by rule.", "rule", r.sanitizations)
+	r.mu.Unlock()
+
+	buf.WriteString("# HELP pathsecurity_validation_duration_seconds Time spent validating a path.\n")
+	buf.WriteString("# TYPE pathsecurity_validation_duration_seconds histogram\n")
+	var cumulative uint64
+	for i := range r.counts {
+		cumulative += r.counts[i].Load()
+		le := "+Inf"
+		if i < len(r.buckets) {
+			le = strconv.FormatFloat(r.buckets[i], 'g', -1, 64)
+		}
+		fmt.Fprintf(&buf, "pathsecurity_validation_duration_seconds_bucket{le=%q} %d\n", le, cumulative)
+	}
+	fmt.Fprintf(&buf, "pathsecurity_validation_duration_seconds_sum %s\n", strconv.FormatFloat(time.Duration(r.durationSum.Load()).Seconds(), 'g', -1, 64))
+	fmt.Fprintf(&buf, "pathsecurity_validation_duration_seconds_count %d\n", cumulative)
+
+	health := pathsecurity.Health()
+	writeCounters(&buf, "pathsecurity_native_incidents_total", "Native library calls that timed out or panicked.", "kind", map[string]uint64{"timeout": health.Timeouts, "panic": health.Panics})
+	buf.WriteString("# HELP pathsecurity_native_stuck_calls Timed-out native library calls still running.\n")
+	buf.WriteString("# TYPE pathsecurity_native_stuck_calls gauge\n")
+	fmt.Fprintf(&buf, "pathsecurity_native_stuck_calls %d\n", health.Stuck)
+
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP serves the metrics for a Prometheus scrape
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// labelEscaper escapes label values as the exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeCounters writes a counter family with one labelled series per key,
+// in sorted order
+func writeCounters(buf *bytes.Buffer, name, help, label string, values map[string]uint64) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(buf, "%s{%s=\"%s\"} %d\n", name, label, labelEscaper.Replace(key), values[key])
+	}
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := New()
+	ps := pathsecurity.NewPathSecurity(pathsecurity.WithMetrics(registry))
+	ps.ValidatePath("docs/readme.md")
+	ps.ValidatePath("../../etc/shadow")
+	ps.ValidatePath("files/../../secret")
+	ps.SanitizePath("uploads/../../secret.txt")
+	ps.ValidatePaths([]string{"a.txt", "b/../../c"})
+
+	var exposition strings.Builder
+	if _, err := registry.WriteTo(&exposition); err != nil {
+		t.Fatalf("WriteTo error = %v", err)
+	}
+	for _, want := range []string{
+		`pathsecurity_validations_total{result="valid"} 2`,
+		`pathsecurity_validations_total{result="rejected"} 3`,
+		`pathsecurity_rejections_total{category="traversal"} 3`,
+		`pathsecurity_sanitizations_total{rule="strip"} 1`,
+		`pathsecurity_validation_duration_seconds_bucket{le="+Inf"} 5`,
+		`pathsecurity_validation_duration_seconds_count 5`,
+		"# TYPE pathsecurity_native_stuck_calls gauge",
+	} {
+		if !strings.Contains(exposition.String(), want+"\n") {
+			t.Errorf("exposition is missing %q:\n%s", want, exposition.String())
+		}
+	}
+}
+
+func TestWithBuckets(t *testing.T) {
+	registry := New(WithBuckets(0.01, 0.001))
+	registry.ObserveValidation(500*time.Microsecond, true)
+	registry.ObserveValidation(5*time.Millisecond, false)
+	registry.ObserveValidation(time.Second, false)
+	registry.ObserveMonitored("hidden\"file")
+
+	var exposition strings.Builder
+	registry.WriteTo(&exposition)
+	for _, want := range []string{
+		`pathsecurity_validation_duration_seconds_bucket{le="0.001"} 1`,
+		`pathsecurity_validation_duration_seconds_bucket{le="0.01"} 2`,
+		`pathsecurity_validation_duration_seconds_bucket{le="+Inf"} 3`,
+		`pathsecurity_validation_duration_seconds_sum 1.0055`,
+		`pathsecurity_monitored_total{category="hidden\"file"} 1`,
+	} {
+		if !strings.Contains(exposition.String(), want+"\n") {
+			t.Errorf("exposition is missing %q:\n%s", want, exposition.String())
+		}
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	New().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got := recorder.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if !strings.Contains(recorder.Body.String(), `pathsecurity_validations_total{result="valid"} 0`) {
+		t.Errorf("body = %q", recorder.Body.String())
+	}
+}
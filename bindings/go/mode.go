@@ -0,0 +1,64 @@
+package pathsecurity
+
+import "fmt"
+
+// Mode selects whether a check may consult the file system
+type Mode int
+
+const (
+	// Lexical judges a path by its text and the configuration alone. It
+	// makes no system calls, does not call the lookups set with
+	// WithHomeDirs and WithExpandEnv, and returns the same result for the
+	// same path and configuration on every machine, so it is safe on hot
+	// paths fed by untrusted input. It cannot tell whether the path exists
+	// or where symlinks in it lead: a "~" that TildeExpand would replace is
+	// rejected with ErrHomeDirectory, environment references are left to
+	// the validator, which rejects them, and the special file check of
+	// WithSensitivePaths is skipped. Observers such as WithAuditFunc and
+	// WithMetrics are still called.
+	Lexical Mode = iota
+	// Resolved runs the Lexical checks with lookups enabled and then
+	// resolves the path against the file system as ValidateResolved does,
+	// following symlinks, and requires the target to lie inside the roots
+	// set with WithAllowedRoots and pass the checks set with
+	// WithTargetChecks and WithTargetOwner. Its result holds only at the
+	// time of the call; open files with OpenInRoot to rule out races with
+	// changes to the tree. WithRealpathCache saves the system calls of
+	// resolving the same trees again.
+	Resolved
+)
+
+// String returns the name of the mode
+func (m Mode) String() string {
+	switch m {
+	case Lexical:
+		return "lexical"
+	case Resolved:
+		return "resolved"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// CheckPath validates path in the given mode and returns the form of it to
+// use: the preprocessed path in Lexical mode and the resolved path in
+// Resolved mode
+func (ps *PathSecurity) CheckPath(path string, mode Mode) (string, error) {
+	switch mode {
+	case Lexical:
+		return ps.lexical().prepare(path)
+	case Resolved:
+		return ps.ValidateResolved(path)
+	}
+	return "", fmt.Errorf("invalid mode %v", mode)
+}
+
+// lexical returns a copy of ps that makes no system calls and calls no
+// lookups. The copy keeps the configuration ID, as the checks it skips are
+// never cached.
+func (ps *PathSecurity) lexical() *PathSecurity {
+	lexical := &PathSecurity{cfg: ps.cfg}
+	lexical.cfg.homeDirs = nil
+	lexical.cfg.expandEnv = nil
+	lexical.cfg.lexicalOnly = true
+	return lexical
+}
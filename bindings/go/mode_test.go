@@ -0,0 +1,46 @@
+package pathsecurity
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckPath(t *testing.T) {
+	root := testTree(t)
+	home, err := filepath.Abs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path         string
+		wantLexical  error
+		wantResolved error
+	}{
+		{filepath.Join(home, "inbox", "new.txt"), nil, nil},
+		{"~/inbox/new.txt", ErrHomeDirectory, nil},
+		{filepath.Join(home, "inbox", "escape", "loot.txt"), nil, ErrSymlinkEscape},
+		{"../etc/passwd", ErrTraversalDetected, ErrTraversalDetected},
+	}
+	ps := NewPathSecurity(
+		WithAllowedRoots(root),
+		WithTildePolicy(TildeExpand),
+		WithHomeDirs(func(string) (string, bool) { return home, true }),
+	)
+	for _, tt := range tests {
+		if _, err := ps.CheckPath(tt.path, Lexical); !errors.Is(err, tt.wantLexical) {
+			t.Errorf("CheckPath(%q, Lexical) = %v, want %v", tt.path, err, tt.wantLexical)
+		}
+		if _, err := ps.CheckPath(tt.path, Resolved); !errors.Is(err, tt.wantResolved) {
+			t.Errorf("CheckPath(%q, Resolved) = %v, want %v", tt.path, err, tt.wantResolved)
+		}
+	}
+}
+
+func TestModeString(t *testing.T) {
+	for mode, want := range map[Mode]string{Lexical: "lexical", Resolved: "resolved", Mode(4): "Mode(4)"} {
+		if got := mode.String(); got != want {
+			t.Errorf("Mode(%d).String() = %q, want %q", int(mode), got, want)
+		}
+	}
+}
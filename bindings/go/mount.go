@@ -0,0 +1,119 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSymlinks bounds the symlinks followed while resolving one path, as
+// the kernel's own limit does
+const maxSymlinks = 40
+
+// linkEnd marks the end of the segments a symlink target expanded into.
+// Validated paths never contain a NUL byte, so it cannot be a real segment.
+const linkEnd = "\x00"
+
+// resolveBeneath resolves the symlinks of an absolute path one component at
+// a time, treating the boundary set with WithMountBoundary as the root of
+// the file system: ".." and relative symlinks may not climb above it,
+// absolute symlink targets outside it are taken relative to it, and no
+// component may be a mount point below it. Components that do not exist yet are resolved
+// lexically, but a symlink pointing at one is rejected as dangling.
+func (ps *PathSecurity) resolveBeneath(path string) (string, error) {
+	boundary, err := filepath.Abs(ps.cfg.mountBoundary)
+	if err != nil {
+		return "", err
+	}
+	realBoundary, err := filepath.EvalSymlinks(boundary)
+	if err != nil {
+		return "", err
+	}
+
+	var rel string
+	switch {
+	case withinRoot(realBoundary, path):
+		rel = relativeTo(realBoundary, path)
+	case withinRoot(boundary, path):
+		rel = relativeTo(boundary, path)
+	default:
+		return "", ps.reject(path, fmt.Errorf("%w: %q is outside %q", ErrMountBoundary, path, boundary))
+	}
+
+	mounts, err := mountPoints()
+	if err != nil {
+		return "", err
+	}
+	crossed := make(map[string]bool)
+	for _, mount := range mounts {
+		if mount != realBoundary && withinRoot(realBoundary, mount) {
+			crossed[mount] = true
+		}
+	}
+
+	current := realBoundary
+	queue := strings.Split(rel, "/")
+	links, openLinks := 0, 0
+	missing := false
+	for len(queue) > 0 {
+		segment := queue[0]
+		queue = queue[1:]
+		switch segment {
+		case linkEnd:
+			openLinks--
+			continue
+		case "", ".":
+			continue
+		case "..":
+			if current == realBoundary {
+				return "", ps.reject(path, fmt.Errorf("%w: %q climbs above %q", ErrMountBoundary, path, boundary))
+			}
+			current = filepath.Dir(current)
+			continue
+		}
+
+		next := filepath.Join(current, segment)
+		if missing {
+			current = next
+			continue
+		}
+		info, err := os.Lstat(next)
+		if os.IsNotExist(err) {
+			if openLinks > 0 {
+				return "", ps.reject(path, fmt.Errorf("%w: dangling symlink in %q", ErrSymlinkEscape, path))
+			}
+			missing = true
+			current = next
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if crossed[next] {
+			return "", ps.reject(path, fmt.Errorf("%w: %q crosses the mount point %q", ErrMountBoundary, path, next))
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		if links++; links > maxSymlinks {
+			return "", ps.reject(path, fmt.Errorf("%w: too many symlinks in %q", ErrSymlinkEscape, path))
+		}
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			current = realBoundary
+			if target = filepath.Clean(target); withinRoot(realBoundary, target) {
+				target = relativeTo(realBoundary, target)
+			}
+		}
+		expanded := strings.Split(filepath.ToSlash(target), "/")
+		queue = append(append(expanded, linkEnd), queue...)
+		openLinks++
+	}
+	return current, nil
+}
@@ -0,0 +1,51 @@
+//go:build linux
+
+package pathsecurity
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// mountPoints lists the mount points of the process's mount namespace
+// from /proc/self/mountinfo, which includes bind mounts
+func mountPoints() ([]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// The mount point is the fifth field
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 5 {
+			mounts = append(mounts, unescapeMountinfo(fields[4]))
+		}
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountinfo decodes the octal escapes, such as "\040" for a
+// space, that mountinfo uses in paths
+func unescapeMountinfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
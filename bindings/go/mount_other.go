@@ -0,0 +1,9 @@
+//go:build !linux
+
+package pathsecurity
+
+// mountPoints lists no mount points on systems without
+// /proc/self/mountinfo, where WithMountBoundary only confines resolution
+func mountPoints() ([]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,57 @@
+package pathsecurity
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithMountBoundary(t *testing.T) {
+	container, err := filepath.Abs(filepath.Join(testTree(t), "container"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(container, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(container, "etc", "hostname"), []byte("container\n"), 0o644)
+	os.Mkdir(filepath.Join(container, "app"), 0o755)
+	for name, target := range map[string]string{
+		"absolute": "/etc/hostname",
+		"climb":    "../../../../../etc/hostname",
+		"procroot": "/proc/self/root/etc",
+	} {
+		if err := os.Symlink(target, filepath.Join(container, "app", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		want   string
+		reject bool
+	}{
+		{name: "app/absolute", want: "etc/hostname"},
+		{name: "app/new.txt", want: "app/new.txt"},
+		{name: "app/climb", reject: true},
+		{name: "app/procroot/hostname", reject: true},
+	}
+	ps := NewPathSecurity(WithAllowedRoots(container), WithMountBoundary(container))
+	for _, tt := range tests {
+		resolved, err := ps.ValidateResolved(filepath.Join(container, tt.name))
+		if tt.reject {
+			if !errors.Is(err, ErrMountBoundary) && !errors.Is(err, ErrSymlinkEscape) {
+				t.Errorf("ValidateResolved(%q) = %q, %v, want a boundary or symlink rejection", tt.name, resolved, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ValidateResolved(%q) error = %v", tt.name, err)
+			continue
+		}
+		if rel, _ := filepath.Rel(container, resolved); rel != filepath.FromSlash(tt.want) {
+			t.Errorf("ValidateResolved(%q) = %q, want %q beneath the boundary", tt.name, resolved, tt.want)
+		}
+	}
+}
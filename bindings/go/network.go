@@ -0,0 +1,218 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NetworkPathKind is the notation a network path is written in
+type NetworkPathKind int
+
+const (
+	// NetworkUNC is a UNC path such as "\\host\share\a" or "//host/share/a",
+	// including the verbatim form "\\?\UNC\host\share\a"
+	NetworkUNC NetworkPathKind = iota
+	// NetworkSMB is an SMB URL such as "smb://host/share/a" or
+	// "cifs://host/share/a"
+	NetworkSMB
+	// NetworkNFS is an NFS export in host:/path notation, as in
+	// "fileserver:/export/a", or an "nfs://host/export/a" URL
+	NetworkNFS
+)
+
+// String returns the name of the kind
+func (k NetworkPathKind) String() string {
+	switch k {
+	case NetworkUNC:
+		return "unc"
+	case NetworkSMB:
+		return "smb"
+	case NetworkNFS:
+		return "nfs"
+	}
+	return fmt.Sprintf("NetworkPathKind(%d)", int(k))
+}
+
+// NetworkPath is a path that names a file on another host
+type NetworkPath struct {
+	Kind NetworkPathKind
+	// Host is the server as written, without user info, port or the
+	// brackets of an IPv6 address
+	Host string
+	// Share is the UNC or SMB share, and empty for NFS
+	Share string
+	// Path is the rest of the path with '/' separators, starting with '/'
+	// unless it is empty
+	Path string
+}
+
+// local returns the path on the server that validation applies to
+func (n NetworkPath) local() string {
+	local := n.Path
+	if n.Share != "" {
+		local = "/" + n.Share + n.Path
+	}
+	if local == "" {
+		return "/"
+	}
+	return local
+}
+
+// ParseNetworkPath reports whether path names a file on another host in
+// UNC, SMB URL or NFS notation, and splits it into host, share and path.
+// Both '/' and '\' separate UNC components, as they do on Windows. Device
+// paths such as "\\.\COM1" and verbatim local paths are not network paths,
+// and neither are drive letters, which have a one-letter host in NFS
+// notation, or URLs with other schemes.
+func ParseNetworkPath(path string) (NetworkPath, bool) {
+	for _, scheme := range []struct {
+		prefix string
+		kind   NetworkPathKind
+	}{{"smb://", NetworkSMB}, {"cifs://", NetworkSMB}, {"nfs://", NetworkNFS}} {
+		if len(path) >= len(scheme.prefix) && strings.EqualFold(path[:len(scheme.prefix)], scheme.prefix) {
+			return parseNetworkURL(scheme.kind, strings.ReplaceAll(path[len(scheme.prefix):], `\`, "/"))
+		}
+	}
+
+	p := strings.ReplaceAll(path, `\`, "/")
+	switch {
+	case len(p) >= 8 && strings.EqualFold(p[:8], "//?/UNC/"):
+		return parseUNC(p[8:])
+	case strings.HasPrefix(p, "//?/"), strings.HasPrefix(p, "//./"):
+		return NetworkPath{}, false
+	case strings.HasPrefix(p, "//"):
+		return parseUNC(p[2:])
+	}
+
+	// "scheme://" is a URL, not an export of a host named like the scheme
+	if i := strings.Index(path, ":/"); i >= 2 && !strings.HasPrefix(path[i+1:], "//") {
+		if host, ok := networkHost(path[:i]); ok {
+			return NetworkPath{Kind: NetworkNFS, Host: host, Path: path[i+1:]}, true
+		}
+	}
+	return NetworkPath{}, false
+}
+
+// parseUNC splits the host, share and path of a UNC path after its leading
+// separators
+func parseUNC(rest string) (NetworkPath, bool) {
+	host, rest, _ := strings.Cut(rest, "/")
+	if host == "" {
+		return NetworkPath{}, false
+	}
+	share, rest, found := strings.Cut(rest, "/")
+	n := NetworkPath{Kind: NetworkUNC, Host: host, Share: share}
+	if found {
+		n.Path = "/" + rest
+	}
+	return n, true
+}
+
+// parseNetworkURL splits an SMB or NFS URL after its scheme
+func parseNetworkURL(kind NetworkPathKind, rest string) (NetworkPath, bool) {
+	authority, rest, found := strings.Cut(rest, "/")
+	if i := strings.LastIndexByte(authority, '@'); i >= 0 {
+		authority = authority[i+1:]
+	}
+	if strings.HasPrefix(authority, "[") {
+		if end := strings.IndexByte(authority, ']'); end >= 0 {
+			authority = authority[:end+1]
+		}
+	} else if i := strings.IndexByte(authority, ':'); i >= 0 {
+		authority = authority[:i]
+	}
+	host, ok := networkHost(authority)
+	if !ok {
+		return NetworkPath{}, false
+	}
+
+	n := NetworkPath{Kind: kind, Host: host}
+	if kind == NetworkSMB {
+		n.Share, rest, found = strings.Cut(rest, "/")
+	}
+	if found {
+		n.Path = "/" + rest
+	}
+	return n, true
+}
+
+// networkHost validates a host name, IPv4 address or bracketed IPv6
+// address and returns it without brackets
+func networkHost(host string) (string, bool) {
+	if len(host) > 2 && host[0] == '[' && host[len(host)-1] == ']' {
+		inner := host[1 : len(host)-1]
+		for i := 0; i < len(inner); i++ {
+			if !isHexDigit(inner[i]) && inner[i] != ':' && inner[i] != '.' {
+				return "", false
+			}
+		}
+		return inner, true
+	}
+	if len(host) < 2 {
+		return "", false
+	}
+	for i := 0; i < len(host); i++ {
+		c := host[i]
+		if !isASCIILetter(c) && (c < '0' || c > '9') && c != '-' && c != '.' && c != '_' {
+			return "", false
+		}
+	}
+	return host, true
+}
+
+// NetworkPolicy selects how UNC paths, SMB URLs and NFS exports are treated
+type NetworkPolicy int
+
+const (
+	// NetworkKeep leaves network paths to the validator, which rejects UNC
+	// prefixes and colons outside a drive letter without classifying them
+	NetworkKeep NetworkPolicy = iota
+	// NetworkReject rejects every network path with an error wrapping
+	// ErrNetworkPath that names its kind and host
+	NetworkReject
+	// NetworkAllow accepts network paths to any host. The share and path
+	// on the server are validated as a rooted local path, so traversal and
+	// every other check still apply to them.
+	NetworkAllow
+	// NetworkAllowHosts is NetworkAllow restricted to the hosts set with
+	// WithNetworkHosts; paths to other hosts are rejected with
+	// ErrNetworkPath
+	NetworkAllowHosts
+)
+
+// String returns the name of the policy
+func (p NetworkPolicy) String() string {
+	switch p {
+	case NetworkKeep:
+		return "keep"
+	case NetworkReject:
+		return "reject"
+	case NetworkAllow:
+		return "allow"
+	case NetworkAllowHosts:
+		return "allow-hosts"
+	}
+	return fmt.Sprintf("NetworkPolicy(%d)", int(p))
+}
+
+// checkNetworkPath applies the policy set with WithNetworkPolicy and
+// returns the form of path the remaining checks apply to: the path on the
+// server for an allowed network path and path itself otherwise
+func (c *config) checkNetworkPath(path string) (string, *ValidationError) {
+	if c.networkPolicy == NetworkKeep {
+		return path, nil
+	}
+	network, ok := ParseNetworkPath(path)
+	if !ok {
+		return path, nil
+	}
+	switch {
+	case c.networkPolicy == NetworkAllow:
+		return network.local(), nil
+	case c.networkPolicy == NetworkAllowHosts && c.networkHosts[strings.ToLower(network.Host)]:
+		return network.local(), nil
+	case c.networkPolicy == NetworkAllowHosts:
+		return "", &ValidationError{Path: path, Reason: fmt.Sprintf("Network path (%s) to host %q outside the allowed hosts", network.Kind, network.Host), Err: ErrNetworkPath}
+	}
+	return "", &ValidationError{Path: path, Reason: fmt.Sprintf("Network path (%s) to host %q", network.Kind, network.Host), Err: ErrNetworkPath}
+}
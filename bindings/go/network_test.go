@@ -0,0 +1,53 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseNetworkPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		want   NetworkPath
+		wantOK bool
+	}{
+		{`\\fileserver\public\report.txt`, NetworkPath{NetworkUNC, "fileserver", "public", "/report.txt"}, true},
+		{"//fileserver/public/../admin$/secret", NetworkPath{NetworkUNC, "fileserver", "public", "/../admin$/secret"}, true},
+		{`\\?\UNC\evil.example\share\x`, NetworkPath{NetworkUNC, "evil.example", "share", "/x"}, true},
+		{"smb://guest@evil.example:445/share/payload.exe", NetworkPath{NetworkSMB, "evil.example", "share", "/payload.exe"}, true},
+		{"fileserver:/export/home/report.txt", NetworkPath{NetworkNFS, "fileserver", "", "/export/home/report.txt"}, true},
+		{"nfs://[fd00::1]/export/data", NetworkPath{NetworkNFS, "fd00::1", "", "/export/data"}, true},
+		{"C:/data/report.txt", NetworkPath{}, false},
+		{"docs/report.txt", NetworkPath{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseNetworkPath(tt.path)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("ParseNetworkPath(%q) = %+v, %t, want %+v, %t", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestWithNetworkPolicy(t *testing.T) {
+	tests := []struct {
+		path          string
+		wantReject    error
+		wantAllowHost error
+	}{
+		{`\\fileserver\public\report.txt`, ErrNetworkPath, nil},
+		{"//fileserver/public/../admin$/secret", ErrNetworkPath, ErrTraversalDetected},
+		{"smb://evil.example/share/payload.exe", ErrNetworkPath, ErrNetworkPath},
+		{"fileserver:/export/home/report.txt", ErrNetworkPath, nil},
+	}
+	posix := NewPathSecurity(WithWindowsSemantics(false), WithNetworkHosts("FileServer"))
+	reject := posix.Clone(WithNetworkPolicy(NetworkReject))
+	allowHosts := posix.Clone(WithNetworkPolicy(NetworkAllowHosts))
+	for _, tt := range tests {
+		if _, err := reject.ValidatePath(tt.path); !errors.Is(err, tt.wantReject) {
+			t.Errorf("reject: ValidatePath(%q) error = %v, want %v", tt.path, err, tt.wantReject)
+		}
+		if _, err := allowHosts.ValidatePath(tt.path); !errors.Is(err, tt.wantAllowHost) {
+			t.Errorf("allow-hosts: ValidatePath(%q) error = %v, want %v", tt.path, err, tt.wantAllowHost)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxObjectKey is the longest key, in bytes of UTF-8, that S3 and GCS
+	// accept
+	maxObjectKey = 1024
+	// objectKeyAvoid are the characters S3 and GCS advise against in keys,
+	// as they need encoding in URLs, are wildcards in gsutil or are
+	// handled inconsistently by clients
+	objectKeyAvoid = "\\{}^%`[]\"<>~#|*?"
+)
+
+// ValidateObjectKey validates a key of an S3 or GCS object. Keys are not
+// file paths: '\' has no meaning, there is no root and no working
+// directory, and most characters are allowed. The rules are those of both
+// stores instead. A key must be valid UTF-8 of 1 to 1024 bytes without
+// control characters or the characters S3 and GCS advise against,
+// \{}^%`[]"<>~#|*? among them, and may not start with
+// ".well-known/acme-challenge/", which GCS reserves. Keys are split at
+// '/' like paths, since tools that sync buckets to disk do the same, so
+// "." and ".." segments are rejected as traversal, and empty segments,
+// including a leading '/', as ambiguous; a trailing '/', which marks a
+// folder, is allowed. Deceptive Unicode is rejected as in ValidatePath.
+// Finally WithMaxPathLength, WithMaxDepth and WithDeniedPatterns apply.
+func (ps *PathSecurity) ValidateObjectKey(key string) error {
+	if verr := checkObjectKey(key); verr != nil {
+		return ps.reject(key, verr)
+	}
+	if err := ps.cfg.checkPolicy(key); err != nil {
+		return ps.reject(key, err)
+	}
+	return nil
+}
+
+// checkObjectKey applies the rules of ValidateObjectKey other than the
+// configured policy
+func checkObjectKey(key string) *ValidationError {
+	switch {
+	case key == "":
+		return &ValidationError{Path: key, Reason: "Empty object key", Err: ErrObjectKey}
+	case len(key) > maxObjectKey:
+		return &ValidationError{Path: key, Reason: fmt.Sprintf("Object key exceeds %d bytes", maxObjectKey), Err: ErrPathTooLong}
+	case !utf8.ValidString(key):
+		return &ValidationError{Path: key, Reason: "Object key is not valid UTF-8", Err: ErrInvalidEncoding}
+	case strings.HasPrefix(key, ".well-known/acme-challenge/"):
+		return &ValidationError{Path: key, Reason: "Object key in the reserved .well-known/acme-challenge/ prefix", Err: ErrObjectKey}
+	}
+
+	for i, r := range key {
+		if unicode.IsControl(r) {
+			return &ValidationError{Path: key, Reason: fmt.Sprintf("Control character %U at offset %d in object key", r, i), Err: ErrControlCharacter}
+		}
+		if strings.ContainsRune(objectKeyAvoid, r) {
+			return &ValidationError{Path: key, Reason: fmt.Sprintf("Character %q at offset %d in object key", r, i), Err: ErrObjectKey}
+		}
+	}
+	if verr := checkUnicodeTricks(key); verr != nil {
+		return verr
+	}
+
+	segments := strings.Split(strings.TrimSuffix(key, "/"), "/")
+	for _, segment := range segments {
+		switch segment {
+		case ".", "..":
+			return &ValidationError{Path: key, Reason: fmt.Sprintf("%q segment in object key", segment), Err: ErrTraversalDetected}
+		case "":
+			return &ValidationError{Path: key, Reason: "Empty segment in object key", Err: ErrObjectKey}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package pathsecurity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateObjectKey(t *testing.T) {
+	tests := []struct {
+		key          string
+		wantCategory string
+	}{
+		{key: "uploads/2024/report.pdf"},
+		{key: "photos/"},
+		{key: "C:/data/file.txt"},
+		{key: "caf\u00e9/men\u00fc.txt"},
+		{key: "/leading/slash", wantCategory: "object_key"},
+		{key: "a//b", wantCategory: "object_key"},
+		{key: "uploads/../secrets", wantCategory: "traversal"},
+		{key: "./config", wantCategory: "traversal"},
+		{key: `dir\file`, wantCategory: "object_key"},
+		{key: "report#1.pdf", wantCategory: "object_key"},
+		{key: "tab\there", wantCategory: "control_character"},
+		{key: ".well-known/acme-challenge/token", wantCategory: "object_key"},
+		{key: strings.Repeat("k", 1025), wantCategory: "too_long"},
+	}
+	ps := NewPathSecurity()
+	for _, tt := range tests {
+		err := ps.ValidateObjectKey(tt.key)
+		if tt.wantCategory == "" {
+			if err != nil {
+				t.Errorf("ValidateObjectKey(%.40q) = %v", tt.key, err)
+			}
+			continue
+		}
+		if got := RejectionCategory(err); err == nil || got != tt.wantCategory {
+			t.Errorf("ValidateObjectKey(%.40q) = %v (%s), want a %s rejection", tt.key, err, got, tt.wantCategory)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+//go:build linux
+
+package pathsecurity
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxOpenat2Retries bounds the retries of openat2 when the kernel gives up
+// on safe resolution because of concurrent renames, so that an attacker
+// renaming directories in a loop cannot keep a call spinning
+const maxOpenat2Retries = 16
+
+// openat2Missing records that the kernel lacks openat2, so later calls go
+// straight to the component walk
+var openat2Missing atomic.Bool
+
+// openInRoot opens rel, a cleaned slash-separated path without ".."
+// segments, beneath root
+func (ps *PathSecurity) openInRoot(root, rel string, flag int, perm fs.FileMode) (*os.File, Confinement, error) {
+	dir, err := syscall.Open(root, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, ConfinementOpenat2, &fs.PathError{Op: "open", Path: root, Err: err}
+	}
+	defer syscall.Close(dir)
+
+	name := filepath.Join(root, filepath.FromSlash(rel))
+	if !openat2Missing.Load() {
+		how := unix.OpenHow{Flags: uint64(flag | unix.O_CLOEXEC), Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS}
+		if ps.cfg.mountBoundary != "" {
+			how.Resolve |= unix.RESOLVE_NO_XDEV
+		}
+		if flag&os.O_CREATE != 0 {
+			// The kernel rejects a mode without O_CREAT
+			how.Mode = uint64(perm.Perm())
+		}
+		fd, err := openat2(dir, rel, &how)
+		switch err {
+		case nil:
+			return os.NewFile(uintptr(fd), name), ConfinementOpenat2, nil
+		case unix.EXDEV:
+			return nil, ConfinementOpenat2, errEscapesRoot
+		case unix.ENOSYS:
+			openat2Missing.Store(true)
+		case unix.EAGAIN:
+			// Still racing renames after every retry; the walk below
+			// refuses symlinks without depending on the kernel's lookup
+		default:
+			return nil, ConfinementOpenat2, &fs.PathError{Op: "openat2", Path: name, Err: err}
+		}
+	}
+	f, err := openWalk(dir, root, rel, flag, perm)
+	return f, ConfinementOpenatWalk, err
+}
+
+// openat2 calls openat2(2), retrying when interrupted and, up to
+// maxOpenat2Retries times, when a concurrent rename made the kernel give up
+// on safe resolution
+func openat2(dir int, rel string, how *unix.OpenHow) (int, error) {
+	for retries := 0; ; {
+		fd, err := unix.Openat2(dir, rel, how)
+		switch {
+		case err == unix.EINTR:
+			continue
+		case err == unix.EAGAIN && retries < maxOpenat2Retries:
+			retries++
+			continue
+		}
+		return fd, err
+	}
+}
+
+// openWalk opens rel beneath dir one component at a time with O_NOFOLLOW,
+// so no symlink is followed anywhere along the way
+func openWalk(dir int, root, rel string, flag int, perm fs.FileMode) (*os.File, error) {
+	name := filepath.Join(root, filepath.FromSlash(rel))
+	segments := strings.Split(rel, "/")
+	current := dir
+	for i, segment := range segments[:len(segments)-1] {
+		next, err := syscall.Openat(current, segment, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+		if err == syscall.ENOTDIR {
+			// A symlink fails O_DIRECTORY before O_NOFOLLOW. Looking it up
+			// by name only picks the error, the open has already failed.
+			if info, lerr := os.Lstat(filepath.Join(root, filepath.Join(segments[:i+1]...))); lerr == nil && info.Mode()&fs.ModeSymlink != 0 {
+				err = syscall.ELOOP
+			}
+		}
+		if current != dir {
+			syscall.Close(current)
+		}
+		if err != nil {
+			return nil, walkError(name, err)
+		}
+		current = next
+	}
+
+	fd, err := syscall.Openat(current, segments[len(segments)-1], flag|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, uint32(perm.Perm()))
+	if current != dir {
+		syscall.Close(current)
+	}
+	if err != nil {
+		return nil, walkError(name, err)
+	}
+	return os.NewFile(uintptr(fd), name), nil
+}
+
+// walkError reports a symlink refused by O_NOFOLLOW as an escape
+func walkError(name string, err error) error {
+	if err == syscall.ELOOP {
+		return errEscapesRoot
+	}
+	return &fs.PathError{Op: "openat", Path: name, Err: err}
+}
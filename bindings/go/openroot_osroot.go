@@ -0,0 +1,47 @@
+//go:build !linux && !js && !plan9 && go1.24
+
+package pathsecurity
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// openInRoot opens rel, a cleaned slash-separated path without ".."
+// segments, beneath root through an os.Root
+func (ps *PathSecurity) openInRoot(root, rel string, flag int, perm fs.FileMode) (*os.File, Confinement, error) {
+	r, err := os.OpenRoot(root)
+	if err != nil {
+		return nil, ConfinementOSRoot, err
+	}
+	defer r.Close()
+
+	f, err := r.OpenFile(filepath.FromSlash(rel), flag, perm)
+	var pathErr *fs.PathError
+	// os.Root does not export the error it returns for names that escape,
+	// so any other failure is told apart by resolving the name again
+	if errors.As(err, &pathErr) && !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrExist) && ps.escapesRoot(root, rel) {
+		return nil, ConfinementOSRoot, errEscapesRoot
+	}
+	return f, ConfinementOSRoot, err
+}
+
+// escapesRoot reports whether rel, resolved beneath root with symlinks
+// followed, lies outside root or runs into a dangling symlink
+func (ps *PathSecurity) escapesRoot(root, rel string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	realRoot, err := filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		realRoot = absRoot
+	}
+	resolved, err := ps.quiet().evalSymlinks(filepath.Join(realRoot, filepath.FromSlash(rel)))
+	if err != nil {
+		return errors.Is(err, ErrSymlinkEscape)
+	}
+	return !withinRoot(realRoot, resolved)
+}
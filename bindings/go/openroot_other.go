@@ -0,0 +1,36 @@
+//go:build !linux && (js || plan9 || !go1.24)
+
+package pathsecurity
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// openInRoot opens rel, a cleaned slash-separated path without ".."
+// segments, beneath root after checking that its symlinks stay inside
+func (ps *PathSecurity) openInRoot(root, rel string, flag int, perm fs.FileMode) (*os.File, Confinement, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, ConfinementLexical, err
+	}
+	realRoot, err := filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		return nil, ConfinementLexical, err
+	}
+
+	// The check must see the tree as it is now, not as cached
+	fresh := &PathSecurity{cfg: ps.cfg}
+	fresh.cfg.realpathCache = nil
+	name := filepath.Join(absRoot, filepath.FromSlash(rel))
+	resolved, err := fresh.evalSymlinks(name)
+	if err != nil {
+		return nil, ConfinementLexical, err
+	}
+	if !ps.cfg.withinRoot(realRoot, resolved) {
+		return nil, ConfinementLexical, errEscapesRoot
+	}
+	f, err := os.OpenFile(resolved, flag, perm)
+	return f, ConfinementLexical, err
+}
@@ -0,0 +1,91 @@
+package pathsecurity
+
+import (
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestOpenInRoot(t *testing.T) {
+	root := testTree(t)
+	ps := NewPathSecurity()
+	created, err := ps.CreateInRoot(root, "inbox/created.txt")
+	if err != nil {
+		t.Fatalf("CreateInRoot = %v", err)
+	}
+	created.WriteString("created")
+	created.Close()
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: "inbox/created.txt", want: "created"},
+		{name: "/inbox/hello.txt", want: "hello"},
+		{name: "inbox/escape/loot.txt", wantErr: ErrSymlinkEscape},
+		{name: "inbox/../../outside.txt", wantErr: ErrTraversalDetected},
+		{name: "inbox/missing.txt", wantErr: os.ErrNotExist},
+	}
+	for _, tt := range tests {
+		f, err := ps.OpenInRoot(root, tt.name)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("OpenInRoot(%q) error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil || string(data) != tt.want {
+			t.Errorf("OpenInRoot(%q) read %q, %v, want %q", tt.name, data, err, tt.want)
+		}
+	}
+
+	if _, err := ps.CreateInRoot(root, "inbox/escape/planted.txt"); !errors.Is(err, ErrSymlinkEscape) {
+		t.Errorf("CreateInRoot through a symlink = %v, want %v", err, ErrSymlinkEscape)
+	}
+}
+
+func TestOpenRooted(t *testing.T) {
+	root := testTree(t)
+	ps := NewPathSecurity()
+	rooted, err := ps.OpenRooted(root, "inbox/hello.txt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenRooted = %v", err)
+	}
+	rooted.Close()
+	if runtime.GOOS == "linux" && !rooted.Confinement.KernelEnforced() {
+		t.Errorf("Confinement on Linux = %s, want a kernel-enforced mechanism", rooted.Confinement)
+	}
+
+	escaped, err := ps.OpenRooted(root, "inbox/escape/loot.txt", os.O_RDONLY, 0)
+	if !errors.Is(err, ErrSymlinkEscape) {
+		t.Errorf("OpenRooted through a symlink = %v, want %v", err, ErrSymlinkEscape)
+	}
+	if escaped.Confinement != rooted.Confinement {
+		t.Errorf("rejected open reported confinement %s, want %s", escaped.Confinement, rooted.Confinement)
+	}
+}
+
+func TestConfinement(t *testing.T) {
+	tests := []struct {
+		c              Confinement
+		want           string
+		kernelEnforced bool
+	}{
+		{ConfinementLexical, "lexical", false},
+		{ConfinementOSRoot, "os-root", true},
+		{ConfinementOpenatWalk, "openat-walk", true},
+		{ConfinementOpenat2, "openat2", true},
+		{Confinement(9), "Confinement(9)", true},
+	}
+	for _, tt := range tests {
+		if got := tt.c.String(); got != tt.want || tt.c.KernelEnforced() != tt.kernelEnforced {
+			t.Errorf("Confinement(%d) = %q, kernel-enforced %t, want %q, %t", int(tt.c), got, tt.c.KernelEnforced(), tt.want, tt.kernelEnforced)
+		}
+	}
+}
@@ -0,0 +1,797 @@
+package pathsecurity
+
+import (
+	"maps"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Option configures a PathSecurity instance
+type Option func(*config)
+
+// config holds the settings applied on top of the native validator
+type config struct {
+	allowedRoots                 []string
+	rootPermissions              map[string]Permission
+	treatEncodedSlashAsSeparator bool
+	exports                      map[string]string
+	caseInsensitive              bool
+	normalizeSeparators          bool
+	nativeSeparators             bool
+	segmentPolicy                SegmentPolicy
+	unicodeFolding               bool
+	warnObserver                 func(path, warning string)
+	expandEnv                    func(name string) (string, bool)
+	tildePolicy                  TildePolicy
+	homeDirs                     func(user string) (string, bool)
+	networkPolicy                NetworkPolicy
+	networkHosts                 map[string]bool
+	dotfilePolicy                DotfilePolicy
+	allowedDotfiles              []string
+	filesystem                   FilesystemProfile
+	shellPolicy                  ShellPolicy
+	servableExtensions           map[string]string
+	fsPathErrors                 bool
+	windowsSemantics             bool
+	rejectShortNames             bool
+	verbatimPaths                bool
+	windowsMaxPath               bool
+	normalization                UnicodeNormalization
+	decoding                     DecodePolicy
+	maxPathLength                int
+	maxInputBytes                int
+	rawBytes                     bool
+	sourceEncoding               SourceEncoding
+	maxDepth                     int
+	maxParentHops                int
+	deniedPatterns               []string
+	rejectAbsolute               bool
+	allowedExtensions            map[string]bool
+	deniedExtensions             map[string]bool
+	maxFilenameLength            int
+	slugFilenames                bool
+	filenamePatterns             []*regexp.Regexp
+	deniedFilenamePatterns       []*regexp.Regexp
+	sanitizeStrategy             SanitizeStrategy
+	sensitivePaths               []string
+	maxGlobWildcards             int
+	auditFunc                    func(Event)
+	metrics                      MetricsCollector
+	cache                        *ValidationCache
+	realpathCache                *RealpathCache
+	nativeTimeout                time.Duration
+	mountBoundary                string
+	targetChecks                 uint8
+	targetOwner                  bool
+	targetUID                    int
+	targetGID                    int
+	stdlibCrossCheck             func(StdlibComparison)
+	stdlibParity                 StdlibParity
+	rules                        []namedRule
+	traversalPatterns            *TraversalPatterns
+	extraTraversalPatterns       *TraversalPatterns
+	failThreshold                Severity
+
+	// lexicalOnly skips the checks that read the file system, as in
+	// Lexical mode
+	lexicalOnly bool
+	// id distinguishes the configuration's entries in a shared cache
+	id uint64
+}
+
+// defaultConfig returns the settings used before any options are applied
+func defaultConfig() config {
+	return config{
+		windowsSemantics: runtime.GOOS == "windows",
+	}
+}
+
+// clone returns a copy of c that shares no slices or maps with it, so
+// options applied to the copy leave c unchanged
+func (c config) clone() config {
+	c.allowedRoots = slices.Clone(c.allowedRoots)
+	c.rootPermissions = maps.Clone(c.rootPermissions)
+	c.exports = maps.Clone(c.exports)
+	c.networkHosts = maps.Clone(c.networkHosts)
+	c.allowedDotfiles = slices.Clone(c.allowedDotfiles)
+	c.servableExtensions = maps.Clone(c.servableExtensions)
+	c.deniedPatterns = slices.Clone(c.deniedPatterns)
+	c.allowedExtensions = maps.Clone(c.allowedExtensions)
+	c.deniedExtensions = maps.Clone(c.deniedExtensions)
+	c.filenamePatterns = slices.Clone(c.filenamePatterns)
+	c.deniedFilenamePatterns = slices.Clone(c.deniedFilenamePatterns)
+	c.sensitivePaths = slices.Clone(c.sensitivePaths)
+	c.rules = slices.Clone(c.rules)
+	return c
+}
+
+// WithAllowedRoots sets the directories that validated paths must fall under
+func WithAllowedRoots(roots ...string) Option {
+	return func(c *config) {
+		for _, root := range roots {
+			c.allowedRoots = append(c.allowedRoots, filepath.Clean(root))
+		}
+	}
+}
+
+// WithRoot adds root to the allowed roots, like WithAllowedRoots, and
+// limits Authorize to the operations in perms for paths under it, such as
+// ReadOnly for static assets or ReadWrite&^PermExecute for an upload
+// directory. Registering the same root again replaces its permissions.
+func WithRoot(root string, perms Permission) Option {
+	return func(c *config) {
+		root = filepath.Clean(root)
+		if !slices.Contains(c.allowedRoots, root) {
+			c.allowedRoots = append(c.allowedRoots, root)
+		}
+		if c.rootPermissions == nil {
+			c.rootPermissions = make(map[string]Permission)
+		}
+		c.rootPermissions[root] = perms
+	}
+}
+
+// WithTreatEncodedSlashAsSeparator makes DetectTraversal treat %2F as a path
+// separator, catching payloads such as "a%2F..%2Fb" aimed at servers that
+// decode slashes before routing. It is off by default.
+func WithTreatEncodedSlashAsSeparator(enabled bool) Option {
+	return func(c *config) {
+		c.treatEncodedSlashAsSeparator = enabled
+	}
+}
+
+// WithExports maps NFS/SMB export names to their backend root directories
+func WithExports(exports map[string]string) Option {
+	return func(c *config) {
+		if c.exports == nil {
+			c.exports = make(map[string]string, len(exports))
+		}
+		for name, root := range exports {
+			c.exports[name] = filepath.Clean(root)
+		}
+	}
+}
+
+// WithCaseInsensitive treats paths as case-insensitive, as on macOS and
+// Windows file systems. Canonicalization applies Unicode case folding, and
+// denied patterns, sensitive paths and allowed roots are matched on folded
+// forms, so "/ETC/Passwd" matches "/etc/passwd" and "/SRV/Data/x" lies
+// under the root "/srv/data".
+func WithCaseInsensitive(enabled bool) Option {
+	return func(c *config) {
+		c.caseInsensitive = enabled
+	}
+}
+
+// WithNormalizeSeparators treats backslashes as forward slashes during
+// canonicalization
+func WithNormalizeSeparators(enabled bool) Option {
+	return func(c *config) {
+		c.normalizeSeparators = enabled
+	}
+}
+
+// WithNativeSeparators rewrites every '/' and '\' in input paths to the
+// separator of the target platform, '\' under WithWindowsSemantics and '/'
+// otherwise, after decoding and Unicode normalization and before any check.
+// Paths that alternate separators to slip past a detector, such as
+// `..\../..\./etc/passwd`, then reach every check and sanitizer in one form,
+// and the result uses that form too.
+func WithNativeSeparators(enabled bool) Option {
+	return func(c *config) {
+		c.nativeSeparators = enabled
+	}
+}
+
+// WithSegmentPolicy sets how "." segments, empty segments and names ending
+// in dots or spaces are treated, as described for SegmentPolicy. The
+// default, SegmentsKeep, passes them through.
+func WithSegmentPolicy(policy SegmentPolicy) Option {
+	return func(c *config) {
+		c.segmentPolicy = policy
+	}
+}
+
+// WithUnicodeFolding folds Unicode lookalikes of dots and slashes to ASCII and
+// strips zero-width and bidirectional control characters during canonicalization
+func WithUnicodeFolding(enabled bool) Option {
+	return func(c *config) {
+		c.unicodeFolding = enabled
+	}
+}
+
+// WithWarnObserver registers a function that is called with non-fatal
+// concerns, such as very deep paths or unusual characters, found in paths
+// that otherwise pass validation
+func WithWarnObserver(observer func(path string, warning string)) Option {
+	return func(c *config) {
+		c.warnObserver = observer
+	}
+}
+
+// WithExpandEnv expands $NAME and ${NAME} references in input paths using
+// lookup before they are validated. Pass os.LookupEnv to use the process
+// environment. References that lookup cannot resolve are rejected with
+// ErrUnresolvedVariable.
+func WithExpandEnv(lookup func(name string) (string, bool)) Option {
+	return func(c *config) {
+		c.expandEnv = lookup
+	}
+}
+
+// WithShellPolicy sets how environment references and command
+// substitutions in input paths are treated, as described for ShellPolicy
+func WithShellPolicy(policy ShellPolicy) Option {
+	return func(c *config) {
+		c.shellPolicy = policy
+	}
+}
+
+// WithTildePolicy sets how a leading "~" or "~user" in input paths is
+// treated, as described for TildePolicy
+func WithTildePolicy(policy TildePolicy) Option {
+	return func(c *config) {
+		c.tildePolicy = policy
+	}
+}
+
+// WithHomeDirs sets the lookup TildeExpand resolves "~user" with, which
+// receives "" for a "~" alone. Pass LookupHomeDir to use the operating
+// system's accounts, or a function returning a fixed directory to expand
+// against a provided home.
+func WithHomeDirs(lookup func(user string) (string, bool)) Option {
+	return func(c *config) {
+		c.homeDirs = lookup
+	}
+}
+
+// WithNetworkPolicy sets how UNC paths, SMB URLs and NFS exports in input
+// paths are treated, as described for NetworkPolicy
+func WithNetworkPolicy(policy NetworkPolicy) Option {
+	return func(c *config) {
+		c.networkPolicy = policy
+	}
+}
+
+// WithNetworkHosts adds to the hosts NetworkAllowHosts accepts network
+// paths to. Hosts are matched case-insensitively against the host as
+// written in the path, so a server reachable by several names or
+// addresses needs each of them listed.
+func WithNetworkHosts(hosts ...string) Option {
+	return func(c *config) {
+		if c.networkHosts == nil {
+			c.networkHosts = make(map[string]bool, len(hosts))
+		}
+		for _, host := range hosts {
+			c.networkHosts[strings.ToLower(host)] = true
+		}
+	}
+}
+
+// WithDotfilePolicy sets how hidden files and directories, such as .git
+// and .env, are treated, as described for DotfilePolicy
+func WithDotfilePolicy(policy DotfilePolicy) Option {
+	return func(c *config) {
+		c.dotfilePolicy = policy
+	}
+}
+
+// WithFilesystemProfile enforces the rules on names and lengths of the
+// file system paths are written to, as described for FilesystemProfile.
+// Validation rejects illegal characters with ErrIllegalCharacter, reserved
+// device names with ErrReservedName, names too long with ErrFilenameTooLong
+// and paths too long with ErrPathTooLong. SanitizePath rewrites names to
+// fit, other than under SanitizeReject, and SanitizeFilename cuts names to
+// the profile's limit when WithMaxFilenameLength allows longer ones.
+func WithFilesystemProfile(profile FilesystemProfile) Option {
+	return func(c *config) {
+		c.filesystem = profile
+	}
+}
+
+// WithAllowedDotfiles adds to the names DotfilesAllowlist accepts, such as
+// ".well-known" for ACME challenges and security.txt. Names are whole
+// segments, with their leading dot.
+func WithAllowedDotfiles(names ...string) Option {
+	return func(c *config) {
+		c.allowedDotfiles = append(c.allowedDotfiles, names...)
+	}
+}
+
+// WithServableExtensions restricts ToHTTPPath, IsServable and ServableType to
+// paths whose extension is in the map, which associates extensions such as
+// ".js" with the MIME type to serve them as. Extensions are matched
+// case-insensitively and the leading dot is optional.
+func WithServableExtensions(types map[string]string) Option {
+	return func(c *config) {
+		if c.servableExtensions == nil {
+			c.servableExtensions = make(map[string]string, len(types))
+		}
+		for ext, contentType := range types {
+			c.servableExtensions[normalizeExtension(ext)] = contentType
+		}
+	}
+}
+
+// WithFSPathErrors returns validation failures as *fs.PathError values with
+// Op "validate", so code that inspects fs.PathError handles them like other
+// file system errors. errors.Is still matches the package's sentinel errors.
+func WithFSPathErrors(enabled bool) Option {
+	return func(c *config) {
+		c.fsPathErrors = enabled
+	}
+}
+
+// WithWindowsSemantics applies Windows path rules on top of the validator:
+// '\' is a separator, device, verbatim, UNC and drive-relative prefixes are
+// rejected, as are reserved device names and components with trailing dots
+// or spaces. It is enabled by default when running on Windows. See
+// WithVerbatimPaths for accepting verbatim paths to long names.
+func WithWindowsSemantics(enabled bool) Option {
+	return func(c *config) {
+		c.windowsSemantics = enabled
+	}
+}
+
+// WithTargetOS applies the path rules of the operating system that will
+// consume validated paths, named as in runtime.GOOS, instead of those of the
+// host, as when a Linux API server generates paths for Windows agents:
+// "windows" enables Windows semantics and case-insensitive matching,
+// "darwin" and "ios" case-insensitive matching, and other names, such as
+// "linux", disable both. An empty name selects runtime.GOOS. Later options
+// override single rules, so WithCaseInsensitive(false) after
+// WithTargetOS("windows") keeps Windows semantics with case-sensitive
+// matching. Only the lexical rules change; files are still opened through
+// the host.
+func WithTargetOS(goos string) Option {
+	return func(c *config) {
+		if goos == "" {
+			goos = runtime.GOOS
+		}
+		c.windowsSemantics = goos == "windows"
+		c.caseInsensitive = goos == "windows" || goos == "darwin" || goos == "ios"
+	}
+}
+
+// targetOSNames are the names WithTargetOS is given in policies
+var targetOSNames = []string{
+	"aix", "android", "darwin", "dragonfly", "freebsd", "illumos", "ios", "js",
+	"linux", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows",
+}
+
+// WithVerbatimPaths accepts verbatim paths to drives and UNC shares, such
+// as `\\?\C:\a` and `\\?\UNC\host\share\a`, under Windows semantics.
+// Windows sends them to the file system without normalization and allows
+// them up to 32767 UTF-16 code units, so they are the way to reach paths
+// beyond MAX_PATH. Their components are checked literally: ".", "..",
+// empty components, '/' and trailing dots or spaces are rejected, and the
+// rest is validated like the equivalent normalized path. Verbatim paths to
+// devices and volumes stay rejected with ErrDevicePath.
+func WithVerbatimPaths(enabled bool) Option {
+	return func(c *config) {
+		c.verbatimPaths = enabled
+	}
+}
+
+// WithWindowsMaxPath rejects paths longer than MAX_PATH, 259 UTF-16 code
+// units, with ErrPathTooLong under Windows semantics, for code that opens
+// them with APIs or on systems without long path support. Verbatim paths
+// accepted with WithVerbatimPaths are exempt. The validator's own limit of
+// 4096 bytes applies either way.
+func WithWindowsMaxPath(enabled bool) Option {
+	return func(c *config) {
+		c.windowsMaxPath = enabled
+	}
+}
+
+// WithRejectShortNames rejects paths with a component in the form of an
+// 8.3 short name, such as "PROGRA~1", with ErrShortName. It is independent
+// of WithWindowsSemantics, as Windows clients reach Linux servers through
+// SMB shares too. NTFS alternate data streams are always rejected.
+func WithRejectShortNames(enabled bool) Option {
+	return func(c *config) {
+		c.rejectShortNames = enabled
+	}
+}
+
+// WithDecoding sets how percent-encoded input is decoded before validation,
+// detection and sanitization. The default, DecodeNone, leaves encoded input
+// to the validator's own encoded-pattern checks.
+func WithDecoding(policy DecodePolicy) Option {
+	return func(c *config) {
+		c.decoding = policy
+	}
+}
+
+// WithUnicodeNormalization normalizes input paths to NFC or NFKC after
+// decoding, so the validator sees the path the file system will store.
+// Deceptive characters reported by DetectUnicodeTricks are rejected
+// regardless of this setting.
+func WithUnicodeNormalization(form UnicodeNormalization) Option {
+	return func(c *config) {
+		c.normalization = form
+	}
+}
+
+// WithMaxPathLength rejects paths longer than n bytes with ErrPathTooLong.
+// The validator's own limit of 4096 bytes still applies, so only smaller
+// values have an effect. Zero or a negative n keeps the default.
+func WithMaxPathLength(n int) Option {
+	return func(c *config) {
+		c.maxPathLength = n
+	}
+}
+
+// WithMaxInputBytes rejects input longer than n bytes with ErrPathTooLong
+// before any preprocessing, so oversized input never reaches the native
+// library. Unlike WithMaxPathLength it also bounds the input of
+// DetectTraversal, SanitizePath and SafeUploadName. Zero or a negative n
+// keeps the default of 64 KiB; the limit cannot be lifted.
+func WithMaxInputBytes(n int) Option {
+	return func(c *config) {
+		c.maxInputBytes = n
+	}
+}
+
+// WithRawBytes lets input that is not valid UTF-8 through to the later
+// stages, for file systems whose names are arbitrary bytes. By default it is
+// rejected with ErrInvalidEncoding before any preprocessing. The native
+// library is never handed such input; paths that hold it are checked by
+// the pure-Go engine instead.
+func WithRawBytes(enabled bool) Option {
+	return func(c *config) {
+		c.rawBytes = enabled
+	}
+}
+
+// WithSourceEncoding sets the encoding ValidateBytes, SanitizeBytes and
+// DecodeBytes transcode byte paths from, EncodingUTF8 by default. Paths
+// given as strings are always UTF-8.
+func WithSourceEncoding(enc SourceEncoding) Option {
+	return func(c *config) {
+		c.sourceEncoding = enc
+	}
+}
+
+// WithMaxDepth rejects paths with more than n named segments after lexical
+// cleaning with ErrPathTooDeep, so "a/./b/" has depth 2. Zero or a
+// negative n disables the limit.
+func WithMaxDepth(n int) Option {
+	return func(c *config) {
+		c.maxDepth = n
+	}
+}
+
+// WithMaxParentHops lets relative paths contain ".." segments as long as
+// they climb at most n levels above the directory the path is relative
+// to, so n = 1 accepts "../include/util.h" and "a/../../b" but rejects
+// "../../etc/passwd" with ErrTraversalDetected. ".." segments that only
+// step back out of a named segment, as in "a/b/../c", are always allowed.
+// Absolute paths may not climb above their root. The backend validator
+// sees the path with its ".." segments resolved lexically; DetectTraversal
+// still reports every ".." segment. By default, and for n of zero or less,
+// any ".." segment is rejected.
+func WithMaxParentHops(n int) Option {
+	return func(c *config) {
+		c.maxParentHops = n
+	}
+}
+
+// WithTraversalPatterns replaces the traversal patterns checked before the
+// validator, DefaultTraversalPatterns unless set, with patterns; paths
+// matching one are rejected with ErrTraversalDetected. Pass
+// DefaultTraversalPatterns().With(...) to add patterns, or an empty set to
+// check none. A nil set restores the defaults.
+func WithTraversalPatterns(patterns *TraversalPatterns) Option {
+	return func(c *config) {
+		c.traversalPatterns = patterns
+	}
+}
+
+// WithExtraTraversalPatterns checks patterns after the others, those of
+// the ruleset in use unless WithTraversalPatterns replaces them. Unlike
+// DefaultTraversalPatterns().With(...), which fixes the ruleset in use
+// when it is called, the extra patterns are added to whichever ruleset
+// UseRuleset puts in use later. A nil set removes them.
+func WithExtraTraversalPatterns(patterns *TraversalPatterns) Option {
+	return func(c *config) {
+		c.extraTraversalPatterns = patterns
+	}
+}
+
+// WithDeniedPatterns rejects paths matching any of the path.Match patterns
+// with ErrDeniedPattern. Each pattern is matched against the whole path,
+// with '\' read as '/', and against every segment, so "*.env" denies
+// "config/.env" and ".git" denies any path through a .git directory.
+// Matching ignores case when WithCaseInsensitive is enabled. A malformed
+// pattern makes validation fail with path.ErrBadPattern rather than being
+// skipped.
+func WithDeniedPatterns(patterns ...string) Option {
+	return func(c *config) {
+		c.deniedPatterns = append(c.deniedPatterns, patterns...)
+	}
+}
+
+// WithAllowAbsolute controls whether absolute paths pass validation. When
+// disabled, paths starting with '/' are rejected with ErrAbsolutePath, as
+// are drive, rooted and UNC paths when Windows semantics are enabled.
+// Absolute paths are allowed by default.
+func WithAllowAbsolute(allowed bool) Option {
+	return func(c *config) {
+		c.rejectAbsolute = !allowed
+	}
+}
+
+// WithAllowedExtensions limits CheckUpload to file names whose final
+// extension is one of exts. Extensions are matched case-insensitively and
+// may be given with or without the leading dot.
+func WithAllowedExtensions(exts ...string) Option {
+	return func(c *config) {
+		if c.allowedExtensions == nil {
+			c.allowedExtensions = make(map[string]bool, len(exts))
+		}
+		for _, ext := range exts {
+			c.allowedExtensions[normalizeExtension(ext)] = true
+		}
+	}
+}
+
+// WithDeniedExtensions makes CheckUpload reject file names carrying any of
+// exts, in any position, so "shell.php.jpg" is denied by ".php" as servers
+// configured for multiple extensions would execute it. Extensions are
+// matched case-insensitively and may be given with or without the dot.
+func WithDeniedExtensions(exts ...string) Option {
+	return func(c *config) {
+		if c.deniedExtensions == nil {
+			c.deniedExtensions = make(map[string]bool, len(exts))
+		}
+		for _, ext := range exts {
+			c.deniedExtensions[normalizeExtension(ext)] = true
+		}
+	}
+}
+
+// WithMaxFilenameLength makes CheckUpload reject file names longer than n
+// bytes and SanitizeFilename cut them to n bytes. Zero or a negative n
+// disables the limit, which SanitizeFilename then puts at 255.
+func WithMaxFilenameLength(n int) Option {
+	return func(c *config) {
+		c.maxFilenameLength = n
+	}
+}
+
+// WithSlugFilenames makes SanitizeFilename return lowercase ASCII slugs,
+// as in "resume-final-v2.pdf" for "Résumé final (v2).PDF", for object
+// store keys and CDN paths that should not depend on how clients encode
+// Unicode. Accents are dropped, letters such as 'ß' are transliterated,
+// runs of whitespace and punctuation become a single '-' and the
+// extension is kept. A name with no letters or digits left is replaced by
+// a hash of it, so the result is deterministic but distinct names stay
+// apart.
+func WithSlugFilenames(enabled bool) Option {
+	return func(c *config) {
+		c.slugFilenames = enabled
+	}
+}
+
+// WithFilenamePattern makes CheckUpload require file names to match re.
+// When set several times, names must match every pattern.
+func WithFilenamePattern(re *regexp.Regexp) Option {
+	return func(c *config) {
+		c.filenamePatterns = append(c.filenamePatterns, re)
+	}
+}
+
+// WithDeniedFilenamePattern makes CheckUpload reject file names matching re
+func WithDeniedFilenamePattern(re *regexp.Regexp) Option {
+	return func(c *config) {
+		c.deniedFilenamePatterns = append(c.deniedFilenamePatterns, re)
+	}
+}
+
+// WithSanitizeStrategy selects how SanitizePath and Classify sanitize
+// paths. The default is SanitizeStrip.
+func WithSanitizeStrategy(strategy SanitizeStrategy) Option {
+	return func(c *config) {
+		c.sanitizeStrategy = strategy
+	}
+}
+
+// WithSensitivePaths rejects paths that point at sensitive system
+// locations with ErrSensitivePath, even when they are lexically clean.
+// Patterns use path.Match syntax per segment: "/proc" matches from the
+// root and covers everything below, "~/.ssh" matches a .ssh directory at
+// any depth and ".env" matches any segment. Without patterns, the list
+// returned by DefaultSensitivePaths is used. Paths that exist are also
+// checked with os.Stat and rejected if they are device files, named pipes
+// or sockets. A malformed pattern makes validation fail.
+func WithSensitivePaths(patterns ...string) Option {
+	return func(c *config) {
+		if len(patterns) == 0 {
+			patterns = defaultSensitivePaths
+		}
+		c.sensitivePaths = append(c.sensitivePaths, patterns...)
+	}
+}
+
+// WithMaxGlobWildcards sets how many wildcards ValidateGlob accepts in one
+// pattern, counting each run of '*', each '?', each character class and
+// each brace group. The default is 16; zero or a negative n keeps it.
+func WithMaxGlobWildcards(n int) Option {
+	return func(c *config) {
+		c.maxGlobWildcards = n
+	}
+}
+
+// WithAuditFunc registers a function called with an Event for every path
+// that is rejected or changed by sanitization, for routing decisions to an
+// audit log or SIEM. It is called synchronously, possibly from several
+// goroutines at once, and should not block.
+func WithAuditFunc(fn func(Event)) Option {
+	return func(c *config) {
+		c.auditFunc = fn
+	}
+}
+
+// WithTargetChecks makes ValidateResolved check the file a path resolves
+// to, reporting every failed check as a finding of a *TargetError wrapping
+// ErrTargetCheck. Checks other than TargetExists pass for targets that do
+// not exist yet. TargetOwner is enabled with WithTargetOwner instead, which
+// names the owner.
+func WithTargetChecks(checks ...TargetCheck) Option {
+	return func(c *config) {
+		for _, check := range checks {
+			switch check {
+			case TargetExists, TargetRegular, TargetNotWorldWritable:
+				c.targetChecks |= 1 << check
+			}
+		}
+	}
+}
+
+// WithTargetOwner makes ValidateResolved require an existing target to be
+// owned by uid and belong to group gid, either of which may be -1 to match
+// any. On platforms without numeric owners every existing target fails the
+// check.
+func WithTargetOwner(uid, gid int) Option {
+	return func(c *config) {
+		c.targetOwner = true
+		c.targetUID = uid
+		c.targetGID = gid
+	}
+}
+
+// WithRule registers a custom detector that runs in the same pipeline as
+// the built-in checks. Its findings are reported by Analyze under
+// CategoryCustom with Rule set to name, at offsets into the canonical
+// path, and count towards the reject severity of a Policy. Validation
+// rejects a path for which a rule reports a high or critical finding with
+// an error wrapping ErrRuleViolation. Rules run in the order registered,
+// once the built-in checks have passed, and their verdicts are cached with
+// WithValidationCache like the rest of validation.
+func WithRule(name string, rule Rule) Option {
+	return func(c *config) {
+		c.rules = append(c.rules, namedRule{name: name, rule: rule})
+	}
+}
+
+// WithFailThreshold sets the severity from which validation rejections are
+// enforced, for rolling the library out to a running service: deploy with
+// MonitorOnly, watch what would be rejected, then lower the threshold.
+// Every rejection has the severity RejectionSeverity returns, from low for
+// file names through critical for traversal and NUL bytes. A path whose
+// rejections are all below the threshold is let through as valid, and the
+// first of them is reported instead as a warning to the WithWarnObserver
+// function, as a DecisionMonitored event to the audit function and to a
+// metrics collector implementing MonitorCollector. The threshold applies
+// to ValidatePath and everything built on it, and to the reject severity
+// of a Policy; failures of the native library, allowed roots, symlink
+// resolution and target checks are always enforced. The default,
+// SeverityLow, enforces every rejection.
+func WithFailThreshold(threshold Severity) Option {
+	return func(c *config) {
+		c.failThreshold = threshold
+	}
+}
+
+// WithValidationCache reuses validation results from cache for paths seen
+// before, skipping the backend validator and the binding's own checks of
+// ValidatePath and everything built on it, including ValidatePaths.
+// Results are keyed on the preprocessed path and the configuration, which
+// counts as new for every NewPathSecurity and Clone call because options
+// can hold functions that cannot be compared; several instances can
+// therefore share one cache without seeing each other's results, and a
+// Policy update starts afresh as old entries age out. Cached rejections
+// are still reported to the audit function and metrics collector, and
+// failures of the native library are never cached. Checks against the
+// file system, such as the special file check of WithSensitivePaths,
+// allowed roots and symlink resolution, run on every call.
+func WithValidationCache(cache *ValidationCache) Option {
+	return func(c *config) {
+		c.cache = cache
+	}
+}
+
+// WithRealpathCache resolves symlinks in Resolved mode, ValidateResolved
+// and the checks built on it through cache, so repeated validations under
+// the same roots skip most system calls. Resolution beneath a boundary set
+// with WithMountBoundary is not cached. A nil cache disables caching.
+func WithRealpathCache(cache *RealpathCache) Option {
+	return func(c *config) {
+		c.realpathCache = cache
+	}
+}
+
+// WithNativeTimeout bounds how long each call into libpath_security_c may
+// take. A call still running after d fails with an error wrapping
+// ErrNativeFailure and ErrNativeTimeout, and is counted by Health; as a C
+// function cannot be interrupted, it keeps running on its own thread until
+// it returns. Each call then runs on a goroutine of its own, which costs
+// about a microsecond. Zero, the default, waits for every call. The
+// timeout has no effect on the pure-Go engine.
+func WithNativeTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.nativeTimeout = d
+	}
+}
+
+// WithMetrics reports every validation, with its latency, every rejection
+// and every sanitization to collector
+func WithMetrics(collector MetricsCollector) Option {
+	return func(c *config) {
+		c.metrics = collector
+	}
+}
+
+// WithMountBoundary confines the symlink resolution of ValidateResolved,
+// Authorize and the fs.FS returned by FS to boundary, such as a
+// container's root file system or a bind-mounted volume. Resolution treats
+// boundary as the root of the file system, as a chroot does: ".." and
+// relative symlinks stop at it, and absolute symlink targets outside it are
+// taken relative to it. Paths outside boundary, paths climbing above it and
+// paths crossing a mount point below it, including one reached through
+// /proc/self/root or a similar link, are rejected with an error wrapping
+// ErrMountBoundary. Mount points are read from /proc/self/mountinfo, so
+// they are only detected on Linux. On Linux OpenFileInRoot also refuses
+// mount points below its root, through openat2 with RESOLVE_NO_XDEV, and
+// reports them like symlink escapes.
+func WithMountBoundary(boundary string) Option {
+	return func(c *config) {
+		c.mountBoundary = boundary
+	}
+}
+
+// WithStdlibParity makes validation accept exactly the paths the standard
+// library check selected by parity accepts, as described for StdlibParity,
+// so checks written with filepath.IsLocal or fs.ValidPath can move to the
+// binding without changing behavior and gain its reporting before its
+// stricter checks are turned on. The default, ParityOff, runs the binding's
+// checks.
+func WithStdlibParity(parity StdlibParity) Option {
+	return func(c *config) {
+		c.stdlibParity = parity
+	}
+}
+
+// WithStdlibCrossCheck compares every path ValidatePath or ValidatePaths
+// judges with path/filepath, as CompareWithStdlib does, and calls fn with
+// the comparison whenever they disagree. It runs detection a second time for
+// every path, so it is meant for tests and staging rather than production.
+func WithStdlibCrossCheck(fn func(StdlibComparison)) Option {
+	return func(c *config) {
+		c.stdlibCrossCheck = fn
+	}
+}
+
+// normalizeExtension lower-cases ext and adds the leading dot if missing
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
@@ -0,0 +1,95 @@
+package pathsecurity
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StdlibParity selects a standard library check whose decisions validation
+// reproduces exactly, for code migrating from it. Under a parity mode the
+// check is the whole of validation: paths reach ValidatePath, CheckPath and
+// the functions built on them as given, without the input pre-flight,
+// expansion, decoding, normalization or any check of the binding, and the
+// fail threshold does not apply. Rejections are still a *ValidationError
+// with a category, reported to the audit function and metrics collector,
+// and explained by Explain. DetectTraversal is not affected.
+type StdlibParity int
+
+const (
+	// ParityOff runs the binding's own checks
+	ParityOff StdlibParity = iota
+	// ParityIsLocal accepts exactly the paths filepath.IsLocal reports as
+	// local. It follows the host operating system, not WithTargetOS or
+	// WithWindowsSemantics, and accepts "a/../b", which stays local.
+	ParityIsLocal
+	// ParityValidPath accepts exactly the paths fs.ValidPath accepts:
+	// unrooted, '/'-separated and without ".", ".." or empty elements
+	// other than the path ".". '\' and ':' are ordinary characters.
+	ParityValidPath
+)
+
+// String returns the name of the mode
+func (p StdlibParity) String() string {
+	switch p {
+	case ParityOff:
+		return "off"
+	case ParityIsLocal:
+		return "is-local"
+	case ParityValidPath:
+		return "valid-path"
+	}
+	return fmt.Sprintf("StdlibParity(%d)", int(p))
+}
+
+// checkParity rejects a path the standard library check selected with
+// WithStdlibParity rejects, with the category of the reason it does
+func (c *config) checkParity(p string) *ValidationError {
+	switch c.stdlibParity {
+	case ParityIsLocal:
+		if filepath.IsLocal(p) {
+			return nil
+		}
+		switch {
+		case p == "":
+			return &ValidationError{Path: p, Reason: "filepath.IsLocal: empty path", Err: ErrInvalidPath}
+		case filepath.IsAbs(p) || os.IsPathSeparator(p[0]) || filepath.VolumeName(p) != "":
+			return &ValidationError{Path: p, Reason: "filepath.IsLocal: rooted path", Err: ErrAbsolutePath}
+		case escapesStart(filepath.Clean(p)):
+			return &ValidationError{Path: p, Reason: "filepath.IsLocal: path climbs above its start", Err: ErrTraversalDetected}
+		}
+		return &ValidationError{Path: p, Reason: "filepath.IsLocal: path is not local", Err: ErrInvalidPath}
+	case ParityValidPath:
+		if fs.ValidPath(p) {
+			return nil
+		}
+		switch {
+		case p == "":
+			return &ValidationError{Path: p, Reason: "fs.ValidPath: empty path", Err: ErrInvalidPath}
+		case p[0] == '/':
+			return &ValidationError{Path: p, Reason: "fs.ValidPath: rooted path", Err: ErrAbsolutePath}
+		}
+		for _, element := range strings.Split(p, "/") {
+			if element == ".." {
+				return &ValidationError{Path: p, Reason: `fs.ValidPath: ".." element`, Err: ErrTraversalDetected}
+			}
+		}
+		return &ValidationError{Path: p, Reason: "fs.ValidPath: \".\" or empty element", Err: ErrInvalidPath}
+	}
+	return nil
+}
+
+// validateParity is validation under a parity mode
+func (ps *PathSecurity) validateParity(path string) (string, error) {
+	if verr := ps.cfg.checkParity(path); verr != nil {
+		return "", ps.reject(path, verr)
+	}
+	response, err := json.Marshal(validateDocument{Valid: true, Path: path})
+	if err != nil {
+		return "", err
+	}
+	return string(response), nil
+}
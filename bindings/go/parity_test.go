@@ -0,0 +1,162 @@
+//go:build parity
+
+package pathsecurity
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The drivers of examples/parity speak one JSON path per line in and one
+// JSON verdict per line out
+var (
+	//go:embed examples/parity/driver.py
+	parityDriverPy string
+	//go:embed examples/parity/driver.js
+	parityDriverJS string
+)
+
+// parityDriver is another language binding running a driver script
+type parityDriver struct {
+	stdin  io.WriteCloser
+	stdout *bufio.Scanner
+}
+
+// startParityDriver runs name with args, skipping the test if the binding
+// cannot be loaded, as checked by running name with probe
+func startParityDriver(t *testing.T, probe []string, name string, args ...string) *parityDriver {
+	t.Helper()
+	if _, err := exec.LookPath(name); err != nil {
+		t.Skipf("%s not installed", name)
+	}
+	if err := exec.Command(name, probe...).Run(); err != nil {
+		t.Skipf("binding not loadable by %s: %v", name, err)
+	}
+
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		stdin.Close()
+		cmd.Wait()
+	})
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(nil, 1<<20)
+	return &parityDriver{stdin: stdin, stdout: scanner}
+}
+
+// validate returns the binding's verdict on path
+func (d *parityDriver) validate(path string) (bool, error) {
+	line, err := json.Marshal(path)
+	if err != nil {
+		return false, err
+	}
+	if _, err := fmt.Fprintf(d.stdin, "%s\n", line); err != nil {
+		return false, err
+	}
+	if !d.stdout.Scan() {
+		if err := d.stdout.Err(); err != nil {
+			return false, err
+		}
+		return false, io.ErrUnexpectedEOF
+	}
+	var verdict struct {
+		Valid bool   `json:"valid"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(d.stdout.Bytes(), &verdict); err != nil {
+		return false, err
+	}
+	if verdict.Error != "" {
+		return false, fmt.Errorf("binding raised %s", verdict.Error)
+	}
+	return verdict.Valid, nil
+}
+
+// parityRewrites reports whether the binding hands the validator a
+// different path than it was given under spec
+func parityRewrites(spec PolicySpec) bool {
+	return spec.TargetOS == "windows" || spec.WindowsSemantics != nil && *spec.WindowsSemantics ||
+		spec.MaxParentHops > 0 || spec.NativeSeparators ||
+		spec.StdlibParity != "" && spec.StdlibParity != "off" ||
+		spec.Decoding == "once" || spec.Decoding == "recursive" ||
+		spec.Normalization != "" && spec.Normalization != "none" ||
+		spec.Segments == "collapse" || spec.Tilde == "expand" || spec.Shell == "expand"
+}
+
+// TestBindingParity runs the conformance vectors through the Python and
+// Node.js bindings, which must agree with the native library called
+// without this binding's checks, and which this binding must not be more
+// lenient than unless it rewrites the path first. A binding that is not
+// installed is skipped; the comparison with the library needs cgo.
+//
+//	go test -tags parity -run TestBindingParity
+func TestBindingParity(t *testing.T) {
+	nodeModule, err := filepath.Abs("../nodejs/native")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bindings := []struct {
+		name  string
+		start func(t *testing.T) *parityDriver
+	}{
+		{"python", func(t *testing.T) *parityDriver {
+			return startParityDriver(t, []string{"-c", "import path_security_python"}, "python3", "-c", parityDriverPy)
+		}},
+		{"node", func(t *testing.T) *parityDriver {
+			return startParityDriver(t, []string{"-e", fmt.Sprintf("require(%q)", nodeModule)}, "node", "-e", parityDriverJS, nodeModule)
+		}},
+	}
+	native := NativeBackend() == nil
+	for _, b := range bindings {
+		t.Run(b.name, func(t *testing.T) {
+			driver := b.start(t)
+			for _, v := range ConformanceVectors().Vectors {
+				valid, err := driver.validate(v.Path)
+				if err != nil {
+					t.Errorf("vector %s %q: %v", v.ID, v.Path, err)
+					continue
+				}
+				if native && !strings.ContainsRune(v.Path, 0) {
+					var response validateDocument
+					raw, err := backendValidatePath(v.Path, 0)
+					if err == nil {
+						err = json.Unmarshal([]byte(raw), &response)
+					}
+					if err != nil {
+						t.Errorf("vector %s %q: native library: %v", v.ID, v.Path, err)
+					} else if response.Valid != valid {
+						t.Errorf("vector %s %q: %s binding returns valid %t, native library %t", v.ID, v.Path, b.name, valid, response.Valid)
+					}
+				}
+
+				policy, err := NewPolicy(v.Policy)
+				if err != nil {
+					t.Errorf("vector %s: policy: %v", v.ID, err)
+					continue
+				}
+				if policy.Check(v.Path) == nil && !valid && !parityRewrites(v.Policy) {
+					t.Errorf("vector %s %q: Go binding accepts what the %s binding rejects", v.ID, v.Path, b.name)
+				}
+			}
+		})
+	}
+}
@@ -1,63 +1,489 @@
-package main
+package pathsecurity
 
-/*
-#cgo LDFLAGS: -L. -lpath_security_c
-#include "path_security.h"
-*/
-import "C"
 import (
+	"encoding/json"
 	"fmt"
-	"unsafe"
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
 )
 
-// PathSecurity provides Go bindings for Path Security
-type PathSecurity struct{}
+// validateResponse is the part of the path_security_validate_path JSON document read by the binding
+type validateResponse struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error"`
+}
+
+// SanitizeResult is the outcome of sanitizing a path
+type SanitizeResult struct {
+	Original  string `json:"original"`
+	Sanitized string `json:"sanitized"`
+	Changed   bool   `json:"changed"`
+}
+
+// PathSecurity provides Go bindings for Path Security. A PathSecurity is
+// safe for concurrent use by multiple goroutines: its configuration is fixed
+// when it is created, and neither backend keeps state between calls. Use
+// Clone to derive an instance with different options.
+type PathSecurity struct {
+	cfg config
+}
 
 // NewPathSecurity creates a new PathSecurity instance
-func NewPathSecurity() *PathSecurity {
-	return &PathSecurity{}
+func NewPathSecurity(opts ...Option) *PathSecurity {
+	ps := &PathSecurity{cfg: defaultConfig()}
+	for _, opt := range opts {
+		opt(&ps.cfg)
+	}
+	ps.cfg.id = configIDs.Add(1)
+	return ps
 }
 
-// ValidatePath validates a file path for security issues
+// Clone returns a new PathSecurity with the receiver's configuration and
+// opts applied on top. The receiver is never modified, so Clone may be
+// called while other goroutines use it.
+func (ps *PathSecurity) Clone(opts ...Option) *PathSecurity {
+	clone := &PathSecurity{cfg: ps.cfg.clone()}
+	for _, opt := range opts {
+		opt(&clone.cfg)
+	}
+	clone.cfg.id = configIDs.Add(1)
+	return clone
+}
+
+// ValidatePath validates a file path for security issues and returns the
+// validator's JSON report. A rejected path yields a *ValidationError that
+// matches a category such as ErrTraversalDetected with errors.Is.
 func (ps *PathSecurity) ValidatePath(path string) (string, error) {
-	cPath := C.CString(path)
-	defer C.free(unsafe.Pointer(cPath))
+	checked, err := ps.preprocess(path)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := ps.validate(checked)
+	if ps.cfg.stdlibCrossCheck != nil {
+		ps.crossCheck(path, checked, err == nil)
+	}
+	if err != nil {
+		return "", err
+	}
+	ps.warn(checked)
+	return response, nil
+}
+
+// DetectTraversal detects if a path contains traversal patterns. Paths
+// rejected by the decoding policy set with WithDecoding and paths through a
+// magic symlink found by FindMagicLink are reported as traversal.
+func (ps *PathSecurity) DetectTraversal(path string) (bool, error) {
+	if err := ps.cfg.checkInput(path); err != nil {
+		return false, ps.reject(err.Path, err)
+	}
+	path, err := ps.expandHome(path)
+	if err != nil {
+		return false, err
+	}
+	path, err = ps.expandEnv(path)
+	if err != nil {
+		return false, err
+	}
+	path, err = ps.decode(path)
+	if err != nil {
+		return true, nil
+	}
+	path = ps.cfg.convertSeparators(ps.cfg.normalization.normalize(path))
+	// Edge-case segments are not traversal, so SegmentsReject is not applied
+	if ps.cfg.segmentPolicy == SegmentsCollapse {
+		path = ps.cfg.collapseSegments(path)
+	}
+	return ps.detectTraversal(path)
+}
+
+// detectTraversal runs traversal detection on an already preprocessed path
+func (ps *PathSecurity) detectTraversal(path string) (bool, error) {
+	if strings.IndexByte(path, 0) >= 0 {
+		return false, ps.reject(path, checkControlChars(path))
+	}
+	if ps.cfg.treatEncodedSlashAsSeparator && hasDotDotSegment(splitEncodedSlashes(path)) {
+		return true, nil
+	}
+	if hasBackslashDotDot(path) {
+		return true, nil
+	}
+	if ps.cfg.windowsSemantics {
+		// The backend sees the same '/'-separated form ValidatePath gives it
+		path = strings.ReplaceAll(path, `\`, "/")
+	}
+	if normalizesToTraversal(path) {
+		return true, nil
+	}
+	// Magic symlinks escape confinement as surely as ".." does
+	if offset, _ := FindMagicLink(path); offset >= 0 {
+		return true, nil
+	}
 
-	result := make([]byte, 256)
-	ret := C.path_security_validate_path(cPath, (*C.char)(unsafe.Pointer(&result[0])), C.size_t(len(result)))
+	return backendDetectTraversal(path, ps.cfg.nativeTimeout)
+}
 
-	if ret != 0 {
-		return "", fmt.Errorf("path validation failed with code: %d", ret)
+// SanitizePath sanitizes a path by removing dangerous patterns, or as
+// selected with WithSanitizeStrategy. Edge-case segments are collapsed or
+// rejected as set with WithSegmentPolicy before the strategy runs. With
+// SegmentsKeep, SanitizeCollapse drops "." and empty segments but keeps
+// trailing dots and spaces, SanitizeEncode keeps them all as literal names,
+// and SanitizeStrip leaves them to the validator's sanitizer. Paths that
+// NFKC and folding of lookalikes turn into traversal, such as "‥/etc",
+// are folded first, so every strategy treats them as it treats "..".
+// SanitizeStrip and SanitizeCollapse run until the path stops changing, so
+// their output sanitizes to itself.
+func (ps *PathSecurity) SanitizePath(path string) (string, error) {
+	path, err := ps.preprocess(path)
+	if err != nil {
+		return "", err
+	}
+	// Canonical paths are left alone by every strategy but SanitizeReject,
+	// which must still run the validator, unless a filesystem profile has
+	// names in them to rewrite
+	if ps.cfg.sanitizeStrategy != SanitizeReject && isCanonicalPath(path) && ps.cfg.fitsFilesystem(path) {
+		return path, nil
 	}
 
-	return C.GoString((*C.char)(unsafe.Pointer(&result[0]))), nil
+	result, err := ps.sanitize(path, nil)
+	if err != nil {
+		return "", err
+	}
+	return result.Sanitized, nil
 }
 
-// DetectTraversal detects if a path contains traversal patterns
-func (ps *PathSecurity) DetectTraversal(path string) (bool, error) {
-	cPath := C.CString(path)
-	defer C.free(unsafe.Pointer(cPath))
+// maxSanitizePasses bounds the passes of the removing strategies
+const maxSanitizePasses = 8
 
-	ret := C.path_security_detect_traversal(cPath)
+// sanitize applies the sanitize strategy to an already preprocessed path,
+// reporting the form of the path before and after each stage that changes
+// it to step unless step is nil
+func (ps *PathSecurity) sanitize(path string, step func(name, before, after string)) (SanitizeResult, error) {
+	if step == nil {
+		step = func(string, string, string) {}
+	}
+	folded := foldTraversal(path)
+	if folded != path {
+		step("fold-traversal", path, folded)
+	}
+	result, err := ps.applyStrategy(folded)
+	if err != nil {
+		return SanitizeResult{}, err
+	}
+	if ps.cfg.sanitizeStrategy == SanitizeStrip || ps.cfg.sanitizeStrategy == SanitizeCollapse {
+		// Removing a pattern can form another, as stripping "../" from
+		// "....//" leaves "../", or a segment the segment policy collapses
+		for pass := 1; pass < maxSanitizePasses; pass++ {
+			input := result.Sanitized
+			if ps.cfg.segmentPolicy == SegmentsCollapse {
+				input = ps.cfg.collapseSegments(input)
+			}
+			if isCanonicalPath(input) {
+				result.Sanitized = input
+				break
+			}
+			next, err := ps.applyStrategy(foldTraversal(input))
+			if err != nil {
+				return SanitizeResult{}, err
+			}
+			if next.Sanitized == result.Sanitized {
+				break
+			}
+			result.Sanitized = next.Sanitized
+		}
+	}
+	if result.Sanitized != folded {
+		step(ps.cfg.sanitizeStrategy.String(), folded, result.Sanitized)
+	}
+	if ps.cfg.sanitizeStrategy != SanitizeReject {
+		fitted, verr := ps.cfg.fitFilesystem(result.Sanitized)
+		if verr != nil {
+			return SanitizeResult{}, ps.reject(path, verr)
+		}
+		if fitted != result.Sanitized {
+			step("filesystem", result.Sanitized, fitted)
+		}
+		result.Sanitized = fitted
+	}
+	result.Original = path
+	result.Changed = result.Sanitized != path
+	ps.auditSanitized(result, ps.cfg.sanitizeStrategy.String())
+	return result, nil
+}
 
-	if ret < 0 {
-		return false, fmt.Errorf("traversal detection failed with code: %d", ret)
+// foldTraversal folds lookalikes in a path that NFKC and Unicode folding
+// turn into traversal, and returns other paths unchanged
+func foldTraversal(path string) string {
+	if !normalizesToTraversal(path) {
+		return path
 	}
+	return unicodeFolder.Replace(norm.NFKC.String(path))
+}
 
-	return ret == 1, nil
+// applyStrategy runs the configured sanitize strategy
+func (ps *PathSecurity) applyStrategy(path string) (SanitizeResult, error) {
+	if prefix, rest, ok := ps.cfg.splitVerbatim(path); ok && ps.cfg.sanitizeStrategy != SanitizeReject {
+		return ps.sanitizeVerbatim(path, prefix, rest)
+	}
+	var sanitized string
+	switch ps.cfg.sanitizeStrategy {
+	case SanitizeCollapse:
+		// Under Windows semantics '\' separates segments too, so "..\"
+		// must be collapsed rather than kept as part of a name
+		collapsed := path
+		if ps.cfg.windowsSemantics {
+			collapsed = strings.ReplaceAll(path, `\`, "/")
+		}
+		sanitized = collapsePath(collapsed)
+	case SanitizeReject:
+		if err := ps.check(path); err != nil {
+			return SanitizeResult{}, err
+		}
+		sanitized = path
+	case SanitizeEncode:
+		sanitized = encodePath(path)
+	default:
+		return sanitizeWithoutNUL(path, ps.cfg.nativeTimeout, backendSanitizePath)
+	}
+	return SanitizeResult{Original: path, Sanitized: sanitized, Changed: sanitized != path}, nil
 }
 
-// SanitizePath sanitizes a path by removing dangerous patterns
-func (ps *PathSecurity) SanitizePath(path string) (string, error) {
-	cPath := C.CString(path)
-	defer C.free(unsafe.Pointer(cPath))
+// sanitizeFilename runs the backend filename sanitizer
+func (ps *PathSecurity) sanitizeFilename(name string) (SanitizeResult, error) {
+	if err := ps.cfg.checkInput(name); err != nil {
+		return SanitizeResult{}, ps.reject(err.Path, err)
+	}
+	result, err := sanitizeWithoutNUL(name, ps.cfg.nativeTimeout, backendSanitizeFilename)
+	if err != nil {
+		return SanitizeResult{}, err
+	}
+	ps.auditSanitized(result, "filename")
+	return result, nil
+}
+
+// sanitizeWithoutNUL removes NUL bytes before running a backend sanitizer,
+// so the native library sees the whole input rather than the part before
+// the first NUL, and reports the result against the original input
+func sanitizeWithoutNUL(input string, timeout time.Duration, sanitizer func(string, time.Duration) (SanitizeResult, error)) (SanitizeResult, error) {
+	if strings.IndexByte(input, 0) < 0 {
+		return sanitizer(input, timeout)
+	}
 
-	result := make([]byte, 256)
-	ret := C.path_security_sanitize_path(cPath, (*C.char)(unsafe.Pointer(&result[0])), C.size_t(len(result)))
+	result, err := sanitizer(strings.ReplaceAll(input, "\x00", ""), timeout)
+	if err != nil {
+		return SanitizeResult{}, err
+	}
+	return SanitizeResult{Original: input, Sanitized: result.Sanitized, Changed: true}, nil
+}
 
-	if ret != 0 {
-		return "", fmt.Errorf("path sanitization failed with code: %d", ret)
+// preprocess checks the raw input, expands environment references, applies
+// the decoding policy and normalizes the result, except under
+// WithStdlibParity
+func (ps *PathSecurity) preprocess(path string) (string, error) {
+	// The standard library sees the input as given
+	if ps.cfg.stdlibParity != ParityOff {
+		return path, nil
 	}
+	path, _, _, err := ps.preprocessStages(path, ignoreStage)
+	return path, err
+}
+
+// preprocessStages runs the stages of preprocess on path, calling stage
+// with the name of each and the path after it, so that Explain and
+// CanonicalizeDetailed report exactly what preprocess does. It returns the
+// number of decoding passes and, when a stage rejects the path, its name.
+func (ps *PathSecurity) preprocessStages(path string, stage func(name, path string)) (string, int, string, error) {
+	if err := ps.cfg.checkInput(path); err != nil {
+		return "", 0, "input", ps.reject(err.Path, err)
+	}
+	path, err := ps.expandHome(path)
+	if err != nil {
+		return "", 0, "expand-home", err
+	}
+	stage("expand-home", path)
+	path, err = ps.expandEnv(path)
+	if err != nil {
+		return "", 0, "expand-env", err
+	}
+	stage("expand-env", path)
+	path, passes, err := ps.decodePasses(path)
+	if err != nil {
+		return "", 0, "decode", err
+	}
+	stage("decode", path)
+	path = ps.cfg.normalization.normalize(path)
+	stage("normalize", path)
+	path = ps.cfg.convertSeparators(path)
+	stage("native-separators", path)
+	path, err = ps.applySegmentPolicy(path)
+	if err != nil {
+		return "", passes, "segments", err
+	}
+	stage("segments", path)
+	return path, passes, "", nil
+}
+
+// ignoreStage is the stage function of preprocess, which reports no stages
+func ignoreStage(string, string) {}
+
+// prepare preprocesses and checks an input path before further processing
+// and reports any non-fatal concerns to the warn observer once it passes
+func (ps *PathSecurity) prepare(path string) (string, error) {
+	path, err := ps.preprocess(path)
+	if err != nil {
+		return "", err
+	}
+	if err := ps.check(path); err != nil {
+		return "", err
+	}
+	ps.warn(path)
+	return path, nil
+}
+
+// validate runs the backend validator on an already preprocessed path and
+// returns its JSON report, or a *ValidationError if the path is rejected
+func (ps *PathSecurity) validate(path string) (string, error) {
+	if ps.cfg.metrics != nil {
+		start := time.Now()
+		response, err := ps.validateUnobserved(path)
+		ps.cfg.metrics.ObserveValidation(time.Since(start), err == nil)
+		return response, err
+	}
+	return ps.validateUnobserved(path)
+}
+
+// validateUnobserved is validate without the metrics collector
+func (ps *PathSecurity) validateUnobserved(path string) (string, error) {
+	if ps.cfg.stdlibParity != ParityOff {
+		return ps.validateParity(path)
+	}
+	if ps.cfg.failThreshold > SeverityLow {
+		return ps.validateMonitored(path)
+	}
+
+	var response string
+	var err error
+	ruleset := RulesetVersion()
+	if entry, ok := ps.cachedValidation(path, ruleset); ok {
+		response, err = entry.response, entry.err
+	} else {
+		response, err = ps.validateUncached(path)
+		ps.cacheValidation(path, ruleset, response, err)
+	}
+	return ps.finishValidation(path, response, err)
+}
+
+// finishValidation applies the checks of validation that read the file
+// system, and so are never cached, to the result of the other checks
+func (ps *PathSecurity) finishValidation(path, response string, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	if verr := ps.cfg.checkSpecialFile(path); verr != nil {
+		return "", ps.reject(path, verr)
+	}
+	return response, nil
+}
+
+// validateUncached is validateUnobserved without the validation cache
+func (ps *PathSecurity) validateUncached(path string) (string, error) {
+	backendPath, err := ps.precheck(path)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := backendValidatePath(backendPath, ps.cfg.nativeTimeout)
+	if err != nil {
+		return "", err
+	}
+	return ps.interpret(path, response)
+}
+
+// precheck applies the binding's own checks ahead of the backend validator
+// and returns the form of the path to hand to the backend
+func (ps *PathSecurity) precheck(path string) (string, error) {
+	if verr := checkControlChars(path); verr != nil {
+		return "", ps.reject(path, verr)
+	}
+	if ps.cfg.treatEncodedSlashAsSeparator && hasDotDotSegment(splitEncodedSlashes(path)) {
+		return "", ps.reject(path, &ValidationError{Path: path, Reason: "Directory traversal through encoded slashes", Err: ErrTraversalDetected})
+	}
+	// Windows semantics report these as traversal segments below
+	if !ps.cfg.windowsSemantics && hasBackslashDotDot(path) {
+		return "", ps.reject(path, &ValidationError{Path: path, Reason: "Directory traversal through backslash separators", Err: ErrTraversalDetected})
+	}
+	if verr := checkUnicodeTricks(path); verr != nil {
+		return "", ps.reject(path, verr)
+	}
+	if verr := ps.cfg.checkShellConstructs(path); verr != nil {
+		return "", ps.reject(path, verr)
+	}
+	// An allowed network path is checked as the path on its server
+	checked, verr := ps.cfg.checkNetworkPath(path)
+	if verr != nil {
+		return "", ps.reject(path, verr)
+	}
+	checked, verr = ps.cfg.checkLongPath(checked)
+	if verr != nil {
+		return "", ps.reject(path, verr)
+	}
+	if verr := ps.cfg.checkTraversalPatterns(checked); verr != nil {
+		return "", ps.reject(path, verr)
+	}
+	if verr := ps.cfg.checkMagicLinks(checked); verr != nil {
+		return "", ps.reject(path, verr)
+	}
+	if err := ps.cfg.checkPolicy(checked); err != nil {
+		return "", ps.reject(path, err)
+	}
+	if err := ps.cfg.checkSensitive(checked); err != nil {
+		return "", ps.reject(path, err)
+	}
+	if verr := ps.cfg.checkDotfiles(checked); verr != nil {
+		return "", ps.reject(path, verr)
+	}
+	backendPath := checked
+	if ps.cfg.windowsSemantics {
+		if verr := checkWindowsPath(checked); verr != nil {
+			return "", ps.reject(path, verr)
+		}
+		backendPath = strings.ReplaceAll(checked, `\`, "/")
+	}
+	if verr := ps.cfg.checkWindowsAliases(checked); verr != nil {
+		return "", ps.reject(path, verr)
+	}
+	if verr := ps.cfg.checkFilesystem(checked); verr != nil {
+		return "", ps.reject(path, verr)
+	}
+	if ps.cfg.maxParentHops > 0 {
+		resolved, err := ps.cfg.resolveParentHops(backendPath)
+		if err != nil {
+			return "", ps.reject(path, err)
+		}
+		backendPath = resolved
+	}
+	return backendPath, nil
+}
+
+// interpret parses the backend validator's JSON report for path
+func (ps *PathSecurity) interpret(path, response string) (string, error) {
+	var parsed validateResponse
+	if err := json.Unmarshal([]byte(response), &parsed); err != nil {
+		return "", fmt.Errorf("%w: path validation returned malformed result: %v", ErrNativeFailure, err)
+	}
+	if !parsed.Valid {
+		return "", ps.reject(path, newValidationError(path, parsed.Error))
+	}
+	if verr := ps.checkRules(path); verr != nil {
+		return "", ps.reject(path, verr)
+	}
+	return response, nil
+}
 
-	return C.GoString((*C.char)(unsafe.Pointer(&result[0]))), nil
+// check validates an already preprocessed path, discarding the JSON report
+func (ps *PathSecurity) check(path string) error {
+	_, err := ps.validate(path)
+	return err
 }
@@ -0,0 +1,205 @@
+package pathsecurity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestValidatePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{path: "/usr/local/bin/app"},
+		{path: "docs/readme.md"},
+		{path: strings.Repeat("nested/", 400) + "file.txt"},
+		{path: "../../../etc/passwd", wantErr: ErrTraversalDetected},
+		{path: "/app/../../etc/passwd", wantErr: ErrTraversalDetected},
+		{path: "safe.txt\x00/../../etc/passwd", wantErr: ErrNullByte},
+		{path: "logs/app\n.log", wantErr: ErrControlCharacter},
+		{path: "name\x1b[31m.txt", wantErr: ErrControlCharacter},
+	}
+	ps := NewPathSecurity()
+	for _, tt := range tests {
+		_, err := ps.ValidatePath(tt.path)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidatePath(%.40q) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+		if tt.wantErr != nil {
+			var verr *ValidationError
+			if !errors.As(err, &verr) || verr.Path != tt.path {
+				t.Errorf("ValidatePath(%.40q) error = %#v, want a *ValidationError for the path", tt.path, err)
+			}
+		}
+	}
+}
+
+func TestDetectTraversal(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/usr/local/bin/app", want: false},
+		{path: "docs/My%20Files/a.txt", want: false},
+		{path: "../../../etc/passwd", want: true},
+		{path: "/app/../../etc/passwd", want: true},
+		{path: `..\..\windows\system32`, want: true},
+		{path: "%2e%2e%2fetc%2fpasswd", want: true},
+		{path: "a%2F..%2Fb", want: true},
+	}
+	ps := NewPathSecurity()
+	for _, tt := range tests {
+		got, err := ps.DetectTraversal(tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("DetectTraversal(%q) = %t, %v, want %t", tt.path, got, err, tt.want)
+		}
+	}
+
+	if _, err := ps.DetectTraversal("safe.txt\x00/../../etc/passwd"); !errors.Is(err, ErrNullByte) {
+		t.Errorf("DetectTraversal with a NUL byte error = %v, want %v", err, ErrNullByte)
+	}
+}
+
+func TestSanitizePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/var/www/html/../app/config.json", want: "/var/www/html/app/config.json"},
+		{path: "/home/user/documents/file.txt", want: "/home/user/documents/file.txt"},
+		{path: "/app/../../etc/passwd", want: "/app/etc/passwd"},
+		{path: "uploads/a.txt\x00.php", want: "uploads/a.txt.php"},
+	}
+	ps := NewPathSecurity()
+	for _, tt := range tests {
+		got, err := ps.SanitizePath(tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("SanitizePath(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+
+	// The result outgrows the initial native result buffer
+	long := strings.Repeat("a/../", 300) + "file.txt"
+	got, err := ps.SanitizePath(long)
+	if err != nil || strings.Contains(got, "..") {
+		t.Errorf("SanitizePath of a %d-byte path = %.40q, %v, want a path without traversal", len(long), got, err)
+	}
+}
+
+func TestSanitizeStrategies(t *testing.T) {
+	tests := []struct {
+		strategy SanitizeStrategy
+		path     string
+		want     string
+		wantErr  error
+	}{
+		{strategy: SanitizeStrip, path: "docs/../../etc/passwd", want: "docs/etc/passwd"},
+		{strategy: SanitizeCollapse, path: "docs/../../etc/passwd", want: "etc/passwd"},
+		{strategy: SanitizeReject, path: "docs/../../etc/passwd", wantErr: ErrTraversalDetected},
+		{strategy: SanitizeEncode, path: "docs/../../etc/passwd", want: "docs/%2E%2E/%2E%2E/etc/passwd"},
+		{strategy: SanitizeStrip, path: "reports/q1 summary.pdf", want: "reports/q1 summary.pdf"},
+		{strategy: SanitizeCollapse, path: "reports/q1 summary.pdf", want: "reports/q1 summary.pdf"},
+		{strategy: SanitizeReject, path: "reports/q1 summary.pdf", want: "reports/q1 summary.pdf"},
+		{strategy: SanitizeEncode, path: "reports/q1 summary.pdf", want: "reports/q1%20summary.pdf"},
+	}
+	for _, tt := range tests {
+		ps := NewPathSecurity(WithSanitizeStrategy(tt.strategy))
+		got, err := ps.SanitizePath(tt.path)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: SanitizePath(%q) = %q, %v, want %v", tt.strategy, tt.path, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: SanitizePath(%q) = %q, %v, want %q", tt.strategy, tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	ps := NewPathSecurity()
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			derived := ps.Clone(WithDeniedPatterns(fmt.Sprintf("secret%d", i)))
+			_, errs[i] = derived.ValidatePath(fmt.Sprintf("data/secret%d", i))
+			ps.DetectTraversal("../../../etc/passwd")
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if !errors.Is(err, ErrDeniedPattern) {
+			t.Errorf("clone %d accepted its denied path: %v", i, err)
+		}
+	}
+	if _, err := ps.ValidatePath("data/secret0"); err != nil {
+		t.Errorf("a clone's options changed the original instance: %v", err)
+	}
+}
+
+// FuzzValidatePath checks CheckInvariants on mutations of the traversal
+// payload corpus, with and without Windows semantics
+func FuzzValidatePath(f *testing.F) {
+	for _, payload := range TraversalPayloads() {
+		f.Add(payload, false)
+		f.Add(payload, true)
+	}
+
+	f.Fuzz(func(t *testing.T, p string, windows bool) {
+		ps := NewPathSecurity(WithWindowsSemantics(windows))
+		ps.ValidatePath(p)
+		if err := ps.CheckInvariants(p); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// FuzzDetectTraversal checks CheckInvariants on mutations of the traversal
+// payload corpus, with and without encoded slashes read as separators
+func FuzzDetectTraversal(f *testing.F) {
+	for _, payload := range TraversalPayloads() {
+		f.Add(payload, false)
+		f.Add(payload, true)
+	}
+
+	f.Fuzz(func(t *testing.T, p string, encodedSlash bool) {
+		ps := NewPathSecurity(WithTreatEncodedSlashAsSeparator(encodedSlash))
+		ps.DetectTraversal(p)
+		if err := ps.CheckInvariants(p); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func BenchmarkValidatePath(b *testing.B) {
+	ps := NewPathSecurity()
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ps.ValidatePath("/usr/local/bin/app")
+		}
+	})
+	cached := ps.Clone(WithValidationCache(NewValidationCache(1024)))
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			cached.ValidatePath("/usr/local/bin/app")
+		}
+	})
+}
+
+func BenchmarkDetectTraversal(b *testing.B) {
+	ps := NewPathSecurity()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ps.DetectTraversal("../../../etc/passwd")
+	}
+}
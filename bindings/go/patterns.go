@@ -0,0 +1,136 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// TraversalPattern describes a traversal technique as data, so that new
+// bypasses can be blocked by configuration rather than a release of the
+// library
+type TraversalPattern struct {
+	// Name identifies the pattern in rejection reasons
+	Name string `json:"name" yaml:"name"`
+	// Expr is a regular expression in the syntax of package regexp,
+	// matched anywhere in paths after preprocessing. An expression that
+	// starts with literal text only runs on paths containing it, so keep
+	// flags such as (?i) after that text where possible.
+	Expr string `json:"expr" yaml:"expr"`
+}
+
+// defaultTraversalPatterns are the bypasses of the embedded ruleset, checked
+// unless WithTraversalPatterns replaces them. The validator rejects most of them
+// too, with less specific reasons, but lets percent-encoded overlong UTF-8
+// and double encoding through unless they are decoded.
+var defaultTraversalPatterns = []TraversalPattern{
+	// "....//" leaves "../" behind when a filter deletes "../" once
+	{Name: "dot-dot-collapse", Expr: `\.\.\.\.[/\\]`},
+	// Tomcat and other servlet containers cut path parameters from
+	// segments, leaving ".."
+	{Name: "path-parameter", Expr: `\.\.;`},
+	// IIS decodes %u escapes, including fullwidth and mathematical slashes
+	{Name: "percent-u-separator", Expr: `%(?i:u(002[ef]|005c|2215|2216|2044|ff0[ef]|ff3c))`},
+	{Name: "overlong-utf8", Expr: `%(?i:c0%(ae|af|2e|2f)|c1%(1c|9c)|e0%80%(ae|af))`},
+	{Name: "double-encoding", Expr: `%25(?i:2e|2f|5c)`},
+}
+
+// TraversalPatterns is a compiled set of traversal patterns. It is
+// immutable and safe for concurrent use, so one set can be shared by any
+// number of PathSecurity values.
+type TraversalPatterns struct {
+	patterns []TraversalPattern
+	compiled []*regexp.Regexp
+	// prefixes are the literal texts every match of the patterns starts
+	// with, so most paths are ruled out without running the expressions
+	prefixes []string
+}
+
+// TraversalMatch is where a traversal pattern matches a path
+type TraversalMatch struct {
+	Pattern TraversalPattern
+	// Text is the matched text and Offset its byte offset in the path
+	Text   string
+	Offset int
+}
+
+// CompileTraversalPatterns compiles patterns into a set. Every pattern
+// needs a unique name and an expression that compiles and does not match
+// the empty string.
+func CompileTraversalPatterns(patterns ...TraversalPattern) (*TraversalPatterns, error) {
+	set := &TraversalPatterns{
+		patterns: slices.Clone(patterns),
+		compiled: make([]*regexp.Regexp, len(patterns)),
+		prefixes: make([]string, len(patterns)),
+	}
+	for i, p := range patterns {
+		switch {
+		case p.Name == "":
+			return nil, fmt.Errorf("traversal pattern %d has no name", i)
+		case slices.ContainsFunc(patterns[:i], func(q TraversalPattern) bool { return q.Name == p.Name }):
+			return nil, fmt.Errorf("traversal pattern %q is defined twice", p.Name)
+		}
+		re, err := regexp.Compile(p.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("traversal pattern %q: %w", p.Name, err)
+		}
+		if re.MatchString("") {
+			return nil, fmt.Errorf("traversal pattern %q matches the empty string", p.Name)
+		}
+		set.compiled[i] = re
+		set.prefixes[i], _ = re.LiteralPrefix()
+	}
+	return set, nil
+}
+
+// DefaultTraversalPatterns returns the set checked unless
+// WithTraversalPatterns replaces it, that of the ruleset in use. The
+// embedded ruleset checks dot-dot collapse such as "....//", path
+// parameters such as "..;/", %u escapes of separators and dots, overlong
+// UTF-8 and double encoding of separators and dots. Extend it with With,
+// or with WithExtraTraversalPatterns to keep following rulesets put in use
+// later with UseRuleset.
+func DefaultTraversalPatterns() *TraversalPatterns {
+	return currentRuleset().patterns
+}
+
+// Patterns returns the definitions of the set, in order
+func (t *TraversalPatterns) Patterns() []TraversalPattern {
+	return slices.Clone(t.patterns)
+}
+
+// With returns a new set with the patterns of t followed by patterns
+func (t *TraversalPatterns) With(patterns ...TraversalPattern) (*TraversalPatterns, error) {
+	return CompileTraversalPatterns(append(slices.Clone(t.patterns), patterns...)...)
+}
+
+// Match returns the match of the first pattern of the set, in order, that
+// matches p, and false if none does
+func (t *TraversalPatterns) Match(p string) (TraversalMatch, bool) {
+	for i, re := range t.compiled {
+		if !strings.Contains(p, t.prefixes[i]) {
+			continue
+		}
+		if loc := re.FindStringIndex(p); loc != nil {
+			return TraversalMatch{Pattern: t.patterns[i], Text: p[loc[0]:loc[1]], Offset: loc[0]}, true
+		}
+	}
+	return TraversalMatch{}, false
+}
+
+// checkTraversalPatterns rejects a path matching a traversal pattern
+func (c *config) checkTraversalPatterns(p string) *ValidationError {
+	set := c.traversalPatterns
+	if set == nil {
+		set = DefaultTraversalPatterns()
+	}
+	m, ok := set.Match(p)
+	if !ok && c.extraTraversalPatterns != nil {
+		m, ok = c.extraTraversalPatterns.Match(p)
+	}
+	if !ok {
+		return nil
+	}
+	return &ValidationError{Path: p, Reason: fmt.Sprintf("Traversal pattern %q matched %q at offset %d", m.Pattern.Name, m.Text, m.Offset), Err: ErrTraversalDetected}
+}
@@ -0,0 +1,73 @@
+package pathsecurity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTraversalPatternsMatch(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantPattern string
+		wantText    string
+		wantOffset  int
+	}{
+		{"static/....//secret", "dot-dot-collapse", "..../", 7},
+		{"app/..;/manager/html", "path-parameter", "..;", 4},
+		{"files/%U2216etc", "percent-u-separator", "%U2216", 6},
+		{"files/%c0%ae%c0%ae/etc", "overlong-utf8", "%c0%ae", 6},
+		{"files/%252e%252e/etc", "double-encoding", "%252e", 6},
+		{"docs/report.pdf", "", "", 0},
+	}
+	patterns := DefaultTraversalPatterns()
+	for _, tt := range tests {
+		match, ok := patterns.Match(tt.path)
+		if ok != (tt.wantPattern != "") || match.Pattern.Name != tt.wantPattern || match.Text != tt.wantText || match.Offset != tt.wantOffset {
+			t.Errorf("Match(%q) = %+v, %t, want %s matching %q at %d", tt.path, match, ok, tt.wantPattern, tt.wantText, tt.wantOffset)
+		}
+	}
+}
+
+func TestWithTraversalPatterns(t *testing.T) {
+	ps := NewPathSecurity()
+	for _, p := range []string{"static/....//secret", "app/..;/manager/html", "files/%U2216etc"} {
+		if _, err := ps.ValidatePath(p); !errors.Is(err, ErrTraversalDetected) {
+			t.Errorf("ValidatePath(%q) error = %v, want %v", p, err, ErrTraversalDetected)
+		}
+	}
+
+	extended, err := DefaultTraversalPatterns().With(TraversalPattern{Name: "nginx-merge-slashes", Expr: `^/+static\.\.`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nginx := ps.Clone(WithTraversalPatterns(extended), WithAllowAbsolute(true))
+	if _, err := nginx.ValidatePath("/static../config"); !errors.Is(err, ErrTraversalDetected) || !strings.Contains(err.Error(), "nginx-merge-slashes") {
+		t.Errorf("ValidatePath with a custom pattern error = %v, want a match of nginx-merge-slashes", err)
+	}
+
+	policy, err := LoadPolicy(strings.NewReader(`{"traversal_patterns": [{"name": "semicolon", "expr": ";"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policy.Check("a;b"); !errors.Is(err, ErrTraversalDetected) {
+		t.Errorf("policy traversal pattern error = %v, want %v", err, ErrTraversalDetected)
+	}
+}
+
+func TestCompileTraversalPatternsErrors(t *testing.T) {
+	tests := []struct {
+		pattern TraversalPattern
+		want    string
+	}{
+		{TraversalPattern{Name: "", Expr: "x"}, "has no name"},
+		{TraversalPattern{Name: "dot-dot-collapse", Expr: "x"}, "defined twice"},
+		{TraversalPattern{Name: "broken", Expr: "(["}, "error parsing regexp"},
+		{TraversalPattern{Name: "empty", Expr: "a*"}, "matches the empty string"},
+	}
+	for _, tt := range tests {
+		if _, err := DefaultTraversalPatterns().With(tt.pattern); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("With(%+v) error = %v, want one containing %q", tt.pattern, err, tt.want)
+		}
+	}
+}
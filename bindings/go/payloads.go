@@ -0,0 +1,81 @@
+package pathsecurity
+
+// traversalPayloads is a curated corpus of traversal payloads in plain,
+// encoded, Unicode, Windows, mixed-separator and magic symlink forms
+var traversalPayloads = []string{
+	// Plain
+	"../etc/passwd",
+	"../../../../../../etc/shadow",
+	"docs/../../secret",
+	"./../.ssh/id_rsa",
+	"/var/www/../../etc/hosts",
+	"..",
+	"a/b/../../../c",
+	"....//....//etc/passwd",
+	".../.../etc",
+
+	// Percent-encoded
+	"%2e%2e/etc/passwd",
+	"%2e%2e%2fetc%2fpasswd",
+	"..%2fetc%2fpasswd",
+	"%2E%2E%5Cwindows%5Cwin.ini",
+	"..%5c..%5cboot.ini",
+	"%252e%252e%252fetc%252fpasswd",
+	"%25252e%25252e/etc",
+	"..%c0%afetc%c0%afpasswd",
+	"%c0%ae%c0%ae/etc/passwd",
+	"%e0%80%ae%e0%80%ae/etc",
+	"..%u2215etc",
+	"..&#47;etc&#47;passwd",
+	"uploads/%00../../etc/passwd",
+
+	// Unicode
+	"\uff0e\uff0e/etc/passwd",
+	"..\u2044etc\u2044passwd",
+	"..\u2215etc",
+	"\u2025/etc",
+	".\u200b./etc/passwd",
+	"invoice\u202efdp.exe",
+	"..\uff0fetc",
+	"\ufe52\ufe52/etc",
+
+	// Windows
+	`..\..\windows\system32\config\sam`,
+	`C:\Windows\..\..\boot.ini`,
+	`\\server\share\..\admin$`,
+	`\\?\C:\Windows\System32`,
+	`\\.\PhysicalDrive0`,
+	"CON", "aux.txt", "LPT1.log",
+	"file.txt:hidden:$DATA",
+	"web.config::$DATA",
+	"PROGRA~1/secret",
+	"trailing.dot.",
+	"trailing space ",
+
+	// Mixed separators
+	`..\../..\./etc/passwd`,
+	`../..\windows/win.ini`,
+	`.\..\/..//etc`,
+	`..\/etc`,
+	`/..\..\/etc/passwd`,
+
+	// Magic symlinks
+	"/proc/self/root/etc/passwd",
+	"/proc/1/root/etc/shadow",
+	"/proc/self/fd/3",
+	"/dev/fd/3",
+
+	// Control characters
+	"file.txt\x00.png",
+	"../etc/passwd\x00",
+	"line\nbreak/../etc",
+	"\x1b[31m../etc",
+}
+
+// TraversalPayloads returns a copy of a curated corpus of traversal
+// payloads, covering plain, percent-encoded, overlong UTF-8, Unicode
+// lookalike, Windows, mixed-separator and magic symlink forms, for seeding
+// fuzzers and regression checks
+func TraversalPayloads() []string {
+	return append([]string(nil), traversalPayloads...)
+}
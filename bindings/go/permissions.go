@@ -0,0 +1,102 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Operation is a kind of file access checked by Authorize
+type Operation int
+
+const (
+	// OpRead reads a file or lists a directory
+	OpRead Operation = iota
+	// OpWrite creates, modifies or removes a file
+	OpWrite
+	// OpExecute runs a file as a program or script
+	OpExecute
+)
+
+// String returns the name of the operation
+func (op Operation) String() string {
+	switch op {
+	case OpRead:
+		return "read"
+	case OpWrite:
+		return "write"
+	case OpExecute:
+		return "execute"
+	}
+	return fmt.Sprintf("Operation(%d)", int(op))
+}
+
+// permission returns the Permission bit that allows op
+func (op Operation) permission() Permission {
+	switch op {
+	case OpRead:
+		return PermRead
+	case OpWrite:
+		return PermWrite
+	case OpExecute:
+		return PermExecute
+	}
+	return 0
+}
+
+// Permission is a set of operations allowed under a root registered with
+// WithRoot
+type Permission uint8
+
+const (
+	// PermRead allows OpRead
+	PermRead Permission = 1 << iota
+	// PermWrite allows OpWrite
+	PermWrite
+	// PermExecute allows OpExecute
+	PermExecute
+)
+
+const (
+	// ReadOnly allows reading and executing but not writing
+	ReadOnly = PermRead | PermExecute
+	// ReadWrite allows every operation
+	ReadWrite = PermRead | PermWrite | PermExecute
+)
+
+// String returns the permissions in the style of ls, such as "r-x"
+func (p Permission) String() string {
+	var b strings.Builder
+	for _, flag := range []struct {
+		perm Permission
+		c    byte
+	}{{PermRead, 'r'}, {PermWrite, 'w'}, {PermExecute, 'x'}} {
+		if p&flag.perm != 0 {
+			b.WriteByte(flag.c)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// Authorize checks that op may be performed on path. The path is resolved
+// as by ValidateResolved and must lie under an allowed root; the most
+// specific root containing it decides, so a read-only root may hold a
+// writable subdirectory registered separately. Roots added with
+// WithAllowedRoots rather than WithRoot permit every operation. An
+// operation the root does not permit is rejected with ErrOperationDenied.
+func (ps *PathSecurity) Authorize(path string, op Operation) error {
+	resolved, root, err := ps.resolveInRoot(path)
+	if err != nil {
+		return err
+	}
+
+	perms, ok := ps.cfg.rootPermissions[root]
+	if !ok {
+		return nil
+	}
+	if perms&op.permission() == 0 {
+		return ps.reject(path, fmt.Errorf("%w: %s of %q under %q (%s)", ErrOperationDenied, op, resolved, root, perms))
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package pathsecurity
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	root := testTree(t)
+	tests := []struct {
+		name    string
+		op      Operation
+		wantErr error
+	}{
+		{name: "locked/report.txt", op: OpRead},
+		{name: "locked/report.txt", op: OpExecute},
+		{name: "locked/report.txt", op: OpWrite, wantErr: ErrOperationDenied},
+		{name: "inbox/new.txt", op: OpWrite},
+		{name: "inbox/hello.txt", op: OpRead},
+		{name: "inbox/run.sh", op: OpExecute, wantErr: ErrOperationDenied},
+		{name: "inbox/escape/loot.txt", op: OpRead, wantErr: ErrSymlinkEscape},
+	}
+	ps := NewPathSecurity(
+		WithRoot(root, ReadOnly),
+		WithRoot(filepath.Join(root, "inbox"), ReadWrite&^PermExecute),
+	)
+	for _, tt := range tests {
+		if err := ps.Authorize(filepath.Join(root, tt.name), tt.op); !errors.Is(err, tt.wantErr) {
+			t.Errorf("Authorize(%q, %s) = %v, want %v", tt.name, tt.op, err, tt.wantErr)
+		}
+	}
+}
+
+func TestOperationString(t *testing.T) {
+	for op, want := range map[Operation]string{
+		OpRead:       "read",
+		OpWrite:      "write",
+		OpExecute:    "execute",
+		Operation(5): "Operation(5)",
+	} {
+		if got := op.String(); got != want {
+			t.Errorf("Operation(%d).String() = %q, want %q", int(op), got, want)
+		}
+	}
+}
@@ -0,0 +1,504 @@
+package pathsecurity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// PolicySpec is the declarative form of a configuration, as read by
+// LoadPolicy, so that security policy can live in configuration management
+// rather than in code. Each field maps to the option of the same name;
+// fields left out keep the defaults of NewPathSecurity. The yaml tags let
+// callers decode YAML documents with the library of their choice and pass
+// the result to NewPolicy.
+type PolicySpec struct {
+	AllowedRoots []string   `json:"allowed_roots,omitempty" yaml:"allowed_roots,omitempty"`
+	Roots        []RootSpec `json:"roots,omitempty" yaml:"roots,omitempty"`
+
+	DeniedPatterns []string `json:"denied_patterns,omitempty" yaml:"denied_patterns,omitempty"`
+	// TraversalPatterns are checked in addition to those of the ruleset in
+	// use
+	TraversalPatterns []TraversalPattern `json:"traversal_patterns,omitempty" yaml:"traversal_patterns,omitempty"`
+	// DefaultSensitivePaths adds DefaultSensitivePaths to SensitivePaths
+	DefaultSensitivePaths bool     `json:"default_sensitive_paths,omitempty" yaml:"default_sensitive_paths,omitempty"`
+	SensitivePaths        []string `json:"sensitive_paths,omitempty" yaml:"sensitive_paths,omitempty"`
+	AllowAbsolute         *bool    `json:"allow_absolute,omitempty" yaml:"allow_absolute,omitempty"`
+	MaxPathLength         int      `json:"max_path_length,omitempty" yaml:"max_path_length,omitempty"`
+	MaxInputBytes         int      `json:"max_input_bytes,omitempty" yaml:"max_input_bytes,omitempty"`
+	RawBytes              bool     `json:"raw_bytes,omitempty" yaml:"raw_bytes,omitempty"`
+	MaxDepth              int      `json:"max_depth,omitempty" yaml:"max_depth,omitempty"`
+	MaxParentHops         int      `json:"max_parent_hops,omitempty" yaml:"max_parent_hops,omitempty"`
+	RejectShortNames      bool     `json:"reject_short_names,omitempty" yaml:"reject_short_names,omitempty"`
+	VerbatimPaths         bool     `json:"verbatim_paths,omitempty" yaml:"verbatim_paths,omitempty"`
+	WindowsMaxPath        bool     `json:"windows_max_path,omitempty" yaml:"windows_max_path,omitempty"`
+
+	AllowedExtensions []string `json:"allowed_extensions,omitempty" yaml:"allowed_extensions,omitempty"`
+	DeniedExtensions  []string `json:"denied_extensions,omitempty" yaml:"denied_extensions,omitempty"`
+	MaxFilenameLength int      `json:"max_filename_length,omitempty" yaml:"max_filename_length,omitempty"`
+	SlugFilenames     bool     `json:"slug_filenames,omitempty" yaml:"slug_filenames,omitempty"`
+
+	// SourceEncoding is "utf-8", "shift-jis", "latin-1" or "windows-1252"
+	SourceEncoding string `json:"source_encoding,omitempty" yaml:"source_encoding,omitempty"`
+	// Decoding is "none", "once", "recursive" or "reject"
+	Decoding string `json:"decoding,omitempty" yaml:"decoding,omitempty"`
+	// Normalization is "none", "NFC" or "NFKC"
+	Normalization string `json:"normalization,omitempty" yaml:"normalization,omitempty"`
+	// Shell is "keep", "reject" or "expand"
+	Shell string `json:"shell,omitempty" yaml:"shell,omitempty"`
+	// AllowedEnv names the process environment variables that $NAME,
+	// ${NAME} and, under "expand", %NAME% references resolve against
+	AllowedEnv []string `json:"allowed_env,omitempty" yaml:"allowed_env,omitempty"`
+	// Tilde is "keep", "reject" or "expand"
+	Tilde string `json:"tilde,omitempty" yaml:"tilde,omitempty"`
+	// HomeDir is the directory "~" expands to under "expand". When it is
+	// empty, "~" and "~user" expand to home directories from the operating
+	// system.
+	HomeDir string `json:"home_dir,omitempty" yaml:"home_dir,omitempty"`
+	// Network is "keep", "reject", "allow" or "allow-hosts"
+	Network string `json:"network,omitempty" yaml:"network,omitempty"`
+	// NetworkHosts are the hosts network paths may name under
+	// "allow-hosts"
+	NetworkHosts []string `json:"network_hosts,omitempty" yaml:"network_hosts,omitempty"`
+	// Dotfiles is "allow", "deny" or "allowlist"
+	Dotfiles string `json:"dotfiles,omitempty" yaml:"dotfiles,omitempty"`
+	// AllowedDotfiles are the hidden names accepted under "allowlist"
+	AllowedDotfiles []string `json:"allowed_dotfiles,omitempty" yaml:"allowed_dotfiles,omitempty"`
+	// Filesystem is "any", "ext4", "ntfs", "fat", "apfs", "smb" or "nfs"
+	Filesystem string `json:"filesystem,omitempty" yaml:"filesystem,omitempty"`
+	// TargetChecks are "exists", "regular" and "not-world-writable"
+	TargetChecks []string `json:"target_checks,omitempty" yaml:"target_checks,omitempty"`
+	// TargetUID and TargetGID are passed to WithTargetOwner when either is
+	// set; the other then matches any
+	TargetUID *int `json:"target_uid,omitempty" yaml:"target_uid,omitempty"`
+	TargetGID *int `json:"target_gid,omitempty" yaml:"target_gid,omitempty"`
+	// Segments is "keep", "collapse" or "reject"
+	Segments string `json:"segments,omitempty" yaml:"segments,omitempty"`
+	// SanitizeStrategy is "strip", "collapse", "reject" or "encode"
+	SanitizeStrategy    string `json:"sanitize_strategy,omitempty" yaml:"sanitize_strategy,omitempty"`
+	CaseInsensitive     bool   `json:"case_insensitive,omitempty" yaml:"case_insensitive,omitempty"`
+	NormalizeSeparators bool   `json:"normalize_separators,omitempty" yaml:"normalize_separators,omitempty"`
+	NativeSeparators    bool   `json:"native_separators,omitempty" yaml:"native_separators,omitempty"`
+	UnicodeFolding      bool   `json:"unicode_folding,omitempty" yaml:"unicode_folding,omitempty"`
+	WindowsSemantics    *bool  `json:"windows_semantics,omitempty" yaml:"windows_semantics,omitempty"`
+	// TargetOS is passed to WithTargetOS, named as in runtime.GOOS;
+	// windows_semantics and a true case_insensitive override its rules
+	TargetOS string `json:"target_os,omitempty" yaml:"target_os,omitempty"`
+	// StdlibParity is "off", "is-local" or "valid-path"
+	StdlibParity string `json:"stdlib_parity,omitempty" yaml:"stdlib_parity,omitempty"`
+
+	// RejectSeverity makes Policy.Check reject paths for which Analyze
+	// reports a finding of at least this severity, "low" through
+	// "critical", even when validation accepts them
+	RejectSeverity string `json:"reject_severity,omitempty" yaml:"reject_severity,omitempty"`
+	// FailThreshold is passed to WithFailThreshold: "low" through
+	// "critical", or "monitor-only"
+	FailThreshold string `json:"fail_threshold,omitempty" yaml:"fail_threshold,omitempty"`
+}
+
+// RootSpec is an allowed root with the permissions given to WithRoot
+type RootSpec struct {
+	Path string `json:"path" yaml:"path"`
+	// Permissions are written like "r-x"; the default is "rwx"
+	Permissions string `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+}
+
+// Policy is a PathSecurity built from a PolicySpec, together with the
+// rules of the spec that are not options. Its rules can be replaced while
+// it is in use with Update, Reload or Watch; each call works with the rules
+// in force when it started, so in-flight validations are never mixed
+// between two rule sets. A Policy is safe for concurrent use.
+type Policy struct {
+	opts  []Option
+	state atomic.Pointer[policyState]
+}
+
+// policyState is one rule set of a Policy
+type policyState struct {
+	spec         PolicySpec
+	ps           *PathSecurity
+	threshold    Severity
+	hasThreshold bool
+}
+
+// LoadPolicy reads a JSON PolicySpec from r and builds a Policy from it with
+// NewPolicy. Unknown fields are rejected, so a misspelled rule fails
+// loudly instead of being ignored.
+func LoadPolicy(r io.Reader, opts ...Option) (*Policy, error) {
+	spec, err := decodePolicySpec(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewPolicy(spec, opts...)
+}
+
+// NewPolicy builds a Policy from spec. Options in opts are applied after
+// those derived from the spec, for settings that only code can provide,
+// such as WithAuditFunc or WithMetrics, and are kept across updates.
+func NewPolicy(spec PolicySpec, opts ...Option) (*Policy, error) {
+	p := &Policy{opts: opts}
+	if err := p.Update(spec); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// decodePolicySpec reads a JSON PolicySpec, rejecting unknown fields
+func decodePolicySpec(r io.Reader) (PolicySpec, error) {
+	var spec PolicySpec
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&spec); err != nil {
+		return PolicySpec{}, fmt.Errorf("policy: %w", err)
+	}
+	return spec, nil
+}
+
+// Update atomically replaces the policy's rules with those of spec. If
+// spec is invalid the error is returned and the current rules stay in
+// force.
+func (p *Policy) Update(spec PolicySpec) error {
+	specOpts, err := spec.options()
+	if err != nil {
+		return err
+	}
+	state := &policyState{spec: spec, ps: NewPathSecurity(append(specOpts, p.opts...)...)}
+	if spec.RejectSeverity != "" {
+		state.threshold, err = parseName("reject_severity", spec.RejectSeverity, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical)
+		if err != nil {
+			return err
+		}
+		state.hasThreshold = true
+	}
+	p.state.Store(state)
+	return nil
+}
+
+// Reload reads a JSON PolicySpec from r, as LoadPolicy does, and applies it
+// with Update
+func (p *Policy) Reload(r io.Reader) error {
+	spec, err := decodePolicySpec(r)
+	if err != nil {
+		return err
+	}
+	return p.Update(spec)
+}
+
+// policyWatchInterval is how often Watch looks for changes to the file
+const policyWatchInterval = time.Second
+
+// Watch reloads the policy from file whenever its size or modification
+// time changes, checking once a second, until ctx is done, and then
+// returns ctx.Err(). The file is loaded once when Watch starts. After
+// every reload attempt onReload, if not nil, is called with nil or with
+// the error that left the previous rules in force, so a broken edit never
+// takes down a running fleet. A missing file is reported once and keeps
+// the previous rules as well.
+func (p *Policy) Watch(ctx context.Context, file string, onReload func(error)) error {
+	var last fs.FileInfo
+	missing := false
+	ticker := time.NewTicker(policyWatchInterval)
+	defer ticker.Stop()
+	for {
+		info, err := os.Stat(file)
+		switch {
+		case err != nil:
+			// Reported once until the file is back
+			if !missing && onReload != nil {
+				onReload(err)
+			}
+			missing = true
+		case missing || last == nil || info.Size() != last.Size() || !info.ModTime().Equal(last.ModTime()):
+			missing, last = false, info
+			err := p.reloadFile(file)
+			if onReload != nil {
+				onReload(err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// reloadFile applies the JSON PolicySpec in file
+func (p *Policy) reloadFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return p.Reload(f)
+}
+
+// Spec returns the spec of the rules in force
+func (p *Policy) Spec() PolicySpec {
+	return p.state.Load().spec
+}
+
+// PathSecurity returns the PathSecurity of the rules in force. It keeps
+// those rules when the policy is later updated.
+func (p *Policy) PathSecurity() *PathSecurity {
+	return p.state.Load().ps
+}
+
+// Check validates path as ValidatePath does and, when the spec sets
+// RejectSeverity, also rejects it with ErrSeverityExceeded if Analyze
+// reports a finding of that severity or above. A finding below the
+// threshold of WithFailThreshold is only reported as monitored.
+func (p *Policy) Check(path string) error {
+	state := p.state.Load()
+	if _, err := state.ps.ValidatePath(path); err != nil {
+		return err
+	}
+	if !state.hasThreshold {
+		return nil
+	}
+
+	report, err := state.ps.Analyze(path)
+	if err != nil {
+		return err
+	}
+	if severity, ok := report.MaxSeverity(); ok && severity >= state.threshold {
+		err := fmt.Errorf("%w: %q has a %s finding", ErrSeverityExceeded, path, severity)
+		if severity < state.ps.cfg.failThreshold {
+			state.ps.monitor(path, err, severity)
+			return nil
+		}
+		return state.ps.reject(path, err)
+	}
+	return nil
+}
+
+// options translates the spec into options
+func (s PolicySpec) options() ([]Option, error) {
+	opts := []Option{
+		WithAllowedRoots(s.AllowedRoots...),
+		WithDeniedPatterns(s.DeniedPatterns...),
+		WithMaxPathLength(s.MaxPathLength),
+		WithMaxInputBytes(s.MaxInputBytes),
+		WithRawBytes(s.RawBytes),
+		WithMaxDepth(s.MaxDepth),
+		WithMaxParentHops(s.MaxParentHops),
+		WithRejectShortNames(s.RejectShortNames),
+		WithVerbatimPaths(s.VerbatimPaths),
+		WithWindowsMaxPath(s.WindowsMaxPath),
+		WithMaxFilenameLength(s.MaxFilenameLength),
+		WithSlugFilenames(s.SlugFilenames),
+		WithCaseInsensitive(s.CaseInsensitive),
+		WithNormalizeSeparators(s.NormalizeSeparators),
+		WithNativeSeparators(s.NativeSeparators),
+		WithUnicodeFolding(s.UnicodeFolding),
+	}
+	if s.TargetOS != "" {
+		if !slices.Contains(targetOSNames, s.TargetOS) {
+			return nil, fmt.Errorf("policy: target_os %q is not one of %s", s.TargetOS, strings.Join(targetOSNames, ", "))
+		}
+		opts = append(opts, WithTargetOS(s.TargetOS))
+		if s.CaseInsensitive {
+			opts = append(opts, WithCaseInsensitive(true))
+		}
+	}
+	for _, root := range s.Roots {
+		perms := ReadWrite
+		if root.Permissions != "" {
+			var err error
+			if perms, err = parsePermission(root.Permissions); err != nil {
+				return nil, fmt.Errorf("policy: root %q: %w", root.Path, err)
+			}
+		}
+		opts = append(opts, WithRoot(root.Path, perms))
+	}
+
+	if len(s.TraversalPatterns) > 0 {
+		patterns, err := CompileTraversalPatterns(s.TraversalPatterns...)
+		if err != nil {
+			return nil, fmt.Errorf("policy: %w", err)
+		}
+		opts = append(opts, WithExtraTraversalPatterns(patterns))
+	}
+
+	sensitive := s.SensitivePaths
+	if s.DefaultSensitivePaths {
+		sensitive = append(DefaultSensitivePaths(), sensitive...)
+	}
+	if len(sensitive) > 0 {
+		opts = append(opts, WithSensitivePaths(sensitive...))
+	}
+	if s.AllowAbsolute != nil {
+		opts = append(opts, WithAllowAbsolute(*s.AllowAbsolute))
+	}
+	if len(s.AllowedExtensions) > 0 {
+		opts = append(opts, WithAllowedExtensions(s.AllowedExtensions...))
+	}
+	if len(s.DeniedExtensions) > 0 {
+		opts = append(opts, WithDeniedExtensions(s.DeniedExtensions...))
+	}
+	if s.WindowsSemantics != nil {
+		opts = append(opts, WithWindowsSemantics(*s.WindowsSemantics))
+	}
+
+	if s.SourceEncoding != "" {
+		enc, err := parseName("source_encoding", s.SourceEncoding, EncodingUTF8, EncodingShiftJIS, EncodingLatin1, EncodingWindows1252)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithSourceEncoding(enc))
+	}
+	if s.Decoding != "" {
+		policy, err := parseName("decoding", s.Decoding, DecodeNone, DecodeOnce, DecodeRecursive, RejectEncoded)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithDecoding(policy))
+	}
+	if s.Normalization != "" {
+		form, err := parseName("normalization", s.Normalization, NormalizeNone, NormalizeNFC, NormalizeNFKC)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithUnicodeNormalization(form))
+	}
+	if len(s.AllowedEnv) > 0 {
+		opts = append(opts, WithExpandEnv(AllowlistedEnv(s.AllowedEnv...)))
+	}
+	if s.Shell != "" {
+		policy, err := parseName("shell", s.Shell, ShellKeep, ShellReject, ShellExpand)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithShellPolicy(policy))
+	}
+	if s.Tilde != "" {
+		policy, err := parseName("tilde", s.Tilde, TildeKeep, TildeReject, TildeExpand)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithTildePolicy(policy))
+	}
+	switch {
+	case s.HomeDir != "":
+		home := s.HomeDir
+		opts = append(opts, WithHomeDirs(func(user string) (string, bool) {
+			return home, user == ""
+		}))
+	case strings.EqualFold(s.Tilde, TildeExpand.String()):
+		opts = append(opts, WithHomeDirs(LookupHomeDir))
+	}
+	if s.Network != "" {
+		policy, err := parseName("network", s.Network, NetworkKeep, NetworkReject, NetworkAllow, NetworkAllowHosts)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithNetworkPolicy(policy))
+	}
+	if len(s.NetworkHosts) > 0 {
+		opts = append(opts, WithNetworkHosts(s.NetworkHosts...))
+	}
+	if s.Dotfiles != "" {
+		policy, err := parseName("dotfiles", s.Dotfiles, DotfilesAllow, DotfilesDeny, DotfilesAllowlist)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithDotfilePolicy(policy))
+	}
+	if len(s.AllowedDotfiles) > 0 {
+		opts = append(opts, WithAllowedDotfiles(s.AllowedDotfiles...))
+	}
+	if s.Filesystem != "" {
+		profile, err := parseName("filesystem", s.Filesystem, FilesystemAny, FilesystemExt4, FilesystemNTFS, FilesystemFAT, FilesystemAPFS, FilesystemSMB, FilesystemNFS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithFilesystemProfile(profile))
+	}
+	for _, name := range s.TargetChecks {
+		check, err := parseName("target_checks", name, TargetExists, TargetRegular, TargetNotWorldWritable)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithTargetChecks(check))
+	}
+	if s.TargetUID != nil || s.TargetGID != nil {
+		uid, gid := -1, -1
+		if s.TargetUID != nil {
+			uid = *s.TargetUID
+		}
+		if s.TargetGID != nil {
+			gid = *s.TargetGID
+		}
+		opts = append(opts, WithTargetOwner(uid, gid))
+	}
+	if s.Segments != "" {
+		policy, err := parseName("segments", s.Segments, SegmentsKeep, SegmentsCollapse, SegmentsReject)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithSegmentPolicy(policy))
+	}
+	if s.StdlibParity != "" {
+		parity, err := parseName("stdlib_parity", s.StdlibParity, ParityOff, ParityIsLocal, ParityValidPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithStdlibParity(parity))
+	}
+	if s.SanitizeStrategy != "" {
+		strategy, err := parseName("sanitize_strategy", s.SanitizeStrategy, SanitizeStrip, SanitizeCollapse, SanitizeReject, SanitizeEncode)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithSanitizeStrategy(strategy))
+	}
+	if s.FailThreshold != "" {
+		threshold, err := parseName("fail_threshold", s.FailThreshold, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical, MonitorOnly)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithFailThreshold(threshold))
+	}
+	return opts, nil
+}
+
+// parseName returns the value among values whose String matches name,
+// ignoring case
+func parseName[T fmt.Stringer](field, name string, values ...T) (T, error) {
+	names := make([]string, len(values))
+	for i, v := range values {
+		if strings.EqualFold(v.String(), name) {
+			return v, nil
+		}
+		names[i] = v.String()
+	}
+	var zero T
+	return zero, fmt.Errorf("policy: %s %q is not one of %s", field, name, strings.Join(names, ", "))
+}
+
+// parsePermission parses permissions written like "r-x" or "rw"
+func parsePermission(s string) (Permission, error) {
+	var perms Permission
+	for _, c := range s {
+		switch c {
+		case 'r':
+			perms |= PermRead
+		case 'w':
+			perms |= PermWrite
+		case 'x':
+			perms |= PermExecute
+		case '-':
+		default:
+			return 0, fmt.Errorf("invalid permissions %q", s)
+		}
+	}
+	return perms, nil
+}
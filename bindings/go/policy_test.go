@@ -0,0 +1,124 @@
+package pathsecurity
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPolicy = `{
+	"roots": [{"path": "/srv/static", "permissions": "r--"}, {"path": "/srv/uploads", "permissions": "rw-"}],
+	"denied_patterns": ["*.bak", ".git"],
+	"decoding": "recursive",
+	"max_depth": 8,
+	"reject_severity": "high"
+}`
+
+func TestLoadPolicy(t *testing.T) {
+	policy, err := LoadPolicy(strings.NewReader(testPolicy))
+	if err != nil {
+		t.Fatalf("LoadPolicy error = %v", err)
+	}
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{path: "/srv/static/css/site.css"},
+		{path: "/srv/uploads/.profile"},
+		{path: "/srv/static/db.bak", wantErr: ErrDeniedPattern},
+		{path: "/srv/static/repo/.git/config", wantErr: ErrDeniedPattern},
+		{path: "/srv/uploads/aux.txt", wantErr: ErrSeverityExceeded},
+		{path: "/srv/static/%252e%252e/etc", wantErr: ErrTraversalDetected},
+		{path: "/srv/static/a/b/c/d/e/f/g/h.css", wantErr: ErrPathTooDeep},
+	}
+	for _, tt := range tests {
+		if err := policy.Check(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("Check(%q) = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+	if spec := policy.Spec(); len(spec.Roots) != 2 || spec.Decoding != "recursive" || spec.MaxDepth != 8 {
+		t.Errorf("Spec() = %+v", spec)
+	}
+}
+
+func TestLoadPolicyErrors(t *testing.T) {
+	for _, spec := range []string{
+		`{"denied_patern": ["*.bak"]}`,
+		`{"decoding": "twice"}`,
+		`{"roots": [{"path": "/srv", "permissions": "rwz"}]}`,
+		`[`,
+	} {
+		if _, err := LoadPolicy(strings.NewReader(spec)); err == nil {
+			t.Errorf("LoadPolicy(%s) succeeded", spec)
+		}
+	}
+}
+
+func TestPolicyReload(t *testing.T) {
+	policy, err := LoadPolicy(strings.NewReader(testPolicy))
+	if err != nil {
+		t.Fatalf("LoadPolicy error = %v", err)
+	}
+	inFlight := policy.PathSecurity()
+	if err := policy.Reload(strings.NewReader(`{"denied_patterns": ["*.css"]}`)); err != nil {
+		t.Fatalf("Reload error = %v", err)
+	}
+	if err := policy.Check("/srv/static/css/site.css"); !errors.Is(err, ErrDeniedPattern) {
+		t.Errorf("Check after Reload = %v, want %v", err, ErrDeniedPattern)
+	}
+	if _, err := inFlight.ValidatePath("/srv/static/css/site.css"); err != nil {
+		t.Errorf("instance taken before Reload now rejects: %v", err)
+	}
+
+	// A broken reload keeps the rules in force
+	if err := policy.Reload(strings.NewReader(`{"decoding": "twice"}`)); err == nil {
+		t.Error("Reload of an invalid policy succeeded")
+	}
+	if err := policy.Check("/srv/static/css/site.css"); !errors.Is(err, ErrDeniedPattern) {
+		t.Errorf("Check after a failed Reload = %v, want %v", err, ErrDeniedPattern)
+	}
+}
+
+func TestPolicyWatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(file, []byte(`{"denied_patterns": ["*.tmp"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := NewPolicy(PolicySpec{})
+	if err != nil {
+		t.Fatalf("NewPolicy error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reloads := make(chan error, 4)
+	done := make(chan error, 1)
+	go func() { done <- policy.Watch(ctx, file, func(err error) { reloads <- err }) }()
+	next := func() error {
+		select {
+		case err := <-reloads:
+			return err
+		case <-time.After(10 * time.Second):
+			t.Fatal("Watch did not reload the file")
+			return nil
+		}
+	}
+
+	if err := next(); err != nil || !errors.Is(policy.Check("cache.tmp"), ErrDeniedPattern) {
+		t.Errorf("first load = %v, cache.tmp %v", err, policy.Check("cache.tmp"))
+	}
+	if err := os.WriteFile(file, []byte(`{"denied_patterns": ["*.tmp", "*.log"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := next(); err != nil || !errors.Is(policy.Check("debug.log"), ErrDeniedPattern) {
+		t.Errorf("reload = %v, debug.log %v", err, policy.Check("debug.log"))
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Watch returned %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,155 @@
+package pathsecurity
+
+import (
+	"container/list"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RealpathCache is a fixed-size cache of resolved paths for Resolved mode,
+// ValidateResolved and the checks built on it, which otherwise resolve
+// every component with a system call on each validation; on NFS, deep
+// trees take tens of milliseconds. Entries expire after a TTL and can be
+// dropped with Invalidate when the tree changes. A resolution may be stale
+// within its TTL: call Invalidate after renaming directories or replacing
+// symlinks inside the roots, and open files with OpenInRoot, which always
+// resolves afresh. It is passed to WithRealpathCache and may be shared
+// by several PathSecurity instances; it is safe for concurrent use.
+type RealpathCache struct {
+	size   int
+	ttl    time.Duration
+	hits   atomic.Uint64
+	misses atomic.Uint64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // of *realpathEntry, most recently used first
+}
+
+// realpathEntry is a cached resolution of an absolute path
+type realpathEntry struct {
+	path     string
+	resolved string
+	expires  time.Time
+}
+
+// NewRealpathCache returns an empty cache holding up to size resolved
+// paths for ttl each. A size below 1 is treated as 1, and a ttl of zero or
+// less keeps entries until they are evicted or invalidated.
+func NewRealpathCache(size int, ttl time.Duration) *RealpathCache {
+	if size < 1 {
+		size = 1
+	}
+	return &RealpathCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// Len returns the number of cached paths, including expired ones not yet
+// looked up again
+func (c *RealpathCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Stats returns how many lookups found a live cached path and how many did
+// not since the cache was created
+func (c *RealpathCache) Stats() (hits, misses uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// Purge removes every cached path
+func (c *RealpathCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.order.Init()
+}
+
+// Invalidate removes the cached paths at or below path, and those that
+// resolve to it or below it, as needed after renaming, removing or
+// replacing it. A relative path is made absolute against the working
+// directory first.
+func (c *RealpathCache) Invalidate(path string) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*realpathEntry)
+		if withinRoot(abs, entry.path) || withinRoot(abs, entry.resolved) {
+			delete(c.entries, entry.path)
+			c.order.Remove(element)
+		}
+		element = next
+	}
+}
+
+// get returns the resolution cached for the absolute path and marks it
+// recently used
+func (c *RealpathCache) get(path string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[path]
+	if ok && c.ttl > 0 && !time.Now().Before(element.Value.(*realpathEntry).expires) {
+		delete(c.entries, path)
+		c.order.Remove(element)
+		ok = false
+	}
+	if !ok {
+		c.misses.Add(1)
+		return "", false
+	}
+	c.hits.Add(1)
+	c.order.MoveToFront(element)
+	return element.Value.(*realpathEntry).resolved, true
+}
+
+// add caches the resolution of an absolute path, evicting the least
+// recently used one if the cache is full
+func (c *RealpathCache) add(path, resolved string) {
+	entry := &realpathEntry{path: path, resolved: resolved}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[path]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		delete(c.entries, oldest.Value.(*realpathEntry).path)
+		c.order.Remove(oldest)
+	}
+	c.entries[path] = c.order.PushFront(entry)
+}
+
+// realpath resolves the symlinks in an absolute path with
+// filepath.EvalSymlinks, through the cache set with WithRealpathCache if
+// there is one. Only successful resolutions are cached, so paths that do
+// not exist yet are looked up again each time.
+func (c *config) realpath(path string) (string, error) {
+	if c.realpathCache == nil {
+		return filepath.EvalSymlinks(path)
+	}
+	if resolved, ok := c.realpathCache.get(path); ok {
+		return resolved, nil
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		c.realpathCache.add(path, resolved)
+	}
+	return resolved, err
+}
@@ -0,0 +1,52 @@
+package pathsecurity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithRealpathCache(t *testing.T) {
+	root := testTree(t)
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, release := range []string{"v1", "v2"} {
+		if err := os.MkdirAll(filepath.Join(root, "releases", release), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	current := filepath.Join(root, "current")
+	if err := os.Symlink(filepath.Join("releases", "v1"), current); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewRealpathCache(1024, time.Minute)
+	ps := NewPathSecurity(WithAllowedRoots(root), WithRealpathCache(cache))
+	resolve := func(want string) {
+		t.Helper()
+		got, err := ps.CheckPath(filepath.Join(current, "app.conf"), Resolved)
+		if want = filepath.Join(abs, want); err != nil || got != want {
+			t.Errorf("CheckPath = %q, %v, want %q", got, err, want)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		resolve(filepath.Join("releases", "v1", "app.conf"))
+	}
+	if hits, misses := cache.Stats(); cache.Len() != 2 || hits != 4 || misses != 5 {
+		t.Errorf("cache = %d entries, %d hits, %d misses, want 2, 4, 5", cache.Len(), hits, misses)
+	}
+
+	// Switching the symlink goes unnoticed until the entry is invalidated
+	if err := os.Remove(current); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("releases", "v2"), current); err != nil {
+		t.Fatal(err)
+	}
+	resolve(filepath.Join("releases", "v1", "app.conf"))
+	cache.Invalidate(current)
+	resolve(filepath.Join("releases", "v2", "app.conf"))
+}
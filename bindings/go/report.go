@@ -0,0 +1,363 @@
+package pathsecurity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Severity ranks how dangerous a finding is
+type Severity int
+
+const (
+	// SeverityLow marks findings that are usually benign, such as hidden files
+	SeverityLow Severity = iota
+	// SeverityMedium marks findings that are dangerous in some contexts
+	SeverityMedium
+	// SeverityHigh marks findings that indicate an attack
+	SeverityHigh
+	// SeverityCritical marks findings that bypass validation in common
+	// stacks, such as NUL byte injection
+	SeverityCritical
+	// MonitorOnly is a fail threshold above every severity, with which
+	// WithFailThreshold lets every rejection through
+	MonitorOnly
+)
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	case SeverityCritical:
+		return "critical"
+	case MonitorOnly:
+		return "monitor-only"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText encodes the severity as its name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// FindingCategory groups findings by the kind of attack they indicate
+type FindingCategory int
+
+const (
+	// CategoryDotDot is a ".." segment
+	CategoryDotDot FindingCategory = iota
+	// CategoryAbsolute is an absolute path or Windows drive, UNC or device prefix
+	CategoryAbsolute
+	// CategoryEncoded is a percent-encoded separator, dot or control
+	// character, or an overlong UTF-8 encoding of one
+	CategoryEncoded
+	// CategorySymlink is an existing path component that is a symlink
+	CategorySymlink
+	// CategoryHiddenFile is a segment starting with a dot
+	CategoryHiddenFile
+	// CategoryDeviceFile is a reserved Windows device name or a /dev path
+	CategoryDeviceFile
+	// CategoryUnicode is a character reported by DetectUnicodeTricks
+	CategoryUnicode
+	// CategoryControl is a NUL byte or other control character
+	CategoryControl
+	// CategoryShortName is a component in the form of an 8.3 short name
+	CategoryShortName
+	// CategoryAlternateStream is an NTFS alternate data stream suffix
+	CategoryAlternateStream
+	// CategoryShell is a construct reported by DetectShellConstructs
+	CategoryShell
+	// CategoryCustom is a finding of a rule registered with WithRule
+	CategoryCustom
+	// CategoryWorldWritable is a file or directory every user may write
+	// to, as reported by ScanTree
+	CategoryWorldWritable
+	// CategoryDepth is an entry nested deeper than ScanTree allows
+	CategoryDepth
+	// CategoryMagicLink is a procfs magic symlink found by FindMagicLink
+	CategoryMagicLink
+)
+
+// String returns the name of the category
+func (c FindingCategory) String() string {
+	switch c {
+	case CategoryDotDot:
+		return "dot-dot"
+	case CategoryAbsolute:
+		return "absolute"
+	case CategoryEncoded:
+		return "encoded"
+	case CategorySymlink:
+		return "symlink"
+	case CategoryHiddenFile:
+		return "hidden-file"
+	case CategoryDeviceFile:
+		return "device-file"
+	case CategoryUnicode:
+		return "unicode"
+	case CategoryControl:
+		return "control-character"
+	case CategoryShortName:
+		return "short-name"
+	case CategoryAlternateStream:
+		return "alternate-stream"
+	case CategoryShell:
+		return "shell"
+	case CategoryCustom:
+		return "custom"
+	case CategoryWorldWritable:
+		return "world-writable"
+	case CategoryDepth:
+		return "depth"
+	case CategoryMagicLink:
+		return "magic-link"
+	}
+	return fmt.Sprintf("FindingCategory(%d)", int(c))
+}
+
+// MarshalText encodes the category as its name
+func (c FindingCategory) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// ReportSchemaVersion is the version of the JSON encoding of Report and
+// Result, written as their "schema_version" field. It is raised whenever a
+// field is removed or changes meaning; new fields may be added within a
+// version.
+const ReportSchemaVersion = 1
+
+// Finding is one suspicious element of a path
+type Finding struct {
+	Category FindingCategory `json:"category"`
+	Severity Severity        `json:"severity"`
+	// Pattern is the text that matched
+	Pattern string `json:"pattern"`
+	// Offset is the byte offset of Pattern in the analyzed path, in the
+	// canonical path for findings of rules, or in Path for findings of
+	// ScanTree
+	Offset int `json:"offset"`
+	// Path is the entry a finding of ScanTree is about, relative to the
+	// scanned root with '/' separators
+	Path string `json:"path,omitempty"`
+	// Rule is the name of the rule that reported a CategoryCustom finding
+	Rule string `json:"rule,omitempty"`
+	// Message explains a finding of a rule or of ScanTree
+	Message string `json:"message,omitempty"`
+}
+
+// Report explains why a path was or was not flagged
+type Report struct {
+	Path string `json:"path"`
+	// Traversal is the result DetectTraversal gives for Path
+	Traversal bool      `json:"traversal"`
+	Findings  []Finding `json:"findings"`
+}
+
+// MarshalJSON encodes the report with its schema version, findings as an
+// empty list rather than null when there are none, and the highest
+// severity as "max_severity" when there are some
+func (r Report) MarshalJSON() ([]byte, error) {
+	type fields Report
+	encoded := struct {
+		SchemaVersion int `json:"schema_version"`
+		fields
+		MaxSeverity *Severity `json:"max_severity,omitempty"`
+	}{SchemaVersion: ReportSchemaVersion, fields: fields(r)}
+	if encoded.Findings == nil {
+		encoded.Findings = []Finding{}
+	}
+	if max, ok := r.MaxSeverity(); ok {
+		encoded.MaxSeverity = &max
+	}
+	return json.Marshal(encoded)
+}
+
+// MaxSeverity returns the highest severity among the findings and false if
+// there are none
+func (r *Report) MaxSeverity() (Severity, bool) {
+	if len(r.Findings) == 0 {
+		return SeverityLow, false
+	}
+	max := r.Findings[0].Severity
+	for _, f := range r.Findings[1:] {
+		if f.Severity > max {
+			max = f.Severity
+		}
+	}
+	return max, true
+}
+
+// Analyze runs DetectTraversal on a path and lists every suspicious element
+// of it, in order of offset within each category. Offsets refer to path as
+// given, before environment expansion or decoding. Findings are reported
+// even for paths DetectTraversal accepts, such as hidden files. Analyze
+// checks existing components of path with os.Lstat to report symlinks,
+// relative paths being looked up from the working directory. The findings
+// of rules registered with WithRule come last.
+func (ps *PathSecurity) Analyze(path string) (Report, error) {
+	return ps.analyze(context.Background(), path)
+}
+
+// analyze implements Analyze, passing ctx to rules
+func (ps *PathSecurity) analyze(ctx context.Context, path string) (Report, error) {
+	traversal, err := ps.DetectTraversal(path)
+	if err != nil && strings.IndexByte(path, 0) < 0 {
+		return Report{}, err
+	}
+	// A NUL byte makes DetectTraversal fail but is exactly what the
+	// report should explain
+	report := Report{Path: path, Traversal: traversal || err != nil}
+
+	report.Findings = append(report.Findings, controlFindings(path)...)
+	report.Findings = append(report.Findings, encodedFindings(path)...)
+	for _, trick := range DetectUnicodeTricks(path) {
+		report.Findings = append(report.Findings, Finding{Category: CategoryUnicode, Severity: SeverityHigh, Pattern: string(trick.Rune), Offset: trick.Offset})
+	}
+	// Drive, UNC and device prefixes are absolute whatever the semantics
+	if kind := ClassifyWindowsPath(path); ps.cfg.isAbsolute(path) || kind != WindowsRelative && kind != WindowsRooted {
+		report.Findings = append(report.Findings, Finding{Category: CategoryAbsolute, Severity: SeverityMedium, Pattern: absolutePrefix(path), Offset: 0})
+	}
+	report.Findings = append(report.Findings, segmentFindings(path)...)
+	if offset, link := FindMagicLink(path); offset >= 0 {
+		report.Findings = append(report.Findings, Finding{Category: CategoryMagicLink, Severity: SeverityCritical, Pattern: link, Offset: offset})
+	}
+	if offset, stream := findAlternateDataStream(path); offset >= 0 {
+		report.Findings = append(report.Findings, Finding{Category: CategoryAlternateStream, Severity: SeverityHigh, Pattern: stream, Offset: offset})
+	}
+	for _, construct := range DetectShellConstructs(path) {
+		severity := SeverityMedium
+		if construct.Kind == ShellCommand {
+			severity = SeverityHigh
+		}
+		report.Findings = append(report.Findings, Finding{Category: CategoryShell, Severity: severity, Pattern: construct.Text, Offset: construct.Offset})
+	}
+	report.Findings = append(report.Findings, symlinkFindings(path)...)
+	if p, err := ps.quiet().preprocess(path); err == nil {
+		report.Findings = append(report.Findings, ps.ruleFindings(ctx, p)...)
+	}
+	return report, nil
+}
+
+// controlFindings reports NUL bytes and other control characters
+func controlFindings(path string) []Finding {
+	var findings []Finding
+	for i, r := range path {
+		if !unicode.IsControl(r) {
+			continue
+		}
+		severity := SeverityHigh
+		if r == 0 {
+			severity = SeverityCritical
+		}
+		findings = append(findings, Finding{Category: CategoryControl, Severity: severity, Pattern: string(r), Offset: i})
+	}
+	return findings
+}
+
+// encodedFindings reports the suspicious and overlong encoded patterns of
+// the validator, as well as double encoding through "%25"
+func encodedFindings(path string) []Finding {
+	var findings []Finding
+	for i := 0; i < len(path); i++ {
+		if path[i] != '%' {
+			continue
+		}
+		if pattern, severity, ok := matchEncoded(path[i:]); ok {
+			findings = append(findings, Finding{Category: CategoryEncoded, Severity: severity, Pattern: pattern, Offset: i})
+			i += len(pattern) - 1
+		}
+	}
+	return findings
+}
+
+// matchEncoded returns the encoded pattern at the start of s
+func matchEncoded(s string) (string, Severity, bool) {
+	for _, pattern := range overlongUTF8Patterns {
+		if len(s) >= len(pattern) && strings.EqualFold(s[:len(pattern)], pattern) {
+			return s[:len(pattern)], SeverityHigh, true
+		}
+	}
+	for _, pattern := range suspiciousEncodedPatterns {
+		if strings.HasPrefix(s, pattern) {
+			return pattern, SeverityMedium, true
+		}
+	}
+	if strings.HasPrefix(s, "%25") {
+		return "%25", SeverityMedium, true
+	}
+	return "", 0, false
+}
+
+// segmentFindings reports ".." segments, hidden files, device names and
+// short names
+func segmentFindings(path string) []Finding {
+	var findings []Finding
+	if strings.HasPrefix(path, "/dev/") {
+		findings = append(findings, Finding{Category: CategoryDeviceFile, Severity: SeverityHigh, Pattern: "/dev/", Offset: 0})
+	}
+
+	start := 0
+	for i := 0; i <= len(path); i++ {
+		if i < len(path) && !isSeparator(rune(path[i])) {
+			continue
+		}
+		segment := path[start:i]
+		switch {
+		case segment == "..":
+			findings = append(findings, Finding{Category: CategoryDotDot, Severity: SeverityHigh, Pattern: segment, Offset: start})
+		case len(segment) > 1 && segment[0] == '.':
+			findings = append(findings, Finding{Category: CategoryHiddenFile, Severity: SeverityLow, Pattern: segment, Offset: start})
+		case segment != "" && IsWindowsReservedName(segment):
+			findings = append(findings, Finding{Category: CategoryDeviceFile, Severity: SeverityHigh, Pattern: segment, Offset: start})
+		case IsWindowsShortName(segment):
+			findings = append(findings, Finding{Category: CategoryShortName, Severity: SeverityMedium, Pattern: segment, Offset: start})
+		}
+		start = i + 1
+	}
+	return findings
+}
+
+// symlinkFindings reports existing components of path that are symlinks
+func symlinkFindings(path string) []Finding {
+	var findings []Finding
+	for i := 1; i <= len(path); i++ {
+		if i < len(path) && path[i] != '/' {
+			continue
+		}
+		if path[i-1] == '/' {
+			continue
+		}
+		info, err := os.Lstat(path[:i])
+		if err != nil {
+			break
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			start := strings.LastIndexByte(path[:i], '/') + 1
+			findings = append(findings, Finding{Category: CategorySymlink, Severity: SeverityMedium, Pattern: path[start:i], Offset: start})
+		}
+	}
+	return findings
+}
+
+// absolutePrefix returns the part of an absolute path that makes it absolute
+func absolutePrefix(path string) string {
+	if len(path) >= 2 && path[1] == ':' && isASCIILetter(path[0]) {
+		if len(path) >= 3 && isSeparator(rune(path[2])) {
+			return path[:3]
+		}
+		return path[:2]
+	}
+	if len(path) >= 2 && isSeparator(rune(path[0])) && isSeparator(rune(path[1])) {
+		return path[:2]
+	}
+	return path[:1]
+}
@@ -0,0 +1,90 @@
+package pathsecurity
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		path          string
+		wantTraversal bool
+		want          []Finding
+	}{
+		{path: "docs/readme.md"},
+		{path: "uploads/.htaccess", want: []Finding{
+			{Category: CategoryHiddenFile, Severity: SeverityLow, Pattern: ".htaccess", Offset: 8},
+		}},
+		{path: "../.ssh/id_rsa", wantTraversal: true, want: []Finding{
+			{Category: CategoryDotDot, Severity: SeverityHigh, Pattern: "..", Offset: 0},
+			{Category: CategoryHiddenFile, Severity: SeverityLow, Pattern: ".ssh", Offset: 3},
+		}},
+		{path: "../%2e%2e/etc/passwd\x00.png", wantTraversal: true, want: []Finding{
+			{Category: CategoryControl, Severity: SeverityCritical, Pattern: "\x00", Offset: 20},
+			{Category: CategoryEncoded, Severity: SeverityMedium, Pattern: "%2e", Offset: 3},
+			{Category: CategoryEncoded, Severity: SeverityMedium, Pattern: "%2e", Offset: 6},
+			{Category: CategoryDotDot, Severity: SeverityHigh, Pattern: "..", Offset: 0},
+		}},
+	}
+	ps := NewPathSecurity()
+	for _, tt := range tests {
+		report, err := ps.Analyze(tt.path)
+		if err != nil {
+			t.Errorf("Analyze(%q) error = %v", tt.path, err)
+			continue
+		}
+		if report.Path != tt.path || report.Traversal != tt.wantTraversal {
+			t.Errorf("Analyze(%q) = path %q, traversal %t, want traversal %t", tt.path, report.Path, report.Traversal, tt.wantTraversal)
+		}
+		if !reflect.DeepEqual(report.Findings, tt.want) {
+			t.Errorf("Analyze(%q) findings = %+v, want %+v", tt.path, report.Findings, tt.want)
+		}
+	}
+
+	// Findings do not depend on whether the backend sees traversal
+	report, err := ps.Analyze(`C:\logs\CON.txt`)
+	want := []Finding{
+		{Category: CategoryAbsolute, Severity: SeverityMedium, Pattern: `C:\`, Offset: 0},
+		{Category: CategoryDeviceFile, Severity: SeverityHigh, Pattern: "CON.txt", Offset: 8},
+	}
+	if err != nil || !reflect.DeepEqual(report.Findings, want) {
+		t.Errorf("Analyze(C:\\logs\\CON.txt) = %+v, %v, want findings %+v", report.Findings, err, want)
+	}
+}
+
+func TestReportMarshalJSON(t *testing.T) {
+	tests := []struct {
+		report Report
+		want   string
+	}{
+		{
+			Report{Path: "docs/readme.md"},
+			`{"schema_version":1,"path":"docs/readme.md","traversal":false,"findings":[]}`,
+		},
+		{
+			Report{Path: "../.ssh/id_rsa", Traversal: true, Findings: []Finding{
+				{Category: CategoryDotDot, Severity: SeverityHigh, Pattern: "..", Offset: 0},
+				{Category: CategoryHiddenFile, Severity: SeverityLow, Pattern: ".ssh", Offset: 3},
+			}},
+			`{"schema_version":1,"path":"../.ssh/id_rsa","traversal":true,"findings":[{"category":"dot-dot","severity":"high","pattern":"..","offset":0},{"category":"hidden-file","severity":"low","pattern":".ssh","offset":3}],"max_severity":"high"}`,
+		},
+	}
+	for _, tt := range tests {
+		encoded, err := json.Marshal(tt.report)
+		if err != nil || string(encoded) != tt.want {
+			t.Errorf("json.Marshal(%+v) = %s, %v, want %s", tt.report, encoded, err, tt.want)
+		}
+	}
+}
+
+func TestMaxSeverity(t *testing.T) {
+	var report Report
+	if _, ok := report.MaxSeverity(); ok {
+		t.Error("MaxSeverity of an empty report reported a severity")
+	}
+	report.Findings = []Finding{{Severity: SeverityMedium}, {Severity: SeverityCritical}, {Severity: SeverityLow}}
+	if got, ok := report.MaxSeverity(); !ok || got != SeverityCritical {
+		t.Errorf("MaxSeverity = %s, %t, want %s", got, ok, SeverityCritical)
+	}
+}
@@ -0,0 +1,104 @@
+package pathsecurity
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ValidateResolved validates a path and then resolves it against the real
+// file system, following symlinks, and returns the resolved path. The target
+// must lie inside one of the roots configured with WithAllowedRoots, which
+// are resolved the same way; otherwise the error wraps ErrSymlinkEscape when
+// a symlink led outside the roots, or ErrNoMatchingRoot when the path was
+// never inside them. Components that do not exist yet are resolved through
+// their deepest existing ancestor. Dangling symlinks are rejected because
+// their eventual target cannot be checked. The target is then checked as
+// set with WithTargetChecks and WithTargetOwner.
+func (ps *PathSecurity) ValidateResolved(path string) (string, error) {
+	resolved, _, err := ps.resolveInRoot(path)
+	if err != nil {
+		return "", err
+	}
+	if err := ps.checkTarget(path, resolved); err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+// resolveInRoot implements ValidateResolved and also returns the allowed
+// root, as configured, whose resolved form most specifically contains the
+// resolved path
+func (ps *PathSecurity) resolveInRoot(path string) (resolved, root string, err error) {
+	path, err = ps.prepare(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", err
+	}
+	resolved, err = ps.evalSymlinks(abs)
+	if err != nil {
+		return "", "", err
+	}
+
+	lexicallyInside := false
+	longest := -1
+	for _, candidate := range ps.cfg.allowedRoots {
+		absRoot, err := filepath.Abs(candidate)
+		if err != nil {
+			return "", "", err
+		}
+		lexicallyInside = lexicallyInside || ps.cfg.withinRoot(absRoot, abs)
+
+		realRoot, err := ps.cfg.realpath(absRoot)
+		if err != nil {
+			realRoot = absRoot
+		}
+		if ps.cfg.withinRoot(realRoot, resolved) && len(realRoot) > longest {
+			root, longest = candidate, len(realRoot)
+		}
+	}
+	if longest >= 0 {
+		return resolved, root, nil
+	}
+
+	if lexicallyInside {
+		return "", "", ps.reject(path, fmt.Errorf("%w: %q resolves to %q", ErrSymlinkEscape, path, resolved))
+	}
+	return "", "", ps.reject(path, fmt.Errorf("%w: %q", ErrNoMatchingRoot, path))
+}
+
+// evalSymlinks resolves symlinks in an absolute path whose trailing
+// components may not exist yet, beneath the boundary set with
+// WithMountBoundary if there is one
+func (ps *PathSecurity) evalSymlinks(path string) (string, error) {
+	if ps.cfg.mountBoundary != "" {
+		return ps.resolveBeneath(path)
+	}
+	resolved, err := ps.cfg.realpath(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	if _, lerr := os.Lstat(path); lerr == nil {
+		return "", ps.reject(path, fmt.Errorf("%w: dangling symlink %q", ErrSymlinkEscape, path))
+	}
+
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	resolvedParent, err := ps.evalSymlinks(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolvedParent, filepath.Base(path)), nil
+}
@@ -0,0 +1,76 @@
+package pathsecurity
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testTree returns a root holding inbox/hello.txt and the symlink
+// inbox/escape to a directory outside it holding loot.txt. The root is
+// relative to the package directory rather than under os.TempDir, which
+// the pure-Go engine rejects as a sensitive location.
+func testTree(t *testing.T) string {
+	t.Helper()
+	root, err := os.MkdirTemp(".", "testtree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "loot.txt"), []byte("loot"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "inbox"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "inbox", "hello.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "inbox", "escape")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	return root
+}
+
+func TestValidateResolved(t *testing.T) {
+	root := testTree(t)
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	absRoot, err := filepath.Abs(realRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: "inbox/hello.txt", want: "inbox/hello.txt"},
+		{name: "inbox/new/report.txt", want: "inbox/new/report.txt"},
+		{name: "inbox/escape/loot.txt", wantErr: ErrSymlinkEscape},
+		{name: "inbox/escape", wantErr: ErrSymlinkEscape},
+		{name: "inbox/../../outside.txt", wantErr: ErrNoMatchingRoot},
+	}
+	ps := NewPathSecurity(WithAllowedRoots(root))
+	for _, tt := range tests {
+		got, err := ps.ValidateResolved(filepath.Join(root, tt.name))
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidateResolved(%q) = %q, %v, want %v", tt.name, got, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if abs, _ := filepath.Abs(got); abs != filepath.Join(absRoot, tt.want) {
+			t.Errorf("ValidateResolved(%q) = %q, want %q", tt.name, got, filepath.Join(absRoot, tt.want))
+		}
+	}
+
+	if _, err := NewPathSecurity(WithAllowedRoots(filepath.Join(root, "inbox"))).ValidateResolved(filepath.Join(root, "other.txt")); !errors.Is(err, ErrNoMatchingRoot) {
+		t.Errorf("ValidateResolved outside the roots = %v, want %v", err, ErrNoMatchingRoot)
+	}
+}
@@ -0,0 +1,170 @@
+package pathsecurity
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveWithin joins untrusted user input onto a trusted base directory
+// and returns the resulting path, guaranteeing that it stays inside
+// baseDir. The input is validated first and then rooted before cleaning, so
+// an absolute input is treated as relative to baseDir, as a chroot would.
+// Resolution is lexical and does not follow symlinks.
+func (ps *PathSecurity) ResolveWithin(baseDir, userInput string) (string, error) {
+	if baseDir == "" {
+		return "", errors.New("base directory is empty")
+	}
+
+	input, err := ps.prepare(userInput)
+	if err != nil {
+		return "", err
+	}
+
+	base := filepath.Clean(baseDir)
+	resolved := filepath.Join(base, filepath.FromSlash(path.Clean("/"+filepath.ToSlash(input))))
+	if !withinRoot(base, resolved) {
+		return "", ps.reject(userInput, fmt.Errorf("%w: %q escapes %q", ErrTraversalDetected, userInput, baseDir))
+	}
+	return resolved, nil
+}
+
+// JoinUntrusted joins untrusted components onto a trusted directory, for
+// use wherever paths are built with filepath.Join or fmt.Sprintf from
+// parts that came from a request, such as a user name and a document ID.
+// It builds a PathSecurity for the call; use the method of the same name
+// to apply options.
+func JoinUntrusted(trusted string, untrusted ...string) (string, error) {
+	return NewPathSecurity().JoinUntrusted(trusted, untrusted...)
+}
+
+// JoinUntrusted joins untrusted components onto the trusted path with
+// filepath.Join, checking that each is exactly one segment: it must pass
+// validation and, once preprocessed, be non-empty, not "." or "..", and
+// free of '/' and '\', whether literal, decoded or a Unicode lookalike.
+// Components failing validation are rejected with its error and the others
+// with one wrapping ErrInvalidSegment, naming the component. The trusted
+// path is not checked, and the result always lies below it.
+func (ps *PathSecurity) JoinUntrusted(trusted string, untrusted ...string) (string, error) {
+	segments := make([]string, 0, len(untrusted)+1)
+	segments = append(segments, trusted)
+	for i, component := range untrusted {
+		if component == "" {
+			return "", ps.reject(component, fmt.Errorf("%w: component %d is empty", ErrInvalidSegment, i))
+		}
+		segment, err := ps.prepare(component)
+		if err != nil {
+			return "", err
+		}
+		if segment == "." || segment == ".." || strings.ContainsAny(component, `/\`) || strings.ContainsAny(unicodeFolder.Replace(segment), `/\`) {
+			return "", ps.reject(component, fmt.Errorf("%w: component %d is %q", ErrInvalidSegment, i, component))
+		}
+		segments = append(segments, segment)
+	}
+
+	joined := filepath.Join(segments...)
+	if rel, err := filepath.Rel(filepath.Clean(trusted), joined); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ps.reject(joined, fmt.Errorf("%w: %q escapes %q", ErrTraversalDetected, joined, trusted))
+	}
+	return joined, nil
+}
+
+// RequirePrefix checks that path lies under prefix, such as the directory
+// of the caller's tenant in a multi-tenant storage layout, and returns its
+// canonical form, which is what should be used from then on. Unlike
+// strings.HasPrefix it compares whole segments of the canonical forms, so
+// "/data/tenantA/../tenantB/x" and "/data/tenantAB/x" are both outside
+// "/data/tenantA", and treats them as Equal does. The path is validated as
+// by Canonicalize; the prefix is trusted and only canonicalized. A
+// relative path is never under an absolute prefix or the other way round.
+// Paths outside the prefix are rejected with an error wrapping
+// ErrOutsidePrefix.
+func (ps *PathSecurity) RequirePrefix(path, prefix string) (string, error) {
+	if prefix == "" {
+		return "", errors.New("prefix is empty")
+	}
+
+	canonical, err := ps.Canonicalize(path)
+	if err != nil {
+		return "", err
+	}
+	if !hasPathPrefix(ps.cfg.comparisonForm(canonical), ps.cfg.comparisonForm(ps.cfg.canonicalize(prefix))) {
+		return "", ps.reject(path, fmt.Errorf("%w: %q is not under %q", ErrOutsidePrefix, path, prefix))
+	}
+	return canonical, nil
+}
+
+// hasPathPrefix reports whether the slash-separated canonical path p is
+// prefix itself or lies below it
+func hasPathPrefix(p, prefix string) bool {
+	switch {
+	case p == prefix:
+		return true
+	case prefix == ".":
+		// Every relative path that passed validation is below "."
+		return !strings.HasPrefix(p, "/") && volumeName(p) == ""
+	case strings.HasSuffix(prefix, "/"):
+		// "/" and volume roots such as "C:/" already end in a separator
+		return strings.HasPrefix(p, prefix)
+	}
+	return strings.HasPrefix(p, prefix+"/")
+}
+
+// RelativeToMatchedRoot validates a path and splits it into the allowed root
+// it falls under and the portion of the path relative to that root. The
+// relative portion never contains ".." segments or a leading separator and
+// uses forward slashes, so it can be used directly as a storage key. When
+// several roots match, the most specific (longest) one wins.
+func (ps *PathSecurity) RelativeToMatchedRoot(path string) (root string, rel string, err error) {
+	path, err = ps.prepare(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	root, ok := ps.matchRoot(filepath.Clean(path))
+	if !ok {
+		return "", "", ps.reject(path, fmt.Errorf("%w: %q", ErrNoMatchingRoot, path))
+	}
+
+	return root, relativeTo(root, filepath.Clean(path)), nil
+}
+
+// relativeTo returns the slash-separated part of clean below root, which
+// must contain it. It drops root's segments by count rather than comparing
+// them, so it also works when they differ from clean's in case.
+func relativeTo(root, clean string) string {
+	sep := string(filepath.Separator)
+	rootSegments := strings.Split(strings.TrimSuffix(root, sep), sep)
+	cleanSegments := strings.Split(strings.TrimSuffix(clean, sep), sep)
+	if len(cleanSegments) <= len(rootSegments) {
+		return "."
+	}
+	return strings.Join(cleanSegments[len(rootSegments):], "/")
+}
+
+// matchRoot returns the longest allowed root containing the cleaned path
+func (ps *PathSecurity) matchRoot(clean string) (string, bool) {
+	match := ""
+	for _, root := range ps.cfg.allowedRoots {
+		if !ps.cfg.withinRoot(root, clean) {
+			continue
+		}
+		if len(root) > len(match) {
+			match = root
+		}
+	}
+	return match, match != ""
+}
+
+// withinRoot reports whether the cleaned path is root itself or lies below it
+func withinRoot(root, clean string) bool {
+	if clean == root {
+		return true
+	}
+	if strings.HasSuffix(root, string(filepath.Separator)) {
+		return strings.HasPrefix(clean, root)
+	}
+	return strings.HasPrefix(clean, root+string(filepath.Separator))
+}
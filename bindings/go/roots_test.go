@@ -0,0 +1,136 @@
+package pathsecurity
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestRelativeToMatchedRoot(t *testing.T) {
+	ps := NewPathSecurity(WithAllowedRoots("/srv/data", "/srv/data/uploads"))
+	tests := []struct {
+		path     string
+		wantRoot string
+		wantRel  string
+		wantErr  error
+	}{
+		{path: "/srv/data/reports/q1.pdf", wantRoot: "/srv/data", wantRel: "reports/q1.pdf"},
+		{path: "/srv/data/uploads/2024/report.pdf", wantRoot: "/srv/data/uploads", wantRel: "2024/report.pdf"},
+		{path: "/srv/data/uploads", wantRoot: "/srv/data/uploads", wantRel: "."},
+		{path: "/srv/data/./reports/q1.pdf", wantRoot: "/srv/data", wantRel: "reports/q1.pdf"},
+		{path: "/srv/database/dump.sql", wantErr: ErrNoMatchingRoot},
+		{path: "/var/log/syslog", wantErr: ErrNoMatchingRoot},
+		{path: "/srv/data/../../etc/passwd", wantErr: ErrTraversalDetected},
+	}
+	for _, tt := range tests {
+		root, rel, err := ps.RelativeToMatchedRoot(tt.path)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RelativeToMatchedRoot(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("RelativeToMatchedRoot(%q) error = %v", tt.path, err)
+			continue
+		}
+		if root != tt.wantRoot || rel != tt.wantRel {
+			t.Errorf("RelativeToMatchedRoot(%q) = %q, %q, want %q, %q", tt.path, root, rel, tt.wantRoot, tt.wantRel)
+		}
+	}
+}
+
+func TestRelativeToMatchedRootCaseInsensitive(t *testing.T) {
+	ps := NewPathSecurity(WithCaseInsensitive(true), WithAllowedRoots("/srv/data"))
+	root, rel, err := ps.RelativeToMatchedRoot("/SRV/Data/Reports/q1.pdf")
+	if err != nil || root != "/srv/data" || rel != "Reports/q1.pdf" {
+		t.Errorf("RelativeToMatchedRoot(/SRV/Data/Reports/q1.pdf) = %q, %q, %v, want /srv/data, Reports/q1.pdf", root, rel, err)
+	}
+	if _, _, err := NewPathSecurity(WithAllowedRoots("/srv/data")).RelativeToMatchedRoot("/SRV/Data/Reports/q1.pdf"); !errors.Is(err, ErrNoMatchingRoot) {
+		t.Errorf("case-sensitive RelativeToMatchedRoot error = %v, want %v", err, ErrNoMatchingRoot)
+	}
+}
+
+func TestResolveWithin(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{input: "css/site.css", want: "/var/www/css/site.css"},
+		{input: "./css/site.css", want: "/var/www/css/site.css"},
+		{input: "/css/site.css", want: "/var/www/css/site.css"},
+		{input: "../../../etc/passwd", wantErr: ErrTraversalDetected},
+		{input: "css/\x00.css", wantErr: ErrNullByte},
+	}
+	ps := NewPathSecurity()
+	for _, tt := range tests {
+		got, err := ps.ResolveWithin("/var/www", tt.input)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("ResolveWithin(/var/www, %q) = %q, %v, want %q, %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRequirePrefix(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr error
+	}{
+		{path: "/data/tenantA/reports/q1.csv", want: "/data/tenantA/reports/q1.csv"},
+		{path: "/data/tenantA", want: "/data/tenantA"},
+		{path: "/data/tenantA/../tenantB/x", wantErr: ErrTraversalDetected},
+		{path: "/data/tenantAB/x", wantErr: ErrOutsidePrefix},
+		{path: "data/tenantA/x", wantErr: ErrOutsidePrefix},
+	}
+	ps := NewPathSecurity(WithWindowsSemantics(false))
+	for _, tt := range tests {
+		got, err := ps.RequirePrefix(tt.path, "/data/tenantA/")
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("RequirePrefix(%q) = %q, %v, want %q, %v", tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+	if got, err := ps.RequirePrefix("/data/tenantA%2f..%2ftenantB/x", "/data/tenantA/"); err == nil {
+		t.Errorf("RequirePrefix of an encoded traversal = %q", got)
+	}
+
+	caseless := ps.Clone(WithCaseInsensitive(true))
+	if got, err := caseless.RequirePrefix("/Data/TenantA/x", "/data/tenanta"); err != nil || got != "/data/tenanta/x" {
+		t.Errorf("case-insensitive RequirePrefix = %q, %v", got, err)
+	}
+	windows := NewPathSecurity(WithWindowsSemantics(true))
+	if got, err := windows.RequirePrefix(`C:\Tenants\A\x.txt`, `C:\Tenants\A`); err != nil || got != `C:\Tenants\A\x.txt` {
+		t.Errorf("Windows RequirePrefix = %q, %v", got, err)
+	}
+	if _, err := windows.RequirePrefix(`C:\Tenants\AB\x.txt`, `C:\Tenants\A`); !errors.Is(err, ErrOutsidePrefix) {
+		t.Errorf("Windows RequirePrefix of a sibling = %v, want %v", err, ErrOutsidePrefix)
+	}
+}
+
+func TestJoinUntrusted(t *testing.T) {
+	tests := []struct {
+		parts   []string
+		want    string
+		wantErr error
+	}{
+		{parts: []string{"alice", "report.pdf"}, want: filepath.Join("/srv/docs", "alice", "report.pdf")},
+		{parts: []string{"alice", "../bob"}, wantErr: ErrTraversalDetected},
+		{parts: []string{"alice/report.pdf"}, wantErr: ErrInvalidSegment},
+		{parts: []string{"alice", "a\u2215b"}, wantErr: ErrUnicodeTrick},
+		{parts: []string{".."}, wantErr: ErrTraversalDetected},
+		{parts: []string{""}, wantErr: ErrInvalidSegment},
+	}
+	for _, tt := range tests {
+		got, err := JoinUntrusted("/srv/docs", tt.parts...)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("JoinUntrusted(%q) = %q, %v, want %q, %v", tt.parts, got, err, tt.want, tt.wantErr)
+		}
+	}
+	// Backends reject these with different errors, but both reject them
+	for _, parts := range [][]string{{`alice\bob`}, {"alice", "%2e%2e"}} {
+		if got, err := JoinUntrusted("/srv/docs", parts...); err == nil {
+			t.Errorf("JoinUntrusted(%q) = %q", parts, got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package pathsecurity
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Rule is a custom detector registered with WithRule, for checks specific
+// to an organization such as "nothing under /secrets" or "paths start with
+// the tenant prefix". Check returns the findings for a path, or none if it
+// is fine. Category and Rule of the findings are filled in by the caller.
+// Rules run after the built-in checks have passed, must be safe for
+// concurrent use and should not block: validation calls them with
+// context.Background, AnalyzeContext with its own context.
+type Rule interface {
+	Check(ctx context.Context, path Canonical) []Finding
+}
+
+// RuleFunc adapts a function to the Rule interface
+type RuleFunc func(ctx context.Context, path Canonical) []Finding
+
+// Check calls f
+func (f RuleFunc) Check(ctx context.Context, path Canonical) []Finding {
+	return f(ctx, path)
+}
+
+// namedRule is a rule as registered with WithRule
+type namedRule struct {
+	name string
+	rule Rule
+}
+
+// ruleCanonical describes a preprocessed path to rules. It carries the
+// fields of CanonicalizeDetailed that follow from the path itself; Steps
+// and Decodings are left empty and Original is the preprocessed path.
+func (c *config) ruleCanonical(p string) Canonical {
+	canonical := Canonical{Original: p}
+	if c.unicodeFolding {
+		p = unicodeFolder.Replace(p)
+	}
+	if c.normalizeSeparators {
+		p = strings.ReplaceAll(p, `\`, "/")
+	}
+	p = c.foldCase(p)
+	canonical.Volume = volumeName(p)
+	canonical.RemovedSegments = removedSegments(p)
+	canonical.Path = path.Clean(p)
+	canonical.Absolute = c.isAbsolute(canonical.Path) || canonical.Volume != ""
+	canonical.Depth = pathDepth(canonical.Path, canonical.Volume)
+	return canonical
+}
+
+// ruleFindings runs the rules registered with WithRule on a preprocessed
+// path and returns their findings in registration order
+func (ps *PathSecurity) ruleFindings(ctx context.Context, p string) []Finding {
+	if len(ps.cfg.rules) == 0 {
+		return nil
+	}
+	canonical := ps.cfg.ruleCanonical(p)
+	var findings []Finding
+	for _, r := range ps.cfg.rules {
+		for _, f := range r.rule.Check(ctx, canonical) {
+			f.Category = CategoryCustom
+			f.Rule = r.name
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// checkRules rejects a preprocessed path for which a rule reports a finding
+// of high or critical severity
+func (ps *PathSecurity) checkRules(p string) *ValidationError {
+	for _, f := range ps.ruleFindings(context.Background(), p) {
+		if verr := ruleViolation(p, f); verr != nil {
+			return verr
+		}
+	}
+	return nil
+}
+
+// ruleViolation returns the rejection of p for a rule finding, or nil if
+// the finding is below high severity
+func ruleViolation(p string, f Finding) *ValidationError {
+	if f.Severity < SeverityHigh {
+		return nil
+	}
+	reason := fmt.Sprintf("Rule %q reported a %s finding", f.Rule, f.Severity)
+	if f.Message != "" {
+		reason = fmt.Sprintf("Rule %q: %s", f.Rule, f.Message)
+	}
+	return &ValidationError{Path: p, Reason: reason, Err: ErrRuleViolation}
+}
@@ -0,0 +1,61 @@
+package pathsecurity
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// noSecrets and tenantPrefix are the custom rules of the tests
+var (
+	noSecrets = RuleFunc(func(_ context.Context, p Canonical) []Finding {
+		if i := strings.Index(p.Path, "secrets/"); i >= 0 {
+			return []Finding{{Severity: SeverityHigh, Pattern: "secrets/", Offset: i, Message: "paths under secrets/ are off limits"}}
+		}
+		return nil
+	})
+	tenantPrefix = RuleFunc(func(_ context.Context, p Canonical) []Finding {
+		if !strings.HasPrefix(p.Path, "tenants/acme/") {
+			return []Finding{{Severity: SeverityMedium, Message: "path outside the tenant prefix"}}
+		}
+		return nil
+	})
+)
+
+func TestWithRule(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr error
+		want    []Finding
+	}{
+		{path: "tenants/acme/report.csv"},
+		{path: "tenants/acme/./secrets/key.pem", wantErr: ErrRuleViolation, want: []Finding{
+			{Category: CategoryCustom, Severity: SeverityHigh, Pattern: "secrets/", Offset: 13, Message: "paths under secrets/ are off limits", Rule: "no-secrets"},
+		}},
+		// Only high and critical findings reject the path
+		{path: "shared/readme.md", want: []Finding{
+			{Category: CategoryCustom, Severity: SeverityMedium, Message: "path outside the tenant prefix", Rule: "tenant-prefix"},
+		}},
+	}
+	ps := NewPathSecurity(WithWindowsSemantics(false), WithRule("no-secrets", noSecrets), WithRule("tenant-prefix", tenantPrefix))
+	for _, tt := range tests {
+		if _, err := ps.ValidatePath(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidatePath(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+		report, err := ps.Analyze(tt.path)
+		if err != nil {
+			t.Fatalf("Analyze(%q) error = %v", tt.path, err)
+		}
+		var custom []Finding
+		for _, f := range report.Findings {
+			if f.Category == CategoryCustom {
+				custom = append(custom, f)
+			}
+		}
+		if !reflect.DeepEqual(custom, tt.want) {
+			t.Errorf("Analyze(%q) custom findings = %+v, want %+v", tt.path, custom, tt.want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package pathsecurity
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RulesetFormat identifies the bundle format SignRuleset writes and
+// LoadRuleset reads
+const RulesetFormat = "path-security.ruleset/v1"
+
+// embeddedRulesetVersion is the Version of the ruleset built into the
+// binding, increased whenever defaultTraversalPatterns changes
+const embeddedRulesetVersion = 1
+
+// maxRulesetBundle bounds the size of a ruleset bundle
+const maxRulesetBundle = 1 << 20
+
+// Ruleset is a versioned pattern database: the traversal patterns checked
+// by every PathSecurity that does not replace them with
+// WithTraversalPatterns. The binding embeds one, and UseRuleset swaps in a
+// newer one at runtime, so that security teams can ship patterns for new
+// bypasses to running services faster than a release of the library is
+// rolled out. Rulesets travel as bundles signed with Ed25519, written by
+// SignRuleset and read by LoadRuleset.
+type Ruleset struct {
+	// Version increases with every ruleset published; UseRuleset only
+	// accepts rulesets newer than the one in use
+	Version int `json:"version"`
+	// Published is when the ruleset was released, for display
+	Published time.Time `json:"published,omitempty"`
+	// TraversalPatterns replace those of the ruleset in use, so they must
+	// include the patterns to keep
+	TraversalPatterns []TraversalPattern `json:"traversal_patterns"`
+}
+
+// activeRuleset is a ruleset in use with its compiled patterns
+type activeRuleset struct {
+	ruleset  Ruleset
+	patterns *TraversalPatterns
+}
+
+// embeddedRuleset compiles the ruleset built into the binding on first use
+var embeddedRuleset = sync.OnceValue(func() *activeRuleset {
+	patterns, err := CompileTraversalPatterns(defaultTraversalPatterns...)
+	if err != nil {
+		panic(err)
+	}
+	return &activeRuleset{ruleset: Ruleset{Version: embeddedRulesetVersion, TraversalPatterns: defaultTraversalPatterns}, patterns: patterns}
+})
+
+// loadedRuleset is the ruleset set with UseRuleset, nil while the embedded
+// one is in use
+var loadedRuleset atomic.Pointer[activeRuleset]
+
+// currentRuleset returns the ruleset in use
+func currentRuleset() *activeRuleset {
+	if active := loadedRuleset.Load(); active != nil {
+		return active
+	}
+	return embeddedRuleset()
+}
+
+// ActiveRuleset returns a copy of the ruleset in use: the embedded one,
+// or the last one set with UseRuleset
+func ActiveRuleset() Ruleset {
+	rs := currentRuleset().ruleset
+	rs.TraversalPatterns = append([]TraversalPattern(nil), rs.TraversalPatterns...)
+	return rs
+}
+
+// RulesetVersion returns the Version of the ruleset in use
+func RulesetVersion() int {
+	return currentRuleset().ruleset.Version
+}
+
+// UseRuleset makes rs the ruleset in use by every PathSecurity, including
+// those already created, for paths validated from then on. Cached
+// validation results of earlier rulesets are not used again. A ruleset
+// whose patterns do not compile is rejected, and one whose Version is not
+// above that of the ruleset in use fails with ErrRulesetOutdated, so that
+// an old bundle cannot be replayed to withdraw patterns.
+func UseRuleset(rs Ruleset) error {
+	patterns, err := CompileTraversalPatterns(rs.TraversalPatterns...)
+	if err != nil {
+		return fmt.Errorf("ruleset %d: %w", rs.Version, err)
+	}
+	rs.TraversalPatterns = patterns.Patterns()
+	next := &activeRuleset{ruleset: rs, patterns: patterns}
+	for {
+		loaded := loadedRuleset.Load()
+		current := loaded
+		if current == nil {
+			current = embeddedRuleset()
+		}
+		if rs.Version <= current.ruleset.Version {
+			return fmt.Errorf("%w: version %d, in use %d", ErrRulesetOutdated, rs.Version, current.ruleset.Version)
+		}
+		if loadedRuleset.CompareAndSwap(loaded, next) {
+			return nil
+		}
+	}
+}
+
+// rulesetBundle is the JSON document of a signed ruleset. Ruleset holds
+// the JSON encoding of a Ruleset, and Signature the Ed25519 signature of
+// the format and that encoding, as signedRuleset lays them out.
+type rulesetBundle struct {
+	Format    string `json:"format"`
+	Ruleset   []byte `json:"ruleset"`
+	Signature []byte `json:"signature"`
+}
+
+// signedRuleset returns the message a bundle's signature covers
+func signedRuleset(format string, ruleset []byte) []byte {
+	return append([]byte(format+"\n"), ruleset...)
+}
+
+// SignRuleset encodes rs as a bundle signed with key, for LoadRuleset to
+// verify with the matching public key on the services it is shipped to
+func SignRuleset(rs Ruleset, key ed25519.PrivateKey) ([]byte, error) {
+	if _, err := CompileTraversalPatterns(rs.TraversalPatterns...); err != nil {
+		return nil, fmt.Errorf("ruleset %d: %w", rs.Version, err)
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("ruleset: private key is %d bytes, want %d", len(key), ed25519.PrivateKeySize)
+	}
+	encoded, err := json.Marshal(rs)
+	if err != nil {
+		return nil, fmt.Errorf("ruleset: %w", err)
+	}
+	return json.Marshal(rulesetBundle{Format: RulesetFormat, Ruleset: encoded, Signature: ed25519.Sign(key, signedRuleset(RulesetFormat, encoded))})
+}
+
+// LoadRuleset verifies a bundle written by SignRuleset against keys and
+// returns its ruleset, to be put in use with UseRuleset. The bundle must
+// be signed by one of keys; otherwise, or without keys, it fails with
+// ErrRulesetSignature. Unknown fields in the ruleset are rejected, and so
+// are patterns that do not compile.
+func LoadRuleset(bundle []byte, keys ...ed25519.PublicKey) (Ruleset, error) {
+	if len(bundle) > maxRulesetBundle {
+		return Ruleset{}, fmt.Errorf("ruleset: bundle is %d bytes, more than the limit of %d", len(bundle), maxRulesetBundle)
+	}
+	var b rulesetBundle
+	if err := json.Unmarshal(bundle, &b); err != nil {
+		return Ruleset{}, fmt.Errorf("ruleset: %w", err)
+	}
+	if b.Format != RulesetFormat {
+		return Ruleset{}, fmt.Errorf("ruleset: format %q, want %q", b.Format, RulesetFormat)
+	}
+	if !verifyRuleset(b, keys) {
+		return Ruleset{}, ErrRulesetSignature
+	}
+
+	var rs Ruleset
+	decoder := json.NewDecoder(bytes.NewReader(b.Ruleset))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rs); err != nil {
+		return Ruleset{}, fmt.Errorf("ruleset: %w", err)
+	}
+	if _, err := CompileTraversalPatterns(rs.TraversalPatterns...); err != nil {
+		return Ruleset{}, fmt.Errorf("ruleset %d: %w", rs.Version, err)
+	}
+	return rs, nil
+}
+
+// verifyRuleset reports whether one of keys signed the bundle
+func verifyRuleset(b rulesetBundle, keys []ed25519.PublicKey) bool {
+	message := signedRuleset(b.Format, b.Ruleset)
+	for _, key := range keys {
+		if len(key) == ed25519.PublicKeySize && ed25519.Verify(key, message, b.Signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadRulesetFile is LoadRuleset for the bundle in file
+func LoadRulesetFile(file string, keys ...ed25519.PublicKey) (Ruleset, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return Ruleset{}, err
+	}
+	defer f.Close()
+	bundle, err := io.ReadAll(io.LimitReader(f, maxRulesetBundle+1))
+	if err != nil {
+		return Ruleset{}, err
+	}
+	return LoadRuleset(bundle, keys...)
+}
+
+// WatchRuleset puts the ruleset bundled in file in use whenever the
+// file's size or modification time changes, checking once a second as
+// Policy.Watch does, until ctx is done, and then returns ctx.Err(). After
+// every attempt onReload, if not nil, is called with nil or with the error
+// that kept the ruleset in use, such as ErrRulesetSignature or, for a
+// bundle already in use, ErrRulesetOutdated. A missing file is reported
+// once.
+func WatchRuleset(ctx context.Context, file string, keys []ed25519.PublicKey, onReload func(error)) error {
+	var last fs.FileInfo
+	missing := false
+	ticker := time.NewTicker(policyWatchInterval)
+	defer ticker.Stop()
+	for {
+		info, err := os.Stat(file)
+		switch {
+		case err != nil:
+			if !missing && onReload != nil {
+				onReload(err)
+			}
+			missing = true
+		case missing || last == nil || info.Size() != last.Size() || !info.ModTime().Equal(last.ModTime()):
+			missing, last = false, info
+			rs, err := LoadRulesetFile(file, keys...)
+			if err == nil {
+				err = UseRuleset(rs)
+			}
+			if onReload != nil {
+				onReload(err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
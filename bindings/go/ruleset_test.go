@@ -0,0 +1,55 @@
+package pathsecurity
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUseRuleset(t *testing.T) {
+	// UseRuleset changes every PathSecurity, so put the embedded ruleset
+	// back for the other tests
+	t.Cleanup(func() { loadedRuleset.Store(nil) })
+	if RulesetVersion() != embeddedRulesetVersion || len(ActiveRuleset().TraversalPatterns) != len(defaultTraversalPatterns) {
+		t.Fatalf("RulesetVersion = %d with %d patterns, want the embedded ruleset", RulesetVersion(), len(ActiveRuleset().TraversalPatterns))
+	}
+
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	update := ActiveRuleset()
+	update.Version++
+	update.Published = time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	update.TraversalPatterns = append(update.TraversalPatterns, TraversalPattern{Name: "servlet-web-inf", Expr: `(?i)web-inf/`})
+	bundle, err := SignRuleset(update, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, stranger, _ := ed25519.GenerateKey(nil)
+	if _, err := LoadRuleset(bundle, stranger.Public().(ed25519.PublicKey)); !errors.Is(err, ErrRulesetSignature) {
+		t.Errorf("LoadRuleset with another key error = %v, want %v", err, ErrRulesetSignature)
+	}
+	if _, err := LoadRuleset(bundle); !errors.Is(err, ErrRulesetSignature) {
+		t.Errorf("LoadRuleset without keys error = %v, want %v", err, ErrRulesetSignature)
+	}
+	shipped, err := LoadRuleset(bundle, key.Public().(ed25519.PublicKey))
+	if err != nil || shipped.Version != update.Version || !shipped.Published.Equal(update.Published) {
+		t.Fatalf("LoadRuleset = version %d published %v, %v", shipped.Version, shipped.Published, err)
+	}
+
+	// A cached verdict of the old ruleset is not reused
+	ps := NewPathSecurity(WithValidationCache(NewValidationCache(16)))
+	if _, err := ps.ValidatePath("app/WEB-INF/web.xml"); err != nil {
+		t.Fatalf("ValidatePath before UseRuleset error = %v", err)
+	}
+	if err := UseRuleset(shipped); err != nil || RulesetVersion() != shipped.Version {
+		t.Fatalf("UseRuleset = %v, version %d", err, RulesetVersion())
+	}
+	if _, err := ps.ValidatePath("app/WEB-INF/web.xml"); !errors.Is(err, ErrTraversalDetected) {
+		t.Errorf("ValidatePath after UseRuleset error = %v, want %v", err, ErrTraversalDetected)
+	}
+	if err := UseRuleset(shipped); !errors.Is(err, ErrRulesetOutdated) {
+		t.Errorf("UseRuleset replayed error = %v, want %v", err, ErrRulesetOutdated)
+	}
+}
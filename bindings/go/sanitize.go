@@ -0,0 +1,107 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SanitizeStrategy selects how SanitizePath treats dangerous input
+type SanitizeStrategy int
+
+const (
+	// SanitizeStrip removes dangerous segments and characters with the
+	// validator's sanitizer, so "a/../b" becomes "a/b"
+	SanitizeStrip SanitizeStrategy = iota
+	// SanitizeCollapse cleans the path lexically like path.Clean, resolving
+	// ".." against preceding segments and dropping any that would climb
+	// above the start, so "a/../b" becomes "b". Under Windows semantics
+	// '\' is treated as '/' first. Other characters are left as they are.
+	SanitizeCollapse
+	// SanitizeReject returns the path unchanged if it passes validation and
+	// the validation error otherwise
+	SanitizeReject
+	// SanitizeEncode percent-encodes every byte outside the URL unreserved
+	// set in each segment, including '%', '\', control characters and all
+	// non-ASCII bytes, and encodes "." and ".." segments as "%2E" and
+	// "%2E%2E", so every segment becomes an inert literal name
+	SanitizeEncode
+)
+
+// String returns the name of the strategy
+func (s SanitizeStrategy) String() string {
+	switch s {
+	case SanitizeStrip:
+		return "strip"
+	case SanitizeCollapse:
+		return "collapse"
+	case SanitizeReject:
+		return "reject"
+	case SanitizeEncode:
+		return "encode"
+	}
+	return fmt.Sprintf("SanitizeStrategy(%d)", int(s))
+}
+
+// collapsePath cleans p lexically without letting it climb above its start
+func collapsePath(p string) string {
+	if p == "" {
+		return ""
+	}
+	cleaned := path.Clean("/" + p)
+	if !strings.HasPrefix(p, "/") {
+		cleaned = strings.TrimPrefix(cleaned, "/")
+		if cleaned == "" {
+			return "."
+		}
+	}
+	return cleaned
+}
+
+// encodePath percent-encodes each segment of p into an inert literal name
+func encodePath(p string) string {
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	b.Grow(len(p))
+	for i, segment := range strings.Split(p, "/") {
+		if i > 0 {
+			b.WriteByte('/')
+		}
+		if segment == "." || segment == ".." {
+			b.WriteString(strings.Repeat("%2E", len(segment)))
+			continue
+		}
+		for j := 0; j < len(segment); j++ {
+			c := segment[j]
+			if isASCIILetter(c) || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0xf])
+		}
+	}
+	return b.String()
+}
+
+// AppendSanitized appends the sanitized form of path to dst and returns the
+// extended buffer, producing the same result as SanitizePath. Paths that
+// are already canonical are appended without any allocation, so proxies can
+// sanitize clean traffic into a reused buffer for free.
+func (ps *PathSecurity) AppendSanitized(dst []byte, path string) ([]byte, error) {
+	path, err := ps.preprocess(path)
+	if err != nil {
+		return dst, err
+	}
+	if ps.cfg.sanitizeStrategy != SanitizeReject && isCanonicalPath(path) && ps.cfg.fitsFilesystem(path) {
+		return append(dst, path...), nil
+	}
+
+	result, err := ps.sanitize(path, nil)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, result.Sanitized...), nil
+}
@@ -0,0 +1,155 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+// canonicalPaths are already in the form every strategy but SanitizeReject
+// leaves alone
+var canonicalPaths = []string{
+	"/home/user/documents/file.txt",
+	"uploads/2024/report-final_v2.pdf",
+	"a",
+	"/srv/www/static/js/app.min.js",
+}
+
+func TestIsCanonicalPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/home/user/file.txt", true},
+		{"docs/readme.md", true},
+		{"file", true},
+		{"", false},
+		{"/", false},
+		{"docs/", false},
+		{"docs//readme.md", false},
+		{"./docs", false},
+		{"docs/./readme.md", false},
+		{"docs/../readme.md", false},
+		{"docs/.hidden", false},
+		{"docs/readme.", false},
+		{"docs/read me.md", false},
+		{`docs\readme.md`, false},
+		{"docs/r%C3%A9sum%C3%A9.md", false},
+		{"docs/résumé.md", false},
+	}
+	for _, tt := range tests {
+		if got := isCanonicalPath(tt.path); got != tt.want {
+			t.Errorf("isCanonicalPath(%q) = %t, want %t", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizePathCanonicalParity(t *testing.T) {
+	for _, strategy := range []SanitizeStrategy{SanitizeStrip, SanitizeCollapse, SanitizeEncode} {
+		ps := NewPathSecurity(WithSanitizeStrategy(strategy))
+		for _, p := range canonicalPaths {
+			full, err := ps.sanitize(p, nil)
+			if err != nil {
+				t.Fatalf("%s: sanitize(%q) error = %v", strategy, p, err)
+			}
+			got, err := ps.SanitizePath(p)
+			if err != nil || got != full.Sanitized {
+				t.Errorf("%s: SanitizePath(%q) = %q, %v, full sanitization gives %q", strategy, p, got, err, full.Sanitized)
+			}
+			appended, err := ps.AppendSanitized(nil, p)
+			if err != nil || string(appended) != full.Sanitized {
+				t.Errorf("%s: AppendSanitized(%q) = %q, %v, full sanitization gives %q", strategy, p, appended, err, full.Sanitized)
+			}
+		}
+	}
+}
+
+func TestSanitizePathCanonicalAllocs(t *testing.T) {
+	ps := NewPathSecurity()
+	buf := make([]byte, 0, 256)
+	for _, p := range canonicalPaths {
+		if allocs := testing.AllocsPerRun(100, func() { ps.SanitizePath(p) }); allocs != 0 {
+			t.Errorf("SanitizePath(%q) allocates %.0f times, want 0", p, allocs)
+		}
+		if allocs := testing.AllocsPerRun(100, func() { buf, _ = ps.AppendSanitized(buf[:0], p) }); allocs != 0 {
+			t.Errorf("AppendSanitized(%q) allocates %.0f times, want 0", p, allocs)
+		}
+	}
+}
+
+func TestWithNativeSeparators(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`..\../..\./etc/passwd`, "etc/passwd"},
+		{`docs\..\..\secret`, "secret"},
+		{`reports\2024/q1.csv`, "reports/2024/q1.csv"},
+	}
+	posix := NewPathSecurity(WithWindowsSemantics(false))
+	native := posix.Clone(WithNativeSeparators(true), WithSanitizeStrategy(SanitizeCollapse))
+	for _, tt := range tests {
+		if got, err := native.SanitizePath(tt.path); err != nil || got != tt.want {
+			t.Errorf("SanitizePath(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+
+	// Backslash traversal is caught without native separators too
+	for _, p := range []string{`..\../..\./etc/passwd`, `docs\..\..\secret`} {
+		if _, err := posix.ValidatePath(p); !errors.Is(err, ErrTraversalDetected) {
+			t.Errorf("ValidatePath(%q) error = %v, want %v", p, err, ErrTraversalDetected)
+		}
+	}
+}
+
+// FuzzSanitizePath checks CheckInvariants on mutations of the traversal
+// payload corpus under every strategy
+func FuzzSanitizePath(f *testing.F) {
+	for _, payload := range TraversalPayloads() {
+		for strategy := SanitizeStrip; strategy <= SanitizeEncode; strategy++ {
+			f.Add(payload, uint8(strategy))
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, p string, n uint8) {
+		strategy := SanitizeStrategy(n % uint8(SanitizeEncode+1))
+		// Outside Windows semantics SanitizeCollapse keeps '\' as a name
+		// character, so it runs with native separators, which split
+		// `..\..` into segments it resolves
+		ps := NewPathSecurity(WithSanitizeStrategy(strategy), WithNativeSeparators(strategy == SanitizeCollapse))
+		ps.SanitizePath(p)
+		if err := ps.CheckInvariants(p); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func BenchmarkSanitizePath(b *testing.B) {
+	ps := NewPathSecurity()
+	for _, bench := range []struct{ name, path string }{
+		{"clean", "/home/user/documents/file.txt"},
+		{"dirty", "../../../etc/passwd"},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ps.SanitizePath(bench.path)
+			}
+		})
+	}
+}
+
+func BenchmarkAppendSanitized(b *testing.B) {
+	ps := NewPathSecurity()
+	buf := make([]byte, 0, 256)
+	for _, bench := range []struct{ name, path string }{
+		{"clean", "/home/user/documents/file.txt"},
+		{"dirty", "../../../etc/passwd"},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf, _ = ps.AppendSanitized(buf[:0], bench.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ChangeKind classifies a SanitizeChange
+type ChangeKind int
+
+const (
+	// ChangeExpanded is a home directory or environment reference replaced
+	// with its value
+	ChangeExpanded ChangeKind = iota
+	// ChangeDecoded is percent-encoding or another encoding undone
+	ChangeDecoded
+	// ChangeNormalized is text rewritten by Unicode normalization, the
+	// conversion of native separators or the folding of lookalikes of
+	// traversal
+	ChangeNormalized
+	// ChangeSegmentRemoved is one or more whole segments dropped, such as
+	// a ".." or an empty segment, with their separator
+	ChangeSegmentRemoved
+	// ChangeRemoved is characters dropped from within a segment
+	ChangeRemoved
+	// ChangeReplaced is characters replaced with others, such as an
+	// illegal character with '_'
+	ChangeReplaced
+	// ChangeInserted is characters added, such as the '_' prefixed to a
+	// reserved device name
+	ChangeInserted
+)
+
+// String returns the name of the kind
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeExpanded:
+		return "expanded"
+	case ChangeDecoded:
+		return "decoded"
+	case ChangeNormalized:
+		return "normalized"
+	case ChangeSegmentRemoved:
+		return "segment-removed"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeReplaced:
+		return "replaced"
+	case ChangeInserted:
+		return "inserted"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// MarshalText encodes the kind as its name
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// SanitizeChange is one alteration SanitizeDetailed records: the text
+// Before, at byte Offset in the path as the step received it, became the
+// text After
+type SanitizeChange struct {
+	// Step is one of "expand-home", "expand-env", "decode", "normalize",
+	// "native-separators", "segments", "fold-traversal", the name of the
+	// sanitize strategy, such as "strip", or "filesystem"
+	Step   string     `json:"step"`
+	Kind   ChangeKind `json:"kind"`
+	Offset int        `json:"offset"`
+	Before string     `json:"before"`
+	After  string     `json:"after"`
+}
+
+// SanitizeDiff is the sanitized form of a path together with every change
+// that led to it, for applications that must log exactly how input was
+// rewritten
+type SanitizeDiff struct {
+	Original string `json:"original"`
+	// Sanitized is the path as returned by SanitizePath
+	Sanitized string `json:"sanitized"`
+	// Changes lists the alterations in the order they were made; each
+	// step's offsets refer to the path as the previous steps left it
+	Changes []SanitizeChange `json:"changes,omitempty"`
+}
+
+// Changed reports whether sanitization altered the path
+func (d SanitizeDiff) Changed() bool {
+	return d.Sanitized != d.Original
+}
+
+// stepKinds are the kinds of the changes of steps that rewrite text
+// without regard to segments. The changes of the other steps are
+// classified by their shape.
+var stepKinds = map[string]ChangeKind{
+	"expand-home":       ChangeExpanded,
+	"expand-env":        ChangeExpanded,
+	"decode":            ChangeDecoded,
+	"normalize":         ChangeNormalized,
+	"native-separators": ChangeNormalized,
+	"fold-traversal":    ChangeNormalized,
+}
+
+// maxDiffCells bounds the table diffText fills; longer rewrites are
+// reported as a single change
+const maxDiffCells = 1 << 20
+
+// SanitizeDetailed is SanitizePath reporting each alteration it makes, from
+// environment expansion and decoding through the sanitize strategy and the
+// filesystem profile. It returns exactly the path SanitizePath does, and
+// rejects exactly the paths it rejects.
+func (ps *PathSecurity) SanitizeDetailed(p string) (SanitizeDiff, error) {
+	d := SanitizeDiff{Original: p}
+	step := func(name, before, after string) {
+		d.Changes = append(d.Changes, ps.cfg.diffStep(name, before, after)...)
+	}
+	apply := func(name, next string) {
+		if next != p {
+			step(name, p, next)
+			p = next
+		}
+	}
+
+	if ps.cfg.stdlibParity == ParityOff {
+		if err := ps.cfg.checkInput(p); err != nil {
+			return SanitizeDiff{}, ps.reject(err.Path, err)
+		}
+		home, err := ps.expandHome(p)
+		if err != nil {
+			return SanitizeDiff{}, err
+		}
+		apply("expand-home", home)
+		expanded, err := ps.expandEnv(p)
+		if err != nil {
+			return SanitizeDiff{}, err
+		}
+		apply("expand-env", expanded)
+		decoded, err := ps.decode(p)
+		if err != nil {
+			return SanitizeDiff{}, err
+		}
+		apply("decode", decoded)
+		apply("normalize", ps.cfg.normalization.normalize(p))
+		apply("native-separators", ps.cfg.convertSeparators(p))
+		segmented, err := ps.applySegmentPolicy(p)
+		if err != nil {
+			return SanitizeDiff{}, err
+		}
+		apply("segments", segmented)
+	}
+
+	d.Sanitized = p
+	if ps.cfg.sanitizeStrategy != SanitizeReject && isCanonicalPath(p) && ps.cfg.fitsFilesystem(p) {
+		return d, nil
+	}
+	result, err := ps.sanitize(p, step)
+	if err != nil {
+		return SanitizeDiff{}, err
+	}
+	d.Sanitized = result.Sanitized
+	return d, nil
+}
+
+// diffStep lists the changes a step made in turning before into after
+func (c *config) diffStep(name, before, after string) []SanitizeChange {
+	if before == after {
+		return nil
+	}
+	kind, fixed := stepKinds[name]
+	var changes []SanitizeChange
+	for _, h := range diffText(before, after) {
+		if !fixed {
+			kind = c.changeKind(before, h)
+		}
+		changes = append(changes, SanitizeChange{Step: name, Kind: kind, Offset: h.offset, Before: h.before, After: h.after})
+	}
+	return changes
+}
+
+// changeKind classifies a hunk by its shape: deleted text that spans whole
+// segments is ChangeSegmentRemoved
+func (c *config) changeKind(s string, h hunk) ChangeKind {
+	separator := func(b byte) bool { return b == '/' || c.windowsSemantics && b == '\\' }
+	switch {
+	case h.before == "":
+		return ChangeInserted
+	case h.after != "":
+		return ChangeReplaced
+	}
+	end := h.offset + len(h.before)
+	startsSegment := h.offset == 0 || separator(s[h.offset-1]) || separator(h.before[0])
+	endsSegment := end == len(s) || separator(s[end]) || separator(h.before[len(h.before)-1])
+	if startsSegment && endsSegment {
+		return ChangeSegmentRemoved
+	}
+	return ChangeRemoved
+}
+
+// hunk is a run of text replaced in a diff, at a byte offset of the old text
+type hunk struct {
+	offset        int
+	before, after string
+}
+
+// diffText lists the runs of text that differ between a and b, aligned on
+// the longest common subsequence of their runes. Inputs whose differing
+// middle would need more than maxDiffCells to align give a single hunk.
+func diffText(a, b string) []hunk {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for prefix > 0 && !utf8.RuneStart(a[prefix]) {
+		prefix--
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for suffix > 0 && !utf8.RuneStart(a[len(a)-suffix]) {
+		suffix--
+	}
+	x, y := []rune(a[prefix:len(a)-suffix]), []rune(b[prefix:len(b)-suffix])
+	if (len(x)+1)*(len(y)+1) > maxDiffCells {
+		return []hunk{{offset: prefix, before: string(x), after: string(y)}}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:]
+	width := len(y) + 1
+	lcs := make([]int32, (len(x)+1)*width)
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else {
+				lcs[i*width+j] = max(lcs[(i+1)*width+j], lcs[i*width+j+1])
+			}
+		}
+	}
+
+	var hunks []hunk
+	var removed, added strings.Builder
+	offset, start := prefix, prefix
+	flush := func() {
+		if removed.Len() > 0 || added.Len() > 0 {
+			hunks = append(hunks, hunk{offset: start, before: removed.String(), after: added.String()})
+			removed.Reset()
+			added.Reset()
+		}
+		start = offset
+	}
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			offset += utf8.RuneLen(x[i])
+			i, j = i+1, j+1
+			flush()
+		case j < len(y) && (i == len(x) || lcs[i*width+j+1] >= lcs[(i+1)*width+j]):
+			added.WriteRune(y[j])
+			j++
+		default:
+			removed.WriteRune(x[i])
+			offset += utf8.RuneLen(x[i])
+			i++
+		}
+	}
+	flush()
+	return widenEscapes(a, b, hunks)
+}
+
+// widenEscapes grows hunks that start or end inside a percent-escape of a
+// to cover all of it, merging hunks that come to overlap, so that
+// re-encoding "%2e" as "%252e" reads as that rather than as "52" inserted
+func widenEscapes(a, b string, hunks []hunk) []hunk {
+	// Each span of a covers whole hunks and text common to a and b, and
+	// grows by delta bytes in b
+	type span struct{ start, end, delta int }
+	var spans []span
+	for _, h := range hunks {
+		start, end := h.offset, h.offset+len(h.before)
+		for k := max(start-2, 0); k < start; k++ {
+			if a[k] == '%' {
+				start = k
+				break
+			}
+		}
+		for k := max(end-2, start); k < end; k++ {
+			if a[k] == '%' && k+3 > end {
+				end = min(k+3, len(a))
+				break
+			}
+		}
+		delta := len(h.after) - len(h.before)
+		if n := len(spans); n > 0 && start <= spans[n-1].end {
+			spans[n-1].end = max(spans[n-1].end, end)
+			spans[n-1].delta += delta
+			continue
+		}
+		spans = append(spans, span{start, end, delta})
+	}
+	widened := make([]hunk, len(spans))
+	shift := 0
+	for i, s := range spans {
+		widened[i] = hunk{offset: s.start, before: a[s.start:s.end], after: b[s.start+shift : s.end+shift+s.delta]}
+		shift += s.delta
+	}
+	return widened
+}
@@ -0,0 +1,47 @@
+package pathsecurity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSanitizeDetailed(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+		// changes are the changes SanitizeDetailed reports
+		changes []SanitizeChange
+	}{
+		{"docs/%2e%2e/%2e%2e/etc/passwd", "docs/etc/passwd", []SanitizeChange{
+			{Step: "decode", Kind: ChangeDecoded, Offset: 5, Before: "%2e%2e", After: ".."},
+			{Step: "decode", Kind: ChangeDecoded, Offset: 12, Before: "%2e%2e", After: ".."},
+			{Step: "strip", Kind: ChangeSegmentRemoved, Offset: 5, Before: "../../", After: ""},
+		}},
+		{"reports/q1<draft>.txt", "reports/q1_draft_.txt", []SanitizeChange{
+			{Step: "filesystem", Kind: ChangeReplaced, Offset: 10, Before: "<", After: "_"},
+			{Step: "filesystem", Kind: ChangeReplaced, Offset: 16, Before: ">", After: "_"},
+		}},
+		{"reports/aux.txt", "reports/_aux.txt", []SanitizeChange{
+			{Step: "filesystem", Kind: ChangeInserted, Offset: 8, Before: "", After: "_"},
+		}},
+		{"reports/q1.txt", "reports/q1.txt", nil},
+	}
+	ps := NewPathSecurity(WithDecoding(DecodeRecursive), WithFilesystemProfile(FilesystemNTFS))
+	for _, tt := range tests {
+		diff, err := ps.SanitizeDetailed(tt.path)
+		if err != nil {
+			t.Errorf("SanitizeDetailed(%q) error = %v", tt.path, err)
+			continue
+		}
+		if diff.Original != tt.path || diff.Sanitized != tt.want || diff.Changed() != (tt.changes != nil) {
+			t.Errorf("SanitizeDetailed(%q) = %q, changed %t, want %q", tt.path, diff.Sanitized, diff.Changed(), tt.want)
+		}
+		if !reflect.DeepEqual(diff.Changes, tt.changes) {
+			t.Errorf("SanitizeDetailed(%q) changes = %+v, want %+v", tt.path, diff.Changes, tt.changes)
+		}
+		// The changelog ends where SanitizePath does
+		if sanitized, err := ps.SanitizePath(tt.path); err != nil || sanitized != diff.Sanitized {
+			t.Errorf("SanitizePath(%q) = %q, %v, want %q", tt.path, sanitized, err, diff.Sanitized)
+		}
+	}
+}
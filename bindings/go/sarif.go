@@ -0,0 +1,217 @@
+package pathsecurity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// SARIFVersion and SARIFSchema identify the format written by SARIFLog
+const (
+	SARIFVersion = "2.1.0"
+	SARIFSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// SARIFLocation is where a checked path was found: URI is the file holding
+// it, relative to the root of the scanned repository, and Line its 1-based
+// line, or 0 if unknown. Results without a URI carry no location, which
+// GitHub code scanning does not display.
+type SARIFLocation struct {
+	URI  string
+	Line int
+}
+
+// SARIFLog collects rejected paths and findings as the results of a SARIF
+// 2.1.0 log, so that paths found while scanning repositories, manifests
+// and build outputs show up in GitHub code scanning and other SARIF
+// consumers. Rejections get a rule named "rejection/" and the label of
+// RejectionCategory, findings one named "finding/" and their category, and
+// severities map to SARIF levels: critical and high to "error", medium to
+// "warning" and low to "note". The zero value is an empty log ready to use;
+// it is not safe for concurrent use. Encode it with encoding/json.
+type SARIFLog struct {
+	rules   []sarifRule
+	indexes map[string]int
+	results []sarifResult
+}
+
+// sarifRule is a reportingDescriptor of the tool's driver
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	DefaultConfig    struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+// sarifMessage is a SARIF message with plain text
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// sarifResult is one result of the run
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+// sarifLocation is a physical location in an artifact
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region *sarifRegion `json:"region,omitempty"`
+	} `json:"physicalLocation"`
+}
+
+// sarifRegion is the part of an artifact a location refers to
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// findingDescriptions describe the rules of the finding categories
+var findingDescriptions = map[FindingCategory]string{
+	CategoryDotDot:          "Parent directory segment",
+	CategoryAbsolute:        "Absolute path or Windows drive, UNC or device prefix",
+	CategoryEncoded:         "Percent-encoded separator, dot or control character",
+	CategorySymlink:         "Path component that is a symlink",
+	CategoryHiddenFile:      "Hidden file or directory",
+	CategoryDeviceFile:      "Device file or reserved Windows device name",
+	CategoryUnicode:         "Deceptive Unicode character",
+	CategoryControl:         "Control character",
+	CategoryShortName:       "Windows 8.3 short name",
+	CategoryAlternateStream: "NTFS alternate data stream",
+	CategoryShell:           "Shell construct",
+	CategoryCustom:          "Custom rule",
+	CategoryWorldWritable:   "World-writable file or directory",
+	CategoryDepth:           "Entry nested too deep",
+	CategoryMagicLink:       "Procfs magic symlink",
+}
+
+// Len returns the number of results in the log
+func (l *SARIFLog) Len() int {
+	return len(l.results)
+}
+
+// AddRejection records the rejection of path, found at the given location,
+// with err as returned by ValidatePath or another check
+func (l *SARIFLog) AddRejection(path string, err error, at SARIFLocation) {
+	sentinel := ErrInvalidPath
+	for _, c := range rejectionCategories {
+		if errors.Is(err, c.err) {
+			sentinel = c.err
+			break
+		}
+	}
+	level := sarifLevel(RejectionSeverity(err))
+	index := l.rule("rejection/"+RejectionCategory(err), sentinel.Error(), level)
+	l.add(index, level, fmt.Sprintf("Path %q is rejected: %s", path, rejectionRule(err)), at)
+}
+
+// AddFinding records a finding about path, found at the given location.
+// Findings of ScanTree are located at their Path when at has no URI.
+func (l *SARIFLog) AddFinding(path string, f Finding, at SARIFLocation) {
+	description, ok := findingDescriptions[f.Category]
+	if !ok {
+		description = f.Category.String()
+	}
+	message := f.Message
+	if message == "" {
+		message = fmt.Sprintf("%s %q at offset %d", description, f.Pattern, f.Offset)
+	}
+	if at.URI == "" && f.Path != "" {
+		at = SARIFLocation{URI: f.Path}
+	} else {
+		message = fmt.Sprintf("Path %q: %s", path, message)
+	}
+	level := sarifLevel(f.Severity)
+	index := l.rule("finding/"+f.Category.String(), description, level)
+	l.add(index, level, message, at)
+}
+
+// AddReport records every finding of an Analyze report on a path found at
+// the given location
+func (l *SARIFLog) AddReport(r Report, at SARIFLocation) {
+	for _, f := range r.Findings {
+		l.AddFinding(r.Path, f, at)
+	}
+}
+
+// rule returns the index of the rule with the given ID, adding it with
+// its description and default level if the log has none yet. A rule
+// reported with several levels keeps the first.
+func (l *SARIFLog) rule(id, description, level string) int {
+	if index, ok := l.indexes[id]; ok {
+		return index
+	}
+	if l.indexes == nil {
+		l.indexes = make(map[string]int)
+	}
+	rule := sarifRule{ID: id, ShortDescription: sarifMessage{Text: description}}
+	rule.DefaultConfig.Level = level
+	l.indexes[id] = len(l.rules)
+	l.rules = append(l.rules, rule)
+	return len(l.rules) - 1
+}
+
+// add appends a result for the rule at index
+func (l *SARIFLog) add(index int, level, message string, at SARIFLocation) {
+	result := sarifResult{RuleID: l.rules[index].ID, RuleIndex: index, Level: level, Message: sarifMessage{Text: message}}
+	if at.URI != "" {
+		var location sarifLocation
+		location.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(at.URI)
+		if at.Line > 0 {
+			location.PhysicalLocation.Region = &sarifRegion{StartLine: at.Line}
+		}
+		result.Locations = []sarifLocation{location}
+	}
+	l.results = append(l.results, result)
+}
+
+// sarifLevel maps a severity to a SARIF result level
+func sarifLevel(s Severity) string {
+	switch {
+	case s >= SeverityHigh:
+		return "error"
+	case s == SeverityMedium:
+		return "warning"
+	}
+	return "note"
+}
+
+// MarshalJSON encodes the log as a SARIF document with a single run of
+// the binding, results as an empty list rather than null when there are
+// none
+func (l SARIFLog) MarshalJSON() ([]byte, error) {
+	type driver struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	type run struct {
+		Tool struct {
+			Driver driver `json:"driver"`
+		} `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	var r run
+	r.Tool.Driver = driver{Name: "path-security", Version: BindingVersion, InformationURI: "https://github.com/redasgard/path-security", Rules: l.rules}
+	r.Results = l.results
+	if r.Tool.Driver.Rules == nil {
+		r.Tool.Driver.Rules = []sarifRule{}
+	}
+	if r.Results == nil {
+		r.Results = []sarifResult{}
+	}
+	return json.Marshal(struct {
+		Schema  string `json:"$schema"`
+		Version string `json:"version"`
+		Runs    []run  `json:"runs"`
+	}{SARIFSchema, SARIFVersion, []run{r}})
+}
@@ -0,0 +1,73 @@
+package pathsecurity
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSARIFLog(t *testing.T) {
+	ps := NewPathSecurity()
+	var log SARIFLog
+	for line, p := range []string{"static/app.js", "../../etc/shadow", "uploads/\x00.php"} {
+		if _, err := ps.ValidatePath(p); err != nil {
+			log.AddRejection(p, err, SARIFLocation{URI: "deploy/assets.txt", Line: line + 1})
+		}
+	}
+	report, err := ps.Analyze(".env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.AddReport(report, SARIFLocation{URI: "deploy/assets.txt", Line: 4})
+	if log.Len() != 3 {
+		t.Errorf("Len = %d, want 3", log.Len())
+	}
+
+	encoded, err := json.Marshal(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Version string `json:"version"`
+		Schema  string `json:"$schema"`
+		Runs    []struct {
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Version != SARIFVersion || decoded.Schema != SARIFSchema || len(decoded.Runs) != 1 {
+		t.Fatalf("SARIF log = version %q, schema %q, %d runs", decoded.Version, decoded.Schema, len(decoded.Runs))
+	}
+	type result struct {
+		RuleID, Level, URI string
+		Line               int
+	}
+	var got []result
+	for _, r := range decoded.Runs[0].Results {
+		location := r.Locations[0].PhysicalLocation
+		got = append(got, result{r.RuleID, r.Level, location.ArtifactLocation.URI, location.Region.StartLine})
+	}
+	want := []result{
+		{"rejection/traversal", "error", "deploy/assets.txt", 2},
+		{"rejection/null_byte", "error", "deploy/assets.txt", 3},
+		{"finding/hidden-file", "note", "deploy/assets.txt", 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SARIF results = %+v, want %+v", got, want)
+	}
+}
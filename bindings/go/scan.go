@@ -0,0 +1,143 @@
+package pathsecurity
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultScanDepth is the nesting ScanTree reports unless WithMaxDepth
+// sets a limit
+const defaultScanDepth = 32
+
+// ScanTree reports the dangerous entries of the directory tree at root. It
+// builds a PathSecurity from opts for the call; use the method of the same
+// name to reuse one.
+func ScanTree(root string, opts ...Option) ([]Finding, error) {
+	return NewPathSecurity(opts...).ScanTree(root)
+}
+
+// ScanTree walks the existing directory tree at root, without following
+// symlinks, and reports its dangerous entries, for auditing artifact stores
+// and extracted archives after the fact: symlinks that resolve outside
+// root, or would if they are dangling, names with control characters,
+// deceptive Unicode or reserved Windows device names, files and
+// directories every user may write to, and entries nested more than
+// WithMaxDepth levels deep, or 32 without a limit. Every Finding has Path
+// set, and Pattern is the name of the entry except for Unicode and control
+// characters. Entries that cannot be read are skipped and the error joins
+// their errors; findings are returned along with it.
+func (ps *PathSecurity) ScanTree(root string) ([]Finding, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	realRoot, err = filepath.Abs(realRoot)
+	if err != nil {
+		return nil, err
+	}
+	maxDepth := ps.cfg.maxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultScanDepth
+	}
+
+	var findings []Finding
+	var errs []error
+	err = filepath.WalkDir(realRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == realRoot {
+				return err
+			}
+			errs = append(errs, err)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		rel, err := filepath.Rel(realRoot, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		entry := scannedEntry{path: rel, name: d.Name()}
+		if p != realRoot {
+			entry.offset = len(rel) - len(entry.name)
+			findings = entry.nameFindings(findings)
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if target, escapes := symlinkEscapes(realRoot, p); escapes {
+				findings = append(findings, entry.finding(CategorySymlink, SeverityCritical, fmt.Sprintf("Symlink to %q leaves the root", target)))
+			}
+		} else if worldWritable(info) {
+			severity := SeverityHigh
+			if info.IsDir() && info.Mode()&fs.ModeSticky != 0 {
+				// Sticky directories stop users from replacing each other's entries
+				severity = SeverityMedium
+			}
+			findings = append(findings, entry.finding(CategoryWorldWritable, severity, fmt.Sprintf("Mode %s lets every user write", info.Mode())))
+		}
+		if p != realRoot && strings.Count(rel, "/")+1 == maxDepth+1 {
+			findings = append(findings, entry.finding(CategoryDepth, SeverityMedium, fmt.Sprintf("Nested more than %d levels deep", maxDepth)))
+		}
+		return nil
+	})
+	if err != nil {
+		return findings, err
+	}
+	return findings, errors.Join(errs...)
+}
+
+// scannedEntry is an entry of the tree walked by ScanTree
+type scannedEntry struct {
+	// path is relative to the root, with '/' separators
+	path string
+	name string
+	// offset is the offset of name in path
+	offset int
+}
+
+// finding returns a finding about the whole entry
+func (e scannedEntry) finding(category FindingCategory, severity Severity, message string) Finding {
+	return Finding{Category: category, Severity: severity, Pattern: e.name, Offset: e.offset, Path: e.path, Message: message}
+}
+
+// nameFindings appends the findings on the name of the entry to findings
+func (e scannedEntry) nameFindings(findings []Finding) []Finding {
+	for _, f := range controlFindings(e.name) {
+		f.Offset += e.offset
+		f.Path = e.path
+		f.Message = "Control character in name"
+		findings = append(findings, f)
+	}
+	for _, trick := range DetectUnicodeTricks(e.name) {
+		findings = append(findings, Finding{Category: CategoryUnicode, Severity: SeverityHigh, Pattern: string(trick.Rune), Offset: e.offset + trick.Offset, Path: e.path, Message: "Deceptive Unicode in name"})
+	}
+	if IsWindowsReservedName(e.name) {
+		findings = append(findings, e.finding(CategoryDeviceFile, SeverityHigh, "Reserved Windows device name"))
+	}
+	return findings
+}
+
+// symlinkEscapes reports whether the symlink at p resolves outside root,
+// judging a dangling or looping symlink by its target, and returns the
+// target
+func symlinkEscapes(root, p string) (string, bool) {
+	target, err := os.Readlink(p)
+	if err != nil {
+		return "", false
+	}
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		resolved = target
+		if !filepath.IsAbs(resolved) {
+			resolved = filepath.Join(filepath.Dir(p), target)
+		}
+	}
+	return target, !withinRoot(root, filepath.Clean(resolved))
+}
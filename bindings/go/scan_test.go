@@ -0,0 +1,63 @@
+package pathsecurity
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestScanTree(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file names and modes of the tree are not portable")
+	}
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a", "b", "c", "d", "e"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"report.txt", "CON.txt", "invoice\u202egnp.exe", "bell\a.txt", "a/b/c/d/e/deep.txt", "shared.txt"} {
+		if err := os.WriteFile(filepath.Join(root, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(root, "shared.txt"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{"latest": "report.txt", "passwd": "/etc/passwd", "a/dangling": "../../missing"} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+	}
+
+	findings, err := ScanTree(root, WithMaxDepth(4))
+	if err != nil {
+		t.Fatalf("ScanTree error = %v", err)
+	}
+	type finding struct {
+		Category FindingCategory
+		Severity Severity
+		Pattern  string
+		Offset   int
+		Path     string
+	}
+	want := []finding{
+		{CategoryDeviceFile, SeverityHigh, "CON.txt", 0, "CON.txt"},
+		{CategoryDepth, SeverityMedium, "e", 8, "a/b/c/d/e"},
+		{CategorySymlink, SeverityCritical, "dangling", 2, "a/dangling"},
+		{CategoryControl, SeverityHigh, "\a", 4, "bell\a.txt"},
+		{CategoryUnicode, SeverityHigh, "\u202e", 7, "invoice\u202egnp.exe"},
+		{CategorySymlink, SeverityCritical, "passwd", 0, "passwd"},
+		{CategoryWorldWritable, SeverityHigh, "shared.txt", 0, "shared.txt"},
+	}
+	var got []finding
+	for _, f := range findings {
+		if f.Message == "" {
+			t.Errorf("finding %+v has no message", f)
+		}
+		got = append(got, finding{f.Category, f.Severity, f.Pattern, f.Offset, f.Path})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanTree findings = %+v, want %+v", got, want)
+	}
+}
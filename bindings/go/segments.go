@@ -0,0 +1,153 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SegmentPolicy selects how input paths with edge-case segments are
+// treated. The edge cases are:
+//
+//   - "." segments, including "." and "./" on their own and the last
+//     segment of "foo/."
+//   - empty segments, from doubled separators as in "foo//bar" or a
+//     trailing separator as in "foo/"
+//   - names ending in dots or spaces, such as "report.txt." or "notes ",
+//     which Windows strips so that they open "report.txt" and "notes"
+//
+// The separators at the start of an absolute or UNC path are not empty
+// segments, and ".." segments are left to traversal detection. Under
+// Windows semantics '\' separates segments too. A segment made only of
+// dots and spaces that starts with ".." counts as "..", since Windows may
+// resolve it to the parent directory.
+type SegmentPolicy int
+
+const (
+	// SegmentsKeep passes edge-case segments through unchanged, except
+	// that Windows semantics already reject names ending in a dot or space
+	SegmentsKeep SegmentPolicy = iota
+	// SegmentsCollapse drops "." and empty segments and trims trailing
+	// dots and spaces from names during preprocessing, as Windows does, so
+	// "./docs//notes./" becomes "docs/notes". A path with nothing left
+	// becomes ".", or its leading separators if it is absolute.
+	SegmentsCollapse
+	// SegmentsReject rejects paths with any edge-case segment with an
+	// error wrapping ErrInvalidPath
+	SegmentsReject
+)
+
+// String returns the name of the policy
+func (p SegmentPolicy) String() string {
+	switch p {
+	case SegmentsKeep:
+		return "keep"
+	case SegmentsCollapse:
+		return "collapse"
+	case SegmentsReject:
+		return "reject"
+	}
+	return fmt.Sprintf("SegmentPolicy(%d)", int(p))
+}
+
+// applySegmentPolicy collapses or rejects the edge-case segments of path
+// as configured with WithSegmentPolicy
+func (ps *PathSecurity) applySegmentPolicy(path string) (string, error) {
+	switch ps.cfg.segmentPolicy {
+	case SegmentsCollapse:
+		return ps.cfg.collapseSegments(path), nil
+	case SegmentsReject:
+		if verr := ps.cfg.checkSegments(path); verr != nil {
+			return "", ps.reject(path, verr)
+		}
+	}
+	return path, nil
+}
+
+// isSegmentSeparator reports whether b separates segments under the
+// configured semantics
+func (c *config) isSegmentSeparator(b byte) bool {
+	return b == '/' || c.windowsSemantics && b == '\\'
+}
+
+// segmentSpans calls fn with the start and end offsets of every segment of
+// p after its leading separators, including empty ones
+func (c *config) segmentSpans(p string, fn func(start, end int)) {
+	start := 0
+	for start < len(p) && c.isSegmentSeparator(p[start]) {
+		start++
+	}
+	if start == len(p) {
+		return
+	}
+	for end := start; end <= len(p); end++ {
+		if end == len(p) || c.isSegmentSeparator(p[end]) {
+			fn(start, end)
+			start = end + 1
+		}
+	}
+}
+
+// trimSegment returns segment as Windows resolves it: without trailing
+// dots and spaces, or ".." for a run of dots and spaces starting with ".."
+func trimSegment(segment string) string {
+	trimmed := strings.TrimRight(segment, ". ")
+	if trimmed == "" && strings.HasPrefix(segment, "..") {
+		return ".."
+	}
+	return trimmed
+}
+
+// collapseSegments drops the edge-case segments of p, keeping its leading
+// separators and the separator after each remaining segment
+func (c *config) collapseSegments(p string) string {
+	var b strings.Builder
+	b.Grow(len(p))
+	lead := 0
+	for lead < len(p) && c.isSegmentSeparator(p[lead]) {
+		lead++
+	}
+	b.WriteString(p[:lead])
+
+	var sep byte
+	kept := false
+	c.segmentSpans(p, func(start, end int) {
+		segment := p[start:end]
+		if segment != ".." {
+			segment = trimSegment(segment)
+		}
+		if segment == "" {
+			return
+		}
+		if kept {
+			b.WriteByte(sep)
+		}
+		b.WriteString(segment)
+		kept = true
+		if end < len(p) {
+			sep = p[end]
+		}
+	})
+	if b.Len() == 0 && p != "" {
+		return "."
+	}
+	return b.String()
+}
+
+// checkSegments returns an error for the first edge-case segment of p
+func (c *config) checkSegments(p string) *ValidationError {
+	var verr *ValidationError
+	c.segmentSpans(p, func(start, end int) {
+		if verr != nil {
+			return
+		}
+		switch segment := p[start:end]; {
+		case segment == "":
+			verr = &ValidationError{Path: p, Reason: fmt.Sprintf("Empty segment at offset %d", start), Err: ErrInvalidPath}
+		case segment == ".":
+			verr = &ValidationError{Path: p, Reason: fmt.Sprintf("Dot segment at offset %d", start), Err: ErrInvalidPath}
+		case segment != ".." && trimSegment(segment) != segment:
+			verr = &ValidationError{Path: p, Reason: fmt.Sprintf("Trailing dot or space in component %q", segment), Err: ErrInvalidPath}
+		}
+	})
+	return verr
+}
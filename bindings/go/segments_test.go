@@ -0,0 +1,70 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithSegmentPolicy(t *testing.T) {
+	tests := []struct {
+		path         string
+		wantKeep     string
+		wantCollapse string
+	}{
+		{".", "%2E", "%2E"},
+		{"./docs", "%2E/docs", "docs"},
+		{"docs/.", "docs/%2E", "docs"},
+		{"docs//notes", "docs//notes", "docs/notes"},
+		{"docs/", "docs/", "docs"},
+		{"report.txt.", "report.txt.", "report.txt"},
+		{"notes ", "notes%20", "notes"},
+		{"/srv//data/./", "/srv//data/%2E/", "/srv/data"},
+	}
+	posix := NewPathSecurity(WithWindowsSemantics(false), WithSanitizeStrategy(SanitizeEncode))
+	keep := posix.Clone(WithSegmentPolicy(SegmentsKeep))
+	collapse := posix.Clone(WithSegmentPolicy(SegmentsCollapse))
+	reject := posix.Clone(WithSegmentPolicy(SegmentsReject))
+	for _, tt := range tests {
+		if got, err := keep.SanitizePath(tt.path); err != nil || got != tt.wantKeep {
+			t.Errorf("keep: SanitizePath(%q) = %q, %v, want %q", tt.path, got, err, tt.wantKeep)
+		}
+		if got, err := collapse.SanitizePath(tt.path); err != nil || got != tt.wantCollapse {
+			t.Errorf("collapse: SanitizePath(%q) = %q, %v, want %q", tt.path, got, err, tt.wantCollapse)
+		}
+		if got, err := reject.SanitizePath(tt.path); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("reject: SanitizePath(%q) = %q, %v, want %v", tt.path, got, err, ErrInvalidPath)
+		}
+	}
+}
+
+func TestWithSegmentPolicyWindows(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr error
+	}{
+		{path: `docs\secret.txt.`, want: `docs\secret.txt`},
+		{path: `C:\data\.\file `, want: `C:\data\file`},
+		{path: `docs\.. \boot.ini`, wantErr: ErrTraversalDetected},
+	}
+	ps := NewPathSecurity(WithWindowsSemantics(true), WithSegmentPolicy(SegmentsCollapse))
+	for _, tt := range tests {
+		got, err := ps.CanonicalizeDetailed(tt.path)
+		if !errors.Is(err, tt.wantErr) || got.Path != tt.want {
+			t.Errorf("CanonicalizeDetailed(%q) = %q, %v, want %q, %v", tt.path, got.Path, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSegmentPolicyString(t *testing.T) {
+	for policy, want := range map[SegmentPolicy]string{
+		SegmentsKeep:     "keep",
+		SegmentsCollapse: "collapse",
+		SegmentsReject:   "reject",
+		SegmentPolicy(7): "SegmentPolicy(7)",
+	} {
+		if got := policy.String(); got != want {
+			t.Errorf("SegmentPolicy(%d).String() = %q, want %q", int(policy), got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// defaultSensitivePaths are the patterns used by WithSensitivePaths when
+// none are given
+var defaultSensitivePaths = []string{
+	"/dev", "/proc", "/sys",
+	"/etc/passwd", "/etc/shadow", "/etc/gshadow", "/etc/sudoers", "/etc/sudoers.d",
+	"/root",
+	"~/.ssh", "~/.aws", "~/.gnupg", "~/.kube", "~/.docker/config.json",
+	".env", ".env.*", ".htpasswd", ".git-credentials", ".netrc",
+	"web.config", "id_rsa", "id_dsa", "id_ecdsa", "id_ed25519",
+}
+
+// DefaultSensitivePaths returns a copy of the patterns WithSensitivePaths
+// uses when called without any, for extending the default list
+func DefaultSensitivePaths() []string {
+	return append([]string(nil), defaultSensitivePaths...)
+}
+
+// checkSensitive rejects paths matching a sensitive pattern
+func (c *config) checkSensitive(p string) error {
+	if c.sensitivePaths == nil {
+		return nil
+	}
+
+	clean := c.foldCase(path.Clean(strings.ReplaceAll(p, `\`, "/")))
+	segments := strings.Split(strings.TrimPrefix(clean, "/"), "/")
+	absolute := strings.HasPrefix(clean, "/")
+	for _, pattern := range c.sensitivePaths {
+		matched, err := matchSensitive(c.foldCase(pattern), segments, absolute)
+		if err != nil {
+			return fmt.Errorf("sensitive path pattern %q: %w", pattern, err)
+		}
+		if matched {
+			return &ValidationError{Path: p, Reason: fmt.Sprintf("Path matches sensitive pattern %q", pattern), Err: ErrSensitivePath}
+		}
+	}
+
+	return nil
+}
+
+// checkSpecialFile rejects paths that exist and are device files, named
+// pipes or sockets when sensitive paths are configured. It is kept apart
+// from checkSensitive because it reads the file system, so its result may
+// not be cached with the rest of validation.
+func (c *config) checkSpecialFile(p string) *ValidationError {
+	if c.sensitivePaths == nil || c.lexicalOnly {
+		return nil
+	}
+	if info, err := os.Stat(p); err == nil {
+		if mode := info.Mode(); mode&(os.ModeDevice|os.ModeNamedPipe|os.ModeSocket|os.ModeIrregular) != 0 {
+			return &ValidationError{Path: p, Reason: fmt.Sprintf("Path is a special file (%v)", mode.Type()), Err: ErrSensitivePath}
+		}
+	}
+	return nil
+}
+
+// matchSensitive matches one pattern against the segments of a cleaned
+// path. Patterns without '/' match any segment, "/" patterns match from the
+// root and "~/" patterns match at any depth, as the home directory is not
+// known. A match of the leading segments also covers everything below them.
+func matchSensitive(pattern string, segments []string, absolute bool) (bool, error) {
+	switch {
+	case strings.HasPrefix(pattern, "/"):
+		if !absolute {
+			return false, nil
+		}
+		return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), segments)
+	case strings.HasPrefix(pattern, "~/"):
+		patternSegments := strings.Split(strings.Trim(pattern[2:], "/"), "/")
+		for i := range segments {
+			if matched, err := matchSegments(patternSegments, segments[i:]); matched || err != nil {
+				return matched, err
+			}
+		}
+		return false, nil
+	default:
+		for _, segment := range segments {
+			if matched, err := path.Match(pattern, segment); matched || err != nil {
+				return matched, err
+			}
+		}
+		return false, nil
+	}
+}
+
+// matchSegments reports whether each pattern segment matches the segment
+// at the same position
+func matchSegments(patternSegments, segments []string) (bool, error) {
+	if len(segments) < len(patternSegments) {
+		return false, nil
+	}
+	for i, pattern := range patternSegments {
+		if matched, err := path.Match(pattern, segments[i]); !matched || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
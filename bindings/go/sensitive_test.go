@@ -0,0 +1,44 @@
+package pathsecurity
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestWithSensitivePaths(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{path: "/srv/app/public/logo.png"},
+		{path: "/proc/self/environ", wantErr: ErrMagicLink},
+		{path: "/home/deploy/.ssh/authorized_keys", wantErr: ErrSensitivePath},
+		{path: "site/web.config", wantErr: ErrSensitivePath},
+		{path: "certs/server.pem", wantErr: ErrSensitivePath},
+		{path: "/dev/null", wantErr: ErrSensitivePath},
+	}
+	ps := NewPathSecurity(WithSensitivePaths(append(DefaultSensitivePaths(), "*.pem")...))
+	for _, tt := range tests {
+		if _, err := ps.ValidatePath(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidatePath(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+
+	if _, err := NewPathSecurity(WithSensitivePaths("/etc/passwd"), WithCaseInsensitive(true)).ValidatePath("/ETC/Passwd"); !errors.Is(err, ErrSensitivePath) {
+		t.Errorf("case-insensitive ValidatePath(/ETC/Passwd) error = %v, want %v", err, ErrSensitivePath)
+	}
+}
+
+func TestDefaultSensitivePaths(t *testing.T) {
+	defaults := DefaultSensitivePaths()
+	for _, want := range []string{"~/.ssh", "web.config", "/dev"} {
+		if !slices.Contains(defaults, want) {
+			t.Errorf("DefaultSensitivePaths() = %q, missing %q", defaults, want)
+		}
+	}
+	defaults[0] = "changed"
+	if DefaultSensitivePaths()[0] == "changed" {
+		t.Error("DefaultSensitivePaths returned a shared slice")
+	}
+}
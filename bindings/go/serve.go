@@ -0,0 +1,62 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"mime"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ToHTTPPath validates a path and returns it as a cleaned, rooted URL path
+// suitable for serving. When servable extensions are configured with
+// WithServableExtensions, paths with any other extension are rejected with
+// ErrUnservableType.
+func (ps *PathSecurity) ToHTTPPath(p string) (string, error) {
+	httpPath, _, err := ps.resolveServable(p)
+	return httpPath, err
+}
+
+// IsServable reports whether a path validates and has a servable extension
+func (ps *PathSecurity) IsServable(p string) bool {
+	_, err := ps.ServableType(p)
+	return err == nil
+}
+
+// ServableType validates a path and returns the MIME type it should be served
+// with. With WithServableExtensions the type comes from the configured map and
+// unknown extensions fail with ErrUnservableType; otherwise it falls back to
+// mime.TypeByExtension and may be empty.
+func (ps *PathSecurity) ServableType(p string) (string, error) {
+	_, contentType, err := ps.resolveServable(p)
+	return contentType, err
+}
+
+// resolveServable validates a path and returns its URL path and MIME type
+func (ps *PathSecurity) resolveServable(p string) (string, string, error) {
+	p, err := ps.prepare(p)
+	if err != nil {
+		return "", "", err
+	}
+
+	httpPath := path.Clean("/" + filepath.ToSlash(p))
+	contentType, err := ps.contentType(httpPath)
+	if err != nil {
+		return "", "", err
+	}
+	return httpPath, contentType, nil
+}
+
+// contentType resolves the MIME type for a cleaned path from its extension
+func (ps *PathSecurity) contentType(p string) (string, error) {
+	ext := strings.ToLower(path.Ext(p))
+	if ps.cfg.servableExtensions == nil {
+		return mime.TypeByExtension(ext), nil
+	}
+
+	contentType, ok := ps.cfg.servableExtensions[ext]
+	if !ok || ext == "" {
+		return "", ps.reject(p, fmt.Errorf("%w: %q", ErrUnservableType, p))
+	}
+	return contentType, nil
+}
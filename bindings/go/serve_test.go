@@ -0,0 +1,49 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithServableExtensions(t *testing.T) {
+	ps := NewPathSecurity(WithServableExtensions(map[string]string{
+		".js": "application/javascript",
+		"css": "text/css",
+	}))
+	tests := []struct {
+		path     string
+		wantType string
+		wantHTTP string
+		wantErr  error
+	}{
+		{path: "static/app.js", wantType: "application/javascript", wantHTTP: "/static/app.js"},
+		{path: "static/APP.JS", wantType: "application/javascript", wantHTTP: "/static/APP.JS"},
+		{path: "static/site.css", wantType: "text/css", wantHTTP: "/static/site.css"},
+		{path: "static/payload.xyz", wantErr: ErrUnservableType},
+		{path: "static/README", wantErr: ErrUnservableType},
+		{path: "static/app.js.php", wantErr: ErrUnservableType},
+		{path: "../static/app.js", wantErr: ErrTraversalDetected},
+	}
+	for _, tt := range tests {
+		contentType, err := ps.ServableType(tt.path)
+		httpPath, httpErr := ps.ToHTTPPath(tt.path)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) || !errors.Is(httpErr, tt.wantErr) {
+				t.Errorf("ServableType(%q), ToHTTPPath(%q) errors = %v, %v, want %v", tt.path, tt.path, err, httpErr, tt.wantErr)
+			}
+			if ps.IsServable(tt.path) {
+				t.Errorf("IsServable(%q) = true", tt.path)
+			}
+			continue
+		}
+		if err != nil || contentType != tt.wantType {
+			t.Errorf("ServableType(%q) = %q, %v, want %q", tt.path, contentType, err, tt.wantType)
+		}
+		if httpErr != nil || httpPath != tt.wantHTTP {
+			t.Errorf("ToHTTPPath(%q) = %q, %v, want %q", tt.path, httpPath, httpErr, tt.wantHTTP)
+		}
+		if !ps.IsServable(tt.path) {
+			t.Errorf("IsServable(%q) = false", tt.path)
+		}
+	}
+}
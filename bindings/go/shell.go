@@ -0,0 +1,192 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ShellPolicy selects how environment references and command substitutions
+// in paths are treated, for callers that later pass paths to a shell or a
+// configuration template
+type ShellPolicy int
+
+const (
+	// ShellKeep applies no checks beyond the validator's, which treats '$'
+	// as suspicious wherever it appears
+	ShellKeep ShellPolicy = iota
+	// ShellReject rejects paths containing any construct reported by
+	// DetectShellConstructs, after expansion with WithExpandEnv, with an
+	// error wrapping ErrShellConstruct
+	ShellReject
+	// ShellExpand also expands %NAME% through the WithExpandEnv lookup, so
+	// $NAME, ${NAME} and %NAME% all resolve against it, and then rejects as
+	// ShellReject does. Pass AllowlistedEnv to resolve only chosen
+	// variables. Command substitutions are never expanded.
+	ShellExpand
+)
+
+// String returns the name of the policy
+func (p ShellPolicy) String() string {
+	switch p {
+	case ShellKeep:
+		return "keep"
+	case ShellReject:
+		return "reject"
+	case ShellExpand:
+		return "expand"
+	}
+	return fmt.Sprintf("ShellPolicy(%d)", int(p))
+}
+
+// ShellConstructKind describes a construct found by DetectShellConstructs
+type ShellConstructKind int
+
+const (
+	// ShellVariable is a $NAME or ${NAME} reference
+	ShellVariable ShellConstructKind = iota
+	// ShellWindowsVariable is a %NAME% reference. A name of two hex
+	// digits, as in "%2e%", is read as percent-encoding instead.
+	ShellWindowsVariable
+	// ShellCommand is a $(...) or `...` command substitution
+	ShellCommand
+)
+
+// String returns the name of the kind
+func (k ShellConstructKind) String() string {
+	switch k {
+	case ShellVariable:
+		return "variable reference"
+	case ShellWindowsVariable:
+		return "Windows variable reference"
+	case ShellCommand:
+		return "command substitution"
+	}
+	return fmt.Sprintf("ShellConstructKind(%d)", int(k))
+}
+
+// ShellConstruct is a shell or template construct in a path
+type ShellConstruct struct {
+	Offset int // byte offset of the construct in the path
+	// Text is the construct as written, up to the end of the path if it is
+	// not terminated
+	Text string
+	Kind ShellConstructKind
+}
+
+// String returns a description such as `command substitution "$(id)" at offset 5`
+func (s ShellConstruct) String() string {
+	return fmt.Sprintf("%s %q at offset %d", s.Kind, s.Text, s.Offset)
+}
+
+// DetectShellConstructs returns every environment reference and command
+// substitution in path: $NAME, ${NAME}, %NAME%, $(...) and backticks. It
+// returns nil for paths without them.
+func DetectShellConstructs(path string) []ShellConstruct {
+	var constructs []ShellConstruct
+	for i := 0; i < len(path); i++ {
+		if construct, ok := shellConstructAt(path, i); ok {
+			constructs = append(constructs, construct)
+			i += len(construct.Text) - 1
+		}
+	}
+	return constructs
+}
+
+// shellConstructAt returns the construct starting at offset i of path
+func shellConstructAt(path string, i int) (ShellConstruct, bool) {
+	rest := path[i:]
+	construct := func(end int, kind ShellConstructKind) (ShellConstruct, bool) {
+		if end < 0 {
+			end = len(rest)
+		}
+		return ShellConstruct{Offset: i, Text: rest[:end], Kind: kind}, true
+	}
+
+	switch {
+	case strings.HasPrefix(rest, "$("):
+		return construct(closingParen(rest), ShellCommand)
+	case rest[0] == '`':
+		if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+			return construct(end+2, ShellCommand)
+		}
+		return construct(-1, ShellCommand)
+	case strings.HasPrefix(rest, "${"):
+		if end := strings.IndexByte(rest, '}'); end >= 0 {
+			return construct(end+1, ShellVariable)
+		}
+		return construct(-1, ShellVariable)
+	case rest[0] == '$' && len(rest) > 1 && isEnvNameByte(rest[1], true):
+		end := 2
+		for end < len(rest) && isEnvNameByte(rest[end], false) {
+			end++
+		}
+		return construct(end, ShellVariable)
+	case rest[0] == '%':
+		if end := strings.IndexByte(rest[1:], '%'); end >= 0 {
+			name := rest[1 : end+1]
+			if isEnvName(name) && !isPercentEncodedByte(name) {
+				return construct(end+2, ShellWindowsVariable)
+			}
+		}
+	}
+	return ShellConstruct{}, false
+}
+
+// closingParen returns the offset just past the parenthesis closing the
+// "$(" at the start of s, or -1 if it is not closed
+func closingParen(s string) int {
+	depth := 0
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// isPercentEncodedByte reports whether name is two hex digits, the body of
+// a percent-encoded byte
+func isPercentEncodedByte(name string) bool {
+	return len(name) == 2 && isHexDigit(name[0]) && isHexDigit(name[1])
+}
+
+// isHexDigit reports whether c is a hexadecimal digit
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// checkShellConstructs rejects a path containing any construct reported by
+// DetectShellConstructs when a ShellPolicy other than ShellKeep is set
+func (c *config) checkShellConstructs(path string) *ValidationError {
+	if c.shellPolicy == ShellKeep {
+		return nil
+	}
+	for i := 0; i < len(path); i++ {
+		if construct, ok := shellConstructAt(path, i); ok {
+			return &ValidationError{Path: path, Reason: "Shell " + construct.String(), Err: ErrShellConstruct}
+		}
+	}
+	return nil
+}
+
+// AllowlistedEnv returns a lookup for WithExpandEnv that resolves only the
+// named variables from the process environment, so that a path cannot
+// reveal or depend on any other variable
+func AllowlistedEnv(names ...string) func(name string) (string, bool) {
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+	return func(name string) (string, bool) {
+		if !allowed[name] {
+			return "", false
+		}
+		return os.LookupEnv(name)
+	}
+}
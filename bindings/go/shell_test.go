@@ -0,0 +1,58 @@
+package pathsecurity
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDetectShellConstructs(t *testing.T) {
+	got := DetectShellConstructs("logs/$(id)/`whoami`/${HOME}/%WINDIR%/%2e%2e/$USER.log")
+	want := []ShellConstruct{
+		{Offset: 5, Text: "$(id)", Kind: ShellCommand},
+		{Offset: 11, Text: "`whoami`", Kind: ShellCommand},
+		{Offset: 20, Text: "${HOME}", Kind: ShellVariable},
+		{Offset: 28, Text: "%WINDIR%", Kind: ShellWindowsVariable},
+		{Offset: 44, Text: "$USER", Kind: ShellVariable},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DetectShellConstructs = %v, want %v", got, want)
+	}
+	if got := DetectShellConstructs("docs/100%/report.pdf"); got != nil {
+		t.Errorf("DetectShellConstructs(docs/100%%/report.pdf) = %v, want none", got)
+	}
+	if got := want[0].String(); got != `command substitution "$(id)" at offset 5` {
+		t.Errorf("ShellConstruct.String() = %q", got)
+	}
+}
+
+func TestWithShellPolicy(t *testing.T) {
+	env := map[string]string{"DATA": "srv/data", "WINDIR": "Windows"}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	tests := []struct {
+		policy  ShellPolicy
+		path    string
+		want    string
+		wantErr error
+	}{
+		{policy: ShellReject, path: "$DATA/report.csv", want: "srv/data/report.csv"},
+		{policy: ShellReject, path: "%WINDIR%/notes.txt", wantErr: ErrShellConstruct},
+		{policy: ShellReject, path: "uploads/$(rm -rf x)", wantErr: ErrShellConstruct},
+		{policy: ShellReject, path: "uploads/`id`", wantErr: ErrShellConstruct},
+		{policy: ShellExpand, path: "$DATA/report.csv", want: "srv/data/report.csv"},
+		{policy: ShellExpand, path: "%WINDIR%/notes.txt", want: "Windows/notes.txt"},
+		{policy: ShellExpand, path: "uploads/$(rm -rf x)", wantErr: ErrShellConstruct},
+		{policy: ShellExpand, path: "uploads/`id`", wantErr: ErrShellConstruct},
+		{policy: ShellExpand, path: "%SECRET%/key", wantErr: ErrUnresolvedVariable},
+	}
+	for _, tt := range tests {
+		ps := NewPathSecurity(WithWindowsSemantics(false), WithShellPolicy(tt.policy), WithExpandEnv(lookup))
+		got, err := ps.Canonicalize(tt.path)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("%s: Canonicalize(%q) = %q, %v, want %q, %v", tt.policy, tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
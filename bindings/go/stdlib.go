@@ -0,0 +1,143 @@
+package pathsecurity
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// StdlibComparison is the outcome of CompareWithStdlib
+type StdlibComparison struct {
+	Path string `json:"path"`
+	// Checked is the form of Path both sides judged, after environment
+	// expansion, decoding and Unicode normalization
+	Checked string `json:"checked"`
+	// Valid and Traversal are the verdicts of ValidatePath and
+	// DetectTraversal
+	Valid     bool `json:"valid"`
+	Traversal bool `json:"traversal"`
+	// Clean and IsLocal are the results of filepath.Clean and
+	// filepath.IsLocal on Checked
+	Clean   string `json:"clean"`
+	IsLocal bool   `json:"is_local"`
+	// Root is the allowed root containing an absolute path, found by root
+	// matching or else by filepath.Rel, and Rel is filepath.Rel from it
+	Root string `json:"root,omitempty"`
+	Rel  string `json:"rel,omitempty"`
+	// Disagreements describes each check where the binding is looser than
+	// the standard library
+	Disagreements []string `json:"disagreements,omitempty"`
+}
+
+// Agrees reports whether the comparison found no disagreements
+func (c StdlibComparison) Agrees() bool {
+	return len(c.Disagreements) == 0
+}
+
+// CompareWithStdlib judges path with both the binding and Go's path/filepath
+// and reports where the binding accepts what the standard library considers
+// an escape: a path ValidatePath accepts that filepath.IsLocal rejects, a
+// path DetectTraversal passes that filepath.Clean resolves above its start,
+// or an absolute path that root matching and filepath.Rel place differently
+// relative to the allowed roots. The binding being stricter, as with hidden
+// files or "a/../b", is not a disagreement.
+//
+// path/filepath follows the host operating system. Under WithWindowsSemantics
+// on another host, '\' is converted to '/' before calling it and the
+// absolute path check is skipped. Containment is not compared with
+// WithCaseInsensitive, which filepath.Rel does not honor. No audit events,
+// metrics or warnings are produced.
+func (ps *PathSecurity) CompareWithStdlib(path string) (StdlibComparison, error) {
+	checked, err := ps.preprocess(path)
+	if err != nil {
+		return StdlibComparison{}, err
+	}
+	quiet := ps.quiet()
+	_, err = quiet.validate(checked)
+	return quiet.compareWithStdlib(path, checked, err == nil), nil
+}
+
+// quiet returns a shallow copy of ps that reports nothing to observers, for
+// running checks on the caller's behalf
+func (ps *PathSecurity) quiet() *PathSecurity {
+	quiet := &PathSecurity{cfg: ps.cfg}
+	quiet.cfg.auditFunc = nil
+	quiet.cfg.metrics = nil
+	quiet.cfg.warnObserver = nil
+	quiet.cfg.stdlibCrossCheck = nil
+	return quiet
+}
+
+// crossCheck passes the comparison for a path ValidatePath has judged to the
+// WithStdlibCrossCheck function if they disagree
+func (ps *PathSecurity) crossCheck(path, checked string, valid bool) {
+	if comparison := ps.quiet().compareWithStdlib(path, checked, valid); !comparison.Agrees() {
+		ps.cfg.stdlibCrossCheck(comparison)
+	}
+}
+
+// compareWithStdlib compares the verdict valid on the preprocessed path
+// checked with path/filepath
+func (ps *PathSecurity) compareWithStdlib(path, checked string, valid bool) StdlibComparison {
+	c := StdlibComparison{Path: path, Checked: checked, Valid: valid}
+	// A NUL byte fails DetectTraversal, which treats it as an attack
+	c.Traversal = true
+	if strings.IndexByte(checked, 0) < 0 {
+		c.Traversal, _ = ps.detectTraversal(checked)
+	}
+
+	hostSemantics := ps.cfg.windowsSemantics == (runtime.GOOS == "windows")
+	native := checked
+	if !hostSemantics && ps.cfg.windowsSemantics {
+		native = strings.ReplaceAll(checked, `\`, "/")
+	}
+	c.Clean = filepath.Clean(native)
+	c.IsLocal = filepath.IsLocal(native)
+
+	absolute := ps.cfg.isAbsolute(checked)
+	if hostSemantics && absolute != filepath.IsAbs(native) {
+		c.disagree("absolute path detection differs from filepath.IsAbs")
+	}
+	if !absolute {
+		// filepath.IsLocal rejects "" as naming nothing rather than as an escape
+		if c.Valid && !c.IsLocal && checked != "" {
+			c.disagree("ValidatePath accepts a path filepath.IsLocal reports as not local")
+		}
+		if !c.Traversal && escapesStart(c.Clean) {
+			c.disagree("DetectTraversal passes a path filepath.Clean resolves to " + c.Clean)
+		}
+		return c
+	}
+
+	if ps.cfg.caseInsensitive || len(ps.cfg.allowedRoots) == 0 {
+		return c
+	}
+	root, matched := ps.matchRoot(c.Clean)
+	if matched {
+		c.Root = root
+		rel, err := filepath.Rel(root, c.Clean)
+		c.Rel = rel
+		if err != nil || escapesStart(rel) {
+			c.disagree("root matching places the path under " + root + ", which filepath.Rel does not")
+		}
+		return c
+	}
+	for _, candidate := range ps.cfg.allowedRoots {
+		if rel, err := filepath.Rel(candidate, c.Clean); err == nil && !escapesStart(rel) {
+			c.Root, c.Rel = candidate, rel
+			c.disagree("filepath.Rel places the path under " + candidate + ", which root matching does not")
+			break
+		}
+	}
+	return c
+}
+
+// disagree records a disagreement
+func (c *StdlibComparison) disagree(msg string) {
+	c.Disagreements = append(c.Disagreements, msg)
+}
+
+// escapesStart reports whether a cleaned relative path climbs above its start
+func escapesStart(clean string) bool {
+	return clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,93 @@
+package pathsecurity
+
+import (
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompareWithStdlib(t *testing.T) {
+	tests := []struct {
+		path      string
+		valid     bool
+		traversal bool
+		clean     string
+		local     bool
+	}{
+		{"docs/readme.md", true, false, "docs/readme.md", true},
+		{"a/../../etc", false, true, "../etc", false},
+		{"/srv/data/file.txt", true, false, "/srv/data/file.txt", false},
+		{"..", false, true, "..", false},
+	}
+	ps := NewPathSecurity(WithAllowedRoots("/srv/data"))
+	for _, tt := range tests {
+		c, err := ps.CompareWithStdlib(tt.path)
+		if err != nil {
+			t.Errorf("CompareWithStdlib(%q) error = %v", tt.path, err)
+			continue
+		}
+		if c.Valid != tt.valid || c.Traversal != tt.traversal || c.Clean != tt.clean || c.IsLocal != tt.local || !c.Agrees() {
+			t.Errorf("CompareWithStdlib(%q) = %+v, want valid %t, traversal %t, clean %q, local %t and agreement", tt.path, c, tt.valid, tt.traversal, tt.clean, tt.local)
+		}
+	}
+
+	if !(StdlibComparison{}).Agrees() || (StdlibComparison{Disagreements: []string{"x"}}).Agrees() {
+		t.Error("Agrees does not follow Disagreements")
+	}
+}
+
+func TestWithStdlibCrossCheck(t *testing.T) {
+	var disagreements []StdlibComparison
+	ps := NewPathSecurity(WithStdlibCrossCheck(func(c StdlibComparison) {
+		disagreements = append(disagreements, c)
+	}))
+	for _, payload := range TraversalPayloads() {
+		ps.ValidatePath(payload)
+	}
+	for _, c := range disagreements {
+		t.Errorf("cross-check disagreement on %q: %v", c.Path, c.Disagreements)
+	}
+}
+
+func TestValidatePathsStdlibCrossCheck(t *testing.T) {
+	paths := []string{"docs/readme.md", "../include/util.h", "../../etc/passwd"}
+	for _, opts := range [][]Option{
+		{WithMaxParentHops(1)},
+		{WithMaxParentHops(1), WithFailThreshold(SeverityHigh)},
+	} {
+		var single, batch []string
+		ps := NewPathSecurity(append(opts, WithStdlibCrossCheck(func(c StdlibComparison) {
+			single = append(single, c.Path)
+		}))...)
+		for _, path := range paths {
+			ps.ValidatePath(path)
+		}
+		ps = NewPathSecurity(append(opts, WithStdlibCrossCheck(func(c StdlibComparison) {
+			batch = append(batch, c.Path)
+		}))...)
+		ps.ValidatePaths(paths)
+
+		if len(single) == 0 || !reflect.DeepEqual(batch, single) {
+			t.Errorf("ValidatePaths cross-checked %q, ValidatePath %q", batch, single)
+		}
+	}
+}
+
+func TestWithStdlibParity(t *testing.T) {
+	isLocal := NewPathSecurity(WithStdlibParity(ParityIsLocal))
+	validPath := NewPathSecurity(WithStdlibParity(ParityValidPath))
+	for _, p := range []string{"a/b", "a/../b", "./a", "../a", "/etc/passwd", "%2e%2e/a", "", "."} {
+		if _, err := isLocal.ValidatePath(p); filepath.IsLocal(p) != (err == nil) {
+			t.Errorf("ParityIsLocal: ValidatePath(%q) error = %v, filepath.IsLocal = %t", p, err, filepath.IsLocal(p))
+		}
+		if _, err := validPath.ValidatePath(p); fs.ValidPath(p) != (err == nil) {
+			t.Errorf("ParityValidPath: ValidatePath(%q) error = %v, fs.ValidPath = %t", p, err, fs.ValidPath(p))
+		}
+	}
+
+	explanation, err := isLocal.Explain("../a")
+	if err != nil || explanation.Valid || len(explanation.Checks) != 1 || explanation.Checks[0].Name != "stdlib-parity" {
+		t.Errorf("ParityIsLocal Explain = %+v, %v, want a single failed stdlib-parity check", explanation, err)
+	}
+}
@@ -0,0 +1,59 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidateForStorage produces the representation of a path that should be
+// stored (for example in a database column) and reused later. The input
+// goes through the same pre-flight checks, expansion and decoding policy
+// as ValidatePath. The stored form is valid UTF-8, contains no control
+// characters, uses forward slashes, has Unicode lookalikes folded and is
+// lexically clean. Paths that are ambiguous after decoding (still
+// containing '%' under a policy that decodes) are rejected rather than
+// guessed at.
+//
+// Values read back from storage should be checked with RevalidateStored
+// before being used to access files.
+func (ps *PathSecurity) ValidateForStorage(path string) (string, error) {
+	decoded, err := ps.preprocess(path)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.ValidString(decoded) || (ps.cfg.decoding != DecodeNone && strings.Contains(decoded, "%")) {
+		return "", ps.reject(path, fmt.Errorf("%w: %q", ErrInvalidEncoding, path))
+	}
+	if i := findControlChar(decoded); i >= 0 {
+		return "", ps.reject(path, fmt.Errorf("%w at offset %d: %q", ErrControlCharacter, i, path))
+	}
+	if err := ps.check(decoded); err != nil {
+		return "", err
+	}
+	ps.warn(decoded)
+
+	cfg := ps.cfg
+	cfg.unicodeFolding = true
+	cfg.normalizeSeparators = true
+	stored := cfg.canonicalize(decoded)
+	if err := ps.check(stored); err != nil {
+		return "", err
+	}
+	return stored, nil
+}
+
+// RevalidateStored confirms that a value previously produced by
+// ValidateForStorage still passes validation and is still in canonical
+// storage form. It returns ErrNotCanonical if the value validates but would
+// be stored differently, which indicates it was written by something else.
+func (ps *PathSecurity) RevalidateStored(stored string) error {
+	canonical, err := ps.ValidateForStorage(stored)
+	if err != nil {
+		return err
+	}
+	if canonical != stored {
+		return ps.reject(stored, fmt.Errorf("%w: %q", ErrNotCanonical, stored))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package pathsecurity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateForStorage(t *testing.T) {
+	tests := []struct {
+		path    string
+		opts    []Option
+		want    string
+		wantErr error
+	}{
+		{path: "uploads/2024/./My%20Report.pdf", opts: []Option{WithDecoding(DecodeOnce)}, want: "uploads/2024/My Report.pdf"},
+		{path: "uploads/2024/./My%20Report.pdf", want: "uploads/2024/My%20Report.pdf"},
+		{path: "uploads/2024/report.pdf", want: "uploads/2024/report.pdf"},
+		{path: "uploads/../../secret", wantErr: ErrTraversalDetected},
+		{path: "uploads/My%2520Report.pdf", opts: []Option{WithDecoding(DecodeOnce)}, wantErr: ErrInvalidEncoding},
+		{path: "uploads/My%20Report.pdf", opts: []Option{WithDecoding(RejectEncoded)}, wantErr: ErrInvalidEncoding},
+		{path: "uploads/\xff/report.pdf", wantErr: ErrInvalidEncoding},
+		{path: "uploads/" + strings.Repeat("a", 64), opts: []Option{WithMaxInputBytes(32)}, wantErr: ErrPathTooLong},
+	}
+	for _, tt := range tests {
+		ps := NewPathSecurity(tt.opts...)
+		got, err := ps.ValidateForStorage(tt.path)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("ValidateForStorage(%q) = %q, %v, want %q, %v", tt.path, got, err, tt.want, tt.wantErr)
+			continue
+		}
+		if err == nil {
+			if err := ps.RevalidateStored(got); err != nil {
+				t.Errorf("RevalidateStored(%q) = %v", got, err)
+			}
+		}
+	}
+
+	ps := NewPathSecurity(WithDecoding(DecodeOnce))
+	if err := ps.RevalidateStored("uploads/2024/./My%20Report.pdf"); !errors.Is(err, ErrNotCanonical) {
+		t.Errorf("RevalidateStored of a raw path = %v, want %v", err, ErrNotCanonical)
+	}
+}
@@ -0,0 +1,145 @@
+package pathsecurity
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// TargetCheck is a check ValidateResolved applies to the file a path
+// resolves to, as enabled with WithTargetChecks and WithTargetOwner
+type TargetCheck int
+
+const (
+	// TargetExists requires the target to exist
+	TargetExists TargetCheck = iota
+	// TargetRegular requires an existing target to be a regular file
+	// rather than a directory, named pipe, socket or device
+	TargetRegular
+	// TargetOwner requires an existing target to have the owner and group
+	// set with WithTargetOwner
+	TargetOwner
+	// TargetNotWorldWritable requires an existing target not to be
+	// writable by every user
+	TargetNotWorldWritable
+)
+
+// String returns the name of the check
+func (t TargetCheck) String() string {
+	switch t {
+	case TargetExists:
+		return "exists"
+	case TargetRegular:
+		return "regular"
+	case TargetOwner:
+		return "owner"
+	case TargetNotWorldWritable:
+		return "not-world-writable"
+	}
+	return fmt.Sprintf("TargetCheck(%d)", int(t))
+}
+
+// MarshalText encodes the check as its name
+func (t TargetCheck) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// TargetFinding is one check the target of a path failed
+type TargetFinding struct {
+	Check  TargetCheck `json:"check"`
+	Reason string      `json:"reason"`
+}
+
+// TargetError lists every check the target of a path failed. Path is the
+// resolved path. It matches ErrTargetCheck with errors.Is.
+type TargetError struct {
+	Path     string          `json:"path"`
+	Findings []TargetFinding `json:"findings"`
+}
+
+// Error implements the error interface
+func (e *TargetError) Error() string {
+	reasons := make([]string, len(e.Findings))
+	for i, f := range e.Findings {
+		reasons[i] = f.Reason
+	}
+	return fmt.Sprintf("%v: %q: %s", ErrTargetCheck, e.Path, strings.Join(reasons, "; "))
+}
+
+// Unwrap returns ErrTargetCheck
+func (e *TargetError) Unwrap() error {
+	return ErrTargetCheck
+}
+
+// targetCheckEnabled reports whether check is to be applied
+func (c *config) targetCheckEnabled(check TargetCheck) bool {
+	if check == TargetOwner {
+		return c.targetOwner
+	}
+	return c.targetChecks&(1<<check) != 0
+}
+
+// checkTarget applies the checks set with WithTargetChecks and
+// WithTargetOwner to resolved, the resolved form of path
+func (ps *PathSecurity) checkTarget(path, resolved string) error {
+	c := &ps.cfg
+	if c.targetChecks == 0 && !c.targetOwner {
+		return nil
+	}
+
+	// The resolved path has no symlinks left, so one appearing now is
+	// reported rather than followed
+	info, err := os.Lstat(resolved)
+	if errors.Is(err, fs.ErrNotExist) {
+		if c.targetCheckEnabled(TargetExists) {
+			return ps.reject(path, &TargetError{Path: resolved, Findings: []TargetFinding{{Check: TargetExists, Reason: "Target does not exist"}}})
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var findings []TargetFinding
+	if c.targetCheckEnabled(TargetRegular) && !info.Mode().IsRegular() {
+		findings = append(findings, TargetFinding{Check: TargetRegular, Reason: fmt.Sprintf("Target is a %s, not a regular file", fileKind(info.Mode()))})
+	}
+	if c.targetOwner {
+		uid, gid, ok := fileOwner(info)
+		if !ok {
+			findings = append(findings, TargetFinding{Check: TargetOwner, Reason: "Owner of target cannot be determined on this platform"})
+		}
+		if ok && c.targetUID >= 0 && uid != c.targetUID {
+			findings = append(findings, TargetFinding{Check: TargetOwner, Reason: fmt.Sprintf("Target is owned by UID %d, not %d", uid, c.targetUID)})
+		}
+		if ok && c.targetGID >= 0 && gid != c.targetGID {
+			findings = append(findings, TargetFinding{Check: TargetOwner, Reason: fmt.Sprintf("Target belongs to GID %d, not %d", gid, c.targetGID)})
+		}
+	}
+	if c.targetCheckEnabled(TargetNotWorldWritable) && worldWritable(info) {
+		findings = append(findings, TargetFinding{Check: TargetNotWorldWritable, Reason: fmt.Sprintf("Target is writable by every user (%v)", info.Mode().Perm())})
+	}
+	if len(findings) > 0 {
+		return ps.reject(path, &TargetError{Path: resolved, Findings: findings})
+	}
+	return nil
+}
+
+// fileKind names the type of file described by mode
+func fileKind(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "directory"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeDevice != 0:
+		return "device"
+	}
+	return "special file"
+}
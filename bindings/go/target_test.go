@@ -0,0 +1,67 @@
+package pathsecurity
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestWithTargetChecks(t *testing.T) {
+	root := testTree(t)
+	inbox := filepath.Join(root, "inbox")
+	os.WriteFile(filepath.Join(inbox, "report.txt"), []byte("q1\n"), 0o644)
+	os.WriteFile(filepath.Join(inbox, "shared.txt"), []byte("q1\n"), 0o644)
+	if err := os.Chmod(filepath.Join(inbox, "shared.txt"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want []TargetCheck
+	}{
+		{"report.txt", nil},
+		{"shared.txt", []TargetCheck{TargetNotWorldWritable}},
+		{"missing.txt", []TargetCheck{TargetExists}},
+		{".", []TargetCheck{TargetRegular}},
+	}
+	ps := NewPathSecurity(WithAllowedRoots(root), WithTargetChecks(TargetExists, TargetRegular, TargetNotWorldWritable))
+	for _, tt := range tests {
+		_, err := ps.CheckPath(filepath.Join(inbox, tt.name), Resolved)
+		if got := failedChecks(t, err); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CheckPath(%q) failed %v, want %v (%v)", tt.name, got, tt.want, err)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	owned := ps.Clone(WithTargetOwner(os.Getuid(), -1))
+	if _, err := owned.CheckPath(filepath.Join(inbox, "report.txt"), Resolved); err != nil {
+		t.Errorf("CheckPath of a file owned by the current user = %v", err)
+	}
+	foreign := ps.Clone(WithTargetOwner(os.Getuid()+1, -1))
+	_, err := foreign.CheckPath(filepath.Join(inbox, "shared.txt"), Resolved)
+	if got, want := failedChecks(t, err), []TargetCheck{TargetOwner, TargetNotWorldWritable}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckPath of another user's file failed %v, want %v (%v)", got, want, err)
+	}
+}
+
+// failedChecks returns the checks listed by the *TargetError err
+func failedChecks(t *testing.T, err error) []TargetCheck {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var target *TargetError
+	if !errors.As(err, &target) || !errors.Is(err, ErrTargetCheck) {
+		t.Fatalf("error = %v, want a *TargetError", err)
+	}
+	checks := make([]TargetCheck, len(target.Findings))
+	for i, f := range target.Findings {
+		checks[i] = f.Check
+	}
+	return checks
+}
@@ -0,0 +1,155 @@
+package pathsecurity
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tempRandomBytes is the number of random bytes SecureTempPath puts into a
+// name, written as twice as many hex digits
+const tempRandomBytes = 16
+
+// SecureTempPath returns a path for a new temporary file in dir, or in
+// os.TempDir if dir is empty, whose name is pattern with its last "*"
+// replaced by 128 random bits in hex, or with them appended if pattern has
+// no "*". The file is not created: open the path with
+// os.O_CREATE|os.O_EXCL and mode 0o600 so an attacker who guesses it
+// cannot have planted a file or symlink there first. The name must pass
+// validation and pattern must not contain a separator. A dir that is
+// writable by everyone but lacks the sticky bit, so other users can
+// rename or delete entries in it, is rejected with ErrInsecureTempDir.
+func (ps *PathSecurity) SecureTempPath(dir, pattern string) (string, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if strings.ContainsAny(pattern, `/\`) {
+		return "", ps.reject(pattern, fmt.Errorf("%w: pattern %q contains a path separator", ErrInvalidFilename, pattern))
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%w: %q is not a directory", ErrParentMissing, dir)
+	}
+	if worldWritable(info) && info.Mode()&os.ModeSticky == 0 {
+		return "", ps.reject(dir, fmt.Errorf("%w: %q is writable by everyone without the sticky bit", ErrInsecureTempDir, dir))
+	}
+
+	random := make([]byte, tempRandomBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	prefix, suffix := pattern, ""
+	if i := strings.LastIndexByte(pattern, '*'); i >= 0 {
+		prefix, suffix = pattern[:i], pattern[i+1:]
+	}
+	name := prefix + hex.EncodeToString(random) + suffix
+	if err := ps.check(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// CheckTempPath flags a path in a shared temporary directory whose name
+// an attacker could predict and claim first, the pattern behind symlink
+// and pre-creation races on /tmp. A directory counts as shared if it is
+// os.TempDir, /tmp, /var/tmp or /dev/shm, or an existing directory
+// writable by everyone. The first component of path below the innermost
+// shared directory must contain a random-looking run, such as those of
+// mkstemp, os.CreateTemp or SecureTempPath; otherwise the path is rejected
+// with ErrPredictablePath. Deeper components are not checked, as they sit
+// in a directory the random component made private. A shared directory
+// without the sticky bit is rejected with ErrInsecureTempDir. The check is
+// lexical apart from reading the modes of path's ancestors, and does not
+// validate path otherwise; paths outside shared directories pass.
+func (ps *PathSecurity) CheckTempPath(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	shared := ""
+	for _, dir := range []string{os.TempDir(), "/tmp", "/var/tmp", "/dev/shm"} {
+		dir = filepath.Clean(dir)
+		if dir != abs && withinRoot(dir, abs) && len(dir) > len(shared) {
+			shared = dir
+		}
+	}
+	for dir := filepath.Dir(abs); len(dir) > len(shared); dir = filepath.Dir(dir) {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() && worldWritable(info) {
+			shared = dir
+			break
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+	if shared == "" {
+		return nil
+	}
+
+	if info, err := os.Stat(shared); err == nil && worldWritable(info) && info.Mode()&os.ModeSticky == 0 {
+		return ps.reject(path, fmt.Errorf("%w: %q is writable by everyone without the sticky bit", ErrInsecureTempDir, shared))
+	}
+	rel, err := filepath.Rel(shared, abs)
+	if err != nil {
+		return err
+	}
+	first, _, _ := strings.Cut(rel, string(filepath.Separator))
+	if !hasRandomRun(first) {
+		return ps.reject(path, fmt.Errorf("%w: %q in %q has no random component", ErrPredictablePath, first, shared))
+	}
+	return nil
+}
+
+// hasRandomRun reports whether name contains a run of ASCII letters and
+// digits that looks generated: at least 6 characters switching between
+// lower case, upper case and digits at least twice, as in mkstemp's
+// "XXXXXX" or a hex string, or holding at least 9 digits in a row, as
+// os.CreateTemp writes. Words with a number such as "report2024" switch
+// once and do not count, while long timestamps cannot be told apart from
+// random digits.
+func hasRandomRun(name string) bool {
+	class := func(c byte) int {
+		switch {
+		case c >= 'a' && c <= 'z':
+			return 1
+		case c >= 'A' && c <= 'Z':
+			return 2
+		case c >= '0' && c <= '9':
+			return 3
+		}
+		return 0
+	}
+
+	for i := 0; i < len(name); {
+		if class(name[i]) == 0 {
+			i++
+			continue
+		}
+		start, switches, digits := i, 0, 0
+		for ; i < len(name) && class(name[i]) != 0; i++ {
+			if class(name[i]) == 3 {
+				digits++
+			} else {
+				digits = 0
+			}
+			if digits >= 9 {
+				return true
+			}
+			if i > start && class(name[i]) != class(name[i-1]) {
+				switches++
+			}
+		}
+		if i-start >= 6 && switches >= 2 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package pathsecurity
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecureTempPath(t *testing.T) {
+	ps := NewPathSecurity(WithWindowsSemantics(false))
+	temp, err := ps.SecureTempPath("", "upload-*.part")
+	if err != nil {
+		t.Fatalf("SecureTempPath error = %v", err)
+	}
+	name := filepath.Base(temp)
+	if !strings.HasPrefix(name, "upload-") || !strings.HasSuffix(name, ".part") || len(name) != len("upload-.part")+32 {
+		t.Errorf("SecureTempPath = %q, want upload-<32 random characters>.part", temp)
+	}
+	if err := ps.CheckTempPath(temp); err != nil {
+		t.Errorf("CheckTempPath(%q) = %v", temp, err)
+	}
+	if again, _ := ps.SecureTempPath("", "upload-*.part"); again == temp {
+		t.Errorf("SecureTempPath returned %q twice", temp)
+	}
+
+	scratch, err := filepath.Abs(filepath.Join(testTree(t), "scratch"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(scratch, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(scratch, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(scratch); err != nil || info.Mode().Perm() != 0o777 {
+		t.Skip("cannot make a world-writable directory")
+	}
+	if _, err := ps.SecureTempPath(scratch, "upload-*.part"); !errors.Is(err, ErrInsecureTempDir) {
+		t.Errorf("SecureTempPath in a world-writable directory without sticky bit = %v, want %v", err, ErrInsecureTempDir)
+	}
+}
+
+func TestCheckTempPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{path: "/tmp/app.lock", wantErr: ErrPredictablePath},
+		{path: "/tmp/report2024.csv", wantErr: ErrPredictablePath},
+		{path: "/tmp/session-1234.json", wantErr: ErrPredictablePath},
+		{path: "/tmp/tmp.k3Jx9Q/data.txt"},
+		{path: "/var/tmp/cache-4b7f0e1a9c2d/index"},
+		{path: "/tmp/go-build3141592653/main"},
+		{path: "/home/user/app.lock"},
+	}
+	ps := NewPathSecurity(WithWindowsSemantics(false))
+	for _, tt := range tests {
+		if err := ps.CheckTempPath(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("CheckTempPath(%q) = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
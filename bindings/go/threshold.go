@@ -0,0 +1,110 @@
+package pathsecurity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MonitorCollector is implemented by MetricsCollectors that also count the
+// rejections WithFailThreshold lets through
+type MonitorCollector interface {
+	// ObserveMonitored records one path that would have been rejected
+	// under the category returned by RejectionCategory
+	ObserveMonitored(category string)
+}
+
+// RejectionSeverity returns the severity of the rejection err matches, as
+// WithFailThreshold compares it: low for file name and parent directory
+// checks, medium for limits and other rules that depend on the
+// application, high for paths that indicate an attack and critical for
+// traversal, NUL bytes and symlink escapes. Other errors are critical.
+func RejectionSeverity(err error) Severity {
+	if severity, ok := rejectionSeverity(err); ok {
+		return severity
+	}
+	return SeverityCritical
+}
+
+// rejectionSeverity returns the severity of the rejection err matches and
+// false if it matches none
+func rejectionSeverity(err error) (Severity, bool) {
+	for _, c := range rejectionCategories {
+		if errors.Is(err, c.err) {
+			return c.severity, true
+		}
+	}
+	return SeverityLow, false
+}
+
+// validateMonitored is validateUnobserved under a fail threshold. The checks
+// run without reporting their rejections, which are then either reported
+// and returned or, below the threshold, reported as monitored.
+func (ps *PathSecurity) validateMonitored(path string) (string, error) {
+	unenforced := &PathSecurity{cfg: ps.cfg}
+	unenforced.cfg.auditFunc = nil
+	unenforced.cfg.metrics = nil
+	unenforced.cfg.failThreshold = SeverityLow
+	response, err := unenforced.validateUnobserved(path)
+	if err == nil {
+		return response, nil
+	}
+	severity, ok := rejectionSeverity(err)
+	if !ok {
+		return "", err
+	}
+	if severity < ps.cfg.failThreshold {
+		// Validation stops at the first rejection, so the checks after it
+		// are run as well in case one reaches the threshold
+		var e Explanation
+		if err := unenforced.explainChecks(&e, path); err != nil {
+			return "", err
+		}
+		if enforced := ps.cfg.enforced(e.Checks); enforced != nil {
+			err, severity = enforced, RejectionSeverity(enforced)
+		}
+	}
+	if severity >= ps.cfg.failThreshold {
+		ps.observeRejection(path, err)
+		return "", err
+	}
+	ps.monitor(path, err, severity)
+	return monitoredResponse(path), nil
+}
+
+// enforced returns the error of the first matched check whose rejection
+// reaches the fail threshold, or nil if there is none
+func (c *config) enforced(checks []ExplainCheck) error {
+	for _, check := range checks {
+		if severity, ok := rejectionSeverity(check.Err); check.Matched && (!ok || severity >= c.failThreshold) {
+			return check.Err
+		}
+	}
+	return nil
+}
+
+// monitoredResponse is the JSON report of validation for a path whose
+// rejection was let through
+func monitoredResponse(path string) string {
+	response, _ := json.Marshal(struct {
+		Valid     bool   `json:"valid"`
+		Path      string `json:"path"`
+		Monitored bool   `json:"monitored"`
+	}{true, path, true})
+	return string(response)
+}
+
+// monitor reports a rejection of path with err, of the given severity, that
+// the fail threshold lets through
+func (ps *PathSecurity) monitor(path string, err error, severity Severity) {
+	if ps.cfg.warnObserver != nil {
+		ps.cfg.warnObserver(path, fmt.Sprintf("would reject (%s): %v", severity, err))
+	}
+	if ps.cfg.auditFunc != nil {
+		ps.cfg.auditFunc(Event{Time: time.Now(), Decision: DecisionMonitored, Path: path, Rule: rejectionRule(err), Err: err})
+	}
+	if collector, ok := ps.cfg.metrics.(MonitorCollector); ok {
+		collector.ObserveMonitored(RejectionCategory(err))
+	}
+}
@@ -0,0 +1,107 @@
+package pathsecurity
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// monitorCounter is a MonitorCollector counting monitored rejections
+type monitorCounter map[string]int
+
+func (monitorCounter) ObserveValidation(time.Duration, bool) {}
+func (monitorCounter) ObserveRejection(string)               {}
+func (monitorCounter) ObserveSanitization(string)            {}
+func (m monitorCounter) ObserveMonitored(category string)    { m[category]++ }
+
+func TestRejectionSeverity(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Severity
+	}{
+		{ErrTraversalDetected, SeverityCritical},
+		{ErrPathTooDeep, SeverityMedium},
+		{ErrInvalidFilename, SeverityLow},
+		{errors.New("other"), SeverityCritical},
+	}
+	for _, tt := range tests {
+		if got := RejectionSeverity(tt.err); got != tt.want {
+			t.Errorf("RejectionSeverity(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWithFailThreshold(t *testing.T) {
+	const deep, traversal = "a/b/c/d/e.txt", "../etc/passwd"
+	tests := []struct {
+		threshold     Severity
+		wantDeep      error
+		wantTraversal error
+		wantAudit     []string
+		wantWarnings  int
+	}{
+		{SeverityLow, ErrPathTooDeep, ErrTraversalDetected, []string{"rejected too_deep", "rejected traversal"}, 0},
+		{SeverityHigh, nil, ErrTraversalDetected, []string{"monitored too_deep", "rejected traversal"}, 1},
+		{MonitorOnly, nil, nil, []string{"monitored too_deep", "monitored traversal"}, 2},
+	}
+	collector := monitorCounter{}
+	for _, tt := range tests {
+		var audit []string
+		warnings := 0
+		ps := NewPathSecurity(
+			WithWindowsSemantics(false),
+			WithMaxDepth(3),
+			WithFailThreshold(tt.threshold),
+			WithMetrics(collector),
+			WithAuditFunc(func(e Event) {
+				audit = append(audit, e.Decision.String()+" "+RejectionCategory(e.Err))
+			}),
+			WithWarnObserver(func(path, warning string) {
+				if strings.HasPrefix(warning, "would reject") {
+					warnings++
+				}
+			}),
+		)
+
+		response, err := ps.ValidatePath(deep)
+		if !errors.Is(err, tt.wantDeep) {
+			t.Errorf("%v: ValidatePath(%q) error = %v, want %v", tt.threshold, deep, err, tt.wantDeep)
+		}
+		if err == nil && response != `{"valid":true,"path":"a/b/c/d/e.txt","monitored":true}` {
+			t.Errorf("%v: ValidatePath(%q) = %s, want a monitored response", tt.threshold, deep, response)
+		}
+		if _, err := ps.ValidatePath(traversal); !errors.Is(err, tt.wantTraversal) {
+			t.Errorf("%v: ValidatePath(%q) error = %v, want %v", tt.threshold, traversal, err, tt.wantTraversal)
+		}
+		// Batches follow the same threshold
+		results := ps.ValidatePaths([]string{deep, traversal})
+		if !errors.Is(results[0].Err, tt.wantDeep) || !errors.Is(results[1].Err, tt.wantTraversal) {
+			t.Errorf("%v: ValidatePaths errors = %v, %v, want %v, %v", tt.threshold, results[0].Err, results[1].Err, tt.wantDeep, tt.wantTraversal)
+		}
+
+		if want := append(tt.wantAudit, tt.wantAudit...); !reflect.DeepEqual(audit, want) {
+			t.Errorf("%v: audit = %q, want %q", tt.threshold, audit, want)
+		}
+		if warnings != 2*tt.wantWarnings {
+			t.Errorf("%v: %d would-reject warnings, want %d", tt.threshold, warnings, 2*tt.wantWarnings)
+		}
+	}
+	if want := (monitorCounter{"too_deep": 4, "traversal": 2}); !reflect.DeepEqual(collector, want) {
+		t.Errorf("monitored rejections = %v, want %v", collector, want)
+	}
+}
+
+func TestPolicyFailThreshold(t *testing.T) {
+	policy, err := NewPolicy(PolicySpec{RejectSeverity: "low", FailThreshold: "medium"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policy.Check("docs/.hidden"); err != nil {
+		t.Errorf("Check below fail_threshold = %v", err)
+	}
+	if _, err := NewPolicy(PolicySpec{FailThreshold: "never"}); err == nil {
+		t.Error(`NewPolicy accepted fail_threshold "never"`)
+	}
+}
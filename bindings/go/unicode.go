@@ -0,0 +1,169 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// UnicodeNormalization selects the Unicode normalization form applied to
+// input paths before validation
+type UnicodeNormalization int
+
+const (
+	// NormalizeNone leaves input paths as they are
+	NormalizeNone UnicodeNormalization = iota
+	// NormalizeNFC composes characters canonically, matching how most
+	// Linux and Windows tools store names
+	NormalizeNFC
+	// NormalizeNFKC also applies compatibility mappings, so fullwidth and
+	// other presentation forms become their plain equivalents
+	NormalizeNFKC
+)
+
+// String returns the name of the normalization form
+func (n UnicodeNormalization) String() string {
+	switch n {
+	case NormalizeNone:
+		return "none"
+	case NormalizeNFC:
+		return "NFC"
+	case NormalizeNFKC:
+		return "NFKC"
+	}
+	return fmt.Sprintf("UnicodeNormalization(%d)", int(n))
+}
+
+// normalize applies the normalization form to path
+func (n UnicodeNormalization) normalize(path string) string {
+	switch n {
+	case NormalizeNFC:
+		return norm.NFC.String(path)
+	case NormalizeNFKC:
+		return norm.NFKC.String(path)
+	}
+	return path
+}
+
+// UnicodeTrickKind describes why a character in a path is considered a trick
+type UnicodeTrickKind int
+
+const (
+	// TrickInvisible is a zero-width or otherwise invisible character
+	TrickInvisible UnicodeTrickKind = iota
+	// TrickBidiControl is a bidirectional override, embedding, isolate or mark
+	TrickBidiControl
+	// TrickPathLookalike is a character that looks like '.', '/' or '\' or
+	// normalizes to one of them
+	TrickPathLookalike
+	// TrickFullwidth is a fullwidth form of an ASCII character
+	TrickFullwidth
+)
+
+// String returns the name of the kind
+func (k UnicodeTrickKind) String() string {
+	switch k {
+	case TrickInvisible:
+		return "invisible character"
+	case TrickBidiControl:
+		return "bidirectional control"
+	case TrickPathLookalike:
+		return "path syntax lookalike"
+	case TrickFullwidth:
+		return "fullwidth form"
+	}
+	return fmt.Sprintf("UnicodeTrickKind(%d)", int(k))
+}
+
+// UnicodeTrick is a character in a path that can disguise its meaning
+type UnicodeTrick struct {
+	Offset int // byte offset of the character in the path
+	Rune   rune
+	Kind   UnicodeTrickKind
+}
+
+// String returns a description such as "bidirectional control U+202E at offset 4"
+func (t UnicodeTrick) String() string {
+	return fmt.Sprintf("%s %U at offset %d", t.Kind, t.Rune, t.Offset)
+}
+
+// pathLookalikes are characters whose shape mimics path syntax. Some of them
+// only reach it under NFKC, others under no normalization form at all.
+var pathLookalikes = map[rune]bool{
+	'\u2044': true, // FRACTION SLASH
+	'\u2215': true, // DIVISION SLASH
+	'\u2571': true, // BOX DRAWINGS LIGHT DIAGONAL UPPER RIGHT TO LOWER LEFT
+	'\u29f8': true, // BIG SOLIDUS
+	'\u2216': true, // SET MINUS
+	'\u29f9': true, // BIG REVERSE SOLIDUS
+	'\u2024': true, // ONE DOT LEADER
+	'\u2025': true, // TWO DOT LEADER
+	'\u2026': true, // HORIZONTAL ELLIPSIS
+}
+
+// DetectUnicodeTricks returns every character in path that can make it read
+// or normalize differently from how it looks: invisible characters,
+// bidirectional controls, lookalikes of '.', '/' and '\' (including any
+// character whose NFKC form contains them) and fullwidth forms. It returns
+// nil for paths without such characters, including all ASCII paths.
+func DetectUnicodeTricks(path string) []UnicodeTrick {
+	var tricks []UnicodeTrick
+	for i, r := range path {
+		if r < utf8.RuneSelf {
+			continue
+		}
+		if kind, ok := unicodeTrickKind(r); ok {
+			tricks = append(tricks, UnicodeTrick{Offset: i, Rune: r, Kind: kind})
+		}
+	}
+	return tricks
+}
+
+// unicodeTrickKind classifies a single non-ASCII rune
+func unicodeTrickKind(r rune) (UnicodeTrickKind, bool) {
+	switch {
+	case r == '\u200b', r == '\u200c', r == '\u200d', r == '\u2060',
+		r == '\ufeff', r == '\u00ad', r == '\u180e':
+		return TrickInvisible, true
+	case r >= '\u202a' && r <= '\u202e', r >= '\u2066' && r <= '\u2069',
+		r == '\u200e', r == '\u200f', r == '\u061c':
+		return TrickBidiControl, true
+	case r >= '\uff01' && r <= '\uff5e':
+		return TrickFullwidth, true
+	case pathLookalikes[r]:
+		return TrickPathLookalike, true
+	}
+	if strings.ContainsAny(norm.NFKC.String(string(r)), `./\`) {
+		return TrickPathLookalike, true
+	}
+	return 0, false
+}
+
+// checkUnicodeTricks rejects a path containing any character reported by
+// DetectUnicodeTricks
+func checkUnicodeTricks(path string) *ValidationError {
+	for i, r := range path {
+		if r < utf8.RuneSelf {
+			continue
+		}
+		if kind, ok := unicodeTrickKind(r); ok {
+			trick := UnicodeTrick{Offset: i, Rune: r, Kind: kind}
+			return &ValidationError{Path: path, Reason: "Unicode " + trick.String(), Err: ErrUnicodeTrick}
+		}
+	}
+	return nil
+}
+
+// normalizesToTraversal reports whether NFKC and Unicode folding turn a
+// non-ASCII path into one with a ".." segment, as with fullwidth dots, a
+// fraction slash or a zero-width space between two dots
+func normalizesToTraversal(path string) bool {
+	for i := 0; i < len(path); i++ {
+		if path[i] >= utf8.RuneSelf {
+			return hasDotDotSegment(strings.ReplaceAll(unicodeFolder.Replace(norm.NFKC.String(path)), `\`, "/"))
+		}
+	}
+	return false
+}
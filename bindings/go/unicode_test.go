@@ -0,0 +1,56 @@
+package pathsecurity
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDetectUnicodeTricks(t *testing.T) {
+	tests := []struct {
+		path string
+		want []UnicodeTrick
+	}{
+		{"docs/readme.md", nil},
+		{"docs/re\u0301sume\u0301.txt", nil},
+		{"\uff0e\uff0e/etc/passwd", []UnicodeTrick{{0, '\uff0e', TrickFullwidth}, {3, '\uff0e', TrickFullwidth}}},
+		{"..\u2044etc", []UnicodeTrick{{2, '\u2044', TrickPathLookalike}}},
+		{"invoice\u202egpj.exe", []UnicodeTrick{{7, '\u202e', TrickBidiControl}}},
+		{"admin\u200b.txt", []UnicodeTrick{{5, '\u200b', TrickInvisible}}},
+	}
+	for _, tt := range tests {
+		if got := DetectUnicodeTricks(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DetectUnicodeTricks(%+q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	trick := UnicodeTrick{Offset: 4, Rune: '\u202e', Kind: TrickBidiControl}
+	if got := trick.String(); got != "bidirectional control U+202E at offset 4" {
+		t.Errorf("UnicodeTrick.String() = %q", got)
+	}
+}
+
+func TestWithUnicodeNormalization(t *testing.T) {
+	tests := []struct {
+		path          string
+		wantTraversal bool
+		wantErr       error
+	}{
+		{path: "\uff0e\uff0e/etc/passwd", wantTraversal: true, wantErr: ErrTraversalDetected},
+		{path: "..\u2044etc", wantTraversal: true, wantErr: ErrUnicodeTrick},
+		{path: "docs/re\u0301sume\u0301.txt"},
+	}
+	ps := NewPathSecurity(WithUnicodeNormalization(NormalizeNFKC))
+	for _, tt := range tests {
+		if got, err := ps.DetectTraversal(tt.path); err != nil || got != tt.wantTraversal {
+			t.Errorf("DetectTraversal(%+q) = %t, %v, want %t", tt.path, got, err, tt.wantTraversal)
+		}
+		if _, err := ps.ValidatePath(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidatePath(%+q) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+
+	if _, err := ps.ValidatePath("invoice\u202egpj.exe"); !errors.Is(err, ErrUnicodeTrick) {
+		t.Errorf("ValidatePath(invoice\\u202egpj.exe) error = %v, want %v", err, ErrUnicodeTrick)
+	}
+}
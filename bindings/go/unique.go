@@ -0,0 +1,91 @@
+package pathsecurity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxUniqueAttempts is the number of candidates UniqueFilename tries after
+// the name itself
+const maxUniqueAttempts = 10000
+
+// CollisionStrategy returns the candidate UniqueFilename tries for a name
+// on the given attempt, counting from 1. It must be deterministic and
+// return a different name for every attempt.
+type CollisionStrategy func(name string, attempt int) string
+
+// NumberedSuffix is the CollisionStrategy of desktop file managers, giving
+// "report (1).pdf", "report (2).pdf" and so on
+func NumberedSuffix(name string, attempt int) string {
+	base, ext := splitFilenameExt(name)
+	return base + " (" + strconv.Itoa(attempt) + ")" + ext
+}
+
+// DashedSuffix gives "report-1.pdf", "report-2.pdf" and so on, keeping
+// names made by WithSlugFilenames free of spaces and parentheses
+func DashedSuffix(name string, attempt int) string {
+	base, ext := splitFilenameExt(name)
+	return base + "-" + strconv.Itoa(attempt) + ext
+}
+
+// HashSuffix gives names such as "report-3f9a0c1d.pdf", with eight hex
+// digits of SHA-256 over the name and attempt, so candidates do not reveal
+// how many files of the same name exist
+func HashSuffix(name string, attempt int) string {
+	base, ext := splitFilenameExt(name)
+	sum := sha256.Sum256([]byte(name + "\x00" + strconv.Itoa(attempt)))
+	return base + "-" + hex.EncodeToString(sum[:4]) + ext
+}
+
+// splitFilenameExt splits a file name before its extension, treating
+// ".tar" and the compression extension after it as one
+func splitFilenameExt(name string) (base, ext string) {
+	ext = path.Ext(name)
+	if ext == name || strings.TrimLeft(name[:len(name)-len(ext)], ".") == "" {
+		return name, ""
+	}
+	base = name[:len(name)-len(ext)]
+	if inner := path.Ext(base); strings.EqualFold(inner, ".tar") && inner != base {
+		return base[:len(base)-len(inner)], inner + ext
+	}
+	return base, ext
+}
+
+// UniqueFilename returns name if exists reports it free, or else the first
+// free candidate of strategy, NumberedSuffix if it is nil. It is meant for
+// names sanitizers have made safe, since many inputs map to the same
+// output, and checks nothing itself. Candidates of the built-in strategies
+// are longer than name by their suffix. After 10000 taken candidates the
+// error wraps ErrNoUniqueName. The name can still be taken before it is
+// used, so create the file with os.O_CREATE|os.O_EXCL and try again on
+// fs.ErrExist.
+func UniqueFilename(name string, exists func(string) bool, strategy CollisionStrategy) (string, error) {
+	if strategy == nil {
+		strategy = NumberedSuffix
+	}
+	if !exists(name) {
+		return name, nil
+	}
+	for attempt := 1; attempt <= maxUniqueAttempts; attempt++ {
+		if candidate := strategy(name, attempt); !exists(candidate) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q after %d attempts", ErrNoUniqueName, name, maxUniqueAttempts)
+}
+
+// ExistsIn returns a function for UniqueFilename that reports whether a
+// name exists in dir, counting dangling symlinks and entries that cannot be
+// inspected as taken
+func ExistsIn(dir string) func(string) bool {
+	return func(name string) bool {
+		_, err := os.Lstat(filepath.Join(dir, name))
+		return !os.IsNotExist(err)
+	}
+}
@@ -0,0 +1,46 @@
+package pathsecurity
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUniqueFilename(t *testing.T) {
+	taken := map[string]bool{"report.pdf": true, "report (1).pdf": true, "report-1.pdf": true, "backup.tar.gz": true}
+	exists := func(name string) bool { return taken[name] }
+	tests := []struct {
+		name     string
+		strategy CollisionStrategy
+		want     string
+	}{
+		{"report.pdf", nil, "report (2).pdf"},
+		{"backup.tar.gz", NumberedSuffix, "backup (1).tar.gz"},
+		{"notes.txt", NumberedSuffix, "notes.txt"},
+		{"report.pdf", DashedSuffix, "report-2.pdf"},
+		{"backup.tar.gz", DashedSuffix, "backup-1.tar.gz"},
+		{"report.pdf", HashSuffix, "report-f08764cf.pdf"},
+		{"backup.tar.gz", HashSuffix, "backup-52ce15af.tar.gz"},
+		{"notes.txt", HashSuffix, "notes.txt"},
+	}
+	for _, tt := range tests {
+		if got, err := UniqueFilename(tt.name, exists, tt.strategy); err != nil || got != tt.want {
+			t.Errorf("UniqueFilename(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	if _, err := UniqueFilename("full.txt", func(string) bool { return true }, nil); !errors.Is(err, ErrNoUniqueName) {
+		t.Errorf("UniqueFilename when every name is taken = %v, want %v", err, ErrNoUniqueName)
+	}
+}
+
+func TestExistsIn(t *testing.T) {
+	inbox := filepath.Join(testTree(t), "inbox")
+	if err := os.WriteFile(filepath.Join(inbox, "report.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := UniqueFilename("report.txt", ExistsIn(inbox), nil); err != nil || got != "report (1).txt" {
+		t.Errorf("UniqueFilename in the inbox = %q, %v, want %q", got, err, "report (1).txt")
+	}
+}
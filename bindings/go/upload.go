@@ -0,0 +1,97 @@
+package pathsecurity
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"path"
+	"strings"
+)
+
+// SafeUploadName reduces the client-supplied name of a multipart upload to a
+// single safe file name. Any directory part is discarded, treating both '/'
+// and '\' as separators since some browsers send full Windows paths, and the
+// remaining component is run through the native filename sanitizer. Names
+// that are empty or a dot reference afterwards are rejected with
+// ErrInvalidFilename.
+func (ps *PathSecurity) SafeUploadName(header *multipart.FileHeader) (string, error) {
+	if header == nil {
+		return "", errors.New("file header is nil")
+	}
+
+	name := header.Filename
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	result, err := ps.sanitizeFilename(name)
+	if err != nil {
+		return "", err
+	}
+
+	safe := result.Sanitized
+	if safe == "" || safe == "." || safe == ".." || strings.ContainsAny(safe, `/\`) {
+		return "", ps.reject(header.Filename, fmt.Errorf("%w: %q", ErrInvalidFilename, header.Filename))
+	}
+	return safe, nil
+}
+
+// CheckUpload checks a client-supplied upload file name against the
+// traversal checks and the file name policy in one call. The whole name,
+// with '\' read as '/', must pass validation; the final component must
+// not be a reserved device name and must satisfy WithMaxFilenameLength,
+// WithAllowedExtensions, WithDeniedExtensions, WithFilenamePattern and
+// WithDeniedFilenamePattern. Trailing dots and spaces, which Windows
+// strips, are ignored when reading extensions.
+func (ps *PathSecurity) CheckUpload(filename string) error {
+	if filename == "" {
+		return ps.reject(filename, fmt.Errorf("%w: %q", ErrInvalidFilename, filename))
+	}
+	p, err := ps.preprocess(filename)
+	if err != nil {
+		return err
+	}
+	if err := ps.check(strings.ReplaceAll(p, `\`, "/")); err != nil {
+		return err
+	}
+
+	name := p
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" || name == "." || name == ".." {
+		return ps.reject(filename, fmt.Errorf("%w: %q", ErrInvalidFilename, filename))
+	}
+	if IsWindowsReservedName(name) {
+		return ps.reject(filename, fmt.Errorf("%w: %q", ErrReservedName, name))
+	}
+	if max := ps.cfg.maxFilenameLength; max > 0 && len(name) > max {
+		return ps.reject(filename, fmt.Errorf("%w: %q is %d bytes, limit %d", ErrFilenameTooLong, name, len(name), max))
+	}
+
+	trimmed := strings.ToLower(strings.TrimRight(name, ". "))
+	if len(ps.cfg.deniedExtensions) > 0 {
+		// Every dotted part after the first counts, as in "shell.php.jpg"
+		parts := strings.Split(trimmed, ".")
+		for _, part := range parts[1:] {
+			if ps.cfg.deniedExtensions["."+part] {
+				return ps.reject(filename, fmt.Errorf("%w: %q in %q", ErrDeniedExtension, "."+part, name))
+			}
+		}
+	}
+	if len(ps.cfg.allowedExtensions) > 0 && !ps.cfg.allowedExtensions[path.Ext(trimmed)] {
+		return ps.reject(filename, fmt.Errorf("%w: %q", ErrExtensionNotAllowed, name))
+	}
+
+	for _, re := range ps.cfg.filenamePatterns {
+		if !re.MatchString(name) {
+			return ps.reject(filename, fmt.Errorf("%w: %q does not match %s", ErrInvalidFilename, name, re))
+		}
+	}
+	for _, re := range ps.cfg.deniedFilenamePatterns {
+		if re.MatchString(name) {
+			return ps.reject(filename, fmt.Errorf("%w: %q matches %s", ErrDeniedPattern, name, re))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+// Package upload saves multipart uploads under a destination directory,
+// combining the steps every handler otherwise glues together by hand:
+// sanitizing the client's file name, enforcing the extension and MIME type
+// policy, picking a free name and writing the file without following
+// symlinks out of the directory.
+package upload
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+var (
+	// ErrTooLarge is returned for uploads larger than Policy.MaxSize
+	ErrTooLarge = errors.New("upload too large")
+	// ErrTypeNotAllowed is returned for uploads whose content, declared
+	// type or extension has a MIME type outside Policy.AllowedTypes
+	ErrTypeNotAllowed = errors.New("upload type not allowed")
+)
+
+const (
+	// sniffLength is the number of bytes http.DetectContentType considers
+	sniffLength = 512
+	// maxCreateAttempts is the number of names Process tries when another
+	// writer takes the chosen one first
+	maxCreateAttempts = 10
+	// defaultPerm is the mode of saved files unless Policy.Perm is set
+	defaultPerm = 0o644
+)
+
+// Policy holds the rules Process applies. The zero value accepts any type
+// and size and saves with pathsecurity.NewPathSecurity().
+type Policy struct {
+	// PathSecurity sanitizes and checks the file name, and its extension
+	// and file name options, such as WithAllowedExtensions and
+	// WithDeniedExtensions, apply to the sanitized name
+	PathSecurity *pathsecurity.PathSecurity
+	// AllowedTypes lists the accepted MIME types, such as "image/png", or
+	// whole top-level types such as "image/*". If it is not empty the type
+	// sniffed from the content, the Content-Type the client declared and
+	// the type of the extension must all be in it, where known.
+	AllowedTypes []string
+	// MaxSize is the largest upload in bytes; 0 means no limit
+	MaxSize int64
+	// Collision picks another name when the sanitized one is taken; nil
+	// means pathsecurity.NumberedSuffix
+	Collision pathsecurity.CollisionStrategy
+	// Perm is the mode of the saved file before the umask; 0 means 0o644
+	Perm fs.FileMode
+	// Atomic writes the upload to a temporary file in destRoot first and
+	// links it into place once complete, so readers never see a partial
+	// file. It needs a file system with hard links.
+	Atomic bool
+	// Rewrite saves uploads whose name fails validation under the name
+	// pathsecurity.SanitizeFilename makes of it instead of rejecting them,
+	// for legacy clients that send sloppy but benign names such as "CON"
+	// or ones that sanitize to nothing, as long as the rewritten name
+	// passes CheckUpload. The extension is kept, so the extension and
+	// type policy still reject what it rejects.
+	Rewrite bool
+}
+
+// SavedFile describes an upload saved by Process
+type SavedFile struct {
+	// Name is the name of the file in the destination directory
+	Name string
+	// Path is Name joined to the destination directory
+	Path string
+	// OriginalName is the file name the client sent
+	OriginalName string
+	// Rewritten reports whether Policy.Rewrite replaced a name that
+	// failed validation
+	Rewritten bool
+	// ContentType is the MIME type sniffed from the content, without
+	// parameters
+	ContentType string
+	// Size is the number of bytes written
+	Size int64
+}
+
+// Process saves the upload in field directly inside destRoot according to
+// policy. The client's name is reduced to a single file name with
+// SafeUploadName and must then pass CheckUpload, or under Policy.Rewrite
+// is sanitized further if it fails; a name that is taken is replaced by
+// the first free candidate of Policy.Collision. The file is created with
+// OpenFileInRoot and O_EXCL, so it never replaces an existing file or
+// follows a symlink out of destRoot, and is removed again if writing
+// fails.
+func Process(field *multipart.FileHeader, destRoot string, policy Policy) (SavedFile, error) {
+	if field == nil {
+		return SavedFile{}, errors.New("file header is nil")
+	}
+	if destRoot == "" {
+		return SavedFile{}, errors.New("destination directory is empty")
+	}
+	ps := policy.PathSecurity
+	if ps == nil {
+		ps = pathsecurity.NewPathSecurity()
+	}
+
+	name, err := ps.SafeUploadName(field)
+	if err == nil {
+		err = ps.CheckUpload(name)
+	}
+	rewritten := false
+	if err != nil && policy.Rewrite {
+		if rewrite := ps.SanitizeFilename(field.Filename); rewrite != name && ps.CheckUpload(rewrite) == nil {
+			name, err, rewritten = rewrite, nil, true
+		}
+	}
+	if err != nil {
+		return SavedFile{}, err
+	}
+	if policy.MaxSize > 0 && field.Size > policy.MaxSize {
+		return SavedFile{}, fmt.Errorf("%w: %q is %d bytes, limit %d", ErrTooLarge, field.Filename, field.Size, policy.MaxSize)
+	}
+
+	src, err := field.Open()
+	if err != nil {
+		return SavedFile{}, err
+	}
+	defer src.Close()
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return SavedFile{}, err
+	}
+	head = head[:n]
+	contentType := mediaType(http.DetectContentType(head))
+	if err := policy.checkTypes(field, name, contentType); err != nil {
+		return SavedFile{}, err
+	}
+
+	saved := SavedFile{OriginalName: field.Filename, Rewritten: rewritten, ContentType: contentType}
+	content := io.MultiReader(bytes.NewReader(head), src)
+	if policy.MaxSize > 0 {
+		// Size is set by mime/multipart, but a FileHeader may be built by hand
+		content = io.LimitReader(content, policy.MaxSize+1)
+	}
+	if policy.Atomic {
+		saved.Name, saved.Size, err = policy.writeAtomic(destRoot, name, content)
+	} else {
+		saved.Name, saved.Size, err = policy.write(ps, destRoot, name, content)
+	}
+	if err != nil {
+		return SavedFile{}, err
+	}
+	saved.Path = filepath.Join(destRoot, saved.Name)
+	return saved, nil
+}
+
+// checkTypes applies AllowedTypes to the sniffed type, the declared type and
+// the type of the extension of name
+func (p *Policy) checkTypes(field *multipart.FileHeader, name, sniffed string) error {
+	if len(p.AllowedTypes) == 0 {
+		return nil
+	}
+	if !p.allowsType(sniffed) {
+		return fmt.Errorf("%w: content of %q is %s", ErrTypeNotAllowed, field.Filename, sniffed)
+	}
+	// application/octet-stream is what clients send when they do not know
+	if declared := mediaType(field.Header.Get("Content-Type")); declared != "" && declared != "application/octet-stream" && !p.allowsType(declared) {
+		return fmt.Errorf("%w: %q declared as %s", ErrTypeNotAllowed, field.Filename, declared)
+	}
+	if byExtension := mediaType(mime.TypeByExtension(path.Ext(name))); byExtension != "" && !p.allowsType(byExtension) {
+		return fmt.Errorf("%w: extension of %q is %s", ErrTypeNotAllowed, name, byExtension)
+	}
+	return nil
+}
+
+// allowsType reports whether AllowedTypes holds the media type t or its
+// top-level type with "/*"
+func (p *Policy) allowsType(t string) bool {
+	for _, allowed := range p.AllowedTypes {
+		allowed = strings.ToLower(allowed)
+		if allowed == t || strings.HasSuffix(allowed, "/*") && strings.HasPrefix(t, allowed[:len(allowed)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaType returns the lowercase media type of a Content-Type value
+// without its parameters, or "" if it is empty or malformed
+func mediaType(contentType string) string {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return t
+}
+
+// write copies content to a new file in destRoot under name or a free
+// candidate, returning the name used and the bytes written
+func (p *Policy) write(ps *pathsecurity.PathSecurity, destRoot, name string, content io.Reader) (string, int64, error) {
+	var f *os.File
+	var unique string
+	var err error
+	for attempt := 0; attempt < maxCreateAttempts; attempt++ {
+		if unique, err = pathsecurity.UniqueFilename(name, pathsecurity.ExistsIn(destRoot), p.Collision); err != nil {
+			return "", 0, err
+		}
+		f, err = ps.OpenFileInRoot(destRoot, unique, os.O_WRONLY|os.O_CREATE|os.O_EXCL, p.perm())
+		if !errors.Is(err, fs.ErrExist) {
+			break
+		}
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	size, err := p.copy(f, content)
+	if err != nil {
+		os.Remove(filepath.Join(destRoot, unique))
+		return "", 0, err
+	}
+	return unique, size, nil
+}
+
+// writeAtomic copies content to a temporary file in destRoot and links it
+// to name or a free candidate once complete, returning the name used and
+// the bytes written
+func (p *Policy) writeAtomic(destRoot, name string, content io.Reader) (string, int64, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", 0, err
+	}
+	// The temporary name is not the client's, so it is not validated,
+	// which would fail under policies such as DotfilesDeny. A single new
+	// component opened with O_EXCL cannot follow a symlink out of destRoot.
+	tempPath := filepath.Join(destRoot, ".upload-"+hex.EncodeToString(random))
+	f, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, p.perm())
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tempPath)
+
+	size, err := p.copy(f, content)
+	if err != nil {
+		return "", 0, err
+	}
+	// A link, unlike a rename, fails instead of replacing a file that
+	// another writer created under the same name in the meantime
+	var unique string
+	for attempt := 0; attempt < maxCreateAttempts; attempt++ {
+		if unique, err = pathsecurity.UniqueFilename(name, pathsecurity.ExistsIn(destRoot), p.Collision); err != nil {
+			return "", 0, err
+		}
+		if err = os.Link(tempPath, filepath.Join(destRoot, unique)); !errors.Is(err, fs.ErrExist) {
+			break
+		}
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return unique, size, nil
+}
+
+// copy writes content to f, syncs and closes it, and fails with
+// ErrTooLarge if content exceeds MaxSize
+func (p *Policy) copy(f *os.File, content io.Reader) (int64, error) {
+	size, err := io.Copy(f, content)
+	if err == nil && p.MaxSize > 0 && size > p.MaxSize {
+		err = fmt.Errorf("%w: more than %d bytes", ErrTooLarge, p.MaxSize)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return size, err
+}
+
+// perm returns the mode of saved files
+func (p *Policy) perm() fs.FileMode {
+	if p.Perm == 0 {
+		return defaultPerm
+	}
+	return p.Perm
+}
@@ -0,0 +1,118 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"reflect"
+	"testing"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+var png = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+
+// fileHeader returns the header of a file field uploaded as filename with
+// contentType, omitting the Content-Type header if it is empty
+func fileHeader(t *testing.T, filename, contentType string, content []byte) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := map[string][]string{
+		"Content-Disposition": {fmt.Sprintf(`form-data; name="file"; filename=%q`, filename)},
+	}
+	if contentType != "" {
+		header["Content-Type"] = []string{contentType}
+	}
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	writer.Close()
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["file"][0]
+}
+
+func TestProcess(t *testing.T) {
+	dest := t.TempDir()
+	policy := Policy{
+		PathSecurity: pathsecurity.NewPathSecurity(pathsecurity.WithDeniedExtensions(".php", ".exe")),
+		AllowedTypes: []string{"image/*"},
+		MaxSize:      1 << 10,
+	}
+	tests := []struct {
+		filename, contentType string
+		content               []byte
+		atomic                bool
+		want                  string
+		wantErr               error
+	}{
+		{filename: `..\..\photos\cat.png`, contentType: "image/png", content: png, want: "cat.png"},
+		{filename: "cat.png", contentType: "image/png", content: png, atomic: true, want: "cat (1).png"},
+		{filename: "cat.png", content: png, want: "cat (2).png"},
+		{filename: "shell.php.png", contentType: "image/png", content: png, wantErr: pathsecurity.ErrDeniedExtension},
+		{filename: "page.html", contentType: "text/html", content: []byte("<html><script>alert(1)</script>"), wantErr: ErrTypeNotAllowed},
+		{filename: "fake.png", contentType: "image/png", content: []byte("#!/bin/sh\nrm -rf /\n"), wantErr: ErrTypeNotAllowed},
+		{filename: "cat.png", contentType: "text/html", content: png, wantErr: ErrTypeNotAllowed},
+		{filename: "huge.png", contentType: "image/png", content: append(png, make([]byte, 2<<10)...), wantErr: ErrTooLarge},
+	}
+	for _, tt := range tests {
+		p := policy
+		p.Atomic = tt.atomic
+		saved, err := Process(fileHeader(t, tt.filename, tt.contentType, tt.content), dest, p)
+		if !errors.Is(err, tt.wantErr) || saved.Name != tt.want {
+			t.Errorf("Process(%q, %q) = %q, %v, want %q, %v", tt.filename, tt.contentType, saved.Name, err, tt.want, tt.wantErr)
+			continue
+		}
+		if err == nil && (saved.ContentType != "image/png" || saved.Size != int64(len(png)) || saved.OriginalName != tt.filename) {
+			t.Errorf("Process(%q) = %+v", tt.filename, saved)
+		}
+	}
+
+	// Rejected uploads and atomic writes leave nothing else behind
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"cat (1).png", "cat (2).png", "cat.png"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("upload directory holds %q, want %q", names, want)
+	}
+}
+
+func TestProcessRewrite(t *testing.T) {
+	dest := t.TempDir()
+	policy := Policy{PathSecurity: pathsecurity.NewPathSecurity(pathsecurity.WithDeniedExtensions(".php")), Rewrite: true}
+	if saved, err := Process(fileHeader(t, "CON.png", "image/png", png), dest, policy); err != nil || saved.Name != "_CON.png" {
+		t.Errorf("Process(CON.png) = %q, %v, want %q", saved.Name, err, "_CON.png")
+	}
+	// A denied extension is not rewritten away
+	if saved, err := Process(fileHeader(t, "shell.php", "image/png", png), dest, policy); !errors.Is(err, pathsecurity.ErrDeniedExtension) {
+		t.Errorf("Process(shell.php) = %q, %v, want %v", saved.Name, err, pathsecurity.ErrDeniedExtension)
+	}
+}
+
+func TestProcessAtomicDotfilesDeny(t *testing.T) {
+	dest := t.TempDir()
+	policy := Policy{PathSecurity: pathsecurity.NewPathSecurity(pathsecurity.WithDotfilePolicy(pathsecurity.DotfilesDeny)), Atomic: true}
+	// The temporary file is hidden, but it is not the client's name
+	if saved, err := Process(fileHeader(t, "cat.png", "image/png", png), dest, policy); err != nil || saved.Name != "cat.png" {
+		t.Errorf("Process(cat.png) = %q, %v, want %q", saved.Name, err, "cat.png")
+	}
+	if saved, err := Process(fileHeader(t, ".htaccess", "text/plain", []byte("Options +ExecCGI")), dest, policy); !errors.Is(err, pathsecurity.ErrHiddenPath) {
+		t.Errorf("Process(.htaccess) = %q, %v, want %v", saved.Name, err, pathsecurity.ErrHiddenPath)
+	}
+	if entries, err := os.ReadDir(dest); err != nil || len(entries) != 1 {
+		t.Errorf("upload directory holds %v, %v, want only cat.png", entries, err)
+	}
+}
@@ -0,0 +1,71 @@
+package pathsecurity
+
+import (
+	"errors"
+	"mime/multipart"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSafeUploadName(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+		wantErr  error
+	}{
+		{filename: "report.pdf", want: "report.pdf"},
+		{filename: `..\..\evil.php`, want: "evil.php"},
+		{filename: "../../../etc/passwd", want: "passwd"},
+		{filename: `C:\Users\me\Desktop\photo.jpg`, want: "photo.jpg"},
+		{filename: `uploads/..\..\avatar.png`, want: "avatar.png"},
+		{filename: "../", wantErr: ErrInvalidFilename},
+		{filename: `dir\..`, wantErr: ErrInvalidFilename},
+		{filename: "", wantErr: ErrInvalidFilename},
+	}
+	ps := NewPathSecurity()
+	for _, tt := range tests {
+		got, err := ps.SafeUploadName(&multipart.FileHeader{Filename: tt.filename})
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("SafeUploadName(%q) = %q, %v, want %v", tt.filename, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("SafeUploadName(%q) = %q, %v, want %q", tt.filename, got, err, tt.want)
+		}
+	}
+
+	if _, err := ps.SafeUploadName(nil); err == nil {
+		t.Error("SafeUploadName(nil) succeeded")
+	}
+}
+
+func TestCheckUpload(t *testing.T) {
+	tests := []struct {
+		filename string
+		wantErr  error
+	}{
+		{filename: "holiday.JPG"},
+		{filename: "avatar.png"},
+		{filename: "shell.php.jpg", wantErr: ErrDeniedExtension},
+		{filename: "notes.txt", wantErr: ErrExtensionNotAllowed},
+		{filename: "noextension", wantErr: ErrExtensionNotAllowed},
+		{filename: "../../avatar.png", wantErr: ErrTraversalDetected},
+		{filename: "my photo.png", wantErr: ErrInvalidFilename},
+		{filename: "CON.png", wantErr: ErrReservedName},
+		{filename: strings.Repeat("a", 61) + ".png", wantErr: ErrFilenameTooLong},
+	}
+	ps := NewPathSecurity(
+		WithAllowedExtensions("jpg", ".png"),
+		WithDeniedExtensions(".php", ".jsp", ".exe"),
+		WithMaxFilenameLength(64),
+		WithFilenamePattern(regexp.MustCompile(`^[A-Za-z0-9._-]+$`)),
+	)
+	for _, tt := range tests {
+		if err := ps.CheckUpload(tt.filename); !errors.Is(err, tt.wantErr) {
+			t.Errorf("CheckUpload(%q) = %v, want %v", tt.filename, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package pathsecurity
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ToURL validates a path and joins it onto base.Path, returning a new URL
+// with the cleaned path set. The path is rooted before cleaning so it can
+// never climb above base.Path, and RawPath is cleared so the result is
+// escaped consistently by url.URL. The base URL is not modified.
+func (ps *PathSecurity) ToURL(base *url.URL, p string) (*url.URL, error) {
+	if base == nil {
+		return nil, errors.New("base URL is nil")
+	}
+	p, err := ps.prepare(p)
+	if err != nil {
+		return nil, err
+	}
+
+	joined := path.Join("/", base.Path, path.Clean("/"+p))
+	if strings.HasSuffix(p, "/") && joined != "/" {
+		joined += "/"
+	}
+
+	u := *base
+	u.Path = joined
+	u.RawPath = ""
+	return &u, nil
+}
+
+// ValidateFileURL parses a file URL, such as the ones browsers and Electron
+// apps hand out, and validates the path it names with the full pipeline of
+// ValidatePath, returning that path after preprocessing. Percent-escapes
+// are decoded once, as the URL syntax requires; escapes left over from
+// double encoding are then subject to WithDecoding like any other input.
+// Drive letters in "file:///C:/dir", "file:///C|/dir" and the common
+// malformed "file://C:/dir" become "C:/dir", and a host other than
+// "localhost" makes the path a UNC path, "//host/dir", which
+// WithNetworkPolicy governs. Under Windows semantics the result uses '\'
+// separators. URLs with another scheme, user info, a query or a fragment,
+// or an encoded '/' or '\' that would split a name in two, are rejected
+// with an error wrapping ErrInvalidPath or ErrInvalidEncoding.
+func (ps *PathSecurity) ValidateFileURL(u string) (string, error) {
+	p, verr := fileURLPath(u)
+	if verr != nil {
+		return "", ps.reject(u, verr)
+	}
+	if ps.cfg.windowsSemantics {
+		p = strings.ReplaceAll(p, "/", `\`)
+	}
+	return ps.prepare(p)
+}
+
+// fileURLPath returns the local or UNC path a file URL names
+func fileURLPath(raw string) (string, *ValidationError) {
+	invalid := func(reason string) (string, *ValidationError) {
+		return "", &ValidationError{Path: raw, Reason: reason, Err: ErrInvalidPath}
+	}
+	u, err := url.Parse(raw)
+	switch {
+	case err != nil:
+		return invalid("Malformed file URL")
+	case !strings.EqualFold(u.Scheme, "file"):
+		return invalid("Not a file URL")
+	case u.Opaque != "":
+		return invalid("File URL without a path starting with '/'")
+	case u.User != nil:
+		return invalid("User info in file URL")
+	case u.RawQuery != "" || u.ForceQuery || u.Fragment != "" || strings.Contains(raw, "#"):
+		return invalid("Query or fragment in file URL")
+	}
+
+	escaped := strings.ToLower(u.EscapedPath())
+	if strings.Contains(escaped, "%2f") || strings.Contains(escaped, "%5c") {
+		return "", &ValidationError{Path: raw, Reason: "Encoded separator in file URL", Err: ErrInvalidEncoding}
+	}
+
+	p, host := u.Path, u.Host
+	if len(host) == 2 && isASCIILetter(host[0]) && (host[1] == ':' || host[1] == '|') {
+		p, host = "/"+host+p, ""
+	}
+	if host != "" && !strings.EqualFold(host, "localhost") {
+		return "//" + host + p, nil
+	}
+	if len(p) >= 3 && p[0] == '/' && isASCIILetter(p[1]) && (p[2] == ':' || p[2] == '|') && (len(p) == 3 || p[3] == '/' || p[3] == '\\') {
+		p = p[1:2] + ":" + p[3:]
+	}
+	return p, nil
+}
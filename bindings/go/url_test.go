@@ -0,0 +1,70 @@
+package pathsecurity
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestToURL(t *testing.T) {
+	base, err := url.Parse("https://cdn.example.com/assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path    string
+		want    string
+		wantErr error
+	}{
+		{path: "images/summer sale/banner#1.png", want: "https://cdn.example.com/assets/images/summer%20sale/banner%231.png"},
+		{path: "css/site.css", want: "https://cdn.example.com/assets/css/site.css"},
+		{path: "../../../etc/passwd", wantErr: ErrTraversalDetected},
+	}
+	ps := NewPathSecurity()
+	for _, tt := range tests {
+		u, err := ps.ToURL(base, tt.path)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ToURL(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if err == nil && u.String() != tt.want {
+			t.Errorf("ToURL(%q) = %s, want %s", tt.path, u, tt.want)
+		}
+	}
+	if base.String() != "https://cdn.example.com/assets" {
+		t.Errorf("ToURL modified the base URL: %s", base)
+	}
+}
+
+func TestValidateFileURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr error
+	}{
+		{url: "file:///home/user/My%20Documents/report.pdf", want: "/home/user/My Documents/report.pdf"},
+		{url: "file://localhost/srv/data/a.txt", want: "/srv/data/a.txt"},
+		{url: "file:///C:/Users/Public/report.pdf", want: "C:/Users/Public/report.pdf"},
+		{url: "file:///C|/Users/Public/report.pdf", want: "C:/Users/Public/report.pdf"},
+		{url: "file://C:/Users/Public/report.pdf", want: "C:/Users/Public/report.pdf"},
+		{url: "file:///srv/data/%2e%2e/%2e%2e/etc/passwd", wantErr: ErrTraversalDetected},
+		{url: "file:///srv/data/a%2F..%2F..%2Fsecret", wantErr: ErrInvalidEncoding},
+		{url: "file:///srv/data/a.txt?download=1", wantErr: ErrInvalidPath},
+		{url: "https://example.com/a.txt", wantErr: ErrInvalidPath},
+	}
+	ps := NewPathSecurity(WithWindowsSemantics(false))
+	for _, tt := range tests {
+		got, err := ps.ValidateFileURL(tt.url)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("ValidateFileURL(%q) = %q, %v, want %q, %v", tt.url, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	windows := NewPathSecurity(WithWindowsSemantics(true))
+	if got, err := windows.ValidateFileURL("file:///C:/Users/Public/report.pdf"); err != nil || got != `C:\Users\Public\report.pdf` {
+		t.Errorf("Windows ValidateFileURL = %q, %v", got, err)
+	}
+	if _, err := windows.ValidateFileURL("file:///C:/Users/Public/../../Windows/win.ini"); !errors.Is(err, ErrTraversalDetected) {
+		t.Errorf("Windows ValidateFileURL of a traversal = %v, want %v", err, ErrTraversalDetected)
+	}
+}
@@ -0,0 +1,179 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// findControlChar returns the byte offset of the first control character in
+// path, or -1 if there is none
+func findControlChar(path string) int {
+	for i, r := range path {
+		if unicode.IsControl(r) {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkControlChars rejects a path containing a NUL byte or any other
+// control character. It runs before the path is handed to the native
+// library, whose C strings would end at the first NUL and so validate only
+// the part of the path before it.
+func checkControlChars(path string) *ValidationError {
+	i := findControlChar(path)
+	if i < 0 {
+		return nil
+	}
+	if path[i] == 0 {
+		return &ValidationError{Path: path, Reason: fmt.Sprintf("Null byte detected in path at offset %d", i), Err: ErrNullByte}
+	}
+	r, _ := utf8.DecodeRuneInString(path[i:])
+	return &ValidationError{Path: path, Reason: fmt.Sprintf("Control character %U detected in path at offset %d", r, i), Err: ErrControlCharacter}
+}
+
+// isCanonicalPath is a cheap, conservative check for paths the sanitizer
+// would return unchanged: only ASCII letters, digits and "._-" in segments,
+// '/' separators, no empty segments apart from a leading root, no segment
+// starting or ending with a dot and no trailing separator. It must never
+// report true for a path the sanitizer would alter, and it does not allocate.
+func isCanonicalPath(path string) bool {
+	if path == "" {
+		return false
+	}
+
+	var prev byte = '/'
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '/':
+			if prev == '/' && i > 0 || prev == '.' {
+				return false
+			}
+		case c == '.':
+			if prev == '/' {
+				return false
+			}
+		case c == '_', c == '-',
+			'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		default:
+			return false
+		}
+		prev = c
+	}
+	return prev != '/' && prev != '.'
+}
+
+// checkPolicy applies the length, absolute path and denied pattern settings
+// to a path
+func (c *config) checkPolicy(p string) error {
+	if c.maxPathLength > 0 && len(p) > c.maxPathLength {
+		return &ValidationError{Path: p, Reason: fmt.Sprintf("Path exceeds configured maximum length of %d bytes", c.maxPathLength), Err: ErrPathTooLong}
+	}
+	if c.maxDepth > 0 {
+		if depth := namedDepth(strings.ReplaceAll(p, `\`, "/")); depth > c.maxDepth {
+			return &ValidationError{Path: p, Reason: fmt.Sprintf("Path has %d segments, more than the configured maximum of %d", depth, c.maxDepth), Err: ErrPathTooDeep}
+		}
+	}
+	if c.rejectAbsolute && c.isAbsolute(p) {
+		return &ValidationError{Path: p, Reason: "Absolute path not allowed", Err: ErrAbsolutePath}
+	}
+	if len(c.deniedPatterns) == 0 {
+		return nil
+	}
+
+	slashed := c.foldCase(strings.ReplaceAll(p, `\`, "/"))
+	candidates := append([]string{slashed}, strings.Split(slashed, "/")...)
+	for _, pattern := range c.deniedPatterns {
+		pattern = c.foldCase(pattern)
+		for _, candidate := range candidates {
+			matched, err := path.Match(pattern, candidate)
+			if err != nil {
+				return fmt.Errorf("denied pattern %q: %w", pattern, err)
+			}
+			if matched && candidate != "" {
+				return &ValidationError{Path: p, Reason: fmt.Sprintf("Path matches denied pattern %q", pattern), Err: ErrDeniedPattern}
+			}
+		}
+	}
+	return nil
+}
+
+// namedDepth counts the segments of a slash-separated path that remain
+// named after lexical cleaning
+func namedDepth(p string) int {
+	depth := 0
+	for _, segment := range strings.Split(path.Clean(p), "/") {
+		if segment != "" && segment != "." && segment != ".." {
+			depth++
+		}
+	}
+	return depth
+}
+
+// resolveParentHops resolves the ".." segments of a slash-separated path
+// lexically for WithMaxParentHops, rejecting paths that climb more levels
+// above their base than allowed
+func (c *config) resolveParentHops(p string) (string, error) {
+	if !hasDotDotSegment(p) {
+		return p, nil
+	}
+
+	absolute := strings.HasPrefix(p, "/")
+	var kept []string
+	hops := 0
+	for _, segment := range strings.Split(p, "/") {
+		if segment != ".." {
+			kept = append(kept, segment)
+			continue
+		}
+		// Step back over "." and empty segments to the last named one
+		for len(kept) > 0 && (kept[len(kept)-1] == "." || kept[len(kept)-1] == "" && !(absolute && len(kept) == 1)) {
+			kept = kept[:len(kept)-1]
+		}
+		if n := len(kept); n > 0 && kept[n-1] != "" {
+			kept = kept[:n-1]
+			continue
+		}
+		if absolute {
+			return "", &ValidationError{Path: p, Reason: "Path climbs above its root directory", Err: ErrTraversalDetected}
+		}
+		hops++
+	}
+	if hops > c.maxParentHops {
+		return "", &ValidationError{Path: p, Reason: fmt.Sprintf("Path climbs %d levels above its base, more than the configured maximum of %d", hops, c.maxParentHops), Err: ErrTraversalDetected}
+	}
+
+	resolved := strings.Join(kept, "/")
+	switch {
+	case absolute && resolved == "":
+		return "/", nil
+	case resolved == "":
+		return ".", nil
+	}
+	return resolved, nil
+}
+
+// convertSeparators rewrites the separators of p to those of the target
+// platform when WithNativeSeparators is enabled
+func (c *config) convertSeparators(p string) string {
+	if !c.nativeSeparators {
+		return p
+	}
+	if c.windowsSemantics {
+		return strings.ReplaceAll(p, "/", `\`)
+	}
+	return strings.ReplaceAll(p, `\`, "/")
+}
+
+// isAbsolute reports whether p is absolute, including Windows prefixes when
+// Windows semantics are enabled
+func (c *config) isAbsolute(p string) bool {
+	if c.windowsSemantics {
+		return ClassifyWindowsPath(p) != WindowsRelative
+	}
+	return strings.HasPrefix(p, "/")
+}
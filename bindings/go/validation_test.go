@@ -0,0 +1,119 @@
+package pathsecurity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPolicyOptions(t *testing.T) {
+	ps := NewPathSecurity(
+		WithMaxPathLength(32),
+		WithDeniedPatterns("*.env", ".git"),
+		WithAllowAbsolute(false),
+		WithCaseInsensitive(true),
+	)
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{path: "docs/readme.md"},
+		{path: "config/PROD.ENV", wantErr: ErrDeniedPattern},
+		{path: "repo/.git/config", wantErr: ErrDeniedPattern},
+		{path: "/srv/data/file.txt", wantErr: ErrAbsolutePath},
+		{path: "a/very/long/path/that/exceeds/the/limit.txt", wantErr: ErrPathTooLong},
+	}
+	for _, tt := range tests {
+		if _, err := ps.ValidatePath(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidatePath(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWithMaxParentHops(t *testing.T) {
+	ps := NewPathSecurity(WithMaxParentHops(1), WithMaxDepth(4))
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{path: "../include/util.h"},
+		{path: "src/../lib/core.h"},
+		{path: "a/../../b.h"},
+		{path: "../../etc/passwd", wantErr: ErrTraversalDetected},
+		{path: "/srv/../../etc", wantErr: ErrTraversalDetected},
+		{path: "a/b/c/d/e.h", wantErr: ErrPathTooDeep},
+		{path: "a/b/c/e.h"},
+	}
+	for _, tt := range tests {
+		if _, err := ps.ValidatePath(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidatePath(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+
+	// Encoded hops are not counted, so they stay rejected
+	if _, err := ps.ValidatePath("..%2f..%2fsecret"); err == nil {
+		t.Error("ValidatePath(..%2f..%2fsecret) succeeded")
+	}
+	if _, err := NewPathSecurity().ValidatePath("../include/util.h"); !errors.Is(err, ErrTraversalDetected) {
+		t.Errorf("parent hops accepted without WithMaxParentHops: %v", err)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	ps := NewPathSecurity(WithMaxPathLength(64))
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{"../../../etc/passwd", ErrTraversalDetected},
+		{"docs/" + strings.Repeat("a", 100), ErrPathTooLong},
+		{"docs/a\x00.txt", ErrNullByte},
+		{"docs/\u202etxt.exe", ErrUnicodeTrick},
+	}
+	for _, tt := range tests {
+		_, err := ps.ValidatePath(tt.path)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidatePath(%.40q) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+		for _, other := range []error{ErrTraversalDetected, ErrPathTooLong, ErrNullByte, ErrUnicodeTrick} {
+			if other != tt.wantErr && errors.Is(err, other) {
+				t.Errorf("ValidatePath(%.40q) error = %v, also matches %v", tt.path, err, other)
+			}
+		}
+	}
+}
+
+func TestWithTargetOS(t *testing.T) {
+	tests := []struct {
+		goos          string
+		wantDevice    error
+		wantTrailing  error
+		wantCaseFolds bool
+	}{
+		{"windows", ErrReservedName, ErrInvalidPath, true},
+		{"darwin", nil, nil, true},
+		{"linux", nil, nil, false},
+	}
+	for _, tt := range tests {
+		ps := NewPathSecurity(WithTargetOS(tt.goos))
+		// A traversal through backslashes is rejected for every target
+		if _, err := ps.ValidatePath(`reports\..\..\agent.ini`); !errors.Is(err, ErrTraversalDetected) {
+			t.Errorf("%s: ValidatePath of a backslash traversal error = %v, want %v", tt.goos, err, ErrTraversalDetected)
+		}
+		if _, err := ps.ValidatePath("dir/CON"); !errors.Is(err, tt.wantDevice) {
+			t.Errorf("%s: ValidatePath(dir/CON) error = %v, want %v", tt.goos, err, tt.wantDevice)
+		}
+		if tt.wantTrailing != nil {
+			if _, err := ps.ValidatePath("dir/file. "); !errors.Is(err, tt.wantTrailing) {
+				t.Errorf("%s: ValidatePath(\"dir/file. \") error = %v, want %v", tt.goos, err, tt.wantTrailing)
+			}
+		}
+		if got := ps.Equal("/Users/A", "/users/a"); got != tt.wantCaseFolds {
+			t.Errorf("%s: Equal(/Users/A, /users/a) = %t, want %t", tt.goos, got, tt.wantCaseFolds)
+		}
+	}
+
+	if _, err := LoadPolicy(strings.NewReader(`{"target_os": "windwos"}`)); err == nil {
+		t.Error(`LoadPolicy accepted target_os "windwos"`)
+	}
+}
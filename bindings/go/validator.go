@@ -0,0 +1,155 @@
+package pathsecurity
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// validatorBatchSize is the most queued paths a worker of a Validator
+// takes for a single ValidatePaths call
+const validatorBatchSize = 64
+
+// Validator distributes validation across a fixed pool of worker
+// goroutines, for services that validate large manifests concurrently
+// without a goroutine per path. Each worker takes the paths queued when it
+// is free, up to a small batch, and validates them with one ValidatePaths
+// call into batch and path slices it reuses, so a busy pool enters the
+// native library once per batch rather than once per path. Results are the
+// same as those of ValidatePath. A Validator is safe for concurrent use;
+// Close stops its workers.
+type Validator struct {
+	ps   *PathSecurity
+	jobs chan validationJob
+	// mu guards closed against sends on jobs racing with Close
+	mu      sync.RWMutex
+	closed  bool
+	workers sync.WaitGroup
+}
+
+// validationJob is a path waiting for a worker, with the channel its
+// result is delivered on
+type validationJob struct {
+	path   string
+	result chan<- Result
+}
+
+// NewValidator starts a Validator with the given number of workers, or
+// runtime.GOMAXPROCS(0) if workers is not positive, validating under opts.
+// Up to one batch per worker can be queued before Submit blocks.
+func NewValidator(workers int, opts ...Option) *Validator {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	v := &Validator{ps: NewPathSecurity(opts...), jobs: make(chan validationJob, workers*validatorBatchSize)}
+	v.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go v.work()
+	}
+	return v
+}
+
+// work validates queued jobs until the queue is closed
+func (v *Validator) work() {
+	defer v.workers.Done()
+	batch := make([]validationJob, 0, validatorBatchSize)
+	paths := make([]string, 0, validatorBatchSize)
+	for job := range v.jobs {
+		batch = append(batch[:0], job)
+	fill:
+		for len(batch) < validatorBatchSize {
+			select {
+			case job, ok := <-v.jobs:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, job)
+			default:
+				break fill
+			}
+		}
+
+		paths = paths[:0]
+		for _, job := range batch {
+			paths = append(paths, job.path)
+		}
+		for i, result := range v.ps.ValidatePaths(paths) {
+			batch[i].result <- result
+		}
+		clear(batch)
+	}
+}
+
+// Submit queues path for validation and returns the channel its Result is
+// delivered on, once. It blocks while the queue is full. After Close, the
+// Result carries ErrValidatorClosed.
+func (v *Validator) Submit(path string) <-chan Result {
+	result := make(chan Result, 1)
+	if err := v.submit(context.Background(), path, result); err != nil {
+		result <- Result{Path: path, Err: err}
+	}
+	return result
+}
+
+// submit queues a job, giving up when ctx is done or the Validator closed
+func (v *Validator) submit(ctx context.Context, path string, result chan<- Result) error {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.closed {
+		return ErrValidatorClosed
+	}
+	select {
+	case v.jobs <- validationJob{path: path, result: result}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ValidateAll validates paths across the workers and returns one Result per
+// path, in input order. If ctx is done first, or the Validator is closed,
+// the Err of every path not validated yet is the context's error, or
+// ErrValidatorClosed, which is also returned. Paths already queued are
+// still validated, their results dropped.
+func (v *Validator) ValidateAll(ctx context.Context, paths []string) ([]Result, error) {
+	results := make([]Result, len(paths))
+	pending := make([]chan Result, len(paths))
+	var failure error
+	for i, path := range paths {
+		pending[i] = make(chan Result, 1)
+		if failure = v.submit(ctx, path, pending[i]); failure != nil {
+			pending = pending[:i]
+			break
+		}
+	}
+	for i, result := range pending {
+		select {
+		case results[i] = <-result:
+			continue
+		case <-ctx.Done():
+		}
+		// Results ready as ctx is done are kept
+		select {
+		case results[i] = <-result:
+		default:
+			failure = ctx.Err()
+			results[i] = Result{Path: paths[i], Err: failure}
+		}
+	}
+	for i := len(pending); i < len(paths); i++ {
+		results[i] = Result{Path: paths[i], Err: failure}
+	}
+	return results, failure
+}
+
+// Close stops accepting paths and returns once the workers have validated
+// every path already queued and exited. It is safe to call more than once.
+func (v *Validator) Close() {
+	v.mu.Lock()
+	if !v.closed {
+		v.closed = true
+		close(v.jobs)
+	}
+	v.mu.Unlock()
+	v.workers.Wait()
+}
@@ -0,0 +1,48 @@
+package pathsecurity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	paths := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		if i%100 == 0 {
+			paths = append(paths, fmt.Sprintf("assets/../../etc/%d", i))
+		} else {
+			paths = append(paths, fmt.Sprintf("assets/%d.js", i))
+		}
+	}
+	pool := NewValidator(4)
+	results, err := pool.ValidateAll(context.Background(), paths)
+	if err != nil || len(results) != len(paths) {
+		t.Fatalf("ValidateAll = %d results, %v, want %d", len(results), err, len(paths))
+	}
+	// Results come back in the order of the paths
+	for i, result := range results {
+		if result.Path != paths[i] || (result.Err != nil) != (i%100 == 0) {
+			t.Errorf("result %d = %q, %v", i, result.Path, result.Err)
+		}
+	}
+
+	if result := <-pool.Submit("assets/app.css"); result.Path != "assets/app.css" || result.Err != nil {
+		t.Errorf("Submit = %q, %v", result.Path, result.Err)
+	}
+	pool.Close()
+	if result := <-pool.Submit("assets/app.css"); !errors.Is(result.Err, ErrValidatorClosed) {
+		t.Errorf("Submit after Close error = %v, want %v", result.Err, ErrValidatorClosed)
+	}
+}
+
+func TestValidatorCanceled(t *testing.T) {
+	pool := NewValidator(2)
+	defer pool.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pool.ValidateAll(ctx, []string{"a", "b", "c"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("ValidateAll with a canceled context error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,53 @@
+package pathsecurity
+
+// BindingVersion is the version of the Go binding
+const BindingVersion = "0.2.0"
+
+// Capabilities reported by Capabilities, each naming a family of checks
+// the validator performs
+const (
+	// CapabilityTraversal is detection of ".." and other traversal
+	// sequences
+	CapabilityTraversal = "traversal"
+	// CapabilityEncoding is detection of percent, overlong UTF-8 and
+	// other encoded traversal
+	CapabilityEncoding = "encoding"
+	// CapabilityUnicode is detection of bidirectional, invisible and
+	// lookalike characters
+	CapabilityUnicode = "unicode"
+	// CapabilityProtocols is rejection of URL schemes such as file://
+	CapabilityProtocols = "protocols"
+	// CapabilityWindows is detection of Windows attacks such as
+	// alternate data streams and reserved names
+	CapabilityWindows = "windows"
+	// CapabilityBatch is validation of many paths in one call, as used by
+	// ValidatePaths
+	CapabilityBatch = "batch"
+	// CapabilitySymlinks is symlink resolution within the validator.
+	// ValidateResolved and the other resolved checks resolve symlinks in
+	// the binding, whether or not the validator reports it.
+	CapabilitySymlinks = "symlinks"
+)
+
+// Version returns the version of the Go binding and of the native library
+// calls go to. clib is empty in builds with the pure-Go engine, when the
+// dlopen build fell back to it, and for libraries that predate version
+// reporting.
+func Version() (binding, clib string) {
+	return BindingVersion, backendVersion()
+}
+
+// Capabilities returns the checks of the validator in use, such as
+// CapabilityUnicode and CapabilityWindows, so callers can fail fast or
+// adjust their policy when deployed against an older native library. For
+// libraries that predate capability reporting it returns what those
+// libraries support; names that this binding does not know may appear for
+// newer ones.
+func Capabilities() []string {
+	return backendCapabilities()
+}
+
+// engineCapabilities returns the capabilities of the pure-Go engine
+func engineCapabilities() []string {
+	return []string{CapabilityTraversal, CapabilityEncoding, CapabilityUnicode, CapabilityProtocols, CapabilityWindows, CapabilityBatch}
+}
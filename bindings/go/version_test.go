@@ -0,0 +1,29 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	binding, clib := Version()
+	if binding != BindingVersion {
+		t.Errorf("Version binding = %q, want %q", binding, BindingVersion)
+	}
+	// Without the native library there is no library version to report
+	err := NativeBackend()
+	if err != nil && !errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("NativeBackend = %v, want nil or %v", err, ErrBackendUnavailable)
+	}
+	if err != nil && clib != "" {
+		t.Errorf("Version library = %q with the pure-Go engine", clib)
+	}
+
+	found := false
+	for _, c := range Capabilities() {
+		found = found || c == CapabilityTraversal
+	}
+	if !found {
+		t.Errorf("Capabilities = %q, want %q among them", Capabilities(), CapabilityTraversal)
+	}
+}
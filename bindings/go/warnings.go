@@ -0,0 +1,41 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// deepPathWarningDepth is the segment count above which a path is reported as deep
+const deepPathWarningDepth = 32
+
+// warn reports non-fatal concerns about a path that passed validation to the
+// observer registered with WithWarnObserver. It does nothing when no observer
+// is registered.
+func (ps *PathSecurity) warn(path string) {
+	observe := ps.cfg.warnObserver
+	if observe == nil {
+		return
+	}
+
+	segments := strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' })
+	if len(segments) > deepPathWarningDepth {
+		observe(path, fmt.Sprintf("path depth %d exceeds %d segments", len(segments), deepPathWarningDepth))
+	}
+
+	for _, segment := range segments {
+		if segment == "." || segment == ".." {
+			continue
+		}
+		if strings.TrimSpace(segment) != segment || strings.HasSuffix(segment, ".") {
+			observe(path, fmt.Sprintf("segment %q has surrounding spaces or a trailing dot", segment))
+		}
+	}
+
+	for _, r := range path {
+		if r >= utf8.RuneSelf {
+			observe(path, "path contains non-ASCII characters")
+			break
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package pathsecurity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithWarnObserver(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{name: "shallow", path: "/data/reports/q1.pdf"},
+		{
+			name: "deep",
+			path: "/data/" + strings.Repeat("level/", 40) + "file.txt",
+			want: []string{"path depth 42 exceeds 32 segments"},
+		},
+		{
+			name: "trailing dot",
+			path: "docs/readme./a.txt",
+			want: []string{`segment "readme." has surrounding spaces or a trailing dot`},
+		},
+		{
+			name: "non-ASCII",
+			path: "docs/résumé.pdf",
+			want: []string{"path contains non-ASCII characters"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			ps := NewPathSecurity(WithWarnObserver(func(path, warning string) {
+				if path != tt.path {
+					t.Errorf("observer called for %q, want %q", path, tt.path)
+				}
+				got = append(got, warning)
+			}))
+			if _, err := ps.ValidatePath(tt.path); err != nil {
+				t.Fatalf("ValidatePath(%q) error = %v", tt.path, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidatePath(%q) warnings = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithWarnObserverRejected(t *testing.T) {
+	called := false
+	ps := NewPathSecurity(WithWarnObserver(func(path, warning string) { called = true }))
+	if _, err := ps.ValidatePath("../" + strings.Repeat("level/", 40)); err == nil {
+		t.Fatal("ValidatePath accepted a traversal")
+	}
+	if called {
+		t.Error("observer called for a rejected path")
+	}
+}
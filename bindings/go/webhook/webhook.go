@@ -0,0 +1,419 @@
+// Package webhook posts rejection events to an HTTP endpoint in batches,
+// so small deployments without a metrics stack still see the traversal
+// attempts that reach their services. An Emitter receives events through
+// pathsecurity.WithAuditFunc and never blocks the validation that raised
+// them: events are queued, sent once a batch fills or the flush interval
+// passes, and dropped, and counted, when the queue is full.
+//
+// Each batch is POSTed as a JSON document with the content type
+// application/json:
+//
+//	{
+//	  "schema": "path-security.events/v1",
+//	  "source": "files-api",
+//	  "sent": "2026-10-14T09:30:00Z",
+//	  "dropped": 0,
+//	  "events": [
+//	    {
+//	      "time": "2026-10-14T09:29:58.123456789Z",
+//	      "decision": "rejected",
+//	      "path": "../../etc/passwd",
+//	      "rule": "Path traversal detected",
+//	      "category": "traversal",
+//	      "severity": "critical"
+//	    }
+//	  ]
+//	}
+//
+// source is the name set with WithSource, and omitted without one. dropped
+// counts the events lost since the previous batch was delivered, to a full
+// queue or to failed deliveries. Each event carries the fields of the
+// pathsecurity.Event it was made from, with the category and severity of
+// its error as RejectionCategory and RejectionSeverity report them, and
+// sanitized for sanitized paths. The schema value changes only when fields
+// are removed or change meaning.
+//
+// A delivery that fails with a network error, 408, 429 or a 5xx status is
+// retried after a backoff that doubles from WithBackoff's initial delay up
+// to its maximum, with jitter, until WithMaxAttempts attempts have been
+// made. Other statuses are not retried. Events queue up meanwhile.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+// Schema identifies the version of the JSON document batches are sent as
+const Schema = "path-security.events/v1"
+
+// Defaults used unless the corresponding option is given
+const (
+	DefaultBatchSize      = 100
+	DefaultFlushInterval  = 5 * time.Second
+	DefaultQueueSize      = 10000
+	DefaultMaxAttempts    = 5
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = time.Minute
+	DefaultTimeout        = 10 * time.Second
+)
+
+// Option configures an Emitter
+type Option func(*Emitter)
+
+// WithSource sets the "source" of every batch, naming the service the
+// events come from
+func WithSource(name string) Option {
+	return func(e *Emitter) {
+		e.source = name
+	}
+}
+
+// WithBatchSize sets the most events sent in one request. The default is
+// DefaultBatchSize; values below 1 are treated as 1.
+func WithBatchSize(n int) Option {
+	return func(e *Emitter) {
+		e.batchSize = n
+	}
+}
+
+// WithFlushInterval sets how long an event waits for its batch to fill
+// before the batch is sent anyway. The default is DefaultFlushInterval.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(e *Emitter) {
+		e.flushInterval = interval
+	}
+}
+
+// WithQueueSize sets how many events can wait to be sent before further
+// events are dropped. The default is DefaultQueueSize; values below 1 are
+// treated as 1.
+func WithQueueSize(n int) Option {
+	return func(e *Emitter) {
+		e.queueSize = n
+	}
+}
+
+// WithMaxAttempts sets how many times a batch is sent before it is
+// dropped. The default is DefaultMaxAttempts; values below 1 are treated
+// as 1.
+func WithMaxAttempts(n int) Option {
+	return func(e *Emitter) {
+		e.maxAttempts = n
+	}
+}
+
+// WithBackoff sets the delay before the first retry of a batch and the
+// longest delay between retries. The defaults are DefaultInitialBackoff and
+// DefaultMaxBackoff.
+func WithBackoff(initial, maximum time.Duration) Option {
+	return func(e *Emitter) {
+		e.initialBackoff, e.maxBackoff = initial, maximum
+	}
+}
+
+// WithClient sets the HTTP client requests are sent with. The default is a
+// client with a timeout of DefaultTimeout.
+func WithClient(client *http.Client) Option {
+	return func(e *Emitter) {
+		e.client = client
+	}
+}
+
+// WithHeader adds a header to every request, such as an Authorization
+// header the endpoint requires
+func WithHeader(key, value string) Option {
+	return func(e *Emitter) {
+		e.header.Add(key, value)
+	}
+}
+
+// WithDecisions sets the decisions whose events are sent. The default is
+// pathsecurity.DecisionRejected alone; add DecisionMonitored to also see
+// the rejections a fail threshold lets through.
+func WithDecisions(decisions ...pathsecurity.Decision) Option {
+	return func(e *Emitter) {
+		e.decisions = append([]pathsecurity.Decision(nil), decisions...)
+	}
+}
+
+// WithErrorFunc sets a function called with the error of every failed
+// delivery attempt, for logging. It is called from the Emitter's
+// goroutine.
+func WithErrorFunc(fn func(error)) Option {
+	return func(e *Emitter) {
+		e.onError = fn
+	}
+}
+
+// Event is an event as sent in a batch
+type Event struct {
+	Time time.Time `json:"time"`
+	// Decision is the name of the pathsecurity.Decision, such as "rejected"
+	Decision  string `json:"decision"`
+	Path      string `json:"path"`
+	Sanitized string `json:"sanitized,omitempty"`
+	Rule      string `json:"rule"`
+	// Category and Severity are empty for sanitized paths
+	Category string `json:"category,omitempty"`
+	Severity string `json:"severity,omitempty"`
+}
+
+// Batch is the JSON document of a request
+type Batch struct {
+	Schema  string    `json:"schema"`
+	Source  string    `json:"source,omitempty"`
+	Sent    time.Time `json:"sent"`
+	Dropped uint64    `json:"dropped"`
+	Events  []Event   `json:"events"`
+}
+
+// Emitter sends events to a webhook endpoint. It is safe for concurrent
+// use; Close flushes the queue and stops it.
+type Emitter struct {
+	url            string
+	source         string
+	batchSize      int
+	flushInterval  time.Duration
+	queueSize      int
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	client         *http.Client
+	header         http.Header
+	decisions      []pathsecurity.Decision
+	onError        func(error)
+
+	queue chan Event
+	// mu guards closed against sends on queue racing with Close
+	mu     sync.RWMutex
+	closed bool
+	// dropped counts events lost since the last delivered batch, and
+	// delivered the events delivered in all
+	dropped   atomic.Uint64
+	delivered atomic.Uint64
+	// ctx is cancelled when Close gives up waiting, aborting the request or
+	// backoff in progress
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New starts an Emitter posting to url. Pass its Emit method to
+// pathsecurity.WithAuditFunc.
+func New(url string, opts ...Option) *Emitter {
+	e := &Emitter{
+		url:            url,
+		batchSize:      DefaultBatchSize,
+		flushInterval:  DefaultFlushInterval,
+		queueSize:      DefaultQueueSize,
+		maxAttempts:    DefaultMaxAttempts,
+		initialBackoff: DefaultInitialBackoff,
+		maxBackoff:     DefaultMaxBackoff,
+		client:         &http.Client{Timeout: DefaultTimeout},
+		header:         make(http.Header),
+		decisions:      []pathsecurity.Decision{pathsecurity.DecisionRejected},
+		done:           make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.batchSize = max(e.batchSize, 1)
+	e.queueSize = max(e.queueSize, 1)
+	e.maxAttempts = max(e.maxAttempts, 1)
+	e.queue = make(chan Event, e.queueSize)
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	go e.run()
+	return e
+}
+
+// Emit queues an event for sending, unless its decision is not one of
+// those set with WithDecisions. It never blocks: when the queue is full, or
+// the Emitter closed, the event is dropped.
+func (e *Emitter) Emit(event pathsecurity.Event) {
+	if !e.sends(event.Decision) {
+		return
+	}
+	sent := Event{Time: event.Time, Decision: event.Decision.String(), Path: event.Path, Sanitized: event.Sanitized, Rule: event.Rule}
+	if event.Err != nil {
+		sent.Category, sent.Severity = pathsecurity.RejectionCategory(event.Err), pathsecurity.RejectionSeverity(event.Err).String()
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		e.dropped.Add(1)
+		return
+	}
+	select {
+	case e.queue <- sent:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// sends reports whether events of decision are sent
+func (e *Emitter) sends(decision pathsecurity.Decision) bool {
+	for _, d := range e.decisions {
+		if d == decision {
+			return true
+		}
+	}
+	return false
+}
+
+// Dropped returns the number of events dropped since the last batch was
+// delivered, which that batch's successor reports
+func (e *Emitter) Dropped() uint64 {
+	return e.dropped.Load()
+}
+
+// Delivered returns the number of events delivered since the Emitter
+// started
+func (e *Emitter) Delivered() uint64 {
+	return e.delivered.Load()
+}
+
+// Close stops accepting events and returns once the events already queued
+// have been sent, or failed to be. If ctx is done first, the delivery in
+// progress is abandoned, the remaining events are dropped and the context's
+// error is returned. It is safe to call more than once.
+func (e *Emitter) Close(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		e.cancel()
+		<-e.done
+		return ctx.Err()
+	}
+}
+
+// run collects queued events into batches and sends them until the queue
+// is closed
+func (e *Emitter) run() {
+	defer close(e.done)
+	defer e.cancel()
+	batch := make([]Event, 0, e.batchSize)
+	timer := time.NewTimer(e.flushInterval)
+	timer.Stop()
+	for {
+		select {
+		case event, ok := <-e.queue:
+			if !ok {
+				e.flush(batch)
+				return
+			}
+			if len(batch) == 0 {
+				timer.Reset(e.flushInterval)
+			}
+			batch = append(batch, event)
+			if len(batch) < e.batchSize {
+				continue
+			}
+		case <-timer.C:
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		e.flush(batch)
+		clear(batch)
+		batch = batch[:0]
+	}
+}
+
+// flush sends a batch, retrying with backoff, and counts its events as
+// dropped if every attempt fails
+func (e *Emitter) flush(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+	if e.ctx.Err() != nil {
+		e.dropped.Add(uint64(len(events)))
+		return
+	}
+	dropped := e.dropped.Swap(0)
+	body, err := json.Marshal(Batch{Schema: Schema, Source: e.source, Sent: time.Now().UTC(), Dropped: dropped, Events: events})
+	if err != nil {
+		e.dropped.Add(dropped + uint64(len(events)))
+		e.report(err)
+		return
+	}
+
+	backoff := e.initialBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := e.post(body)
+		if err == nil {
+			e.delivered.Add(uint64(len(events)))
+			return
+		}
+		e.report(err)
+		if !retry || attempt == e.maxAttempts {
+			break
+		}
+		// Jitter over the upper half of the delay keeps the retries of
+		// many emitters from arriving together
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-time.After(delay):
+		case <-e.ctx.Done():
+		}
+		if e.ctx.Err() != nil {
+			break
+		}
+		backoff = min(2*backoff, e.maxBackoff)
+	}
+	e.dropped.Add(dropped + uint64(len(events)))
+}
+
+// post sends a batch once and reports whether a failure may be retried
+func (e *Emitter) post(body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(e.ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for key, values := range e.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "path-security-go/"+pathsecurity.BindingVersion)
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("webhook: %w", err)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook: %s answered %s", e.url, resp.Status)
+	}
+	return false, fmt.Errorf("webhook: %s answered %s", e.url, resp.Status)
+}
+
+// report passes a delivery error to the function set with WithErrorFunc
+func (e *Emitter) report(err error) {
+	if e.onError != nil {
+		e.onError(err)
+	}
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+func TestEmitter(t *testing.T) {
+	var mu sync.Mutex
+	var batches []Batch
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// The first delivery fails and is retried
+		if attempts++; attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var batch Batch
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("decoding batch: %v", err)
+		}
+		batches = append(batches, batch)
+	}))
+	defer server.Close()
+
+	var failures []error
+	emitter := New(server.URL, WithSource("files-api"), WithHeader("Authorization", "Bearer test"),
+		WithBatchSize(2), WithBackoff(time.Millisecond, 5*time.Millisecond),
+		WithErrorFunc(func(err error) { failures = append(failures, err) }))
+	ps := pathsecurity.NewPathSecurity(pathsecurity.WithAuditFunc(emitter.Emit))
+	for _, p := range []string{"../../etc/passwd", "reports/q1.txt", "uploads/shell.php\x00.png", "/proc/self/root/etc/shadow"} {
+		ps.ValidatePath(p)
+	}
+	if err := emitter.Close(context.Background()); err != nil {
+		t.Fatalf("Close error = %v", err)
+	}
+
+	if emitter.Delivered() != 3 || emitter.Dropped() != 0 || attempts != 3 {
+		t.Errorf("emitter delivered %d and dropped %d events in %d attempts, want 3, 0, 3", emitter.Delivered(), emitter.Dropped(), attempts)
+	}
+	if len(failures) != 1 || !strings.Contains(failures[0].Error(), "503") {
+		t.Errorf("error function saw %v, want the 503", failures)
+	}
+	type event struct{ Decision, Path, Category, Severity string }
+	var got []event
+	for _, batch := range batches {
+		if batch.Schema != Schema || batch.Source != "files-api" {
+			t.Errorf("batch schema %q from %q", batch.Schema, batch.Source)
+		}
+		for _, e := range batch.Events {
+			got = append(got, event{e.Decision, e.Path, e.Category, e.Severity})
+		}
+	}
+	want := []event{
+		{"rejected", "../../etc/passwd", "traversal", "critical"},
+		{"rejected", "uploads/shell.php\x00.png", "null_byte", "critical"},
+		{"rejected", "/proc/self/root/etc/shadow", "magic_link", "critical"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("webhook received %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,200 @@
+package pathsecurity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WindowsPathKind classifies a path by how Windows interprets its prefix
+type WindowsPathKind int
+
+const (
+	// WindowsRelative is a path relative to the current directory, such as "a\b"
+	WindowsRelative WindowsPathKind = iota
+	// WindowsRooted is rooted on the current drive, such as "\a\b"
+	WindowsRooted
+	// WindowsDriveRelative is relative to a drive's current directory, such as "C:a"
+	WindowsDriveRelative
+	// WindowsDriveAbsolute is an absolute path on a drive, such as "C:\a"
+	WindowsDriveAbsolute
+	// WindowsUNC is a network path, such as "\\server\share\a"
+	WindowsUNC
+	// WindowsDevice is a device namespace path, such as "\\.\COM1"
+	WindowsDevice
+	// WindowsVerbatim is an extended-length path that bypasses normalization, such as "\\?\C:\a"
+	WindowsVerbatim
+)
+
+// String returns the name of the kind
+func (k WindowsPathKind) String() string {
+	switch k {
+	case WindowsRelative:
+		return "relative"
+	case WindowsRooted:
+		return "rooted"
+	case WindowsDriveRelative:
+		return "drive-relative"
+	case WindowsDriveAbsolute:
+		return "drive-absolute"
+	case WindowsUNC:
+		return "unc"
+	case WindowsDevice:
+		return "device"
+	case WindowsVerbatim:
+		return "verbatim"
+	}
+	return fmt.Sprintf("WindowsPathKind(%d)", int(k))
+}
+
+// ClassifyWindowsPath reports how Windows interprets the prefix of a path.
+// Both '/' and '\' are treated as separators, as Windows does.
+func ClassifyWindowsPath(path string) WindowsPathKind {
+	p := strings.ReplaceAll(path, "/", `\`)
+	switch {
+	case strings.HasPrefix(p, `\\?\`), strings.HasPrefix(p, `\??\`):
+		return WindowsVerbatim
+	case strings.HasPrefix(p, `\\.\`):
+		return WindowsDevice
+	case strings.HasPrefix(p, `\\`):
+		return WindowsUNC
+	case strings.HasPrefix(p, `\`):
+		return WindowsRooted
+	case len(p) >= 2 && isASCIILetter(p[0]) && p[1] == ':':
+		if len(p) > 2 && p[2] == '\\' {
+			return WindowsDriveAbsolute
+		}
+		return WindowsDriveRelative
+	}
+	return WindowsRelative
+}
+
+// IsWindowsReservedName reports whether a path component names a DOS device
+// such as CON, NUL or COM1. Windows ignores extensions and trailing dots and
+// spaces when matching, so "nul.txt" and "CON ." are reserved as well.
+func IsWindowsReservedName(component string) bool {
+	base := strings.TrimRight(component, ". ")
+	if i := strings.IndexByte(base, '.'); i >= 0 {
+		base = base[:i]
+	}
+	base = strings.ToUpper(strings.TrimRight(base, " "))
+	for _, reserved := range windowsReservedNames {
+		if base == reserved {
+			return true
+		}
+	}
+	// Superscript digits are accepted as COM and LPT port numbers
+	if len(base) > 3 && (strings.HasPrefix(base, "COM") || strings.HasPrefix(base, "LPT")) {
+		switch base[3:] {
+		case "¹", "²", "³":
+			return true
+		}
+	}
+	return false
+}
+
+// IsWindowsShortName reports whether a path component has the form of an
+// 8.3 short name such as "PROGRA~1" or "REPORT~2.TXT". Windows opens the
+// file with the long name behind such an alias, so it slips past filters
+// written for the long name.
+func IsWindowsShortName(component string) bool {
+	base, ext := component, ""
+	if i := strings.LastIndexByte(component, '.'); i >= 0 {
+		base, ext = component[:i], component[i+1:]
+	}
+	if len(base) > 8 || len(ext) > 3 || strings.IndexByte(ext, '~') >= 0 {
+		return false
+	}
+	tilde := strings.LastIndexByte(base, '~')
+	if tilde < 1 || tilde == len(base)-1 || base[tilde+1] == '0' {
+		return false
+	}
+	for i := tilde + 1; i < len(base); i++ {
+		if base[i] < '0' || base[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// AlternateDataStream returns the stream suffix of the first component
+// naming an NTFS alternate data stream, such as ":hidden:$DATA" for
+// "file.txt:hidden:$DATA", and false if there is none. A drive letter at
+// the start of the path is not a stream.
+func AlternateDataStream(path string) (string, bool) {
+	offset, stream := findAlternateDataStream(path)
+	return stream, offset >= 0
+}
+
+// findAlternateDataStream returns the offset and text of the stream suffix
+// reported by AlternateDataStream, or -1
+func findAlternateDataStream(path string) (int, string) {
+	start := 0
+	if len(path) >= 2 && isASCIILetter(path[0]) && path[1] == ':' {
+		start = 2
+	}
+	i := strings.IndexByte(path[start:], ':')
+	if i < 0 {
+		return -1, ""
+	}
+	stream := path[start+i:]
+	if end := strings.IndexAny(stream, `/\`); end >= 0 {
+		stream = stream[:end]
+	}
+	return start + i, stream
+}
+
+// checkWindowsAliases rejects names that reach a file other than the one
+// they spell: NTFS alternate data streams always, and 8.3 short names when
+// WithRejectShortNames is enabled
+func (c *config) checkWindowsAliases(path string) *ValidationError {
+	if stream, ok := AlternateDataStream(path); ok {
+		return &ValidationError{Path: path, Reason: fmt.Sprintf("NTFS alternate data stream %q", stream), Err: ErrAlternateDataStream}
+	}
+	if !c.rejectShortNames {
+		return nil
+	}
+	for _, segment := range windowsSegments(path) {
+		if IsWindowsShortName(segment) {
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("8.3 short name %q", segment), Err: ErrShortName}
+		}
+	}
+	return nil
+}
+
+// windowsSegments splits a path on both Windows separators
+func windowsSegments(path string) []string {
+	return strings.FieldsFunc(path, isSeparator)
+}
+
+// checkWindowsPath applies Windows path semantics: traversal through either
+// separator, device and verbatim prefixes, UNC paths, drive-relative paths,
+// reserved device names and components with trailing dots or spaces, which
+// Windows silently strips.
+func checkWindowsPath(path string) *ValidationError {
+	switch kind := ClassifyWindowsPath(path); kind {
+	case WindowsDevice, WindowsVerbatim:
+		return &ValidationError{Path: path, Reason: fmt.Sprintf("Windows %s path prefix", kind), Err: ErrDevicePath}
+	case WindowsUNC:
+		return &ValidationError{Path: path, Reason: "UNC path", Err: ErrUNCPath}
+	case WindowsDriveRelative:
+		return &ValidationError{Path: path, Reason: "Drive-relative path", Err: ErrInvalidPath}
+	}
+
+	segments := windowsSegments(path)
+	for i, segment := range segments {
+		if i == 0 && len(segment) == 2 && segment[1] == ':' {
+			continue
+		}
+		switch {
+		case segment == "..":
+			return &ValidationError{Path: path, Reason: "Directory traversal segment", Err: ErrTraversalDetected}
+		case segment == ".":
+			continue
+		case IsWindowsReservedName(segment):
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("Reserved device name %q", segment), Err: ErrReservedName}
+		case strings.HasSuffix(segment, ".") || strings.HasSuffix(segment, " "):
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("Trailing dot or space in component %q", segment), Err: ErrInvalidPath}
+		}
+	}
+	return nil
+}
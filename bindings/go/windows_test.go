@@ -0,0 +1,103 @@
+package pathsecurity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyWindowsPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want WindowsPathKind
+	}{
+		{`a\b`, WindowsRelative},
+		{`..\..\windows\system32`, WindowsRelative},
+		{`\a\b`, WindowsRooted},
+		{`C:a`, WindowsDriveRelative},
+		{`C:\Users\me\report.docx`, WindowsDriveAbsolute},
+		{`C:/Users/me/report.docx`, WindowsDriveAbsolute},
+		{`\\server\share\a`, WindowsUNC},
+		{`//server/share/a`, WindowsUNC},
+		{`\\.\COM1`, WindowsDevice},
+		{`\\?\C:\secret`, WindowsVerbatim},
+		{`\??\C:\secret`, WindowsVerbatim},
+	}
+	for _, tt := range tests {
+		if got := ClassifyWindowsPath(tt.path); got != tt.want {
+			t.Errorf("ClassifyWindowsPath(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+	if got := WindowsPathKind(42).String(); got != "WindowsPathKind(42)" {
+		t.Errorf("WindowsPathKind(42).String() = %q", got)
+	}
+}
+
+func TestWithWindowsSemantics(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{path: `C:\Users\me\report.docx`},
+		{path: `docs\readme.txt`},
+		{path: `..\..\windows\system32`, wantErr: ErrTraversalDetected},
+		{path: `\\?\C:\secret`, wantErr: ErrDevicePath},
+		{path: `\\.\COM1`, wantErr: ErrDevicePath},
+		{path: `logs\NUL.txt`, wantErr: ErrReservedName},
+		{path: `logs\com1`, wantErr: ErrReservedName},
+		{path: `docs\readme.txt. `, wantErr: ErrInvalidPath},
+	}
+	ps := NewPathSecurity(WithWindowsSemantics(true))
+	for _, tt := range tests {
+		if _, err := ps.ValidatePath(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidatePath(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWithRejectShortNames(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{path: "PROGRA~1/app/config.ini", wantErr: ErrShortName},
+		{path: "docs/REPORT~2.TXT", wantErr: ErrShortName},
+		{path: "file.txt:hidden:$DATA", wantErr: ErrAlternateDataStream},
+		{path: "C:/web/index.asp::$DATA", wantErr: ErrAlternateDataStream},
+		{path: "docs/report.txt"},
+	}
+	ps := NewPathSecurity(WithRejectShortNames(true))
+	for _, tt := range tests {
+		if _, err := ps.ValidatePath(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidatePath(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+
+	// A tilde that does not form a short name is left to the backend
+	if _, err := ps.ValidatePath("notes~draft.txt"); errors.Is(err, ErrShortName) {
+		t.Errorf("ValidatePath(notes~draft.txt) error = %v, not a short name", err)
+	}
+	if _, err := NewPathSecurity().ValidatePath("PROGRA~1/app/config.ini"); errors.Is(err, ErrShortName) {
+		t.Errorf("short names rejected without WithRejectShortNames: %v", err)
+	}
+}
+
+func TestAlternateDataStream(t *testing.T) {
+	tests := []struct {
+		path       string
+		wantStream string
+		wantOK     bool
+	}{
+		{"file.txt:hidden:$DATA", ":hidden:$DATA", true},
+		{"C:/web/index.asp::$DATA", "::$DATA", true},
+		{"notes:draft", ":draft", true},
+		{"C:/web/index.asp", "", false},
+		{`C:\web\index.asp`, "", false},
+		{"notes~draft.txt", "", false},
+	}
+	for _, tt := range tests {
+		stream, ok := AlternateDataStream(tt.path)
+		if stream != tt.wantStream || ok != tt.wantOK {
+			t.Errorf("AlternateDataStream(%q) = %q, %t, want %q, %t", tt.path, stream, ok, tt.wantStream, tt.wantOK)
+		}
+	}
+}
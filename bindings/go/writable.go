@@ -0,0 +1,74 @@
+package pathsecurity
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// CheckWritable verifies that path could be created inside root without
+// creating it: the path must pass validation and lie strictly below root,
+// and its parent directory must exist and be writable. A relative path is
+// interpreted relative to root. Containment is checked lexically and again
+// after resolving symlinks in the parent and in root, so a parent that
+// leads outside root through a symlink fails with ErrSymlinkEscape, as
+// does a path that is itself a symlink to a file outside root.
+func (ps *PathSecurity) CheckWritable(root, path string) error {
+	path, err := ps.prepare(path)
+	if err != nil {
+		return err
+	}
+
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	target := filepath.Clean(path)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(root, target)
+	}
+
+	parent := filepath.Dir(target)
+	if !withinRoot(root, parent) {
+		return ps.reject(path, fmt.Errorf("%w: %q is not inside %q", ErrTraversalDetected, path, root))
+	}
+
+	realParent, err := ps.cfg.realpath(parent)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ps.reject(path, fmt.Errorf("%w: %q", ErrParentMissing, parent))
+	}
+	if err != nil {
+		return err
+	}
+	realRoot, err := ps.cfg.realpath(root)
+	if err != nil {
+		return err
+	}
+	if !withinRoot(realRoot, realParent) {
+		return ps.reject(path, fmt.Errorf("%w: %q resolves to %q, outside %q", ErrSymlinkEscape, parent, realParent, root))
+	}
+
+	if link, err := os.Lstat(target); err == nil && link.Mode()&fs.ModeSymlink != 0 {
+		realTarget, err := ps.cfg.realpath(target)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err != nil || !withinRoot(realRoot, realTarget) {
+			return ps.reject(path, fmt.Errorf("%w: %q is a symlink leading outside %q", ErrSymlinkEscape, target, root))
+		}
+	}
+
+	info, err := os.Stat(realParent)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return ps.reject(path, fmt.Errorf("%w: %q is not a directory", ErrParentMissing, parent))
+	}
+	if !dirWritable(realParent, info) {
+		return ps.reject(path, fmt.Errorf("%w: %q", ErrParentNotWritable, parent))
+	}
+	return nil
+}
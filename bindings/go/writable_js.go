@@ -0,0 +1,31 @@
+//go:build js
+
+package pathsecurity
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// dirWritable reports whether dir carries any write permission bit, as
+// the host file system offers no access check
+func dirWritable(_ string, info fs.FileInfo) bool {
+	return info.Mode().Perm()&0o222 != 0
+}
+
+// worldWritable reports whether every user may write to the file, or
+// create entries in the directory, described by info, as Node.js reports
+// the modes of the host
+func worldWritable(info fs.FileInfo) bool {
+	return info.Mode().Perm()&0o002 != 0
+}
+
+// fileOwner returns the user and group IDs owning the file described by
+// info
+func fileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
@@ -0,0 +1,73 @@
+package pathsecurity
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckWritable(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "inbox"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "passwd"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	links := map[string]string{
+		"escape":      outside,
+		"inside":      filepath.Join(root, "inbox"),
+		"passwd":      filepath.Join(outside, "passwd"),
+		"dangling":    filepath.Join(outside, "missing"),
+		"inbox/local": filepath.Join(root, "notes.txt"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+	}
+
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{path: "inbox/new.txt"},
+		{path: "inside/new.txt"},
+		{path: "inbox/local"},
+		{path: "missing/new.txt", wantErr: ErrParentMissing},
+		{path: "notes.txt/new.txt", wantErr: ErrParentMissing},
+		{path: "../outside.txt", wantErr: ErrTraversalDetected},
+		{path: "escape/new.txt", wantErr: ErrSymlinkEscape},
+		{path: "passwd", wantErr: ErrSymlinkEscape},
+		{path: "dangling", wantErr: ErrSymlinkEscape},
+	}
+	ps := NewPathSecurity()
+	for _, tt := range tests {
+		err := ps.CheckWritable(root, tt.path)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("CheckWritable(%q) = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckWritableNotWritable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root may write to any directory")
+	}
+	root := t.TempDir()
+	locked := filepath.Join(root, "locked")
+	if err := os.Mkdir(locked, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+
+	if err := NewPathSecurity().CheckWritable(root, "locked/new.txt"); !errors.Is(err, ErrParentNotWritable) {
+		t.Errorf("CheckWritable(%q) = %v, want %v", "locked/new.txt", err, ErrParentNotWritable)
+	}
+}
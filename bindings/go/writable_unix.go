@@ -0,0 +1,32 @@
+//go:build unix
+
+package pathsecurity
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// accessWrite is the W_OK mode bit for access(2)
+const accessWrite = 0x2
+
+// dirWritable reports whether the current process may create entries in dir
+func dirWritable(dir string, _ fs.FileInfo) bool {
+	return syscall.Access(dir, accessWrite) == nil
+}
+
+// worldWritable reports whether every user may write to the file, or
+// create entries in the directory, described by info
+func worldWritable(info fs.FileInfo) bool {
+	return info.Mode().Perm()&0o002 != 0
+}
+
+// fileOwner returns the user and group IDs owning the file described by
+// info
+func fileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
@@ -0,0 +1,15 @@
+module github.com/redasgard/path-security/bindings/go/yamlpolicy
+
+go 1.21
+
+require (
+	github.com/redasgard/path-security/bindings/go v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace github.com/redasgard/path-security/bindings/go => ../
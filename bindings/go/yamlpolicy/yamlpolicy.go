@@ -0,0 +1,38 @@
+// Package yamlpolicy reads Path Security policies written in YAML, for
+// configuration management that keeps its files in YAML rather than JSON.
+// It is a module of its own so that the bindings do not depend on a YAML
+// library.
+package yamlpolicy
+
+import (
+	"fmt"
+	"io"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadPolicyYAML reads a YAML PolicySpec from r, using the field names of
+// its yaml tags, and builds a Policy from it with pathsecurity.NewPolicy.
+// Like pathsecurity.LoadPolicy, it rejects unknown fields, so a misspelled
+// rule fails loudly instead of being ignored.
+func LoadPolicyYAML(r io.Reader, opts ...pathsecurity.Option) (*pathsecurity.Policy, error) {
+	spec, err := DecodeSpec(r)
+	if err != nil {
+		return nil, err
+	}
+	return pathsecurity.NewPolicy(spec, opts...)
+}
+
+// DecodeSpec reads a YAML PolicySpec from r, rejecting unknown fields. Pass
+// the result to Policy.Update to reload a policy loaded with
+// LoadPolicyYAML.
+func DecodeSpec(r io.Reader) (pathsecurity.PolicySpec, error) {
+	var spec pathsecurity.PolicySpec
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&spec); err != nil {
+		return pathsecurity.PolicySpec{}, fmt.Errorf("policy: %w", err)
+	}
+	return spec, nil
+}
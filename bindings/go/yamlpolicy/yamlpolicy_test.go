@@ -0,0 +1,95 @@
+package yamlpolicy
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	pathsecurity "github.com/redasgard/path-security/bindings/go"
+)
+
+const testPolicy = `
+roots:
+  - path: /srv/static
+    permissions: r--
+  - path: /srv/uploads
+    permissions: rw-
+denied_patterns: ["*.bak", ".git"]
+decoding: recursive
+max_depth: 8
+reject_severity: high
+`
+
+const testPolicyJSON = `{
+	"roots": [{"path": "/srv/static", "permissions": "r--"}, {"path": "/srv/uploads", "permissions": "rw-"}],
+	"denied_patterns": ["*.bak", ".git"],
+	"decoding": "recursive",
+	"max_depth": 8,
+	"reject_severity": "high"
+}`
+
+func TestLoadPolicyYAML(t *testing.T) {
+	policy, err := LoadPolicyYAML(strings.NewReader(testPolicy))
+	if err != nil {
+		t.Fatalf("LoadPolicyYAML error = %v", err)
+	}
+	want, err := pathsecurity.LoadPolicy(strings.NewReader(testPolicyJSON))
+	if err != nil {
+		t.Fatalf("LoadPolicy error = %v", err)
+	}
+	if got, want := policy.Spec(), want.Spec(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Spec() = %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{path: "/srv/static/css/site.css"},
+		{path: "/srv/static/db.bak", wantErr: pathsecurity.ErrDeniedPattern},
+		{path: "/srv/uploads/aux.txt", wantErr: pathsecurity.ErrSeverityExceeded},
+		{path: "/srv/static/%252e%252e/etc", wantErr: pathsecurity.ErrTraversalDetected},
+		{path: "/srv/static/a/b/c/d/e/f/g/h.css", wantErr: pathsecurity.ErrPathTooDeep},
+	}
+	for _, tt := range tests {
+		if err := policy.Check(tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("Check(%q) = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadPolicyYAMLErrors(t *testing.T) {
+	for _, spec := range []string{
+		"denied_patern: [\"*.bak\"]",
+		"decoding: twice",
+		"roots: [{path: /srv, permissions: rwz}]",
+		"[",
+		"max_depth: deep",
+	} {
+		if _, err := LoadPolicyYAML(strings.NewReader(spec)); err == nil {
+			t.Errorf("LoadPolicyYAML(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestDecodeSpec(t *testing.T) {
+	spec, err := DecodeSpec(strings.NewReader(testPolicy))
+	if err != nil {
+		t.Fatalf("DecodeSpec error = %v", err)
+	}
+	policy, err := pathsecurity.NewPolicy(pathsecurity.PolicySpec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policy.Update(spec); err != nil {
+		t.Fatalf("Update error = %v", err)
+	}
+	if err := policy.Check("/srv/static/db.bak"); !errors.Is(err, pathsecurity.ErrDeniedPattern) {
+		t.Errorf("Check after Update = %v, want %v", err, pathsecurity.ErrDeniedPattern)
+	}
+
+	if _, err := DecodeSpec(strings.NewReader("denied_patern: [\"*.bak\"]")); err == nil {
+		t.Error("DecodeSpec accepted an unknown field")
+	}
+}